@@ -11,6 +11,17 @@ import (
 	"github.com/dreamup/qa-agent/internal/agent"
 	"github.com/dreamup/qa-agent/internal/evaluator"
 	"github.com/dreamup/qa-agent/internal/reporter"
+	"github.com/dreamup/qa-agent/internal/session"
+)
+
+const (
+	// lambdaCleanupReserve is kept free before the invocation deadline for uploads and cleanup
+	lambdaCleanupReserve = 30 * time.Second
+	// lambdaSessionOverhead estimates loading, start detection and evaluation time
+	lambdaSessionOverhead = 90 * time.Second
+	// defaultLambdaGameplay and minLambdaGameplay bound the gameplay phase
+	defaultLambdaGameplay = 30 * time.Second
+	minLambdaGameplay     = 10 * time.Second
 )
 
 // LambdaEvent represents the input event for Lambda
@@ -19,10 +30,12 @@ type LambdaEvent struct {
 	GameURL string `json:"game_url"`
 	// Timeout in seconds (default: 280 for 5min Lambda - buffer)
 	Timeout int `json:"timeout,omitempty"`
-	// UploadToS3 determines if artifacts should be uploaded
+	// UploadToS3 determines if artifacts should be uploaded (to S3 or GCS, per STORAGE_BACKEND)
 	UploadToS3 bool `json:"upload_to_s3"`
-	// BucketName for S3 uploads (optional, defaults to env var)
+	// BucketName for uploads (optional, defaults to S3_BUCKET_NAME or GCS_BUCKET_NAME)
 	BucketName string `json:"bucket_name,omitempty"`
+	// MaxDuration is the gameplay time in seconds (default 30, capped by the time budget)
+	MaxDuration int `json:"max_duration,omitempty"`
 	// Metadata for the test
 	Metadata map[string]string `json:"metadata,omitempty"`
 }
@@ -33,7 +46,7 @@ type LambdaResponse struct {
 	Success bool `json:"success"`
 	// ReportID is the unique report identifier
 	ReportID string `json:"report_id,omitempty"`
-	// ReportURL is the S3 URL (if uploaded)
+	// ReportURL is the storage URL (if uploaded)
 	ReportURL string `json:"report_url,omitempty"`
 	// Status is the test outcome (passed, failed, error)
 	Status string `json:"status,omitempty"`
@@ -71,105 +84,63 @@ func HandleRequest(ctx context.Context, event LambdaEvent) (LambdaResponse, erro
 		event.Timeout = 240 // 5 min Lambda - 60s buffer (was 280 - 20s)
 	}
 
-	// Create context with timeout
+	// Create context with timeout, ending early enough before the invocation's own
+	// deadline to leave time for uploads and cleanup
 	testCtx, cancel := context.WithTimeout(ctx, time.Duration(event.Timeout)*time.Second)
 	defer cancel()
+	if deadline, ok := ctx.Deadline(); ok {
+		testCtx, cancel = context.WithDeadline(testCtx, deadline.Add(-lambdaCleanupReserve))
+		defer cancel()
+	}
 
-	// Initialize report builder
-	reportBuilder := reporter.NewReportBuilder(event.GameURL)
-	reportBuilder.AddMetadata("lambda_execution", "true")
-	reportBuilder.AddMetadata("lambda_region", os.Getenv("AWS_REGION"))
+	gameplay := gameplayBudget(testCtx, event.MaxDuration)
+
+	chromeOptions, err := agent.ChromeOptionsFromEnv()
+	if err != nil {
+		return LambdaResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid Chrome configuration: %v", err),
+		}, nil
+	}
 
+	metadata := map[string]string{
+		"lambda_execution": "true",
+		"lambda_region":    os.Getenv("AWS_REGION"),
+		"gameplay_budget":  gameplay.String(),
+	}
 	// Add custom metadata
 	for k, v := range event.Metadata {
-		reportBuilder.AddMetadata(k, v)
+		metadata[k] = v
 	}
 
-	// Run test with retry logic
-	var report *reporter.Report
-	var screenshots []*agent.Screenshot
-	var logFilepath string
-
-	err := agent.WithRetry(testCtx, func() error {
-		// Create browser manager (always headless in lambda)
-		bm, err := agent.NewBrowserManager(true)
-		if err != nil {
-			return agent.NewBrowserError("failed to create browser", err)
-		}
-		defer bm.Close()
-
-		// Start console logger
-		consoleLogger := agent.NewConsoleLogger()
-		if err := consoleLogger.StartCapture(bm.GetContext()); err != nil {
-			// Non-fatal, continue without console logs
-			fmt.Fprintf(os.Stderr, "Warning: console logger failed: %v\n", err)
-		}
-
-		// Load game
-		if err := bm.LoadGame(event.GameURL); err != nil {
-			return agent.NewNetworkError("failed to load game", err)
-		}
-
-		// Capture initial screenshot
-		initialScreenshot, err := agent.CaptureScreenshot(bm.GetContext(), agent.ContextInitial)
-		if err != nil {
-			return agent.NewBrowserError("failed to capture initial screenshot", err)
-		}
-		if err := initialScreenshot.SaveToTemp(); err != nil {
-			return agent.NewStorageError("failed to save screenshot", err)
-		}
-
-		// Wait for gameplay
-		time.Sleep(5 * time.Second)
-
-		// Capture final screenshot
-		finalScreenshot, err := agent.CaptureScreenshot(bm.GetContext(), agent.ContextFinal)
-		if err != nil {
-			return agent.NewBrowserError("failed to capture final screenshot", err)
-		}
-		if err := finalScreenshot.SaveToTemp(); err != nil {
-			return agent.NewStorageError("failed to save screenshot", err)
-		}
-
-		screenshots = []*agent.Screenshot{initialScreenshot, finalScreenshot}
-
-		// Save console logs
-		logPath, err := consoleLogger.SaveToTemp()
-		if err != nil {
-			// Non-fatal
-			fmt.Fprintf(os.Stderr, "Warning: failed to save logs: %v\n", err)
-		} else {
-			logFilepath = logPath
-		}
-
-		// Evaluate with LLM (with retry)
-		logs := consoleLogger.GetLogs()
-		gameEval, err := evaluator.NewGameEvaluator("")
-		if err != nil {
-			// Non-fatal - continue without evaluation
-			fmt.Fprintf(os.Stderr, "Warning: LLM evaluator unavailable: %v\n", err)
-		} else {
-			score, err := gameEval.EvaluateGame(testCtx, screenshots, logs)
-			if err != nil {
-				// Log but don't fail
-				fmt.Fprintf(os.Stderr, "Warning: LLM evaluation failed: %v\n", err)
-			} else {
-				reportBuilder.SetScore(score)
-			}
-		}
-
-		// Build report
-		reportBuilder.SetScreenshots(screenshots)
-		reportBuilder.SetConsoleLogs(logs)
-
-		builtReport, err := reportBuilder.Build()
-		if err != nil {
-			return fmt.Errorf("failed to build report: %w", err)
-		}
+	// Keep LLM retries short to stay within the Lambda's time budget
+	var gameEval session.Evaluator = skipEvaluation{}
+	if ge, err := evaluator.NewGameEvaluator(""); err != nil {
+		// Non-fatal - continue without evaluation
+		fmt.Fprintf(os.Stderr, "Warning: LLM evaluator unavailable: %v\n", err)
+	} else {
+		ge.SetRetryConfig(agent.RetryConfig{
+			MaxAttempts:     2,
+			InitialDelay:    2 * time.Second,
+			MaxDelay:        5 * time.Second,
+			BackoffFactor:   2.0,
+			RetryableErrors: []agent.ErrorCategory{agent.ErrorCategoryLLM},
+		})
+		gameEval = lenientEvaluator{ge}
+	}
 
-		report = builtReport
-		return nil
+	// Run the same pipeline as the server (always headless in lambda)
+	var artifacts session.Artifacts
+	report, err := session.RunSession(testCtx, session.Options{
+		URL:            event.GameURL,
+		Headless:       true,
+		MaxDuration:    gameplay,
+		BrowserOptions: chromeOptions,
+		Metadata:       metadata,
+		Evaluator:      gameEval,
+		Artifacts:      &artifacts,
 	})
+	defer removeArtifacts(&artifacts)
 
 	duration := time.Since(startTime)
 
@@ -200,39 +171,98 @@ func HandleRequest(ctx context.Context, event LambdaEvent) (LambdaResponse, erro
 		Duration: duration.Seconds(),
 	}
 
-	// Upload to S3 if requested
+	// Upload artifacts if requested (STORAGE_BACKEND selects s3 or gcs)
 	if event.UploadToS3 {
-		bucketName := event.BucketName
-		if bucketName == "" {
-			bucketName = os.Getenv("S3_BUCKET_NAME")
+		store, err := reporter.NewArtifactStore(event.BucketName)
+		// S3_PRESIGN=true keeps an S3 bucket private and returns presigned URLs, valid for
+		// S3_PRESIGN_TTL or until the function's role credentials expire
+		s3Uploader, isS3 := store.(*reporter.S3Uploader)
+		presign := err == nil && isS3 && os.Getenv("S3_PRESIGN") == "true"
+		var presignTTL time.Duration
+		if presign {
+			presignTTL, err = reporter.PresignTTLFromEnv()
 		}
-
-		uploader, err := reporter.NewS3Uploader(bucketName, "")
 		if err != nil {
 			// Non-fatal
-			fmt.Fprintf(os.Stderr, "Warning: S3 upload skipped: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Warning: Artifact upload skipped: %v\n", err)
 		} else {
-			err = uploader.UploadReportWithArtifacts(testCtx, report, screenshots, logFilepath)
+			if presign {
+				s3Uploader.SetPresign(presignTTL)
+			}
+
+			// Upload with the invocation's context: the test budget may already be spent
+			err = reporter.UploadReportWithArtifacts(ctx, store, report, artifacts.Screenshots, artifacts.ConsoleLogPath, artifacts.VideoPath)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: S3 upload failed: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Warning: Artifact upload failed: %v\n", err)
+			} else if presign {
+				response.ReportURL, err = s3Uploader.PresignReportURL(ctx, report.ReportID, presignTTL)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: Failed to presign report URL: %v\n", err)
+				}
 			} else {
-				response.ReportURL = uploader.GetReportURL(report.ReportID)
+				response.ReportURL = reporter.ReportURL(store, report.ReportID)
 			}
 		}
 	}
 
 	// Clean up temp files
 	os.Remove(reportPath)
-	if logFilepath != "" {
-		os.Remove(logFilepath)
+
+	return response, nil
+}
+
+// gameplayBudget returns how long to play: the requested seconds (default 30), shortened so
+// the rest of the session still fits before ctx's deadline
+func gameplayBudget(ctx context.Context, requestedSeconds int) time.Duration {
+	gameplay := defaultLambdaGameplay
+	if requestedSeconds > 0 {
+		gameplay = time.Duration(requestedSeconds) * time.Second
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if available := time.Until(deadline) - lambdaSessionOverhead; available < gameplay {
+			gameplay = available
+		}
+	}
+	if gameplay < minLambdaGameplay {
+		gameplay = minLambdaGameplay
 	}
-	for _, ss := range screenshots {
+	return gameplay
+}
+
+// skipEvaluation stands in for the evaluator when no API key is configured
+type skipEvaluation struct{}
+
+func (skipEvaluation) EvaluateGame(ctx context.Context, screenshots []*agent.Screenshot, logs []agent.ConsoleLog) (*evaluator.PlayabilityScore, error) {
+	return nil, nil
+}
+
+// lenientEvaluator reports a failed evaluation as a missing score rather than failing the test
+type lenientEvaluator struct {
+	*evaluator.GameEvaluator
+}
+
+func (e lenientEvaluator) EvaluateGame(ctx context.Context, screenshots []*agent.Screenshot, logs []agent.ConsoleLog) (*evaluator.PlayabilityScore, error) {
+	score, err := e.GameEvaluator.EvaluateGame(ctx, screenshots, logs)
+	if err != nil {
+		// Log but don't fail
+		fmt.Fprintf(os.Stderr, "Warning: LLM evaluation failed: %v\n", err)
+		return nil, nil
+	}
+	return score, nil
+}
+
+// removeArtifacts deletes a session's temp files
+func removeArtifacts(artifacts *session.Artifacts) {
+	for _, path := range []string{artifacts.ConsoleLogPath, artifacts.VideoPath, artifacts.NetworkLogPath} {
+		if path != "" {
+			os.Remove(path)
+		}
+	}
+	for _, ss := range artifacts.Screenshots {
 		if ss.Filepath != "" {
 			os.Remove(ss.Filepath)
 		}
 	}
-
-	return response, nil
 }
 
 func main() {
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dreamup/qa-agent/internal/reporter"
+)
+
+// exitCodeGateFailed is the exit code when a test ran but failed its --min-score or
+// --fail-on-critical gate, so CI can tell a failing game from a test that couldn't run (1)
+const exitCodeGateFailed = 2
+
+// exitError is an error that exits the CLI with a specific code
+type exitError struct {
+	code int
+	msg  string
+}
+
+func (e *exitError) Error() string {
+	return e.msg
+}
+
+// checkGate returns an exitError if the report fails the CI gate: an overall score below
+// minScore (0 = no minimum) or, with failOnCritical, any critical issue
+func checkGate(report *reporter.Report, minScore int, failOnCritical bool) error {
+	var reasons []string
+	if minScore > 0 {
+		if report.Score == nil {
+			reasons = append(reasons, fmt.Sprintf("no overall score to compare with --min-score %d (is OPENAI_API_KEY set?)", minScore))
+		} else if report.Score.OverallScore < minScore {
+			reasons = append(reasons, fmt.Sprintf("overall score %d is below %d", report.Score.OverallScore, minScore))
+		}
+	}
+	if failOnCritical && len(report.Summary.CriticalIssues) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d critical issue(s): %s",
+			len(report.Summary.CriticalIssues), strings.Join(report.Summary.CriticalIssues, "; ")))
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+	return &exitError{code: exitCodeGateFailed, msg: "test gate failed: " + strings.Join(reasons, ", ")}
+}
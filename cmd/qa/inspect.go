@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+	"github.com/dreamup/qa-agent/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Inspect command flags
+	inspectURL      string
+	inspectHeadless bool
+	inspectJSON     bool
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Show what the agent detects on a game URL without playing it",
+	Long: `Load a game URL and report the UI elements and start button the agent detects,
+plus a suggested game mechanics description to fill in. Nothing is played or
+evaluated, so this is a quick way to check a new game before a full test.`,
+	RunE: runInspect,
+}
+
+func init() {
+	inspectCmd.Flags().StringVarP(&inspectURL, "url", "u", "", "Game URL to inspect (required)")
+	inspectCmd.Flags().BoolVar(&inspectHeadless, "headless", true, "Run browser in headless mode")
+	inspectCmd.Flags().BoolVar(&inspectJSON, "json", false, "Print the inspection as JSON")
+
+	inspectCmd.MarkFlagRequired("url")
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	chromeOptions, err := agent.ChromeOptionsFromEnv()
+	if err != nil {
+		return fmt.Errorf("invalid Chrome configuration: %w", err)
+	}
+
+	if !inspectJSON {
+		fmt.Printf("🔍 Inspecting %s...\n", inspectURL)
+	}
+	inspection, err := session.Inspect(cmd.Context(), session.Options{
+		URL:            inspectURL,
+		Headless:       inspectHeadless,
+		BrowserOptions: chromeOptions,
+	})
+	if err != nil {
+		return fmt.Errorf("inspection failed: %w", err)
+	}
+
+	if inspectJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(inspection)
+	}
+
+	fmt.Printf("\n🧩 Detected Elements:\n")
+	if len(inspection.Elements) == 0 {
+		fmt.Println("   (none)")
+	}
+	names := make([]string, 0, len(inspection.Elements))
+	for name := range inspection.Elements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		element := inspection.Elements[name]
+		if element.Text != "" {
+			fmt.Printf("   %s: %s (%q)\n", name, element.Selector, element.Text)
+		} else {
+			fmt.Printf("   %s: %s\n", name, element.Selector)
+		}
+	}
+	if frame := inspection.GameFrame; frame != nil {
+		fmt.Printf("   Game iframe: %dx%d at (%d, %d) %s\n", frame.Width, frame.Height, frame.X, frame.Y, frame.URL)
+	}
+
+	fmt.Printf("\n👁️  Vision Start Button:\n")
+	switch {
+	case inspection.StartButton != "":
+		fmt.Printf("   %q\n", inspection.StartButton)
+	case inspection.VisionError != "":
+		fmt.Printf("   ⚠️  %s\n", inspection.VisionError)
+	default:
+		fmt.Println("   (none found)")
+	}
+
+	fmt.Printf("\n📝 Suggested Game Mechanics:\n")
+	for _, line := range strings.Split(strings.TrimSpace(inspection.SuggestedMechanics), "\n") {
+		fmt.Printf("   %s\n", line)
+	}
+
+	fmt.Printf("\n📸 Screenshot: data/media/%s\n", inspection.Screenshot.Filepath)
+	fmt.Printf("⏱️  Took %.1f seconds\n", inspection.Duration.Seconds())
+	return nil
+}
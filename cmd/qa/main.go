@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -15,6 +16,10 @@ var (
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
@@ -31,4 +36,6 @@ and evaluate game functionality using LLM analysis.`,
 func init() {
 	// Add subcommands here
 	rootCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(inspectCmd)
+	rootCmd.AddCommand(replayCmd)
 }
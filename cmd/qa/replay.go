@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+	"github.com/dreamup/qa-agent/internal/evaluator"
+	"github.com/dreamup/qa-agent/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Replay command flags
+	replayActions  string
+	replayURL      string
+	replayOutput   string
+	replayHeadless bool
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Replay a test's recorded actions on a game URL",
+	Long: `Load a game URL and send it the clicks, drags, key and gamepad events recorded
+during an earlier test, with their original timing, instead of playing it. Fresh
+screenshots and video are captured and a new report is generated, so a bug found
+by a test can be reproduced and checked after a fix.
+
+The actions file can be a saved report, the response of
+GET /api/reports/{id}/actions, or a JSON array of actions.`,
+	RunE: runReplay,
+}
+
+func init() {
+	replayCmd.Flags().StringVarP(&replayActions, "actions", "a", "", "File with the recorded actions to replay (required)")
+	replayCmd.Flags().StringVarP(&replayURL, "url", "u", "", "Game URL to replay the actions on (required)")
+	replayCmd.Flags().StringVarP(&replayOutput, "output", "o", "./qa-results", "Output directory for test results")
+	replayCmd.Flags().BoolVar(&replayHeadless, "headless", true, "Run browser in headless mode")
+
+	replayCmd.MarkFlagRequired("actions")
+	replayCmd.MarkFlagRequired("url")
+}
+
+// loadRecordedActions reads actions from a JSON array, an {"actions": [...]} object or a
+// full report
+func loadRecordedActions(path string) ([]agent.RecordedAction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read actions file: %w", err)
+	}
+
+	var actions []agent.RecordedAction
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &actions)
+	} else {
+		var file struct {
+			Actions  []agent.RecordedAction `json:"actions"`
+			Evidence *struct {
+				Actions []agent.RecordedAction `json:"actions"`
+			} `json:"evidence"`
+		}
+		err = json.Unmarshal(data, &file)
+		actions = file.Actions
+		if file.Evidence != nil && len(actions) == 0 {
+			actions = file.Evidence.Actions
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid actions file %s: %w", path, err)
+	}
+	if len(actions) == 0 {
+		return nil, fmt.Errorf("no recorded actions in %s", path)
+	}
+	return actions, nil
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	actions, err := loadRecordedActions(replayActions)
+	if err != nil {
+		return err
+	}
+	first, last := actions[0], actions[len(actions)-1]
+
+	fmt.Printf("🚀 DreamUp QA Agent v%s\n", version)
+	fmt.Printf("⏯️  Replay Configuration:\n")
+	fmt.Printf("   URL: %s\n", replayURL)
+	fmt.Printf("   Actions: %d over %v\n", len(actions), agent.ReplayDelay(first, last))
+	fmt.Printf("   Output Directory: %s\n", replayOutput)
+	fmt.Printf("   Headless Mode: %v\n", replayHeadless)
+	fmt.Println()
+
+	if err := EnsureOutputDir(replayOutput); err != nil {
+		return err
+	}
+
+	chromeOptions, err := agent.ChromeOptionsFromEnv()
+	if err != nil {
+		return fmt.Errorf("invalid Chrome configuration: %w", err)
+	}
+
+	var gameEval session.Evaluator
+	if ge, err := evaluator.NewGameEvaluator(""); err != nil {
+		fmt.Printf("⚠️  Warning: Could not initialize evaluator: %v\n", err)
+		fmt.Println("   Skipping AI evaluation (set OPENAI_API_KEY to enable)")
+		gameEval = skipEvaluation{}
+	} else {
+		gameEval = ge
+	}
+
+	fmt.Println("🎮 Replaying recorded actions...")
+	var artifacts session.Artifacts
+	report, err := session.RunSession(cmd.Context(), session.Options{
+		URL:            replayURL,
+		Headless:       replayHeadless,
+		Replay:         actions,
+		BrowserOptions: chromeOptions,
+		Metadata: map[string]string{
+			"agent_version": version,
+			"replay_file":   replayActions,
+		},
+		Progress: func(percent int, message string) {
+			fmt.Printf("   [%3d%%] %s\n", percent, message)
+		},
+		Evaluator: gameEval,
+		Artifacts: &artifacts,
+	})
+	if err != nil {
+		return fmt.Errorf("replay failed: %w", err)
+	}
+
+	return printReport(os.Stdout, report, artifacts, "")
+}
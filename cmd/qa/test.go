@@ -2,22 +2,41 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"time"
 
-	"github.com/chromedp/chromedp"
 	"github.com/dreamup/qa-agent/internal/agent"
 	"github.com/dreamup/qa-agent/internal/evaluator"
 	"github.com/dreamup/qa-agent/internal/reporter"
+	"github.com/dreamup/qa-agent/internal/session"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Test command flags
-	testURL       string
-	outputDir     string
-	headless      bool
-	maxDuration   int
+	testURL        string
+	outputDir      string
+	headless       bool
+	maxDuration    int
+	renderTimeout  int
+	shotInterval   int
+	maxScreenshots int
+	keyHoldMs      int
+	annotate       bool
+	shotFormat     string
+	shotQuality    int
+	seed           int64
+	minScore       int
+	failOnCritical bool
+	outputFormat   string
+	a11yTags       []string
+	a11yWeights    string
+	junitPath      string
+	ensembleRuns   int
+	ensembleModels []string
 )
 
 var testCmd = &cobra.Command{
@@ -25,7 +44,10 @@ var testCmd = &cobra.Command{
 	Short: "Run QA test on a game URL",
 	Long: `Execute a QA test session on a specified game URL.
 The agent will launch a browser, navigate to the game, interact with it,
-capture screenshots, and generate a test report.`,
+capture screenshots, and generate a test report.
+
+Exit codes: 0 when the test ran (and passed --min-score and --fail-on-critical,
+if given), 1 when it couldn't run, 2 when it ran but failed one of those gates.`,
 	RunE: runTest,
 }
 
@@ -34,254 +56,279 @@ func init() {
 	testCmd.Flags().StringVarP(&testURL, "url", "u", "", "Game URL to test (required)")
 	testCmd.Flags().StringVarP(&outputDir, "output", "o", "./qa-results", "Output directory for test results")
 	testCmd.Flags().BoolVar(&headless, "headless", true, "Run browser in headless mode")
-	testCmd.Flags().IntVarP(&maxDuration, "max-duration", "d", 300, "Maximum test duration in seconds")
+	testCmd.Flags().IntVarP(&maxDuration, "max-duration", "d", 60, "Gameplay duration in seconds")
+	testCmd.Flags().IntVar(&renderTimeout, "render-timeout", 15, "Seconds the game gets to render before the test fails (-1 to skip the check)")
+	testCmd.Flags().IntVar(&shotInterval, "screenshot-interval", 2, "Seconds between gameplay screenshots")
+	testCmd.Flags().IntVar(&maxScreenshots, "max-screenshots", agent.DefaultMaxScreenshots, "Gameplay screenshots to keep; longer runs are thinned evenly")
+	testCmd.Flags().IntVar(&keyHoldMs, "key-hold", 0, "Milliseconds to hold each gameplay key (0 taps keys; for games that need sustained input)")
+	testCmd.Flags().BoolVar(&annotate, "annotate", false, "Also save each screenshot marked up with the actions taken after it (data/media/annotated_*)")
+	testCmd.Flags().StringVar(&shotFormat, "screenshot-format", "png", "Screenshot encoding: png, or jpeg for smaller files and faster vision requests")
+	testCmd.Flags().IntVar(&shotQuality, "screenshot-quality", agent.DefaultJPEGQuality, "JPEG screenshot quality (1-100)")
+	testCmd.Flags().Int64Var(&seed, "seed", 0, "Seed for random gameplay input, to reproduce a run (0 picks one; reports show the seed used)")
+	testCmd.Flags().IntVar(&minScore, "min-score", 0, fmt.Sprintf("Exit with code %d if the overall score is below this (0 = no minimum; needs OPENAI_API_KEY)", exitCodeGateFailed))
+	testCmd.Flags().BoolVar(&failOnCritical, "fail-on-critical", false, fmt.Sprintf("Exit with code %d if the report has critical issues", exitCodeGateFailed))
+	testCmd.Flags().StringVar(&outputFormat, "format", "human", "Output format: human, or json to print the full report on stdout (progress goes to stderr)")
+	testCmd.Flags().StringSliceVar(&a11yTags, "a11y-tags", nil, "axe rule tags for the accessibility audit (default wcag2a,wcag2aa,wcag21aa)")
+	testCmd.Flags().StringVar(&a11yWeights, "a11y-weights", "", "Accessibility score weights, e.g. critical=15,serious=10,moderate=5,minor=2,half=100")
+	testCmd.Flags().StringVar(&junitPath, "junit", "", "Write a JUnit XML report to this path (for CI)")
+	testCmd.Flags().IntVar(&ensembleRuns, "ensemble", 1, fmt.Sprintf("Evaluate N times and report median scores (max %d)", evaluator.MaxEnsembleRuns))
+	testCmd.Flags().StringSliceVar(&ensembleModels, "ensemble-models", nil, "Rotate ensemble runs through these evaluation models (comma-separated)")
 
 	// Mark required flags
 	testCmd.MarkFlagRequired("url")
 }
 
 func runTest(cmd *cobra.Command, args []string) error {
-	fmt.Printf("🚀 DreamUp QA Agent v%s\n", version)
-	fmt.Printf("📋 Test Configuration:\n")
-	fmt.Printf("   URL: %s\n", testURL)
-	fmt.Printf("   Output Directory: %s\n", outputDir)
-	fmt.Printf("   Headless Mode: %v\n", headless)
-	fmt.Printf("   Max Duration: %d seconds\n", maxDuration)
-	fmt.Println()
-
-	// Initialize report builder
-	reportBuilder := reporter.NewReportBuilder(testURL)
-	reportBuilder.AddMetadata("agent_version", version)
-	reportBuilder.AddMetadata("headless", fmt.Sprintf("%v", headless))
-
-	// Ensure output directory exists
-	if err := EnsureOutputDir(outputDir); err != nil {
-		return err
+	if minScore < 0 || minScore > 100 {
+		return fmt.Errorf("--min-score must be between 0 and 100")
 	}
 
-	fmt.Println("🌐 Starting browser...")
-	// Create browser manager
-	bm, err := agent.NewBrowserManager()
+	weights, err := agent.ParseAccessibilityWeights(a11yWeights)
 	if err != nil {
-		return fmt.Errorf("failed to create browser manager: %w", err)
+		return fmt.Errorf("--a11y-weights: %w", err)
 	}
-	defer bm.Close()
 
-	fmt.Println("📝 Starting console log capture...")
-	// Create and start console logger
-	consoleLogger := agent.NewConsoleLogger()
-	if err := consoleLogger.StartCapture(bm.GetContext()); err != nil {
-		return fmt.Errorf("failed to start console logger: %w", err)
+	// With --format json, stdout carries only the report; progress goes to stderr
+	var out io.Writer = os.Stdout
+	switch outputFormat {
+	case "human":
+	case "json":
+		out = os.Stderr
+	default:
+		return fmt.Errorf("--format must be human or json")
 	}
 
-	fmt.Printf("📍 Navigating to %s...\n", testURL)
-	// Load the game URL
-	if err := bm.LoadGame(testURL); err != nil {
-		return fmt.Errorf("failed to load game: %w", err)
+	fmt.Fprintf(out, "🚀 DreamUp QA Agent v%s\n", version)
+	fmt.Fprintf(out, "📋 Test Configuration:\n")
+	fmt.Fprintf(out, "   URL: %s\n", testURL)
+	fmt.Fprintf(out, "   Output Directory: %s\n", outputDir)
+	fmt.Fprintf(out, "   Headless Mode: %v\n", headless)
+	fmt.Fprintf(out, "   Max Duration: %d seconds\n", maxDuration)
+	fmt.Fprintln(out)
+
+	// Ensure output directory exists
+	if err := EnsureOutputDir(outputDir); err != nil {
+		return err
 	}
 
-	fmt.Println("📸 Capturing initial screenshot...")
-	// Capture initial screenshot
-	initialScreenshot, err := agent.CaptureScreenshot(bm.GetContext(), agent.ContextInitial)
+	chromeOptions, err := agent.ChromeOptionsFromEnv()
 	if err != nil {
-		return fmt.Errorf("failed to capture initial screenshot: %w", err)
+		return fmt.Errorf("invalid Chrome configuration: %w", err)
 	}
-
-	if err := initialScreenshot.SaveToTemp(); err != nil {
-		return fmt.Errorf("failed to save initial screenshot: %w", err)
+	screenshotFormat, err := agent.ParseScreenshotFormat(shotFormat, shotQuality)
+	if err != nil {
+		return fmt.Errorf("invalid --screenshot-format/--screenshot-quality: %w", err)
 	}
-	fmt.Printf("   Saved: %s\n", initialScreenshot.Filepath)
-
-	// Wait for page resources to load (reduced from 4s to 2s)
-	fmt.Println("⏳ Waiting for page to load...")
-	time.Sleep(2 * time.Second)
-
-	// Handle cookie consent if present
-	fmt.Println("🍪 Checking for cookie consent...")
-	detector := agent.NewUIDetector(bm.GetContext())
-	var uiWarnings []string
-	if clicked, err := detector.AcceptCookieConsent(); err != nil {
-		warning := fmt.Sprintf("Cookie consent check failed: %v", err)
-		uiWarnings = append(uiWarnings, warning)
-		fmt.Printf("   ⚠️  Warning: %s\n", warning)
-	} else if clicked {
-		fmt.Println("   ✅ Cookie consent accepted")
-		// Brief wait for dialog animation (reduced from 1s to 500ms)
-		time.Sleep(500 * time.Millisecond)
+	chromeOptions = append(chromeOptions, agent.WithScreenshotFormat(screenshotFormat))
+
+	// Evaluation is optional locally; without an API key the report simply has no score
+	var gameEval session.Evaluator
+	if ge, err := evaluator.NewGameEvaluator(""); err != nil {
+		fmt.Fprintf(out, "⚠️  Warning: Could not initialize evaluator: %v\n", err)
+		fmt.Fprintln(out, "   Skipping AI evaluation (set OPENAI_API_KEY to enable)")
+		gameEval = skipEvaluation{}
 	} else {
-		fmt.Println("   No cookie consent dialog detected")
+		if ensembleRuns > evaluator.MaxEnsembleRuns {
+			return fmt.Errorf("--ensemble must be at most %d", evaluator.MaxEnsembleRuns)
+		}
+		ge.SetEnsemble(ensembleRuns, ensembleModels...)
+		gameEval = ge
 	}
 
-	// Try to find and click the start/play button
-	fmt.Println("🎮 Looking for start button...")
-	if clicked, err := detector.ClickStartButton(); err != nil {
-		warning := fmt.Sprintf("Start button check failed: %v", err)
-		uiWarnings = append(uiWarnings, warning)
-		fmt.Printf("   ⚠️  Warning: %s\n", warning)
-	} else if clicked {
-		fmt.Println("   ✅ Game started!")
-		// Brief wait for game initialization (reduced from 1s to 500ms)
-		time.Sleep(500 * time.Millisecond)
-	} else {
-		fmt.Println("   No start button detected, game may auto-start")
+	fmt.Fprintln(out, "🎮 Running test session...")
+	var artifacts session.Artifacts
+	report, err := session.RunSession(cmd.Context(), session.Options{
+		URL:                  testURL,
+		Headless:             headless,
+		MaxDuration:          time.Duration(maxDuration) * time.Second,
+		RenderTimeout:        time.Duration(renderTimeout) * time.Second,
+		ScreenshotInterval:   time.Duration(shotInterval) * time.Second,
+		MaxScreenshots:       maxScreenshots,
+		KeyHold:              time.Duration(keyHoldMs) * time.Millisecond,
+		AnnotateScreenshots:  annotate,
+		Seed:                 seed,
+		AccessibilityTags:    a11yTags,
+		AccessibilityWeights: weights,
+		BrowserOptions:       chromeOptions,
+		Metadata:             map[string]string{"agent_version": version},
+		Progress: func(percent int, message string) {
+			fmt.Fprintf(out, "   [%3d%%] %s\n", percent, message)
+		},
+		Evaluator: gameEval,
+		Artifacts: &artifacts,
+	})
+	if err != nil {
+		return fmt.Errorf("test failed: %w", err)
 	}
 
-	// Wait for game to render initial state (reduced from 3s to 2s)
-	fmt.Println("⏳ Waiting for gameplay...")
-	time.Sleep(2 * time.Second)
-
-	// Simulate some gameplay interactions
-	fmt.Println("🕹️  Simulating gameplay...")
-	gameplayActions := []struct {
-		key  string
-		desc string
-	}{
-		{"ArrowUp", "up"},
-		{"ArrowDown", "down"},
-		{"Space", "space"},
-		{"ArrowLeft", "left"},
-		{"ArrowRight", "right"},
+	if err := printReport(out, report, artifacts, junitPath); err != nil {
+		return err
 	}
-
-	for _, action := range gameplayActions {
-		chromedp.Run(bm.GetContext(),
-			chromedp.KeyEvent(action.key),
-		)
-		time.Sleep(200 * time.Millisecond)
+	if outputFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
 	}
 
-	fmt.Println("   Game interactions completed")
-	// Brief wait for game state to settle (reduced from 1s to 500ms)
-	time.Sleep(500 * time.Millisecond)
-
-	fmt.Println("📸 Capturing final screenshot...")
-	// Capture final screenshot
-	finalScreenshot, err := agent.CaptureScreenshot(bm.GetContext(), agent.ContextFinal)
-	if err != nil {
-		return fmt.Errorf("failed to capture final screenshot: %w", err)
+	// Fail CI pipelines when the game doesn't meet the requested bar
+	if err := checkGate(report, minScore, failOnCritical); err != nil {
+		cmd.SilenceUsage = true
+		return err
 	}
+	return nil
+}
 
-	if err := finalScreenshot.SaveToTemp(); err != nil {
-		return fmt.Errorf("failed to save final screenshot: %w", err)
+// printReport prints a finished session's results and saves the report (plus JUnit XML if
+// junitPath is set), uploading artifacts if a storage backend is configured
+func printReport(out io.Writer, report *reporter.Report, artifacts session.Artifacts, junitPath string) error {
+	// Display log summary
+	logs := report.Evidence.LogSummary
+	fmt.Fprintf(out, "\n📊 Console Log Summary:\n")
+	fmt.Fprintf(out, "   Total: %d logs\n", logs.Total)
+	fmt.Fprintf(out, "   Errors: %d\n", logs.Errors)
+	fmt.Fprintf(out, "   Uncaught Exceptions: %d\n", logs.Exceptions)
+	fmt.Fprintf(out, "   Warnings: %d\n", logs.Warnings)
+	fmt.Fprintf(out, "   Distinct: %d exceptions, %d errors, %d warnings\n", logs.UniqueExceptions, logs.UniqueErrors, logs.UniqueWarnings)
+	for _, repeated := range logs.TopRepeated {
+		fmt.Fprintf(out, "   Repeated %dx (%s): %s\n", repeated.Count, repeated.Level, repeated.Message)
 	}
-	fmt.Printf("   Saved: %s\n", finalScreenshot.Filepath)
 
-	// Save console logs
-	fmt.Println("💾 Saving console logs...")
-	logFilepath, err := consoleLogger.SaveToTemp()
-	if err != nil {
-		return fmt.Errorf("failed to save console logs: %w", err)
+	// Show the raw accessibility counts alongside the score, so they can be judged directly
+	if perf := report.Evidence.PerformanceMetrics; perf != nil && perf.Accessibility != nil {
+		a11y := perf.Accessibility
+		fmt.Fprintf(out, "\n♿ Accessibility: %d/100 (%d critical, %d serious, %d moderate, %d minor; %d elements affected)\n",
+			a11y.Score, a11y.Critical, a11y.Serious, a11y.Moderate, a11y.Minor, a11y.AffectedNodes)
 	}
-	fmt.Printf("   Saved: %s\n", logFilepath)
 
-	// Display log summary
-	logs := consoleLogger.GetLogs()
-	errors := consoleLogger.GetLogsByLevel(agent.LogLevelError)
-	warnings := consoleLogger.GetLogsByLevel(agent.LogLevelWarning)
-
-	fmt.Printf("\n📊 Console Log Summary:\n")
-	fmt.Printf("   Total: %d logs\n", len(logs))
-	fmt.Printf("   Errors: %d\n", len(errors))
-	fmt.Printf("   Warnings: %d\n", len(warnings))
-
-	// Evaluate game playability using LLM
-	fmt.Println("\n🤖 Evaluating game playability with AI...")
-	gameEval, err := evaluator.NewGameEvaluator("")
-	if err != nil {
-		fmt.Printf("⚠️  Warning: Could not initialize evaluator: %v\n", err)
-		fmt.Println("   Skipping AI evaluation (set OPENAI_API_KEY to enable)")
-	} else {
-		screenshots := []*agent.Screenshot{initialScreenshot, finalScreenshot}
-		score, err := gameEval.EvaluateGame(context.Background(), screenshots, logs)
-		if err != nil {
-			fmt.Printf("⚠️  Warning: AI evaluation failed: %v\n", err)
-		} else {
-			// Display evaluation results
-			fmt.Printf("\n🎯 AI Evaluation Results:\n")
-			fmt.Printf("   Overall Score: %d/100\n", score.OverallScore)
-			fmt.Printf("   Loads Correctly: %v\n", score.LoadsCorrectly)
-			fmt.Printf("   Interactivity: %d/100\n", score.InteractivityScore)
-			fmt.Printf("   Visual Quality: %d/100\n", score.VisualQuality)
-			fmt.Printf("   Error Severity: %d/100\n", score.ErrorSeverity)
-
-			if len(score.Issues) > 0 {
-				fmt.Printf("\n   Issues Found:\n")
-				for _, issue := range score.Issues {
-					fmt.Printf("   - %s\n", issue)
-				}
-			}
+	if score := report.Score; score != nil {
+		// Display evaluation results
+		fmt.Fprintf(out, "\n🎯 AI Evaluation Results:\n")
+		fmt.Fprintf(out, "   Overall Score: %d/100\n", score.OverallScore)
+		fmt.Fprintf(out, "   Loads Correctly: %v\n", score.LoadsCorrectly)
+		fmt.Fprintf(out, "   Interactivity: %d/100\n", score.InteractivityScore)
+		fmt.Fprintf(out, "   Visual Quality: %d/100\n", score.VisualQuality)
+		fmt.Fprintf(out, "   Error Severity: %d/100\n", score.ErrorSeverity)
+		if score.Confidence > 0 {
+			fmt.Fprintf(out, "   Confidence: %.2f\n", score.Confidence)
+		}
+		if e := score.Ensemble; e != nil {
+			fmt.Fprintf(out, "   Ensemble: %d runs, overall scores %v (std dev %.1f)\n", e.Runs, e.OverallScores, e.StdDev)
+		}
 
-			if len(score.Recommendations) > 0 {
-				fmt.Printf("\n   Recommendations:\n")
-				for _, rec := range score.Recommendations {
-					fmt.Printf("   - %s\n", rec)
+		if len(score.Issues) > 0 {
+			fmt.Fprintf(out, "\n   Issues Found:\n")
+			for _, issue := range score.Issues {
+				fmt.Fprintf(out, "   - %s\n", issue)
+			}
+			for _, ev := range score.IssueEvidence {
+				if ev.Screenshot != "" {
+					fmt.Fprintf(out, "     %q seen in the %s screenshot\n", ev.Issue, ev.Screenshot)
+				}
+				if ev.LogSnippet != "" {
+					fmt.Fprintf(out, "     %q from console: %s\n", ev.Issue, ev.LogSnippet)
 				}
 			}
-
-			fmt.Printf("\n   Reasoning: %s\n", score.Reasoning)
-
-			// Set score in report builder
-			reportBuilder.SetScore(score)
 		}
-	}
 
-	// Build report
-	fmt.Println("\n📊 Generating test report...")
-	screenshots := []*agent.Screenshot{initialScreenshot, finalScreenshot}
-	reportBuilder.SetScreenshots(screenshots)
-	reportBuilder.SetConsoleLogs(logs)
+		if len(score.Recommendations) > 0 {
+			fmt.Fprintf(out, "\n   Recommendations:\n")
+			for _, rec := range score.Recommendations {
+				fmt.Fprintf(out, "   - %s\n", rec)
+			}
+		}
 
-	// Add UI warnings to report metadata
-	for i, warning := range uiWarnings {
-		reportBuilder.AddMetadata(fmt.Sprintf("ui_warning_%d", i+1), warning)
-	}
-	if len(uiWarnings) > 0 {
-		reportBuilder.AddMetadata("ui_warning_count", fmt.Sprintf("%d", len(uiWarnings)))
+		fmt.Fprintf(out, "\n   Reasoning: %s\n", score.Reasoning)
 	}
 
-	report, err := reportBuilder.Build()
-	if err != nil {
-		return fmt.Errorf("failed to build report: %w", err)
-	}
+	fmt.Fprintln(out, "\n📊 Generating test report...")
 
 	// Save report locally
 	reportPath, err := report.SaveToTemp()
 	if err != nil {
 		return fmt.Errorf("failed to save report: %w", err)
 	}
-	fmt.Printf("   Report saved: %s\n", reportPath)
+	fmt.Fprintf(out, "   Report saved: %s\n", reportPath)
+
+	// Write JUnit XML for CI pipelines
+	if junitPath != "" {
+		if err := writeJUnitFile(report, junitPath); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "   JUnit report saved: %s\n", junitPath)
+	}
 
-	// Upload to S3 (optional)
-	s3Uploader, err := reporter.NewS3Uploader("", "")
+	// Upload artifacts (optional, STORAGE_BACKEND selects s3 or gcs)
+	store, err := reporter.NewArtifactStore("")
+	// S3_PRESIGN=true keeps an S3 bucket private and prints presigned URLs, valid for
+	// S3_PRESIGN_TTL or until temporary credentials expire
+	s3Uploader, isS3 := store.(*reporter.S3Uploader)
+	presign := err == nil && isS3 && os.Getenv("S3_PRESIGN") == "true"
+	var presignTTL time.Duration
+	if presign {
+		presignTTL, err = reporter.PresignTTLFromEnv()
+	}
 	if err != nil {
-		fmt.Printf("   ⚠️  S3 upload skipped (configure AWS credentials to enable): %v\n", err)
+		fmt.Fprintf(out, "   ⚠️  Artifact upload skipped (configure cloud credentials to enable): %v\n", err)
 	} else {
-		fmt.Println("   Uploading artifacts to S3...")
-		err = s3Uploader.UploadReportWithArtifacts(context.Background(), report, screenshots, logFilepath)
+		if presign {
+			s3Uploader.SetPresign(presignTTL)
+		}
+
+		fmt.Fprintln(out, "   Uploading artifacts...")
+		err = reporter.UploadReportWithArtifacts(context.Background(), store, report, artifacts.Screenshots, artifacts.ConsoleLogPath, artifacts.VideoPath)
 		if err != nil {
-			fmt.Printf("   ⚠️  S3 upload failed: %v\n", err)
+			fmt.Fprintf(out, "   ⚠️  Artifact upload failed: %v\n", err)
+		} else if presign {
+			reportURL, err := s3Uploader.PresignReportURL(context.Background(), report.ReportID, presignTTL)
+			if err != nil {
+				fmt.Fprintf(out, "   ⚠️  Failed to presign report URL: %v\n", err)
+			} else {
+				fmt.Fprintf(out, "   ✅ Report uploaded (presigned link): %s\n", reportURL)
+			}
 		} else {
-			s3URL := s3Uploader.GetReportURL(report.ReportID)
-			fmt.Printf("   ✅ Report uploaded: %s\n", s3URL)
+			fmt.Fprintf(out, "   ✅ Report uploaded: %s\n", reporter.ReportURL(store, report.ReportID))
 		}
 	}
 
 	// Display summary
-	fmt.Printf("\n📋 Test Summary:\n")
-	fmt.Printf("   Status: %s\n", report.Summary.Status)
-	fmt.Printf("   Duration: %.2f seconds\n", report.Duration.Seconds())
+	fmt.Fprintf(out, "\n📋 Test Summary:\n")
+	fmt.Fprintf(out, "   Status: %s\n", report.Summary.Status)
+	fmt.Fprintf(out, "   Duration: %.2f seconds\n", report.Duration.Seconds())
 	if len(report.Summary.PassedChecks) > 0 {
-		fmt.Printf("   ✅ Passed: %d checks\n", len(report.Summary.PassedChecks))
+		fmt.Fprintf(out, "   ✅ Passed: %d checks\n", len(report.Summary.PassedChecks))
 	}
 	if len(report.Summary.FailedChecks) > 0 {
-		fmt.Printf("   ⚠️  Failed: %d checks\n", len(report.Summary.FailedChecks))
+		fmt.Fprintf(out, "   ⚠️  Failed: %d checks\n", len(report.Summary.FailedChecks))
 	}
 	if len(report.Summary.CriticalIssues) > 0 {
-		fmt.Printf("   ❌ Critical: %d issues\n", len(report.Summary.CriticalIssues))
+		fmt.Fprintf(out, "   ❌ Critical: %d issues\n", len(report.Summary.CriticalIssues))
 	}
 
-	fmt.Println("\n✅ Test completed successfully!")
-	fmt.Printf("📁 Report ID: %s\n", report.ReportID)
+	fmt.Fprintln(out, "\n✅ Test completed successfully!")
+	fmt.Fprintf(out, "📁 Report ID: %s\n", report.ReportID)
 
 	return nil
 }
+
+// writeJUnitFile writes the report as JUnit XML to path
+func writeJUnitFile(report *reporter.Report, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create junit file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := reporter.WriteJUnit(report, f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// skipEvaluation stands in for the evaluator when no API key is configured
+type skipEvaluation struct{}
+
+func (skipEvaluation) EvaluateGame(ctx context.Context, screenshots []*agent.Screenshot, logs []agent.ConsoleLog) (*evaluator.PlayabilityScore, error) {
+	return nil, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/dreamup/qa-agent/internal/agent"
+	"github.com/dreamup/qa-agent/internal/db"
+	"github.com/dreamup/qa-agent/internal/reporter"
+	"github.com/dreamup/qa-agent/internal/session"
+)
+
+// loadBaseline returns the stored baseline for the job's game, or nil if it has none
+func (s *Server) loadBaseline(job *TestJob) *reporter.Baseline {
+	record, err := s.db.GetBaseline(job.Request.URL)
+	if err != nil {
+		job.logger.Printf("Warning: Failed to load visual baseline: %v", err)
+		return nil
+	}
+	if record == nil {
+		return nil
+	}
+
+	job.logger.Printf("Comparing against visual baseline from %s", record.CreatedAt.Format("2006-01-02 15:04"))
+	return &reporter.Baseline{
+		ReportID: record.ReportID,
+		Screenshot: &agent.Screenshot{
+			Context:   agent.ContextFinal,
+			Timestamp: record.CreatedAt,
+			Data:      record.Screenshot,
+			Width:     record.Width,
+			Height:    record.Height,
+		},
+	}
+}
+
+// saveBaseline stores the run's final screenshot as its game's new baseline. Runs whose
+// game never rendered are not saved, since their final screenshot is a blank page.
+func (s *Server) saveBaseline(job *TestJob, report *reporter.Report, artifacts *session.Artifacts) {
+	if report.Metadata["ended_reason"] == "failed_to_render" {
+		job.logger.Printf("Warning: Not saving visual baseline: the game failed to render")
+		return
+	}
+
+	var final *agent.Screenshot
+	for _, ss := range artifacts.Screenshots {
+		if ss.Context == agent.ContextFinal {
+			final = ss
+		}
+	}
+	if final == nil {
+		job.logger.Printf("Warning: Not saving visual baseline: no final screenshot")
+		return
+	}
+
+	if err := s.db.SaveBaseline(db.BaselineRecord{
+		GameURL:    job.Request.URL,
+		ReportID:   report.ReportID,
+		Screenshot: final.Data,
+		Width:      final.Width,
+		Height:     final.Height,
+	}); err != nil {
+		job.logger.Printf("Warning: Failed to save visual baseline: %v", err)
+		return
+	}
+	job.logger.Printf("✓ Saved final screenshot as the visual baseline for %s", job.Request.URL)
+}
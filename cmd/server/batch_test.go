@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+	"github.com/dreamup/qa-agent/internal/db"
+)
+
+// newTestServer returns a server with a temporary database whose only browser slot is
+// taken, so submitted tests stay queued instead of launching Chrome
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	s := NewServer("0", "", 1)
+	database, err := db.New(filepath.Join(t.TempDir(), "tests.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.db = database
+	if err := s.scheduler.acquire(context.Background(), priorityHigh); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		s.mu.RLock()
+		for _, job := range s.jobs {
+			job.cancel()
+		}
+		s.mu.RUnlock()
+		database.Close()
+	})
+	return s
+}
+
+func submitBatch(t *testing.T, s *Server, req BatchTestRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	s.handleBatchTestSubmit(rec, httptest.NewRequest(http.MethodPost, "/api/batch-tests", bytes.NewReader(body)))
+	return rec
+}
+
+func TestBatchTestSubmit(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := submitBatch(t, s, BatchTestRequest{
+		URLs:               []string{"https://example.com/a", "https://example.com/b"},
+		GameMechanics:      "click to jump",
+		GameMechanicsByURL: map[string]string{"https://example.com/b": "arrow keys to steer"},
+		Tags:               []string{" Nightly ", "nightly"},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp BatchTestResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.TestIDs) != 2 {
+		t.Fatalf("got %d test IDs, want 2", len(resp.TestIDs))
+	}
+
+	// Entries get the same defaults and normalization as single submissions
+	wantMechanics := []string{"click to jump", "arrow keys to steer"}
+	for i, testID := range resp.TestIDs {
+		s.mu.RLock()
+		job := s.jobs[testID]
+		s.mu.RUnlock()
+		if job == nil {
+			t.Fatalf("test %s isn't registered", testID)
+		}
+		req := job.Request
+		if req.GameMechanics != wantMechanics[i] || req.Priority != "low" || req.MaxDuration != 60 ||
+			req.Width != agent.DefaultViewportWidth || req.Height != agent.DefaultViewportHeight ||
+			!reflect.DeepEqual(req.Tags, []string{"nightly"}) {
+			t.Errorf("test %d request = %+v", i, req)
+		}
+		if record, err := s.db.GetTest(testID); err != nil || record == nil {
+			t.Errorf("test %d isn't in the database: %v", i, err)
+		}
+	}
+}
+
+func TestBatchTestSubmitEntryErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         BatchTestRequest
+		wantIndexes []int
+	}{
+		{
+			name:        "empty URL",
+			req:         BatchTestRequest{URLs: []string{"https://example.com/a", "", "https://example.com/c"}},
+			wantIndexes: []int{1},
+		},
+		{
+			name:        "invalid priority applies to every entry",
+			req:         BatchTestRequest{URLs: []string{"https://example.com/a", "https://example.com/b"}, Priority: "urgent"},
+			wantIndexes: []int{0, 1},
+		},
+		{
+			name:        "invalid tag applies to every entry",
+			req:         BatchTestRequest{URLs: []string{"https://example.com/a"}, Tags: []string{" "}},
+			wantIndexes: []int{0},
+		},
+	}
+	for _, tt := range tests {
+		s := newTestServer(t)
+		rec := submitBatch(t, s, tt.req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", tt.name, rec.Code)
+			continue
+		}
+		var resp BatchTestErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Errorf("%s: decoding error response: %v", tt.name, err)
+			continue
+		}
+		var indexes []int
+		for _, entry := range resp.Entries {
+			indexes = append(indexes, entry.Index)
+			if entry.URL != tt.req.URLs[entry.Index] || entry.Error == "" {
+				t.Errorf("%s: entry %+v doesn't describe URL %d", tt.name, entry, entry.Index)
+			}
+		}
+		if !reflect.DeepEqual(indexes, tt.wantIndexes) {
+			t.Errorf("%s: rejected entries %v, want %v", tt.name, indexes, tt.wantIndexes)
+		}
+
+		// A batch with an invalid entry starts none of its tests
+		s.mu.RLock()
+		jobs, batches := len(s.jobs), len(s.batchJobs)
+		s.mu.RUnlock()
+		if jobs != 0 || batches != 0 {
+			t.Errorf("%s: rejected batch registered %d tests and %d batches", tt.name, jobs, batches)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxRequestBodyBytes caps JSON request bodies; real requests are a few KB even with
+// scripts and cookies
+const maxRequestBodyBytes = 1 << 20
+
+// decodeJSONBody decodes the request body, a single JSON object, into v. Bodies over
+// maxRequestBodyBytes and fields v doesn't have are rejected, so a misspelt field such as
+// "gameMechanic" fails instead of being ignored. Errors describe the problem for a 400.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, v any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		var sizeErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, io.EOF):
+			return fmt.Errorf("request body is empty")
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return fmt.Errorf("request body is truncated JSON")
+		case errors.As(err, &syntaxErr):
+			return fmt.Errorf("malformed JSON at byte %d: %v", syntaxErr.Offset, err)
+		case errors.As(err, &typeErr):
+			if typeErr.Field == "" {
+				return fmt.Errorf("request body must be a JSON object")
+			}
+			return fmt.Errorf("field %q must be %s, not %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		case errors.As(err, &sizeErr):
+			return fmt.Errorf("request body exceeds %d bytes", sizeErr.Limit)
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			// encoding/json has no error type for unknown fields
+			return fmt.Errorf("unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+		default:
+			return err
+		}
+	}
+
+	if dec.More() {
+		return fmt.Errorf("request body must contain a single JSON object")
+	}
+	return nil
+}
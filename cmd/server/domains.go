@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// domainPolicy restricts which game hosts may be tested (ALLOWED_DOMAINS / DENIED_DOMAINS).
+// Entries are host names like "games.example.com", or "*.example.com" for any subdomain
+// of example.com (but not example.com itself). Denied entries win over allowed ones; an
+// empty allowlist allows every host that isn't denied.
+type domainPolicy struct {
+	allowed []string
+	denied  []string
+}
+
+// domainPolicyFromEnv reads the comma-separated ALLOWED_DOMAINS and DENIED_DOMAINS lists
+func domainPolicyFromEnv() (domainPolicy, error) {
+	allowed, err := parseDomainList(os.Getenv("ALLOWED_DOMAINS"))
+	if err != nil {
+		return domainPolicy{}, fmt.Errorf("ALLOWED_DOMAINS: %w", err)
+	}
+	denied, err := parseDomainList(os.Getenv("DENIED_DOMAINS"))
+	if err != nil {
+		return domainPolicy{}, fmt.Errorf("DENIED_DOMAINS: %w", err)
+	}
+	return domainPolicy{allowed: allowed, denied: denied}, nil
+}
+
+// parseDomainList parses a comma-separated list of host names and "*." wildcards
+func parseDomainList(value string) ([]string, error) {
+	var domains []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = normalizeHost(entry)
+		if entry == "" {
+			continue
+		}
+		host := strings.TrimPrefix(entry, "*.")
+		if host == "" || strings.ContainsAny(host, "*/:@ ") {
+			return nil, fmt.Errorf("invalid domain %q (use a host name like example.com or *.example.com)", entry)
+		}
+		domains = append(domains, entry)
+	}
+	return domains, nil
+}
+
+// normalizeHost lowercases a host name and drops a trailing dot
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// matchDomain reports whether host is pattern, or a subdomain of it for a "*." pattern
+func matchDomain(pattern, host string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(host, suffix)
+	}
+	return host == pattern
+}
+
+// restricted reports whether any allowlist or denylist is configured
+func (p domainPolicy) restricted() bool {
+	return len(p.allowed) > 0 || len(p.denied) > 0
+}
+
+// check returns an error if the game at rawURL may not be tested
+func (p domainPolicy) check(rawURL string) error {
+	if !p.restricted() {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("Invalid URL %q: %v", rawURL, err)
+	}
+	host := normalizeHost(u.Hostname())
+	if host == "" {
+		return fmt.Errorf("URL %q has no host", rawURL)
+	}
+
+	for _, pattern := range p.denied {
+		if matchDomain(pattern, host) {
+			return fmt.Errorf("Testing %s is not allowed on this server", host)
+		}
+	}
+	if len(p.allowed) == 0 {
+		return nil
+	}
+	for _, pattern := range p.allowed {
+		if matchDomain(pattern, host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("Testing %s is not allowed on this server", host)
+}
+
+// rejectDomain answers 403 if a game URL's host isn't allowed, returning true if it did
+func (s *Server) rejectDomain(w http.ResponseWriter, urls ...string) bool {
+	for _, u := range urls {
+		if err := s.domains.check(u); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// defaultShutdownGrace is how long shutdown waits for running tests when
+// SHUTDOWN_GRACE_SECONDS is unset
+const defaultShutdownGrace = 2 * time.Minute
+
+// drainCleanupWait is how long interrupted tests get to close their browsers
+const drainCleanupWait = 10 * time.Second
+
+// interruptedMessage is the status message of tests stopped by a shutdown
+const interruptedMessage = "Test interrupted by server shutdown"
+
+// isFinished reports whether a test status is final
+func isFinished(status string) bool {
+	switch status {
+	case "completed", "failed", "cancelled", "interrupted":
+		return true
+	}
+	return false
+}
+
+// isDraining reports whether shutdown has begun
+func (s *Server) isDraining() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.draining
+}
+
+// rejectDraining answers 503 if the server is shutting down, returning true if it did
+func (s *Server) rejectDraining(w http.ResponseWriter) bool {
+	if !s.isDraining() {
+		return false
+	}
+	w.Header().Set("Retry-After", "30")
+	http.Error(w, "Server is shutting down; resubmit shortly", http.StatusServiceUnavailable)
+	return true
+}
+
+// drain stops new submissions and waits up to shutdownGrace for in-flight tests. Tests
+// still unfinished after that are cancelled and marked interrupted, so they don't stay
+// "running" in the database forever.
+func (s *Server) drain() {
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(done)
+	}()
+
+	running, queued := s.scheduler.stats()
+	log.Printf("⏳ Draining: waiting up to %v for %d running tests (%d queued tests will not start)",
+		s.shutdownGrace, running, queued[priorityLow]+queued[priorityNormal]+queued[priorityHigh])
+
+	select {
+	case <-done:
+		log.Println("✅ All tests finished")
+		return
+	case <-time.After(s.shutdownGrace):
+	}
+
+	s.mu.Lock()
+	var interrupted []*TestJob
+	for _, job := range s.jobs {
+		if !isFinished(job.Status) {
+			job.Status = "interrupted"
+			job.Message = interruptedMessage
+			job.UpdatedAt = time.Now()
+			interrupted = append(interrupted, job)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, job := range interrupted {
+		job.cancel()
+		if err := s.db.UpdateTestStatusMessage(job.ID, "interrupted", interruptedMessage); err != nil {
+			log.Printf("Warning: Failed to update test status in database: %v", err)
+		}
+	}
+	log.Printf("⚠️  Interrupted %d tests still running after %v", len(interrupted), s.shutdownGrace)
+
+	// Cancelled tests close their browsers on the way out
+	select {
+	case <-done:
+	case <-time.After(drainCleanupWait):
+		log.Println("Warning: Some tests did not stop in time; their browsers may be left running")
+	}
+}
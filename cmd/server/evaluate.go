@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg" // uploads may be JPEG
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+	"github.com/dreamup/qa-agent/internal/evaluator"
+)
+
+const (
+	// maxEvaluateImageBytes caps each uploaded screenshot
+	maxEvaluateImageBytes = 10 << 20
+	// maxEvaluateLogBytes caps the uploaded console log JSON
+	maxEvaluateLogBytes = 1 << 20
+)
+
+// Score uploaded screenshots without running a browser: POST /api/evaluate.
+// Multipart form: "screenshots" (1-5 PNG or JPEG files, in capture order), optional "logs"
+// (a JSON array of {"level", "message"} console entries, as a file or field) and optional "genre".
+func (s *Server) handleEvaluate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, evaluator.MaxEvaluationImages*maxEvaluateImageBytes+maxEvaluateLogBytes)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid multipart form: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["screenshots"]
+	if len(files) == 0 {
+		http.Error(w, "At least one screenshot is required", http.StatusBadRequest)
+		return
+	}
+	if len(files) > evaluator.MaxEvaluationImages {
+		http.Error(w, fmt.Sprintf("At most %d screenshots are allowed", evaluator.MaxEvaluationImages), http.StatusBadRequest)
+		return
+	}
+
+	screenshots := make([]*agent.Screenshot, 0, len(files))
+	for i, fh := range files {
+		if fh.Size > maxEvaluateImageBytes {
+			http.Error(w, fmt.Sprintf("Screenshot %q exceeds %d MB", fh.Filename, maxEvaluateImageBytes>>20), http.StatusBadRequest)
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read screenshot %q: %v", fh.Filename, err), http.StatusBadRequest)
+			return
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read screenshot %q: %v", fh.Filename, err), http.StatusBadRequest)
+			return
+		}
+		screenshot, err := uploadedScreenshot(data)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Screenshot %q: %v", fh.Filename, err), http.StatusBadRequest)
+			return
+		}
+
+		// Label them like a test's screenshots: the first and last bracket gameplay
+		screenshot.Context = agent.ContextGameplay
+		if i == 0 {
+			screenshot.Context = agent.ContextInitial
+		} else if i == len(files)-1 {
+			screenshot.Context = agent.ContextFinal
+		}
+		screenshots = append(screenshots, screenshot)
+	}
+
+	logs, err := uploadedLogs(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid logs: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	genre, err := evaluator.ParseGenre(r.FormValue("genre"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gameEval, err := evaluator.NewGameEvaluator("")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Evaluator initialization failed: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	gameEval.SetGenre(genre)
+
+	// The server's WriteTimeout can be shorter than an evaluation with retries
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(5 * time.Minute)); err != nil {
+		log.Printf("Warning: Failed to extend write deadline for evaluation: %v", err)
+	}
+
+	log.Printf("🧠 Evaluating %d uploaded screenshots (%d console logs)", len(screenshots), len(logs))
+	score, err := gameEval.EvaluateGame(r.Context(), screenshots, logs)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Evaluation failed: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(score)
+}
+
+// uploadedScreenshot validates an uploaded PNG or JPEG image; the evaluator sends either
+// as is
+func uploadedScreenshot(data []byte) (*agent.Screenshot, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("not a PNG or JPEG image: %w", err)
+	}
+	if format != "png" && format != "jpeg" {
+		return nil, fmt.Errorf("not a PNG or JPEG image: %s", format)
+	}
+
+	return &agent.Screenshot{
+		Timestamp: time.Now(),
+		Data:      data,
+		Width:     config.Width,
+		Height:    config.Height,
+		Format:    agent.ImageFormat(format),
+	}, nil
+}
+
+// uploadedLogs reads the optional "logs" form file or field
+func uploadedLogs(r *http.Request) ([]agent.ConsoleLog, error) {
+	var data []byte
+	if f, _, err := r.FormFile("logs"); err == nil {
+		defer f.Close()
+		if data, err = io.ReadAll(io.LimitReader(f, maxEvaluateLogBytes+1)); err != nil {
+			return nil, err
+		}
+	} else {
+		data = []byte(r.FormValue("logs"))
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if len(data) > maxEvaluateLogBytes {
+		return nil, fmt.Errorf("logs exceed %d MB", maxEvaluateLogBytes>>20)
+	}
+
+	var entries []struct {
+		Level   agent.LogLevel `json:"level"`
+		Message string         `json:"message"`
+		Source  string         `json:"source"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	logs := make([]agent.ConsoleLog, 0, len(entries))
+	for _, e := range entries {
+		if e.Level == "" {
+			e.Level = agent.LogLevelLog
+		}
+		logs = append(logs, agent.ConsoleLog{Level: e.Level, Message: e.Message, Source: e.Source, Timestamp: time.Now()})
+	}
+	return logs, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dreamup/qa-agent/internal/reporter"
+)
+
+func TestHARServedOnlyWithReport(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mediaDir := filepath.Join("data", "media")
+	if err := os.MkdirAll(mediaDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"network_20250102_030405_abcd1234.har": `{"log":{}}`,
+		"screenshot_final_1.png":               "png",
+	} {
+		if err := os.WriteFile(filepath.Join(mediaDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The media file server doesn't serve HARs, however the path is spelled
+	media := http.StripPrefix("/media/", mediaHandler(mediaDir))
+	for path, want := range map[string]int{
+		"/media/screenshot_final_1.png":                    http.StatusOK,
+		"/media/network_20250102_030405_abcd1234.har":      http.StatusNotFound,
+		"/media/network_20250102_030405_abcd1234.HAR":      http.StatusNotFound,
+		"/media/x/../network_20250102_030405_abcd1234.har": http.StatusNotFound,
+		"/media/network_20250102_030405_abcd1234.har/":     http.StatusNotFound,
+		"/media/%6eetwork_20250102_030405_abcd1234%2ehar":  http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		media.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s: status %d, want %d", path, rec.Code, want)
+		}
+	}
+
+	// The report route serves it under the same auth policy as the report itself
+	s := NewServer("0", "", 1)
+	s.authToken = "token"
+	s.jobs["test-1"] = &TestJob{ID: "test-1", Report: &reporter.Report{
+		ReportID: "report-1",
+		Evidence: &reporter.Evidence{NetworkLog: "network_20250102_030405_abcd1234.har"},
+	}}
+	reports := s.authMiddleware(s.handleTestReport)
+	for _, path := range []string{"/api/reports/test-1", "/api/reports/test-1/har"} {
+		rec := httptest.NewRecorder()
+		reports(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s: status %d, want 200", path, rec.Code)
+		}
+		rec = httptest.NewRecorder()
+		reports(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("POST %s without a token: status %d, want 401", path, rec.Code)
+		}
+	}
+}
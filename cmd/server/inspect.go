@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+	"github.com/dreamup/qa-agent/internal/session"
+)
+
+// inspectTimeout bounds POST /api/tests/inspect; an inspection only loads the page
+const inspectTimeout = 2 * time.Minute
+
+// InspectResponse is the result of POST /api/tests/inspect
+type InspectResponse struct {
+	*session.Inspection
+	// ScreenshotURL serves the captured screenshot
+	ScreenshotURL string `json:"screenshot_url"`
+}
+
+// Load a game and report what the agent detects, without playing it: POST /api/tests/inspect.
+// Takes the same body as POST /api/tests; only the URL, browser and network fields apply.
+func (s *Server) handleTestInspect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.rejectDraining(w) {
+		return
+	}
+
+	var req TestRequest
+	if err := decodeJSONBody(w, r, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := validateTestRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.rejectDomain(w, req.URL) {
+		return
+	}
+
+	// Cookies were validated above
+	cookies, _ := requestCookies(req)
+
+	// The server's WriteTimeout is shorter than an inspection
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(inspectTimeout + 30*time.Second)); err != nil {
+		log.Printf("Warning: Failed to extend write deadline for inspection: %v", err)
+	}
+
+	// Inspections share the browser slots with tests; someone is waiting on this one
+	if err := s.scheduler.acquire(r.Context(), priorityHigh); err != nil {
+		return
+	}
+	defer s.scheduler.release()
+
+	headless := req.Headless || os.Getenv("FORCE_HEADLESS") == "true"
+	ctx, cancel := context.WithTimeout(r.Context(), inspectTimeout)
+	defer cancel()
+
+	log.Printf("🔍 Inspecting %s", req.URL)
+	inspection, err := session.Inspect(ctx, session.Options{
+		URL:            req.URL,
+		Headless:       headless,
+		Cookies:        cookies,
+		LocalStorage:   req.LocalStorage,
+		NetworkProfile: agent.NetworkProfile(req.NetworkProfile),
+		BrowserOptions: s.browserOptions(req),
+	})
+	if errors.Is(err, session.ErrCancelled) {
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Inspection failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(InspectResponse{
+		Inspection:    inspection,
+		ScreenshotURL: "/media/" + inspection.Screenshot.Filepath,
+	})
+}
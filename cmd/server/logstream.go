@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+)
+
+// maxLogHistory caps how many recent console logs are replayed to late subscribers
+const maxLogHistory = 500
+
+// logBroadcaster fans console logs for one test out to live subscribers
+type logBroadcaster struct {
+	mu      sync.Mutex
+	history []agent.ConsoleLog
+	subs    map[chan agent.ConsoleLog]struct{}
+	done    chan struct{}
+	closed  bool
+}
+
+func newLogBroadcaster() *logBroadcaster {
+	return &logBroadcaster{
+		subs: make(map[chan agent.ConsoleLog]struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// publish records a log and forwards it to subscribers. Slow subscribers drop logs
+// rather than block the browser event goroutine.
+func (b *logBroadcaster) publish(entry agent.ConsoleLog) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.history = append(b.history, entry)
+	if len(b.history) > maxLogHistory {
+		b.history = b.history[len(b.history)-maxLogHistory:]
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
+
+// subscribe returns the logs captured so far and a channel of new ones.
+// Call unsubscribe when done listening.
+func (b *logBroadcaster) subscribe() (history []agent.ConsoleLog, ch chan agent.ConsoleLog, unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	history = append([]agent.ConsoleLog(nil), b.history...)
+	ch = make(chan agent.ConsoleLog, 64)
+	if !b.closed {
+		b.subs[ch] = struct{}{}
+	}
+	return history, ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+// close marks the test finished so streams can end
+func (b *logBroadcaster) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		close(b.done)
+	}
+}
+
+// logStreamHeartbeat keeps idle SSE connections open through proxies
+const logStreamHeartbeat = 15 * time.Second
+
+// Stream a running test's console logs as Server-Sent Events: GET /api/tests/{id}/logs.
+// Only levels the test's console logger captures are streamed; ?levels=error,warning
+// narrows that further. Sends a "done" event when the test ends.
+func (s *Server) handleTestLogs(w http.ResponseWriter, r *http.Request, testID string) {
+	s.mu.RLock()
+	job, exists := s.jobs[testID]
+	s.mu.RUnlock()
+
+	if !exists || job.logs == nil {
+		http.Error(w, "Test not found", http.StatusNotFound)
+		return
+	}
+
+	var captured map[agent.LogLevel]bool
+	if job.console != nil {
+		captured = job.console.Filter
+	}
+	var levels map[agent.LogLevel]bool
+	if v := r.URL.Query().Get("levels"); v != "" {
+		levels = make(map[agent.LogLevel]bool)
+		for _, name := range strings.Split(v, ",") {
+			level, err := agent.ParseLogLevel(strings.TrimSpace(name))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid levels: %v", err), http.StatusBadRequest)
+				return
+			}
+			levels[level] = true
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// The server's WriteTimeout would cut long-running streams short
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Warning: Failed to clear write deadline for log stream: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(entry agent.ConsoleLog) {
+		if captured != nil && !captured[entry.Level] {
+			return
+		}
+		if levels != nil && !levels[entry.Level] {
+			return
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "event: log\ndata: %s\n\n", data)
+	}
+
+	history, ch, unsubscribe := job.logs.subscribe()
+	defer unsubscribe()
+
+	for _, entry := range history {
+		send(entry)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case entry := <-ch:
+			send(entry)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-job.logs.done:
+			// Drain logs published just before the test finished
+			for drained := false; !drained; {
+				select {
+				case entry := <-ch:
+					send(entry)
+				default:
+					drained = true
+				}
+			}
+			s.mu.RLock()
+			status := job.Status
+			s.mu.RUnlock()
+			fmt.Fprintf(w, "event: done\ndata: {\"status\":%q}\n\n", status)
+			flusher.Flush()
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+)
+
+// streamedLevels returns the levels of the logs streamed by GET /api/tests/{id}/logs?query
+func streamedLevels(t *testing.T, s *Server, job *TestJob, query string) (int, []agent.LogLevel) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.handleTestLogs(rec, httptest.NewRequest(http.MethodGet, "/api/tests/"+job.ID+"/logs?"+query, nil), job.ID)
+
+	var levels []agent.LogLevel
+	for _, event := range strings.Split(rec.Body.String(), "\n\n") {
+		data, ok := strings.CutPrefix(event, "event: log\ndata: ")
+		if !ok {
+			continue
+		}
+		var entry agent.ConsoleLog
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			t.Fatalf("bad log event %q: %v", event, err)
+		}
+		levels = append(levels, entry.Level)
+	}
+	return rec.Code, levels
+}
+
+func TestHandleTestLogsFilters(t *testing.T) {
+	s := newTestServer(t)
+	filtered := s.newTestJob(TestRequest{URL: "https://example.com/a", ConsoleLevels: []string{"error", "warning"}})
+	unfiltered := s.newTestJob(TestRequest{URL: "https://example.com/b"})
+	for _, job := range []*TestJob{filtered, unfiltered} {
+		for _, level := range []agent.LogLevel{agent.LogLevelLog, agent.LogLevelWarning, agent.LogLevelError, agent.LogLevelDebug} {
+			job.logs.publish(agent.ConsoleLog{Level: level, Message: string(level)})
+		}
+		job.logs.close()
+	}
+
+	tests := []struct {
+		name  string
+		job   *TestJob
+		query string
+		want  []agent.LogLevel
+	}{
+		{name: "logger filter", job: filtered, want: []agent.LogLevel{agent.LogLevelWarning, agent.LogLevelError}},
+		{name: "narrowed by query", job: filtered, query: "levels=error", want: []agent.LogLevel{agent.LogLevelError}},
+		{name: "query can't widen the filter", job: filtered, query: "levels=log,error", want: []agent.LogLevel{agent.LogLevelError}},
+		{name: "query outside the filter", job: filtered, query: "levels=debug", want: nil},
+		{name: "no filter", job: unfiltered, want: []agent.LogLevel{agent.LogLevelLog, agent.LogLevelWarning, agent.LogLevelError, agent.LogLevelDebug}},
+		{name: "no filter, narrowed by query", job: unfiltered, query: "levels=log,%20debug", want: []agent.LogLevel{agent.LogLevelLog, agent.LogLevelDebug}},
+	}
+	for _, tt := range tests {
+		code, levels := streamedLevels(t, s, tt.job, tt.query)
+		if code != http.StatusOK {
+			t.Errorf("%s: status %d", tt.name, code)
+			continue
+		}
+		if !reflect.DeepEqual(levels, tt.want) {
+			t.Errorf("%s: streamed %v, want %v", tt.name, levels, tt.want)
+		}
+	}
+
+	if code, _ := streamedLevels(t, s, filtered, "levels=fatal"); code != http.StatusBadRequest {
+		t.Errorf("unknown level: status %d, want 400", code)
+	}
+}
+
+func TestValidateTestRequestConsoleLevels(t *testing.T) {
+	req := TestRequest{URL: "https://example.com", ConsoleLevels: []string{"error", "exception"}}
+	if err := validateTestRequest(&req); err != nil {
+		t.Errorf("valid levels rejected: %v", err)
+	}
+	req = TestRequest{URL: "https://example.com", ConsoleLevels: []string{"error", "fatal"}}
+	if err := validateTestRequest(&req); err == nil {
+		t.Error("unknown level accepted")
+	}
+}
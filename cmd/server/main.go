@@ -2,23 +2,31 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/dreamup/qa-agent/internal/agent"
 	"github.com/dreamup/qa-agent/internal/db"
 	"github.com/dreamup/qa-agent/internal/evaluator"
+	"github.com/dreamup/qa-agent/internal/logging"
 	"github.com/dreamup/qa-agent/internal/reporter"
+	"github.com/dreamup/qa-agent/internal/session"
+	"github.com/dreamup/qa-agent/pkg/client"
 	"github.com/google/uuid"
 )
 
@@ -26,47 +34,19 @@ const (
 	version = "0.1.0"
 )
 
-// TestRequest represents a test submission
-type TestRequest struct {
-	URL           string `json:"url"`
-	MaxDuration   int    `json:"maxDuration,omitempty"`
-	Headless      bool   `json:"headless"`
-	GameMechanics string `json:"gameMechanics,omitempty"` // Optional description of how to play the game
-}
-
-// TestResponse represents the test submission response
-type TestResponse struct {
-	TestID string `json:"testId"`
-	Status string `json:"status"`
-}
-
-// BatchTestRequest represents a batch test submission (max 10 URLs)
-type BatchTestRequest struct {
-	URLs          []string `json:"urls"`
-	MaxDuration   int      `json:"maxDuration,omitempty"`
-	Headless      bool     `json:"headless"`
-	GameMechanics string   `json:"gameMechanics,omitempty"` // Optional description of how to play the game
-}
-
-// BatchTestResponse represents the batch test submission response
-type BatchTestResponse struct {
-	BatchID string   `json:"batchId"`
-	TestIDs []string `json:"testIds"`
-	Status  string   `json:"status"`
-}
-
-// BatchTestStatus represents the status of a batch test
-type BatchTestStatus struct {
-	BatchID        string       `json:"batchId"`
-	Status         string       `json:"status"`
-	Tests          []TestStatus `json:"tests"`
-	TotalTests     int          `json:"totalTests"`
-	CompletedTests int          `json:"completedTests"`
-	FailedTests    int          `json:"failedTests"`
-	RunningTests   int          `json:"runningTests"`
-	CreatedAt      time.Time    `json:"createdAt"`
-	UpdatedAt      time.Time    `json:"updatedAt"`
-}
+// API request/response types are shared with the Go client package so the two cannot drift
+type (
+	TestRequest            = client.TestRequest
+	TestResponse           = client.TestResponse
+	RerunResponse          = client.RerunResponse
+	ReplayResponse         = client.ReplayResponse
+	BatchTestRequest       = client.BatchTestRequest
+	BatchTestResponse      = client.BatchTestResponse
+	BatchTestStatus        = client.BatchTestStatus
+	BatchTestErrorResponse = client.BatchTestErrorResponse
+	BatchEntryError        = client.BatchEntryError
+	TestStatus             = client.TestStatus
+)
 
 // BatchJob represents a batch of test jobs
 type BatchJob struct {
@@ -77,16 +57,6 @@ type BatchJob struct {
 	UpdatedAt time.Time
 }
 
-// TestStatus represents the current status of a test
-type TestStatus struct {
-	TestID    string    `json:"testId"`
-	Status    string    `json:"status"`
-	Progress  int       `json:"progress"`
-	Message   string    `json:"message,omitempty"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-}
-
 // TestJob represents a running test
 type TestJob struct {
 	ID        string
@@ -100,6 +70,12 @@ type TestJob struct {
 	Error     error
 	ctx       context.Context
 	cancel    context.CancelFunc
+	logs      *logBroadcaster      // Live console logs for /api/tests/{id}/logs
+	console   *agent.ConsoleLogger // Captures the test's console logs; its Filter also limits the live stream
+	logger    *logging.Logger      // Tags log lines with the test ID and current phase
+	rerunOf   string               // ID of the test this one re-runs, if any
+	replayOf  string               // ID of the test whose recorded actions this one replays, if any
+	replay    []agent.RecordedAction
 }
 
 // Server manages the API and test execution
@@ -109,21 +85,67 @@ type Server struct {
 	mu             sync.RWMutex
 	port           string
 	apiKey         string
-	testSemaphore  chan struct{} // Limits concurrent tests
+	scheduler      *scheduler // Hands out the maxConcurrent browser slots by priority
 	maxConcurrent  int
 	db             *db.Database
+	evidenceBudget reporter.EvidenceBudget     // Caps screenshots/logs stored per report
+	authToken      string                      // Bearer token required on mutating requests (empty = no auth)
+	videoFormat    agent.VideoFormat           // Recording format supported by ffmpeg (empty = recording disabled)
+	videoDisabled  string                      // Why recording is disabled, reported as video_unavailable metadata
+	retention      time.Duration               // Delete tests and media older than this (0 = keep forever)
+	chromeOptions  []agent.BrowserOption       // Chrome binary and flags from CHROME_PATH / EXTRA_CHROME_FLAGS
+	syncMaxWait    time.Duration               // Longest POST /api/tests/sync waits before answering 504
+	testOverhead   time.Duration               // Time a test gets beyond its gameplay duration before it times out
+	shutdownGrace  time.Duration               // How long shutdown waits for in-flight tests before interrupting them
+	draining       bool                        // Set on shutdown; new submissions are refused
+	domains        domainPolicy                // Game hosts that may be tested (ALLOWED_DOMAINS / DENIED_DOMAINS)
+	a11yTags       []string                    // axe rule tags audited (ACCESSIBILITY_TAGS; nil = agent defaults)
+	a11yWeights    *agent.AccessibilityWeights // Accessibility scoring (ACCESSIBILITY_WEIGHTS; nil = agent defaults)
+	shotFormat     agent.ScreenshotFormat      // Screenshot encoding (SCREENSHOT_FORMAT / SCREENSHOT_QUALITY)
+	inflight       sync.WaitGroup              // Tests started and not yet finished, awaited on shutdown
 }
 
-func NewServer(port, apiKey string) *Server {
-	maxConcurrent := 20 // Maximum 20 concurrent tests
+// defaultMaxConcurrent is the test concurrency used when MAX_CONCURRENT_TESTS is unset
+const defaultMaxConcurrent = 20
+
+// defaultTestOverhead covers browser setup, game start detection and evaluation retries
+// on top of a test's gameplay duration
+const defaultTestOverhead = 3 * time.Minute
+
+// ensembleRunAllowance is the extra time given for each additional ensemble evaluation
+const ensembleRunAllowance = time.Minute
+
+func NewServer(port, apiKey string, maxConcurrent int) *Server {
+	if maxConcurrent < 1 {
+		maxConcurrent = defaultMaxConcurrent
+	}
 	return &Server{
 		jobs:          make(map[string]*TestJob),
 		batchJobs:     make(map[string]*BatchJob),
 		port:          port,
 		apiKey:        apiKey,
-		testSemaphore: make(chan struct{}, maxConcurrent),
-		maxConcurrent: maxConcurrent,
+		scheduler:      newScheduler(maxConcurrent),
+		maxConcurrent:  maxConcurrent,
+		evidenceBudget: reporter.DefaultEvidenceBudget,
+		syncMaxWait:    defaultSyncMaxWait,
+		testOverhead:   defaultTestOverhead,
+		shutdownGrace:  defaultShutdownGrace,
+		videoFormat:    agent.VideoFormatMP4,
+	}
+}
+
+// envInt reads a non-negative integer environment variable, falling back to def when unset or invalid
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Printf("⚠️  Invalid %s %q, using default %d", name, v, def)
+		return def
 	}
+	return n
 }
 
 // fileExists checks if a file exists
@@ -152,12 +174,33 @@ func (s *Server) corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// Auth middleware - requires a bearer token on mutating requests when API_AUTH_TOKEN is set.
+// Read-only (GET/HEAD) requests stay open.
+func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.authToken == "" || r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.authToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
 // Config endpoint - returns server configuration
 func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	forceHeadless := os.Getenv("FORCE_HEADLESS") == "true"
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"forceHeadless": forceHeadless,
+		"maxConcurrent": s.maxConcurrent,
 	})
 }
 
@@ -171,31 +214,136 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Submit a new test
-func (s *Server) handleTestSubmit(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req TestRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
-		return
-	}
-
-	// Validate request
+// validateTestRequest applies defaults to a test request and checks its options
+func validateTestRequest(req *TestRequest) error {
 	if req.URL == "" {
-		http.Error(w, "URL is required", http.StatusBadRequest)
-		return
+		return fmt.Errorf("URL is required")
 	}
 
 	// Set defaults
 	if req.MaxDuration == 0 {
 		req.MaxDuration = 60
 	}
+	if req.Width == 0 {
+		req.Width = agent.DefaultViewportWidth
+	}
+	if req.Height == 0 {
+		req.Height = agent.DefaultViewportHeight
+	}
+	viewport := agent.Viewport{Width: req.Width, Height: req.Height}
+	if err := viewport.Validate(); err != nil {
+		return fmt.Errorf("Invalid viewport: %v", err)
+	}
+	if req.Proxy != "" {
+		if _, err := agent.ValidateProxyURL(req.Proxy); err != nil {
+			return err
+		}
+	}
+	if _, err := agent.ResolveControls(req.Controls); err != nil {
+		return err
+	}
+	if req.KeyHold < 0 || time.Duration(req.KeyHold)*time.Millisecond > agent.MaxKeyHold {
+		return fmt.Errorf("keyHoldMs must be between 0 and %d", agent.MaxKeyHold.Milliseconds())
+	}
+	if req.MaxLevels < 0 || req.MaxLevels > agent.MaxLevelsLimit {
+		return fmt.Errorf("maxLevels must be between 1 and %d (0 = default)", agent.MaxLevelsLimit)
+	}
+	if req.StuckPatience < 0 {
+		return fmt.Errorf("stuckPatience must not be negative")
+	}
+	if req.ScreenshotInterval < 0 || req.ScreenshotInterval > 60 {
+		return fmt.Errorf("screenshotInterval must be between 1 and 60 seconds (0 = default)")
+	}
+	if req.MaxScreenshots < 0 || req.MaxScreenshots == 1 || req.MaxScreenshots > 100 {
+		return fmt.Errorf("maxScreenshots must be between 2 and 100 (0 = default)")
+	}
+	if req.RenderTimeout < -1 {
+		return fmt.Errorf("renderTimeout must be -1 (disabled) or a number of seconds")
+	}
+	if req.BasicAuth != nil && req.BasicAuth.User == "" {
+		return fmt.Errorf("basicAuth.user is required")
+	}
+	for name := range req.Headers {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("Invalid header name %q", name)
+		}
+	}
+	if _, err := agent.ParseNetworkProfile(req.NetworkProfile); err != nil {
+		return err
+	}
+	if _, err := evaluator.ParseGenre(req.Genre); err != nil {
+		return err
+	}
+	for _, level := range req.ConsoleLevels {
+		if _, err := agent.ParseLogLevel(level); err != nil {
+			return fmt.Errorf("consoleLevels: %v", err)
+		}
+	}
+	if req.Ensemble < 0 || req.Ensemble > evaluator.MaxEnsembleRuns {
+		return fmt.Errorf("ensemble must be between 1 and %d (0 = single run)", evaluator.MaxEnsembleRuns)
+	}
+	if _, err := requestCookies(*req); err != nil {
+		return fmt.Errorf("Invalid cookies: %v", err)
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return err
+	}
+	req.Tags = tags
+	if _, err := parsePriority(req.Priority); err != nil {
+		return err
+	}
+	return nil
+}
 
-	// Create test job
+const (
+	// maxTags caps the tags on one test
+	maxTags = 20
+	// maxTagLength caps a tag's length in characters
+	maxTagLength = 64
+)
+
+// normalizeTags trims, lowercases and de-duplicates tags, rejecting empty, overlong or
+// control-character tags
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("At most %d tags are allowed", maxTags)
+	}
+
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, fmt.Errorf("Tags must not be empty")
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("Tag %q is longer than %d characters", tag, maxTagLength)
+		}
+		if strings.ContainsFunc(tag, unicode.IsControl) {
+			return nil, fmt.Errorf("Tag %q contains control characters", tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+// tagParams reads ?tag= query parameters (repeatable; tests must have every tag)
+func tagParams(r *http.Request) []string {
+	var tags []string
+	for _, tag := range r.URL.Query()["tag"] {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// newTestJob registers a pending test job and persists it to the database
+func (s *Server) newTestJob(req TestRequest) *TestJob {
 	testID := uuid.New().String()
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -209,6 +357,9 @@ func (s *Server) handleTestSubmit(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt: time.Now(),
 		ctx:       ctx,
 		cancel:    cancel,
+		logs:      newLogBroadcaster(),
+		console:   agent.NewConsoleLogger(consoleLevels(req)...),
+		logger:    logging.New("test_id", testID),
 	}
 
 	s.mu.Lock()
@@ -216,11 +367,53 @@ func (s *Server) handleTestSubmit(w http.ResponseWriter, r *http.Request) {
 	s.mu.Unlock()
 
 	// Persist test to database
-	if err := s.db.CreateTest(testID, req.URL, "pending"); err != nil {
+	if err := s.db.CreateTest(testID, req.URL, "pending", testParams(req)); err != nil {
 		log.Printf("Warning: Failed to persist test to database: %v", err)
 		// Continue anyway - test will run in memory
 	}
 
+	return job
+}
+
+// consoleLevels returns the console log levels a test captures (nil = all). The request
+// has been validated, so unknown levels don't occur.
+func consoleLevels(req TestRequest) []agent.LogLevel {
+	var levels []agent.LogLevel
+	for _, name := range req.ConsoleLevels {
+		if level, err := agent.ParseLogLevel(name); err == nil {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+// Submit a new test
+func (s *Server) handleTestSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.rejectDraining(w) {
+		return
+	}
+
+	var req TestRequest
+	if err := decodeJSONBody(w, r, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateTestRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.rejectDomain(w, req.URL) {
+		return
+	}
+
+	job := s.newTestJob(req)
+	testID := job.ID
+
 	// Start test execution in background
 	go s.executeTest(job)
 
@@ -245,7 +438,27 @@ func (s *Server) handleTestStatus(w http.ResponseWriter, r *http.Request) {
 	s.mu.RUnlock()
 
 	if !exists {
-		http.Error(w, "Test not found", http.StatusNotFound)
+		// Tests from a previous server process are only in the database
+		record, err := s.db.GetTest(testID)
+		if err != nil || record == nil {
+			http.Error(w, "Test not found", http.StatusNotFound)
+			return
+		}
+		status := TestStatus{
+			TestID:    record.ID,
+			Status:    record.Status,
+			Message:   record.StatusMessage,
+			CreatedAt: record.CreatedAt,
+			UpdatedAt: record.CreatedAt,
+		}
+		if isFinished(record.Status) {
+			status.Progress = 100
+		}
+		if record.CompletedAt != nil {
+			status.UpdatedAt = *record.CompletedAt
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
 		return
 	}
 
@@ -262,47 +475,259 @@ func (s *Server) handleTestStatus(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(status)
 }
 
+// Cancel a queued or running test
+func (s *Server) handleTestCancel(w http.ResponseWriter, r *http.Request) {
+	testID := r.URL.Path[len("/api/tests/"):]
+	if testID == "" {
+		http.Error(w, "Test ID required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	job, exists := s.jobs[testID]
+	s.mu.RUnlock()
+	if !exists {
+		http.Error(w, "Test not found", http.StatusNotFound)
+		return
+	}
+
+	status, cancelled := s.cancelJob(job, "Test cancelled by user")
+	if !cancelled {
+		http.Error(w, fmt.Sprintf("Test already %s", status.Status), http.StatusConflict)
+		return
+	}
+	log.Printf("Test %s cancelled", testID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// cancelJob cancels a queued or running test with message, and returns its status
+// afterwards. A test that already finished is left as it is, and cancelled is false.
+func (s *Server) cancelJob(job *TestJob, message string) (status TestStatus, cancelled bool) {
+	s.mu.Lock()
+	if !isFinished(job.Status) {
+		// Mark cancelled before signalling so executeTest's in-flight updates are ignored
+		job.Status = "cancelled"
+		job.Message = message
+		job.UpdatedAt = time.Now()
+		cancelled = true
+	}
+	status = TestStatus{
+		TestID:    job.ID,
+		Status:    job.Status,
+		Progress:  job.Progress,
+		Message:   job.Message,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+	s.mu.Unlock()
+	if !cancelled {
+		return status, false
+	}
+
+	job.cancel()
+
+	if err := s.db.UpdateTestStatus(job.ID, "cancelled"); err != nil {
+		log.Printf("Warning: Failed to update test status in database: %v", err)
+	}
+	return status, true
+}
+
 // Get test report
 func (s *Server) handleTestReport(w http.ResponseWriter, r *http.Request) {
-	testID := r.URL.Path[len("/api/reports/"):]
+	// Path is /api/reports/{testID} or /api/reports/{testID}/{view}
+	path := strings.TrimPrefix(r.URL.Path, "/api/reports/")
+	if path == "compare" {
+		s.handleReportCompare(w, r)
+		return
+	}
+	testID, view, _ := strings.Cut(path, "/")
 	if testID == "" {
 		http.Error(w, "Test ID required", http.StatusBadRequest)
 		return
 	}
 
+	report, status, err := s.loadReport(testID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	switch view {
+	case "":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	case "html":
+		html, err := reporter.RenderHTML(report)
+		if err != nil {
+			log.Printf("Failed to render HTML report for test %s: %v", testID, err)
+			http.Error(w, "Failed to render report", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(html)
+	case "snapshot":
+		s.handleReportSnapshot(w, r, testID, report)
+	case "har":
+		s.handleReportHAR(w, testID, report)
+	case "dom":
+		s.handleReportDOM(w, report)
+	case "actions":
+		s.handleReportActions(w, report)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleReportCompare diffs two reports: GET /api/reports/compare?a={testID}&b={testID}
+func (s *Server) handleReportCompare(w http.ResponseWriter, r *http.Request) {
+	idA, idB := r.URL.Query().Get("a"), r.URL.Query().Get("b")
+	if idA == "" || idB == "" {
+		http.Error(w, "Both a and b test IDs are required", http.StatusBadRequest)
+		return
+	}
+
+	reportA, status, err := s.loadReport(idA)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Report a: %v", err), status)
+		return
+	}
+	reportB, status, err := s.loadReport(idB)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Report b: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reporter.Compare(reportA, reportB))
+}
+
+// handleReportHAR serves the HAR network capture saved alongside the report
+func (s *Server) handleReportHAR(w http.ResponseWriter, testID string, report *reporter.Report) {
+	filename := report.Evidence.NetworkLog
+	if filename == "" {
+		http.Error(w, "Network log was not captured for this test", http.StatusNotFound)
+		return
+	}
+
+	// Security: the filename comes from stored report data, keep it inside the media directory
+	if strings.Contains(filename, "..") || strings.Contains(filename, "/") || !strings.HasPrefix(filename, "network_") {
+		http.Error(w, "Invalid network log", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := os.ReadFile(filepath.Join(".", "data", "media", filename))
+	if err != nil {
+		log.Printf("Failed to read HAR %s for test %s: %v", filename, testID, err)
+		http.Error(w, "Network log not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="dreamup-%s.har"`, testID))
+	w.Write(data)
+}
+
+// mediaHandler serves the media directory's screenshots and videos. HAR network captures
+// are only served with their report (/api/reports/{id}/har), under the report routes' auth.
+func mediaHandler(dir string) http.Handler {
+	files := http.FileServer(http.Dir(dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(strings.ToLower(path.Clean("/"+r.URL.Path)), ".har") {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// handleReportDOM serves the final DOM snapshot. It is served as plain text: the HTML
+// comes from the game page and must not run on this origin.
+func (s *Server) handleReportDOM(w http.ResponseWriter, report *reporter.Report) {
+	if report.Evidence == nil || report.Evidence.DOMSnapshot == "" {
+		http.Error(w, "No DOM snapshot was captured for this test", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write([]byte(report.Evidence.DOMSnapshot))
+}
+
+// handleReportActions serves the input actions recorded during the test, for reproducing
+// the run
+func (s *Server) handleReportActions(w http.ResponseWriter, report *reporter.Report) {
+	var actions []agent.RecordedAction
+	var dropped int
+	if report.Evidence != nil {
+		actions, dropped = report.Evidence.Actions, report.Evidence.ActionsDropped
+	}
+	if actions == nil {
+		actions = []agent.RecordedAction{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"actions": actions,
+		"dropped": dropped,
+	})
+}
+
+// loadReport returns the report for a test, checking active jobs before the database.
+// On failure it also returns the HTTP status to respond with.
+func (s *Server) loadReport(testID string) (*reporter.Report, int, error) {
 	// First check in-memory jobs (for active tests)
 	s.mu.RLock()
 	job, exists := s.jobs[testID]
 	s.mu.RUnlock()
 
 	if exists && job.Report != nil {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(job.Report)
-		return
+		return job.Report, http.StatusOK, nil
 	}
 
 	// If not in memory, check database
 	dbTest, err := s.db.GetTest(testID)
-	if err != nil {
-		http.Error(w, "Test not found", http.StatusNotFound)
-		return
+	if err != nil || dbTest == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("Test not found")
 	}
 
 	// Parse the report data JSON
 	if dbTest.ReportData == "" {
-		http.Error(w, "Report not available", http.StatusNotFound)
-		return
+		return nil, http.StatusNotFound, fmt.Errorf("Report not available")
 	}
 
 	var report reporter.Report
 	if err := json.Unmarshal([]byte(dbTest.ReportData), &report); err != nil {
 		log.Printf("Failed to parse report for test %s: %v", testID, err)
-		http.Error(w, "Failed to parse report", http.StatusInternalServerError)
+		return nil, http.StatusInternalServerError, fmt.Errorf("Failed to parse report")
+	}
+
+	return &report, http.StatusOK, nil
+}
+
+// Export a report as a single self-contained HTML file with inlined media
+func (s *Server) handleReportSnapshot(w http.ResponseWriter, r *http.Request, testID string, report *reporter.Report) {
+	html, err := reporter.RenderSnapshot(report, filepath.Join(".", "data", "media"))
+	if err != nil {
+		log.Printf("Failed to render snapshot for test %s: %v", testID, err)
+		http.Error(w, "Failed to render snapshot", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(report)
+	// Inlined video can make the snapshot large; give the write more room than the server default
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Now().Add(2 * time.Minute))
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.URL.Query().Get("download") != "false" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"qa-report-%s.html\"", testID))
+	}
+	w.Write(html)
+}
+
+// isScreenshotFilename reports whether a media filename is a screenshot or an annotated
+// copy of one (see agent.AnnotateScreenshots)
+func isScreenshotFilename(filename string) bool {
+	return strings.HasPrefix(filename, "screenshot_") || strings.HasPrefix(filename, "annotated_screenshot_")
 }
 
 // Serve screenshot files
@@ -315,7 +740,7 @@ func (s *Server) handleScreenshot(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Security: prevent directory traversal and only allow screenshot files
-	if strings.Contains(filename, "..") || strings.Contains(filename, "/") || !strings.HasPrefix(filename, "screenshot_") {
+	if strings.Contains(filename, "..") || strings.Contains(filename, "/") || !isScreenshotFilename(filename) {
 		http.Error(w, "Invalid filename", http.StatusBadRequest)
 		return
 	}
@@ -351,6 +776,12 @@ func (s *Server) handleVideo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// /api/videos/{filename}/thumbnail.gif serves a looping GIF preview
+	if strings.HasSuffix(filename, "/thumbnail.gif") {
+		s.handleVideoThumbnail(w, r, strings.TrimSuffix(filename, "/thumbnail.gif"))
+		return
+	}
+
 	// Security: prevent directory traversal and only allow video files
 	if strings.Contains(filename, "..") || strings.Contains(filename, "/") || !strings.HasPrefix(filename, "gameplay_") {
 		http.Error(w, "Invalid filename", http.StatusBadRequest)
@@ -368,8 +799,47 @@ func (s *Server) handleVideo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set content type for MP4 video
-	w.Header().Set("Content-Type", "video/mp4")
+	// Set content type from the container format
+	contentType := "video/mp4"
+	if strings.HasSuffix(filename, ".webm") {
+		contentType = "video/webm"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write(data)
+}
+
+// Serve a GIF thumbnail for a video, generating and caching it on first request
+func (s *Server) handleVideoThumbnail(w http.ResponseWriter, r *http.Request, filename string) {
+	if strings.Contains(filename, "..") || strings.Contains(filename, "/") || !strings.HasPrefix(filename, "gameplay_") {
+		http.Error(w, "Invalid filename", http.StatusBadRequest)
+		return
+	}
+
+	mediaDir := filepath.Join(".", "data", "media")
+	videoPath := filepath.Join(mediaDir, filename)
+	gifPath := filepath.Join(mediaDir, agent.GIFThumbnailName(filename))
+
+	if _, err := os.Stat(gifPath); os.IsNotExist(err) {
+		if _, err := os.Stat(videoPath); err != nil {
+			http.Error(w, "Video not found", http.StatusNotFound)
+			return
+		}
+		if err := agent.GenerateGIFFromVideo(videoPath, gifPath); err != nil {
+			log.Printf("Failed to generate GIF thumbnail for %s: %v", filename, err)
+			http.Error(w, "Failed to generate thumbnail", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	data, err := os.ReadFile(gifPath)
+	if err != nil {
+		log.Printf("Failed to read GIF thumbnail %s: %v", gifPath, err)
+		http.Error(w, "Thumbnail not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/gif")
 	w.Header().Set("Cache-Control", "public, max-age=3600")
 	w.Write(data)
 }
@@ -382,9 +852,32 @@ type ReportSummary struct {
 	Status       string  `json:"status"`
 	OverallScore *int    `json:"overallScore"`
 	Duration     int     `json:"duration"`
+	// Request parameters, for auditing and re-runs
+	MaxDuration   int      `json:"maxDuration"`
+	Headless      bool     `json:"headless"`
+	GameMechanics string   `json:"gameMechanics,omitempty"`
+	Tags          []string `json:"tags"`
+	// Message explains an interrupted test
+	Message string `json:"message,omitempty"`
+}
+
+// TestListResponse is one page of the test history
+type TestListResponse struct {
+	Tests  []ReportSummary `json:"tests"`
+	Total  int             `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
 }
 
-// List all tests
+const (
+	// defaultTestListLimit is the page size when no limit is given
+	defaultTestListLimit = 100
+	// maxTestListLimit caps the page size
+	maxTestListLimit = 500
+)
+
+// List tests, one page at a time (?limit=&offset=), optionally searching game URLs (?q=)
+// and filtering by tags (?tag=, repeatable)
 func (s *Server) handleTestList(w http.ResponseWriter, r *http.Request) {
 	// Get query parameters
 	statusFilter := r.URL.Query().Get("status")
@@ -392,8 +885,41 @@ func (s *Server) handleTestList(w http.ResponseWriter, r *http.Request) {
 		statusFilter = "all"
 	}
 
-	// Query database for tests (default: 100 most recent)
-	dbTests, err := s.db.ListTests(statusFilter, 100, 0)
+	limit := defaultTestListLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	if limit > maxTestListLimit {
+		limit = maxTestListLimit
+	}
+
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	// ?q= filters to game URLs containing the query (empty = all tests), ?tag= to tagged tests
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	tags := tagParams(r)
+
+	// Query database for one page of tests (most recent first)
+	dbTests, err := s.db.SearchTests(query, statusFilter, tags, limit, offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	total, err := s.db.CountSearchTests(query, statusFilter, tags)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
 		return
@@ -408,17 +934,72 @@ func (s *Server) handleTestList(w http.ResponseWriter, r *http.Request) {
 		}
 
 		summaries = append(summaries, ReportSummary{
-			ReportID:     dbTest.ID,
-			GameURL:      dbTest.GameURL,
-			Timestamp:    dbTest.CreatedAt.Format(time.RFC3339),
-			Status:       dbTest.Status,
-			OverallScore: score,
-			Duration:     dbTest.Duration,
+			ReportID:      dbTest.ID,
+			GameURL:       dbTest.GameURL,
+			Timestamp:     dbTest.CreatedAt.Format(time.RFC3339),
+			Status:        dbTest.Status,
+			OverallScore:  score,
+			Duration:      dbTest.Duration,
+			MaxDuration:   dbTest.MaxDuration,
+			Headless:      dbTest.Headless,
+			GameMechanics: dbTest.GameMechanics,
+			Tags:          dbTest.Tags,
+			Message:       dbTest.StatusMessage,
 		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(summaries)
+	json.NewEncoder(w).Encode(TestListResponse{
+		Tests:  summaries,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// Aggregate statistics for the dashboard (?q=&tag=&since=&until=, dates as YYYY-MM-DD or RFC3339)
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter := db.StatsFilter{
+		URLSubstring: strings.TrimSpace(r.URL.Query().Get("q")),
+		Tags:         tagParams(r),
+	}
+	for _, param := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		v := r.URL.Query().Get(param.name)
+		if v == "" {
+			continue
+		}
+		t, err := parseDateParam(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid %s: use YYYY-MM-DD or RFC3339", param.name), http.StatusBadRequest)
+			return
+		}
+		*param.dst = t
+	}
+
+	stats, err := s.db.Stats(filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// parseDateParam parses a query date as RFC3339 or a local YYYY-MM-DD day
+func parseDateParam(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, time.Local)
 }
 
 // Submit a batch test (up to 10 URLs)
@@ -427,9 +1008,12 @@ func (s *Server) handleBatchTestSubmit(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if s.rejectDraining(w) {
+		return
+	}
 
 	var req BatchTestRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeJSONBody(w, r, &req); err != nil {
 		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
 		return
 	}
@@ -445,17 +1029,44 @@ func (s *Server) handleBatchTestSubmit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate each URL
+	// Per-URL mechanics must refer to submitted URLs
+	submitted := make(map[string]bool, len(req.URLs))
 	for _, url := range req.URLs {
-		if url == "" {
-			http.Error(w, "All URLs must be non-empty", http.StatusBadRequest)
+		submitted[url] = true
+	}
+	for url := range req.GameMechanicsByURL {
+		if !submitted[url] {
+			http.Error(w, fmt.Sprintf("gameMechanicsByUrl contains URL not in batch: %s", url), http.StatusBadRequest)
 			return
 		}
 	}
 
-	// Set defaults
-	if req.MaxDuration == 0 {
-		req.MaxDuration = 60
+	// Batches yield to interactive tests unless they ask otherwise
+	if req.Priority == "" {
+		req.Priority = priorityLow.String()
+	}
+
+	// Each URL is validated as a single test would be, and the batch is only started if
+	// they all pass
+	requests := make([]TestRequest, len(req.URLs))
+	var entryErrors []BatchEntryError
+	for i, url := range req.URLs {
+		requests[i] = batchTestRequest(req, url)
+		if err := validateTestRequest(&requests[i]); err != nil {
+			entryErrors = append(entryErrors, BatchEntryError{Index: i, URL: url, Error: err.Error()})
+		}
+	}
+	if len(entryErrors) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(BatchTestErrorResponse{
+			Error:   fmt.Sprintf("%d of %d URLs are invalid", len(entryErrors), len(req.URLs)),
+			Entries: entryErrors,
+		})
+		return
+	}
+	if s.rejectDomain(w, req.URLs...) {
+		return
 	}
 
 	// Create batch ID
@@ -463,37 +1074,9 @@ func (s *Server) handleBatchTestSubmit(w http.ResponseWriter, r *http.Request) {
 	testIDs := make([]string, 0, len(req.URLs))
 
 	// Create individual test jobs for each URL
-	for _, url := range req.URLs {
-		testID := uuid.New().String()
-		ctx, cancel := context.WithCancel(context.Background())
-
-		job := &TestJob{
-			ID: testID,
-			Request: TestRequest{
-				URL:         url,
-				MaxDuration: req.MaxDuration,
-				Headless:    req.Headless,
-			},
-			Status:    "pending",
-			Progress:  0,
-			Message:   "Test queued in batch",
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			ctx:       ctx,
-			cancel:    cancel,
-		}
-
-		s.mu.Lock()
-		s.jobs[testID] = job
-		s.mu.Unlock()
-
-		// Persist test to database
-		if err := s.db.CreateTest(testID, url, "pending"); err != nil {
-			log.Printf("Warning: Failed to persist batch test to database: %v", err)
-			// Continue anyway - test will run in memory
-		}
-
-		testIDs = append(testIDs, testID)
+	for _, testReq := range requests {
+		job := s.newTestJob(testReq)
+		testIDs = append(testIDs, job.ID)
 
 		// Start test execution in background
 		go s.executeTest(job)
@@ -524,6 +1107,23 @@ func (s *Server) handleBatchTestSubmit(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// batchTestRequest is the single test run for one URL of a batch
+func batchTestRequest(req BatchTestRequest, url string) TestRequest {
+	// Per-URL mechanics take precedence over the batch-wide description
+	mechanics := req.GameMechanics
+	if m, ok := req.GameMechanicsByURL[url]; ok && m != "" {
+		mechanics = m
+	}
+	return TestRequest{
+		URL:           url,
+		MaxDuration:   req.MaxDuration,
+		Headless:      req.Headless,
+		GameMechanics: mechanics,
+		Tags:          req.Tags,
+		Priority:      req.Priority,
+	}
+}
+
 // Get batch test status
 func (s *Server) handleBatchTestStatus(w http.ResponseWriter, r *http.Request) {
 	batchID := r.URL.Path[len("/api/batch-tests/"):]
@@ -561,7 +1161,7 @@ func (s *Server) handleBatchTestStatus(w http.ResponseWriter, r *http.Request) {
 			switch job.Status {
 			case "completed":
 				completedCount++
-			case "failed":
+			case "failed", "cancelled", "interrupted":
 				failedCount++
 			case "running":
 				runningCount++
@@ -610,10 +1210,10 @@ func (s *Server) monitorBatchStatus(batchID string) {
 
 		for _, testID := range batchJob.TestIDs {
 			if job, ok := s.jobs[testID]; ok {
-				if job.Status != "completed" && job.Status != "failed" {
+				if !isFinished(job.Status) {
 					allComplete = false
 				}
-				if job.Status == "failed" {
+				if job.Status == "failed" || job.Status == "cancelled" || job.Status == "interrupted" {
 					anyFailed = true
 					failedCount++
 				}
@@ -658,606 +1258,313 @@ func (s *Server) monitorBatchStatus(batchID string) {
 	}
 }
 
+// browserOptions configures the browser for a request
+func (s *Server) browserOptions(req TestRequest) []agent.BrowserOption {
+	browserOptions := append([]agent.BrowserOption{
+		agent.WithViewport(req.Width, req.Height),
+		agent.WithProxy(req.Proxy),
+		agent.WithExtraHeaders(req.Headers),
+		agent.WithScreenshotFormat(s.shotFormat),
+	}, s.chromeOptions...)
+	if req.BasicAuth != nil {
+		browserOptions = append(browserOptions, agent.WithBasicAuth(req.BasicAuth.User, req.BasicAuth.Pass))
+	}
+	return browserOptions
+}
+
 // Execute a test job
 func (s *Server) executeTest(job *TestJob) {
-	// Acquire semaphore slot (blocks if at max concurrency)
-	s.testSemaphore <- struct{}{}
+	testsSubmitted.Inc()
+	job.logger.SetPhase("queued")
+
+	// Tests that reach here after shutdown began (e.g. batch members) never start
+	s.mu.Lock()
+	if s.draining {
+		s.mu.Unlock()
+		s.updateJob(job.ID, "interrupted", 0, interruptedMessage)
+		job.cancel()
+		job.logs.close()
+		return
+	}
+	s.inflight.Add(1)
+	s.mu.Unlock()
+	defer s.inflight.Done()
+
+	// Wait for a browser slot; higher-priority tests are served first. Priority was
+	// validated on submission.
+	p, _ := parsePriority(job.Request.Priority)
+	if err := s.scheduler.acquire(job.ctx, p); err != nil {
+		job.logger.Printf("Test %s cancelled while queued", job.ID)
+		job.cancel()
+		job.logs.close()
+		return
+	}
+	// Queued tests don't start once shutdown has begun; they couldn't finish in time
+	if s.isDraining() {
+		s.scheduler.release()
+		s.updateJob(job.ID, "interrupted", 0, interruptedMessage)
+		job.cancel()
+		job.logs.close()
+		return
+	}
+	executionStart := time.Now()
 	defer func() {
-		<-s.testSemaphore // Release slot when done
+		testDuration.Observe(time.Since(executionStart).Seconds())
+		s.scheduler.release() // Release slot when done
+		job.cancel()          // Release the job context
+		job.logs.close()      // End live log streams
 		if r := recover(); r != nil {
 			s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Panic: %v", r))
 		}
 	}()
 
-	log.Printf("Starting test %s for URL: %s (concurrent: %d/%d)",
-		job.ID, job.Request.URL, len(s.testSemaphore), s.maxConcurrent)
-
-	// Note: Duration enforcement is handled by the gameplay loops themselves.
-	// Standard gameplay mode checks time.Since(gameplayStart) < gameplayDuration
-	// Intelligent gameplay mode limits the number of attempts based on duration
-	// No separate timeout handler is needed - tests complete naturally when duration is reached
+	// The test may have been cancelled just as it got a slot
+	if s.jobCancelled(job, "browser start") {
+		return
+	}
 
-	// Update status to running
-	s.updateJob(job.ID, "running", 10, "Initializing browser...")
+	running, _ := s.scheduler.stats()
+	job.logger.Printf("Starting test %s for URL: %s (priority: %s, concurrent: %d/%d)",
+		job.ID, job.Request.URL, p, running, s.maxConcurrent)
 
-	// Create browser manager with headless setting from request
 	// In production (Docker/deployed), always use headless mode regardless of request
 	headless := job.Request.Headless
 	if os.Getenv("FORCE_HEADLESS") == "true" {
 		headless = true
 	}
-	bm, err := agent.NewBrowserManager(headless)
-	if err != nil {
-		s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Failed to create browser: %v", err))
-		return
-	}
-	defer bm.Close()
+	browserOptions := s.browserOptions(job.Request)
 
-	// Start console logger
-	consoleLogger := agent.NewConsoleLogger()
-	if err := consoleLogger.StartCapture(bm.GetContext()); err != nil {
-		s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Failed to start console logger: %v", err))
-		return
-	}
-
-	s.updateJob(job.ID, "running", 20, "Navigating to URL...")
-
-	// Navigate to URL
-	if err := bm.LoadGame(job.Request.URL); err != nil {
-		s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Navigation failed: %v", err))
+	// Cookies were validated on submission
+	cookies, err := requestCookies(job.Request)
+	if err != nil {
+		s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Failed to seed browser storage: %v", err))
 		return
 	}
 
-	s.updateJob(job.ID, "running", 30, "Capturing initial screenshot...")
-
-	// Capture initial screenshot
-	initialScreenshot, err := agent.CaptureScreenshot(bm.GetContext(), agent.ContextInitial)
+	// The server has no hard deadline, so ride out rate limits longer than the default
+	gameEval, err := evaluator.NewGameEvaluator("")
 	if err != nil {
-		s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Screenshot failed: %v", err))
+		job.logger.Printf("Warning: Could not initialize evaluator: %v", err)
+		s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Evaluator initialization failed: %v", err))
 		return
 	}
-	if err := initialScreenshot.SaveToTemp(); err != nil {
-		s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Failed to save screenshot: %v", err))
+	gameEval.SetRetryConfig(agent.RetryConfig{
+		MaxAttempts:     5,
+		InitialDelay:    2 * time.Second,
+		MaxDelay:        60 * time.Second,
+		BackoffFactor:   2.0,
+		RetryableErrors: []agent.ErrorCategory{agent.ErrorCategoryLLM},
+	})
+	// Genre was validated on submission
+	genre, _ := evaluator.ParseGenre(job.Request.Genre)
+	gameEval.SetGenre(genre)
+	gameEval.SetEnsemble(job.Request.Ensemble, job.Request.EnsembleModels...)
+
+	// Bound the whole run, not just gameplay, so a stuck browser or evaluator can't hold
+	// the slot indefinitely. The clock starts once the test has a slot.
+	timeout := s.testTimeout(job.Request)
+	runCtx, cancelRun := context.WithTimeout(job.ctx, timeout)
+	defer cancelRun()
+
+	var artifacts session.Artifacts
+
+	report, err := session.RunSession(runCtx, session.Options{
+		URL:                  job.Request.URL,
+		Headless:             headless,
+		MaxDuration:          time.Duration(job.Request.MaxDuration) * time.Second,
+		GameMechanics:        job.Request.GameMechanics,
+		SettleTimeout:        time.Duration(job.Request.SettleTimeout) * time.Millisecond,
+		MaxLevels:            job.Request.MaxLevels,
+		Controls:             job.Request.Controls,
+		KeyHold:              time.Duration(job.Request.KeyHold) * time.Millisecond,
+		Replay:               job.replay,
+		Seed:                 job.Request.Seed,
+		AccessibilityTags:    s.a11yTags,
+		AccessibilityWeights: s.a11yWeights,
+		StuckPatience:        job.Request.StuckPatience,
+		ScreenshotInterval:   time.Duration(job.Request.ScreenshotInterval) * time.Second,
+		MaxScreenshots:       job.Request.MaxScreenshots,
+		RenderTimeout:        time.Duration(job.Request.RenderTimeout) * time.Second,
+		Cookies:              cookies,
+		LocalStorage:         job.Request.LocalStorage,
+		NetworkProfile:       agent.NetworkProfile(job.Request.NetworkProfile),
+		Genre:                genre,
+		CaptureHAR:           job.Request.CaptureHAR,
+		AnnotateScreenshots:  job.Request.AnnotateScreenshots,
+		BrowserOptions:       browserOptions,
+		VideoFormat:          s.videoFormat,
+		VideoUnavailable:     s.videoDisabled,
+		VideoURL: func(videoPath string) string {
+			return fmt.Sprintf("/api/videos/%s", filepath.Base(videoPath))
+		},
+		Baseline:       s.loadBaseline(job),
+		EvidenceBudget: s.evidenceBudget,
+		Metadata:       jobMetadata(job),
+		Logger:         job.logger,
+		Progress: func(percent int, message string) {
+			s.updateJob(job.ID, "running", percent, message)
+		},
+		ConsoleLogger: job.console,
+		OnConsoleLog:  job.logs.publish,
+		Evaluator:     gameEval,
+		Artifacts:     &artifacts,
+	})
+	if err != nil && job.ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		job.logger.Printf("Test %s timed out after %v: %v", job.ID, timeout, err)
+		testsTimedOut.Inc()
+		s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Test timed out after %v", timeout))
 		return
 	}
-
-	s.updateJob(job.ID, "running", 40, "Loading game page...")
-
-	// Wait for page load
-	time.Sleep(2 * time.Second)
-
-	// Remove ads and handle cookie consent with improved logic
-	log.Printf("Removing ads and handling cookie consent...")
-	if err := bm.RemoveAdsAndCookieConsent(); err != nil {
-		log.Printf("Warning: Ad blocking/cookie consent failed: %v", err)
-	} else {
-		log.Printf("Ad blocking and cookie consent handling completed")
-		time.Sleep(200 * time.Millisecond)
+	if errors.Is(err, session.ErrCancelled) {
+		return
 	}
-
-	detector := agent.NewUIDetector(bm.GetContext())
-
-	s.updateJob(job.ID, "running", 50, "Starting game...")
-
-	// Use vision + DOM to detect and click start button
-	log.Printf("Using GPT-4o vision + DOM to detect and click start button...")
-	visionDOMDetector, err := agent.NewVisionDOMDetector(bm.GetContext())
-	startButtonClicked := false
 	if err != nil {
-		log.Printf("Warning: Could not create vision DOM detector: %v", err)
-		log.Printf("Falling back to DOM-only start button detection...")
-	} else {
-		// Take screenshot for vision analysis
-		visionScreenshot, err := agent.CaptureScreenshot(bm.GetContext(), agent.ContextInitial)
-		if err != nil {
-			log.Printf("Warning: Could not capture screenshot for vision: %v", err)
-		} else {
-			// Detect and click start button
-			err := visionDOMDetector.DetectAndClickStartButton(visionScreenshot)
-			if err != nil {
-				log.Printf("Warning: Vision+DOM start button click failed: %v", err)
-				log.Printf("Falling back to DOM-only start button detection...")
-			} else {
-				log.Printf("✓ Vision+DOM successfully clicked start button")
-				startButtonClicked = true
-				time.Sleep(300 * time.Millisecond) // Wait for click to register
-			}
-		}
+		s.updateJob(job.ID, "failed", 100, err.Error())
+		return
 	}
 
-	// Fallback: Try simple DOM-based start button detection
-	if !startButtonClicked {
-		log.Printf("Trying DOM-based start button detection...")
-		clicked, err := detector.ClickStartButton()
-		if err != nil {
-			log.Printf("Warning: DOM start button detection failed: %v", err)
-			log.Printf("Game may require manual start or will auto-start")
-		} else if clicked {
-			log.Printf("✓ DOM successfully clicked start button")
-			time.Sleep(300 * time.Millisecond) // Wait for click to register
-		} else {
-			log.Printf("No start button found - game may auto-start")
-		}
+	// Save report to job (unless it was cancelled while the report was being built)
+	s.mu.Lock()
+	if j, ok := s.jobs[job.ID]; ok && j.Status != "cancelled" && j.Status != "interrupted" {
+		j.Report = report
+		j.Status = "completed"
+		testsCompleted.Inc()
+		j.Progress = 100
+		j.Message = "Test completed successfully"
+		j.UpdatedAt = time.Now()
 	}
+	s.mu.Unlock()
 
-	s.updateJob(job.ID, "running", 55, "Waiting for game to load...")
-
-	// Vision-based gameplay detection loop
-	// Keep checking if game has started, if not, use vision to suggest next action
-	maxAttempts := 10
-	gameStarted := false
-	var lastDescription string
-	var lastScreenshotHash string
-	repeatedScreenCount := 0
-
-	for attempt := 1; attempt <= maxAttempts && !gameStarted; attempt++ {
-		log.Printf("Gameplay detection attempt %d/%d...", attempt, maxAttempts)
-
-		// Wait for UI to settle (reduced for faster detection)
-		waitTime := 300 * time.Millisecond
-		if repeatedScreenCount > 0 {
-			// If we're seeing the same screen repeatedly, wait a bit longer
-			waitTime = 500 * time.Millisecond
-			log.Printf("Seeing repeated screen, waiting %v for animations...", waitTime)
-		}
-		time.Sleep(waitTime)
-
-		// Take screenshot for vision analysis
-		screenshot, err := agent.CaptureScreenshot(bm.GetContext(), agent.ContextInitial)
-		if err != nil {
-			log.Printf("Warning: Could not capture screenshot for gameplay detection: %v", err)
-			break
-		}
-
-		// Compute screenshot hash to detect if screen has changed
-		currentHash := screenshot.Hash()
-
-		// Use vision to check if gameplay has started and suggest action
-		if visionDOMDetector != nil {
-			// Skip vision API if screenshot hash matches previous (screen hasn't changed)
-			if currentHash == lastScreenshotHash && lastScreenshotHash != "" {
-				log.Printf("⚡ Screenshot unchanged (hash match), skipping vision API call")
-				repeatedScreenCount++
-				continue
-			}
-			lastScreenshotHash = currentHash
-
-			// Ask vision AI: "Is the game actively playing, or do we need to click something?"
-			action, err := visionDOMDetector.DetectGameplayState(screenshot, job.Request.GameMechanics)
-			if err != nil {
-				log.Printf("Warning: Vision gameplay detection failed: %v", err)
-				// Continue anyway - might be playing
-				gameStarted = true
-				break
-			}
-
-			if action.GameStarted {
-				log.Printf("✓ Vision confirmed game is playing!")
-				gameStarted = true
-				break
-			} else if action.ActionNeeded {
-				log.Printf("Vision detected action needed: %s", action.Description)
-
-				// Track repeated screens to detect stuck states
-				if action.Description == lastDescription {
-					repeatedScreenCount++
-					log.Printf("⚠ Same screen detected %d times in a row", repeatedScreenCount)
-				} else {
-					repeatedScreenCount = 0
-					lastDescription = action.Description
-				}
-
-				// Inspect canvas coordinates on first attempt for debugging
-				if attempt == 1 {
-					if err := visionDOMDetector.InspectCanvasCoordinates(); err != nil {
-						log.Printf("Canvas inspection failed: %v", err)
-					}
-				}
-
-				// Try coordinate-based click first (works for canvas-rendered buttons)
-				if action.ClickX > 0 && action.ClickY > 0 {
-					// If we've seen the same screen multiple times, try small coordinate variations
-					clickX := action.ClickX
-					clickY := action.ClickY
-
-					if repeatedScreenCount > 2 {
-						// Add random variation of ±10 pixels to try hitting the button from different angles
-						variation := 10
-						offsetX := (repeatedScreenCount % 3) - 1 // -1, 0, or 1
-						offsetY := ((repeatedScreenCount / 3) % 3) - 1
-						clickX += offsetX * variation
-						clickY += offsetY * variation
-						log.Printf("Trying coordinate variation: (%d, %d) -> (%d, %d)", action.ClickX, action.ClickY, clickX, clickY)
-					}
-
-					log.Printf("Attempting to click at coordinates: (%d, %d)", clickX, clickY)
-
-				// Save screenshot with visual marker showing where we're clicking
-				markerLabel := fmt.Sprintf("attempt%d", attempt)
-				markerPath, markerErr := agent.SaveScreenshotWithClickMarker(screenshot, clickX, clickY, markerLabel)
-				if markerErr != nil {
-					log.Printf("Warning: Could not save click marker screenshot: %v", markerErr)
-				} else {
-					log.Printf("📍 Saved screenshot with click marker: %s", markerPath)
-				}
-					err := visionDOMDetector.ClickAt(clickX, clickY)
-					if err != nil {
-						log.Printf("Warning: Coordinate click failed: %v", err)
-					} else {
-						log.Printf("✓ Clicked at vision-suggested coordinates")
-						continue // Continue to next iteration to check if game started
-					}
-				}
-
-				// Fallback to DOM text-based click (works for HTML buttons)
-				if action.ButtonText != "" {
-					log.Printf("Attempting DOM click for button text: %s", action.ButtonText)
-					err := visionDOMDetector.ClickButtonByText(action.ButtonText)
-					if err != nil {
-						log.Printf("Warning: Could not click suggested button: %v", err)
-						// Try clicking the canvas as final fallback
-						log.Printf("Fallback: clicking canvas center...")
-						if focused, focusErr := detector.FocusGameCanvas(); focusErr == nil && focused {
-							time.Sleep(200 * time.Millisecond)
-						}
-					} else {
-						log.Printf("✓ Clicked suggested button: %s", action.ButtonText)
-					}
-				}
-			} else {
-				log.Printf("Vision suggests waiting for game to initialize...")
-			}
-		} else {
-			// No vision available, assume game started after first attempt
-			gameStarted = true
-			break
-		}
+	if s.jobCancelled(job, "saving results") {
+		return
 	}
 
-	if !gameStarted {
-		log.Printf("Could not confirm game started after %d attempts, proceeding anyway...", maxAttempts)
+	// Games that never render are reported without an evaluation
+	var overallScore int
+	if report.Score != nil {
+		overallScore = report.Score.OverallScore
 	}
 
-	// Initialize video recorder (needed for both intelligent and standard gameplay)
-	log.Printf("Initializing video recorder...")
-	videoRecorder := agent.NewVideoRecorder(bm.GetContext())
-
-	// Start video recording early to capture all gameplay
-	log.Printf("Starting video recording...")
-	if err := videoRecorder.StartRecording(); err != nil {
-		log.Printf("Warning: Failed to start video recording: %v", err)
-		log.Printf("Continuing without video recording...")
-	} else {
-		log.Printf("✓ Video recording started")
-	}
-
-	// Declare variables for standard gameplay mode (must be before goto to avoid compilation error)
-	var useCanvasMode bool
-	var focused bool
-	var gameplayDuration time.Duration = time.Duration(job.Request.MaxDuration) * time.Second
-	var gameplayStart time.Time
-	var gameplayScreenshots []*agent.Screenshot
-	var lastScreenshotTime time.Time
-	var screenshotInterval time.Duration = 2 * time.Second
-	var gameplayMode string = "keyboard"
-	var unchangedCount int = 0
-	var lastGameplayHash string = ""
-	const unchangedThreshold = 5
-	var screenWidth int = 1280
-	var screenHeight int = 720
-
-	// === INTELLIGENT GAMEPLAY MODE ===
-	// If game mechanics are provided, use AI-powered gameplay agent
-	// This is inspired by Stagehand's action sequencing and self-healing patterns
-	if job.Request.GameMechanics != "" && visionDOMDetector != nil {
-		log.Printf("🎮 Starting intelligent gameplay mode (game mechanics provided)")
-		log.Printf("Game mechanics: %s", job.Request.GameMechanics)
-
-		// Create gameplay agent
-		gameplayAgent, err := agent.NewGameplayAgent(bm.GetContext(), visionDOMDetector)
-		if err != nil {
-			log.Printf("Warning: Could not create gameplay agent: %v", err)
-			log.Printf("Falling back to standard gameplay mode...")
-		} else {
-			s.updateJob(job.ID, "running", 65, "Playing game with AI-guided actions...")
-
-			// Determine game name from URL (simple extraction)
-			gameName := "unknown"
-			if strings.Contains(strings.ToLower(job.Request.URL), "angry") {
-				gameName = "angry_birds"
-			}
-
-			// Execute AI-powered gameplay loop
-			// This will:
-			// 1. Use vision to detect slingshot and targets
-			// 2. Calculate optimal aim using GPT-4o
-			// 3. Execute precise CDP mouse drags
-			// 4. Cache successful actions for self-healing
-			// Estimate ~10-15s per attempt (Vision API call + drag action)
-			estimatedSecondsPerAttempt := 12
-			maxGameplayAttempts := job.Request.MaxDuration / estimatedSecondsPerAttempt
-			if maxGameplayAttempts < 1 {
-				maxGameplayAttempts = 1 // At least one attempt
-			}
-			log.Printf("Executing up to %d AI-guided gameplay attempts (duration: %ds)...", maxGameplayAttempts, job.Request.MaxDuration)
-
-			err = gameplayAgent.PlayGameLevel(gameName, job.Request.GameMechanics, maxGameplayAttempts)
-			if err != nil {
-				log.Printf("Warning: Gameplay agent failed: %v", err)
-				log.Printf("Continuing with test anyway...")
-			} else {
-				log.Printf("✓ AI-guided gameplay completed successfully")
-
-				// Show cached successful actions
-				cachedDrags := gameplayAgent.GetCachedDragsForGame(gameName)
-				if len(cachedDrags) > 0 {
-					log.Printf("📦 Cached %d successful actions for future self-healing", len(cachedDrags))
-				}
-			}
-
-			s.updateJob(job.ID, "running", 85, "Finalizing test...")
-
-			// Skip to evidence collection after AI gameplay
-			goto collectEvidence
-		}
+	// Persist completed test to database
+	if err := s.db.CompleteTest(
+		job.ID,
+		"completed",
+		overallScore,
+		int(report.Duration.Seconds()),
+		report.ReportID,
+		report,
+	); err != nil {
+		job.logger.Printf("Warning: Failed to persist completed test to database: %v", err)
 	}
 
-	// === STANDARD GAMEPLAY MODE ===
-	// Detect if game uses canvas or DOM rendering
-	log.Printf("Detecting game rendering type...")
-	focused, err = detector.FocusGameCanvas()
-	if err != nil || !focused {
-		log.Printf("No canvas detected or focus failed - using DOM/window event mode")
-		useCanvasMode = false
-	} else {
-		log.Printf("Canvas detected and focused - using canvas event mode")
-		useCanvasMode = true
+	if job.Request.SaveBaseline {
+		s.saveBaseline(job, report, &artifacts)
 	}
 
-	// Add small delay after detection
-	time.Sleep(200 * time.Millisecond)
-
-	s.updateJob(job.ID, "running", 60, "Playing game with keyboard controls...")
-
-	// Simulate realistic gameplay with varied interactions over time
-	gameplayStart = time.Now()
-	lastScreenshotTime = time.Now()
-
-	log.Printf("Starting %v of adaptive gameplay (starting with keyboard)...", gameplayDuration)
-
-	// Gameplay loop - adaptive input mode
-	for time.Since(gameplayStart) < gameplayDuration {
-		progress := 60 + int(25*time.Since(gameplayStart).Seconds()/gameplayDuration.Seconds())
-		s.updateJob(job.ID, "running", progress, fmt.Sprintf("Playing game... %.0fs elapsed", time.Since(gameplayStart).Seconds()))
-
-		// Capture screenshot for both saving and change detection
-		screenshot, err := agent.CaptureScreenshot(bm.GetContext(), agent.ContextGameplay)
-		var currentHash string
-		if err == nil && screenshot != nil {
-			currentHash = screenshot.Hash()
-			screenWidth = screenshot.Width
-			screenHeight = screenshot.Height
-
-			// Save screenshot every 2 seconds
-			if time.Since(lastScreenshotTime) >= screenshotInterval {
-				if err := screenshot.SaveToTemp(); err != nil {
-					log.Printf("Warning: Failed to save gameplay screenshot: %v", err)
-				} else {
-					gameplayScreenshots = append(gameplayScreenshots, screenshot)
-					log.Printf("✓ Captured gameplay screenshot (%d total)", len(gameplayScreenshots))
-				}
-				lastScreenshotTime = time.Now()
-			}
-
-			// Check if screen changed since last action
-			if currentHash == lastGameplayHash && lastGameplayHash != "" {
-				unchangedCount++
-				log.Printf("[Adaptive] Screen unchanged (%d/%d) in %s mode", unchangedCount, unchangedThreshold, gameplayMode)
-			} else {
-				if unchangedCount > 0 {
-					log.Printf("[Adaptive] Screen changed! %s mode is working", gameplayMode)
-				}
-				unchangedCount = 0
-			}
-			lastGameplayHash = currentHash
-		}
-
-		// Adaptive mode switching based on effectiveness
-		if unchangedCount >= unchangedThreshold {
-			switch gameplayMode {
-			case "keyboard":
-				log.Printf("🔄 Keyboard not effective, switching to mouse clicks")
-				gameplayMode = "mouse-click"
-				unchangedCount = 0
-			case "mouse-click":
-				log.Printf("🔄 Mouse clicks not effective, switching to mouse drags")
-				gameplayMode = "mouse-drag"
-				unchangedCount = 0
-			case "mouse-drag":
-				log.Printf("🔄 Mouse drags not effective, cycling back to keyboard")
-				gameplayMode = "keyboard"
-				unchangedCount = 0
-			}
-		}
-
-		// Perform actions based on current mode
-		switch gameplayMode {
-		case "keyboard":
-			// Send varied key presses (existing behavior)
-			gameplayActions := []string{
-				"ArrowUp", "ArrowUp",
-				"ArrowRight", "ArrowRight", "ArrowRight",
-				"Space",
-				"ArrowLeft", "ArrowLeft",
-				"ArrowDown",
-				"Space",
-				"ArrowRight",
-			}
-
-			for _, key := range gameplayActions {
-				var sent bool
-				var err error
-
-				if useCanvasMode {
-					sent, err = detector.SendKeyboardEventToCanvas(key)
-				} else {
-					sent, err = detector.SendKeyboardEventToWindow(key)
-				}
-
-				if err != nil {
-					log.Printf("Error sending key %s: %v", key, err)
-				} else if !sent {
-					log.Printf("Warning: Failed to send key %s", key)
-				}
-				time.Sleep(150 * time.Millisecond)
-			}
-			time.Sleep(200 * time.Millisecond)
-
-		case "mouse-click":
-			// Perform 3-4 random clicks in game area
-			clickCount := 3 + rand.Intn(2) // 3 or 4 clicks
-			for i := 0; i < clickCount; i++ {
-				if visionDOMDetector != nil {
-					err := agent.PerformRandomClick(bm.GetContext(), screenWidth, screenHeight)
-					if err != nil {
-						log.Printf("Random click %d failed: %v", i+1, err)
-					}
-				}
-				time.Sleep(300 * time.Millisecond)
-			}
-			time.Sleep(500 * time.Millisecond)
-
-		case "mouse-drag":
-			// Try different drag patterns
-			patterns := []agent.DragPattern{
-				agent.DragPatternHorizontalLeft,  // Slingshot style
-				agent.DragPatternVerticalUp,       // Upward swipe
-				agent.DragPatternHorizontalRight,  // Right swipe
-			}
-			pattern := patterns[rand.Intn(len(patterns))]
+	job.logger.Printf("Test %s completed with score: %d/100", job.ID, overallScore)
+}
 
-			if visionDOMDetector != nil {
-				err := agent.PerformRandomDrag(bm.GetContext(), pattern, screenWidth, screenHeight)
-				if err != nil {
-					log.Printf("Drag %s failed: %v", pattern, err)
-				}
-			}
-			time.Sleep(1 * time.Second) // Wait longer after drags
-		}
+// testTimeout is the longest a test may run once it has a browser slot: its gameplay
+// duration plus the server's overhead, with extra time for ensemble evaluations
+func (s *Server) testTimeout(req TestRequest) time.Duration {
+	timeout := time.Duration(req.MaxDuration)*time.Second + s.testOverhead
+	if req.Ensemble > 1 {
+		timeout += time.Duration(req.Ensemble-1) * ensembleRunAllowance
 	}
+	return timeout
+}
 
-	log.Printf("Gameplay simulation completed after %v", time.Since(gameplayStart))
+// retentionInterval is how often old tests and media are cleaned up
+const retentionInterval = 24 * time.Hour
 
-collectEvidence:
-	// Stop video recording
-	var videoPath string
-	if videoRecorder.IsRecording {
-		log.Printf("Stopping video recording...")
-		if err := videoRecorder.StopRecording(); err != nil {
-			log.Printf("Warning: Failed to stop video recording: %v", err)
-		} else {
-			log.Printf("✓ Video recording stopped")
-			log.Printf("Recorded %d frames over %v", videoRecorder.GetFrameCount(), videoRecorder.GetDuration())
-
-			// Save video to temp file
-			log.Printf("Saving video as MP4...")
-			videoPath, err = videoRecorder.SaveToTemp()
-			if err != nil {
-				log.Printf("Warning: Failed to save video: %v", err)
-			} else {
-				log.Printf("✓ Video saved to: %s", videoPath)
-			}
-		}
+// runRetention deletes expired tests now and then once per retentionInterval
+func (s *Server) runRetention() {
+	for {
+		s.cleanupExpired()
+		time.Sleep(retentionInterval)
 	}
+}
 
-	s.updateJob(job.ID, "running", 70, "Capturing final screenshot...")
-
-	// Wait for game state to settle
-	time.Sleep(200 * time.Millisecond)
-
-	// Capture final screenshot
-	finalScreenshot, err := agent.CaptureScreenshot(bm.GetContext(), agent.ContextFinal)
+// cleanupExpired deletes tests older than the retention window, their in-memory jobs,
+// and the media files (screenshots, videos, HAR logs) their reports refer to. Media is
+// found from the deleted rows' reports, so files still referenced by a kept report
+// are never removed.
+func (s *Server) cleanupExpired() {
+	tests, err := s.db.DeleteTestsOlderThan(s.retention)
 	if err != nil {
-		s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Final screenshot failed: %v", err))
-		return
-	}
-	if err := finalScreenshot.SaveToTemp(); err != nil {
-		s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Failed to save final screenshot: %v", err))
+		log.Printf("[Retention] Failed to delete old tests: %v", err)
 		return
 	}
 
-	s.updateJob(job.ID, "running", 80, "Getting console logs...")
-
-	// Get console logs
-	logs := consoleLogger.GetLogs()
-
-	s.updateJob(job.ID, "running", 90, "Evaluating with AI...")
-
-	// Evaluate with LLM
-	gameEval, err := evaluator.NewGameEvaluator("")
-	if err != nil {
-		log.Printf("Warning: Could not initialize evaluator: %v", err)
-		s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Evaluator initialization failed: %v", err))
-		return
+	expired := make(map[string]bool, len(tests))
+	var mediaFiles []string
+	reports := 0
+	for _, test := range tests {
+		expired[test.ID] = true
+		if test.ReportData == "" {
+			continue
+		}
+		var report reporter.Report
+		if err := json.Unmarshal([]byte(test.ReportData), &report); err != nil {
+			log.Printf("[Retention] Failed to parse report %s, its media is kept: %v", test.ReportID, err)
+			continue
+		}
+		reports++
+		mediaFiles = append(mediaFiles, report.MediaFiles()...)
 	}
+	cutoff := time.Now().Add(-s.retention)
 
-	// Combine all screenshots: initial, gameplay screenshots, final
-	screenshots := []*agent.Screenshot{initialScreenshot}
-	screenshots = append(screenshots, gameplayScreenshots...)
-	screenshots = append(screenshots, finalScreenshot)
-	score, err := gameEval.EvaluateGame(job.ctx, screenshots, logs)
-	if err != nil {
-		s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Evaluation failed: %v", err))
-		return
+	s.mu.Lock()
+	for id, job := range s.jobs {
+		if expired[id] || (job.Status != "running" && job.Status != "pending" && job.CreatedAt.Before(cutoff)) {
+			delete(s.jobs, id)
+		}
 	}
+	s.mu.Unlock()
 
-	// Build report
-	reportBuilder := reporter.NewReportBuilder(job.Request.URL)
-	reportBuilder.AddMetadata("test_id", job.ID)
-	reportBuilder.AddMetadata("headless", fmt.Sprintf("%v", job.Request.Headless))
-	reportBuilder.SetScreenshots(screenshots)
-	reportBuilder.SetConsoleLogs(logs)
-	reportBuilder.SetScore(score)
-
-	// Set video URL if video was recorded
-	if videoPath != "" {
-		// Extract filename from path
-		videoFilename := filepath.Base(videoPath)
-		videoURL := fmt.Sprintf("/api/videos/%s", videoFilename)
-		reportBuilder.SetVideoURL(videoURL)
-		log.Printf("Video URL set to: %s", videoURL)
+	mediaDir := filepath.Join(".", "data", "media")
+	removed := 0
+	for _, name := range mediaFiles {
+		err := os.Remove(filepath.Join(mediaDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[Retention] Failed to remove %s: %v", name, err)
+			continue
+		}
+		removed++
 	}
 
-	report, err := reportBuilder.Build()
-	if err != nil {
-		s.updateJob(job.ID, "failed", 100, fmt.Sprintf("Report build failed: %v", err))
-		return
+	if len(tests) > 0 {
+		log.Printf("[Retention] Deleted %d tests, %d reports and %d media files older than %v", len(tests), reports, removed, s.retention)
 	}
+}
 
-	// Save report to job
-	s.mu.Lock()
-	if j, ok := s.jobs[job.ID]; ok {
-		j.Report = report
-		j.Status = "completed"
-		j.Progress = 100
-		j.Message = "Test completed successfully"
-		j.UpdatedAt = time.Now()
+// requestCookies converts a request's cookies for the browser, defaulting domain and path from the game URL
+func requestCookies(req TestRequest) ([]http.Cookie, error) {
+	cookies := make([]http.Cookie, len(req.Cookies))
+	for i, c := range req.Cookies {
+		cookies[i] = http.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HttpOnly: c.HTTPOnly,
+		}
 	}
-	s.mu.Unlock()
+	return agent.CookieDefaults(cookies, req.URL)
+}
 
-	// Persist completed test to database
-	if err := s.db.CompleteTest(
-		job.ID,
-		"completed",
-		score.OverallScore,
-		int(report.Duration.Seconds()),
-		report.ReportID,
-		report,
-	); err != nil {
-		log.Printf("Warning: Failed to persist completed test to database: %v", err)
+// jobCancelled reports whether the test was cancelled and logs the phase it was aborted at
+func (s *Server) jobCancelled(job *TestJob, phase string) bool {
+	if job.ctx.Err() == nil {
+		job.logger.SetPhase(phase)
+		return false
 	}
-
-	log.Printf("Test %s completed with score: %d/100", job.ID, score.OverallScore)
+	job.logger.Printf("Test %s cancelled, aborting before %s", job.ID, phase)
+	return true
 }
 
 // Update job status
@@ -1266,6 +1573,13 @@ func (s *Server) updateJob(id, status string, progress int, message string) {
 	defer s.mu.Unlock()
 
 	if job, ok := s.jobs[id]; ok {
+		// A cancelled or interrupted test is final; ignore late updates from the aborting goroutine
+		if job.Status == "cancelled" || job.Status == "interrupted" {
+			return
+		}
+		if status == "failed" && job.Status != "failed" {
+			testsFailed.Inc()
+		}
 		job.Status = status
 		job.Progress = progress
 		job.Message = message
@@ -1279,6 +1593,9 @@ func (s *Server) updateJob(id, status string, progress int, message string) {
 }
 
 func main() {
+	// LOG_FORMAT=json emits JSON lines tagged with test_id/phase for log aggregation
+	logging.Setup(os.Getenv("LOG_FORMAT"))
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
@@ -1286,7 +1603,55 @@ func main() {
 
 	apiKey := os.Getenv("OPENAI_API_KEY")
 
-	server := NewServer(port, apiKey)
+	maxConcurrent := envInt("MAX_CONCURRENT_TESTS", defaultMaxConcurrent)
+	server := NewServer(port, apiKey, maxConcurrent)
+	log.Printf("🧪 Max concurrent tests: %d", server.maxConcurrent)
+
+	// Optional bearer token for submitting/cancelling tests
+	server.authToken = os.Getenv("API_AUTH_TOKEN")
+	if server.authToken != "" {
+		log.Printf("🔒 API authentication enabled for mutating endpoints")
+	}
+
+	// Limit concurrent LLM/vision calls separately from test concurrency (OpenAI rate limits)
+	maxVisionCalls := envInt("MAX_CONCURRENT_VISION_CALLS", agent.DefaultLLMConcurrency)
+	agent.SetLLMConcurrency(maxVisionCalls)
+	log.Printf("🧠 Max concurrent vision calls: %d", maxVisionCalls)
+
+	// Evidence budget keeps long, noisy runs from producing huge reports
+	server.evidenceBudget = reporter.EvidenceBudget{
+		MaxBytes:       int64(envInt("EVIDENCE_MAX_MB", int(reporter.DefaultEvidenceBudget.MaxBytes/(1024*1024)))) * 1024 * 1024,
+		MaxScreenshots: envInt("EVIDENCE_MAX_SCREENSHOTS", reporter.DefaultEvidenceBudget.MaxScreenshots),
+		MaxConsoleLogs: envInt("EVIDENCE_MAX_LOGS", reporter.DefaultEvidenceBudget.MaxConsoleLogs),
+	}
+
+	// Accessibility audits check WCAG 2.0 A/AA and 2.1 AA unless ACCESSIBILITY_TAGS says otherwise
+	a11yTags, err := agent.ParseAccessibilityTags(os.Getenv("ACCESSIBILITY_TAGS"))
+	if err != nil {
+		log.Fatalf("Invalid ACCESSIBILITY_TAGS: %v", err)
+	}
+	server.a11yTags = a11yTags
+	if server.a11yWeights, err = agent.ParseAccessibilityWeights(os.Getenv("ACCESSIBILITY_WEIGHTS")); err != nil {
+		log.Fatalf("Invalid ACCESSIBILITY_WEIGHTS: %v", err)
+	}
+
+	// Screenshots are PNG unless SCREENSHOT_FORMAT=jpeg, which makes them several times smaller
+	if server.shotFormat, err = agent.ParseScreenshotFormat(os.Getenv("SCREENSHOT_FORMAT"), envInt("SCREENSHOT_QUALITY", 0)); err != nil {
+		log.Fatalf("Invalid SCREENSHOT_FORMAT/SCREENSHOT_QUALITY: %v", err)
+	}
+
+	// Pick a video format the installed ffmpeg can encode (VIDEO_FORMAT=mp4|webm)
+	videoFormat, err := agent.SelectVideoFormat(agent.VideoFormat(strings.ToLower(os.Getenv("VIDEO_FORMAT"))))
+	if errors.Is(err, agent.ErrFFmpegMissing) {
+		log.Printf("⚠️  Video recording disabled: %v", err)
+		server.videoDisabled = "ffmpeg not found"
+	} else if err != nil {
+		log.Printf("⚠️  Video recording disabled: %v", err)
+		server.videoDisabled = "no supported ffmpeg encoder"
+	} else {
+		log.Printf("🎬 Video format: %s", videoFormat)
+	}
+	server.videoFormat = videoFormat
 
 	// Initialize database
 	dbPath := os.Getenv("DB_PATH")
@@ -1301,35 +1666,90 @@ func main() {
 	server.db = database
 	log.Printf("📦 Database initialized: %s", dbPath)
 
+	// Report retention (REPORT_RETENTION_DAYS=0 keeps everything)
+	if days := envInt("REPORT_RETENTION_DAYS", 0); days > 0 {
+		server.retention = time.Duration(days) * 24 * time.Hour
+		log.Printf("🧹 Deleting reports and media older than %d days", days)
+		go server.runRetention()
+	}
+
+	if secs := envInt("SYNC_MAX_WAIT_SECONDS", 0); secs > 0 {
+		server.syncMaxWait = time.Duration(secs) * time.Second
+	}
+
+	// Shutdown waits this long for running tests before interrupting them
+	if secs := envInt("SHUTDOWN_GRACE_SECONDS", 0); secs > 0 {
+		server.shutdownGrace = time.Duration(secs) * time.Second
+	}
+
+	// Tests time out after their gameplay duration plus this overhead
+	if secs := envInt("TEST_OVERHEAD_SECONDS", 0); secs > 0 {
+		server.testOverhead = time.Duration(secs) * time.Second
+	}
+
+	// Chrome binary and extra flags (CHROME_PATH, EXTRA_CHROME_FLAGS, ...)
+	server.chromeOptions, err = agent.ChromeOptionsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid Chrome configuration: %v", err)
+	}
+
+	// Game hosts operators allow testing (ALLOWED_DOMAINS / DENIED_DOMAINS)
+	server.domains, err = domainPolicyFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid domain configuration: %v", err)
+	}
+	if len(server.domains.allowed) > 0 {
+		log.Printf("🔒 Testing restricted to: %s", strings.Join(server.domains.allowed, ", "))
+	}
+	if len(server.domains.denied) > 0 {
+		log.Printf("🔒 Testing denied for: %s", strings.Join(server.domains.denied, ", "))
+	}
+
+	// Tests left unfinished by a crash will never complete; REQUEUE_INTERRUPTED_TESTS=true
+	// runs them again
+	server.reconcileOrphans(os.Getenv("REQUEUE_INTERRUPTED_TESTS") == "true")
+
 	// Setup routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", server.corsMiddleware(server.handleHealth))
+	mux.Handle("/metrics", server.metricsHandler())
 	mux.HandleFunc("/api/config", server.corsMiddleware(server.handleConfig))
-	mux.HandleFunc("/api/tests", server.corsMiddleware(server.handleTestSubmit))
-	mux.HandleFunc("/api/tests/", server.corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/tests", server.corsMiddleware(server.authMiddleware(server.handleTestSubmit)))
+	mux.HandleFunc("/api/tests/sync", server.corsMiddleware(server.authMiddleware(server.handleTestSubmitSync)))
+	mux.HandleFunc("/api/tests/inspect", server.corsMiddleware(server.authMiddleware(server.handleTestInspect)))
+	mux.HandleFunc("/api/tests/", server.corsMiddleware(server.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "GET" {
 			// Check if it's a list or single test request
 			testID := r.URL.Path[len("/api/tests/"):]
-			if testID == "" || testID == "list" {
+			if id, ok := strings.CutSuffix(testID, "/logs"); ok {
+				server.handleTestLogs(w, r, id)
+			} else if testID == "" || testID == "list" {
 				server.handleTestList(w, r)
 			} else {
 				server.handleTestStatus(w, r)
 			}
+		} else if id, ok := strings.CutSuffix(r.URL.Path[len("/api/tests/"):], "/rerun"); ok && r.Method == http.MethodPost {
+			server.handleTestRerun(w, r, id)
+		} else if id, ok := strings.CutSuffix(r.URL.Path[len("/api/tests/"):], "/replay"); ok && r.Method == http.MethodPost {
+			server.handleTestReplay(w, r, id)
+		} else if r.Method == http.MethodDelete {
+			server.handleTestCancel(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-	}))
-	mux.HandleFunc("/api/reports/", server.corsMiddleware(server.handleTestReport))
+	})))
+	mux.HandleFunc("/api/reports/", server.corsMiddleware(server.authMiddleware(server.handleTestReport)))
 	mux.HandleFunc("/api/screenshots/", server.corsMiddleware(server.handleScreenshot))
 	mux.HandleFunc("/api/videos/", server.corsMiddleware(server.handleVideo))
-	mux.HandleFunc("/api/batch-tests", server.corsMiddleware(server.handleBatchTestSubmit))
+	mux.HandleFunc("/api/stats", server.corsMiddleware(server.handleStats))
+	mux.HandleFunc("/api/evaluate", server.corsMiddleware(server.authMiddleware(server.handleEvaluate)))
+	mux.HandleFunc("/api/batch-tests", server.corsMiddleware(server.authMiddleware(server.handleBatchTestSubmit)))
 	mux.HandleFunc("/api/batch-tests/", server.corsMiddleware(server.handleBatchTestStatus))
 
 	// Serve media files (videos and screenshots)
 	mediaDir := "./data/media"
 	if _, err := os.Stat(mediaDir); err == nil {
-		mediaFS := http.FileServer(http.Dir(mediaDir))
-		mux.Handle("/media/", http.StripPrefix("/media/", mediaFS))
+		mux.Handle("/media/", http.StripPrefix("/media/", mediaHandler(mediaDir)))
 		log.Printf("📁 Serving media files from: %s", mediaDir)
 	}
 
@@ -1369,11 +1789,22 @@ func main() {
 		log.Printf("🚀 DreamUp QA API Server v%s", version)
 		log.Printf("🌐 Listening on http://localhost:%s", port)
 		log.Printf("📊 Health check: http://localhost:%s/health", port)
+		log.Printf("📈 Prometheus metrics: http://localhost:%s/metrics", port)
 		log.Printf("📝 API endpoints:")
 		log.Printf("   POST   /api/tests            - Submit new test")
+		log.Printf("   POST   /api/tests/sync       - Run a test and wait for its report")
 		log.Printf("   GET    /api/tests/{id}       - Get test status")
-		log.Printf("   GET    /api/tests/list       - List all tests")
+		log.Printf("   DELETE /api/tests/{id}       - Cancel a running test")
+		log.Printf("   GET    /api/tests/{id}/logs  - Stream console logs live (SSE, ?levels=error,warning)")
+		log.Printf("   GET    /api/tests/list       - List tests (?status=&q=&limit=&offset=)")
 		log.Printf("   GET    /api/reports/{id}     - Get test report")
+		log.Printf("   GET    /api/reports/{id}/html     - View report as HTML")
+		log.Printf("   GET    /api/reports/compare?a={id}&b={id} - Diff two reports")
+		log.Printf("   GET    /api/reports/{id}/snapshot - Export report as self-contained HTML")
+		log.Printf("   GET    /api/reports/{id}/har      - Download HAR network log (captureHar tests)")
+		log.Printf("   GET    /api/reports/{id}/dom      - Final DOM snapshot (as text)")
+		log.Printf("   GET    /api/videos/{file}/thumbnail.gif - Looping GIF preview of gameplay")
+		log.Printf("   GET    /api/stats            - Aggregate test statistics (?q=&since=&until=)")
 		log.Printf("   POST   /api/batch-tests      - Submit batch test (up to 10 URLs)")
 		log.Printf("   GET    /api/batch-tests/{id} - Get batch test status")
 
@@ -1389,6 +1820,9 @@ func main() {
 
 	log.Println("Shutting down server...")
 
+	// Let running tests finish (status polling keeps working meanwhile)
+	server.drain()
+
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
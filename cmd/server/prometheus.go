@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus metrics for test throughput and failures, served on /metrics
+var (
+	testsSubmitted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "dreamup_tests_submitted_total",
+		Help: "Tests accepted for execution (single and batch).",
+	})
+	testsCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "dreamup_tests_completed_total",
+		Help: "Tests that finished with a report.",
+	})
+	testsFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "dreamup_tests_failed_total",
+		Help: "Tests that failed before producing a report.",
+	})
+	testsTimedOut = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "dreamup_tests_timed_out_total",
+		Help: "Tests stopped for exceeding their time budget (also counted as failed).",
+	})
+	testDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "dreamup_test_duration_seconds",
+		Help:    "Wall-clock time tests spent executing, excluding time queued for a browser slot.",
+		Buckets: []float64{15, 30, 60, 90, 120, 180, 300, 600},
+	})
+)
+
+// metricsHandler registers the server's metrics and returns the /metrics handler
+func (s *Server) metricsHandler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		testsSubmitted,
+		testsCompleted,
+		testsFailed,
+		testsTimedOut,
+		testDuration,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "dreamup_tests_running",
+			Help: "Tests currently holding a browser slot.",
+		}, func() float64 {
+			running, _ := s.scheduler.stats()
+			return float64(running)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "dreamup_tests_max_concurrent",
+			Help: "Browser slots available (MAX_CONCURRENT_TESTS).",
+		}, func() float64 {
+			return float64(s.maxConcurrent)
+		}),
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+	for p := priority(0); p < numPriorities; p++ {
+		registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "dreamup_tests_queued",
+			Help:        "Tests waiting for a browser slot, by priority.",
+			ConstLabels: prometheus.Labels{"priority": p.String()},
+		}, func() float64 {
+			_, queued := s.scheduler.stats()
+			return float64(queued[p])
+		}))
+	}
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
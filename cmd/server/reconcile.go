@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log"
+)
+
+// orphanedMessage is the status message of tests a previous server process left unfinished
+const orphanedMessage = "Test interrupted: the server restarted before it finished"
+
+// reconcileOrphans resolves tests a previous server process left pending or running, e.g.
+// after a crash: their jobs existed only in that process, so they are marked interrupted.
+// With requeue, each one is then re-run from its stored request (without credentials), the
+// same way POST /api/tests/{id}/rerun would.
+func (s *Server) reconcileOrphans(requeue bool) {
+	orphans, err := s.db.InterruptUnfinishedTests(orphanedMessage)
+	if err != nil {
+		log.Printf("Warning: Failed to mark unfinished tests as interrupted: %v", err)
+		return
+	}
+	if len(orphans) == 0 {
+		return
+	}
+	log.Printf("📦 Marked %d unfinished tests from a previous run as interrupted", len(orphans))
+
+	if !requeue {
+		return
+	}
+
+	requeued := 0
+	for i := range orphans {
+		orphan := &orphans[i]
+		req, err := requestFromRecord(orphan)
+		if err == nil {
+			err = validateTestRequest(&req)
+		}
+		if err == nil {
+			err = s.domains.check(req.URL)
+		}
+		if err != nil {
+			log.Printf("Warning: Not requeuing interrupted test %s: %v", orphan.ID, err)
+			continue
+		}
+
+		job := s.newTestJob(req)
+		job.rerunOf = orphan.ID
+		log.Printf("🔁 Test %s re-runs interrupted test %s", job.ID, orphan.ID)
+		go s.executeTest(job)
+		requeued++
+	}
+	log.Printf("🔁 Requeued %d of %d interrupted tests", requeued, len(orphans))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// Replay a test's recorded actions in a new test: POST /api/tests/{id}/replay.
+// The new test loads the same game with the same configuration, then sends the original
+// clicks, drags, key and gamepad events with their original timing instead of playing,
+// capturing fresh screenshots and video. See originalRequest for which credentials carry over.
+func (s *Server) handleTestReplay(w http.ResponseWriter, r *http.Request, testID string) {
+	if s.rejectDraining(w) {
+		return
+	}
+
+	report, status, err := s.loadReport(testID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if report.Evidence == nil || len(report.Evidence.Actions) == 0 {
+		http.Error(w, "Test has no recorded actions to replay", http.StatusUnprocessableEntity)
+		return
+	}
+
+	req, omitted, status, err := s.originalRequest(testID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if err := validateTestRequest(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Original request is no longer valid: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+	if s.rejectDomain(w, req.URL) {
+		return
+	}
+
+	newJob := s.newTestJob(req)
+	newJob.replayOf = testID
+	newJob.replay = report.Evidence.Actions
+	log.Printf("⏯️  Test %s replays %d actions from test %s", newJob.ID, len(newJob.replay), testID)
+
+	go s.executeTest(newJob)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReplayResponse{
+		TestID:   newJob.ID,
+		Status:   "pending",
+		ReplayOf: testID,
+		Actions:  len(newJob.replay),
+		Omitted:  omitted,
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dreamup/qa-agent/internal/db"
+)
+
+// storedRequest is req without credentials (headers, basic auth, cookies and proxy
+// passwords), as persisted for re-runs
+func storedRequest(req TestRequest) TestRequest {
+	req.Headers = nil
+	req.BasicAuth = nil
+	req.Cookies = nil
+	if u, err := url.Parse(req.Proxy); err == nil && u.User != nil {
+		req.Proxy = ""
+	}
+	return req
+}
+
+// testParams are the parameters stored in the database for req
+func testParams(req TestRequest) db.TestParams {
+	return db.TestParams{
+		MaxDuration:   req.MaxDuration,
+		Headless:      req.Headless,
+		GameMechanics: req.GameMechanics,
+		Tags:          req.Tags,
+		Request:       storedRequest(req),
+	}
+}
+
+// jobMetadata is the report metadata for a job
+func jobMetadata(job *TestJob) map[string]string {
+	metadata := map[string]string{"test_id": job.ID}
+	if job.rerunOf != "" {
+		metadata["rerun_of"] = job.rerunOf
+	}
+	if job.replayOf != "" {
+		metadata["replay_of"] = job.replayOf
+	}
+	if len(job.Request.Tags) > 0 {
+		metadata["tags"] = strings.Join(job.Request.Tags, ",")
+	}
+	return metadata
+}
+
+// requestFromRecord rebuilds a test's request from the database, without credentials
+func requestFromRecord(record *db.TestRecord) (TestRequest, error) {
+	// Tests recorded before the full request was stored only have its main parameters
+	req := TestRequest{
+		URL:           record.GameURL,
+		MaxDuration:   record.MaxDuration,
+		Headless:      record.Headless,
+		GameMechanics: record.GameMechanics,
+	}
+	if record.RequestData != "" {
+		if err := json.Unmarshal([]byte(record.RequestData), &req); err != nil {
+			return TestRequest{}, fmt.Errorf("invalid stored request: %w", err)
+		}
+	}
+	return req, nil
+}
+
+// originalRequest returns a test's request for running it again. It's taken from memory
+// if the test is still there (credentials included), otherwise from the database (without
+// credentials, which are listed in omitted). On failure it also returns the HTTP status.
+func (s *Server) originalRequest(testID string) (req TestRequest, omitted []string, status int, err error) {
+	s.mu.RLock()
+	job, inMemory := s.jobs[testID]
+	if inMemory {
+		req = job.Request
+	}
+	s.mu.RUnlock()
+	if inMemory {
+		return req, nil, http.StatusOK, nil
+	}
+
+	record, err := s.db.GetTest(testID)
+	if err != nil {
+		return TestRequest{}, nil, http.StatusInternalServerError, fmt.Errorf("Failed to load test: %v", err)
+	}
+	if record == nil {
+		return TestRequest{}, nil, http.StatusNotFound, fmt.Errorf("Test not found")
+	}
+	req, err = requestFromRecord(record)
+	if err != nil {
+		return TestRequest{}, nil, http.StatusInternalServerError, fmt.Errorf("Failed to read stored request: %v", err)
+	}
+	return req, []string{"headers", "basicAuth", "cookies", "proxy credentials"}, http.StatusOK, nil
+}
+
+// Re-run a test with the same configuration: POST /api/tests/{id}/rerun.
+// See originalRequest for which credentials carry over.
+func (s *Server) handleTestRerun(w http.ResponseWriter, r *http.Request, testID string) {
+	if s.rejectDraining(w) {
+		return
+	}
+
+	req, omitted, status, err := s.originalRequest(testID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if err := validateTestRequest(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Original request is no longer valid: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+	if s.rejectDomain(w, req.URL) {
+		return
+	}
+
+	newJob := s.newTestJob(req)
+	newJob.rerunOf = testID
+	log.Printf("🔁 Test %s re-runs test %s", newJob.ID, testID)
+
+	go s.executeTest(newJob)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RerunResponse{
+		TestID:  newJob.ID,
+		Status:  "pending",
+		RerunOf: testID,
+		Omitted: omitted,
+	})
+}
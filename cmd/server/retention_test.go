@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/reporter"
+)
+
+func TestCleanupExpiredDeletesReportMedia(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mediaDir := filepath.Join("data", "media")
+	if err := os.MkdirAll(mediaDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer(t)
+
+	// complete stores a report for a new test and writes the media files it refers to
+	complete := func(name string, report *reporter.Report, files ...string) *TestJob {
+		t.Helper()
+		job := s.newTestJob(TestRequest{URL: "https://example.com/" + name})
+		if err := s.db.CompleteTest(job.ID, "completed", 80, 10, report.ReportID, report); err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			if err := os.WriteFile(filepath.Join(mediaDir, file), []byte(file), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return job
+	}
+
+	old := complete("old", &reporter.Report{
+		ReportID: "report-old",
+		Evidence: &reporter.Evidence{
+			Screenshots: []reporter.ScreenshotInfo{
+				{Filepath: "screenshot_initial_old.png"},
+				{Filepath: "screenshot_final_old.png", AnnotatedFilepath: "screenshot_final_old_annotated.png"},
+			},
+			VideoURL:         "/api/videos/gameplay_old.mp4",
+			VisualRegression: &reporter.VisualRegression{DiffImage: "screenshot_diff_old.png"},
+			NetworkLog:       "network_old.har",
+		},
+	}, "screenshot_initial_old.png", "screenshot_final_old.png", "screenshot_final_old_annotated.png",
+		"gameplay_old.mp4", "gameplay_old.gif", "screenshot_diff_old.png", "network_old.har")
+	// An expired test that never produced a report
+	unfinished := s.newTestJob(TestRequest{URL: "https://example.com/unfinished"})
+
+	time.Sleep(50 * time.Millisecond)
+	cutoff := time.Now()
+	time.Sleep(50 * time.Millisecond)
+
+	recent := complete("recent", &reporter.Report{
+		ReportID: "report-recent",
+		Evidence: &reporter.Evidence{
+			Screenshots: []reporter.ScreenshotInfo{{Filepath: "screenshot_final_recent.png"}},
+			VideoURL:    "/api/videos/gameplay_recent.mp4",
+		},
+	}, "screenshot_final_recent.png", "gameplay_recent.mp4")
+	// A file no report refers to, however old, is left alone
+	if err := os.WriteFile(filepath.Join(mediaDir, "unreferenced.png"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	longAgo := time.Now().Add(-365 * 24 * time.Hour)
+	if err := os.Chtimes(filepath.Join(mediaDir, "unreferenced.png"), longAgo, longAgo); err != nil {
+		t.Fatal(err)
+	}
+
+	s.retention = time.Since(cutoff)
+	s.cleanupExpired()
+
+	tests := []struct {
+		file     string
+		wantKept bool
+	}{
+		{file: "screenshot_initial_old.png"},
+		{file: "screenshot_final_old.png"},
+		{file: "screenshot_final_old_annotated.png"},
+		{file: "gameplay_old.mp4"},
+		{file: "gameplay_old.gif"},
+		{file: "screenshot_diff_old.png"},
+		{file: "network_old.har"},
+		{file: "screenshot_final_recent.png", wantKept: true},
+		{file: "gameplay_recent.mp4", wantKept: true},
+		{file: "unreferenced.png", wantKept: true},
+	}
+	for _, tt := range tests {
+		_, err := os.Stat(filepath.Join(mediaDir, tt.file))
+		if kept := err == nil; kept != tt.wantKept {
+			t.Errorf("%s: kept = %v, want %v", tt.file, kept, tt.wantKept)
+		}
+	}
+
+	for _, job := range []*TestJob{old, unfinished, recent} {
+		record, err := s.db.GetTest(job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.mu.RLock()
+		_, inMemory := s.jobs[job.ID]
+		s.mu.RUnlock()
+		wantKept := job == recent
+		if (record != nil) != wantKept || inMemory != wantKept {
+			t.Errorf("%s: in database %v, in memory %v, want %v", job.Request.URL, record != nil, inMemory, wantKept)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// priority orders tests waiting for a browser slot; higher values run first
+type priority int
+
+const (
+	priorityLow priority = iota
+	priorityNormal
+	priorityHigh
+
+	numPriorities = 3
+)
+
+// priorityNames are the request values for each priority
+var priorityNames = [numPriorities]string{"low", "normal", "high"}
+
+func (p priority) String() string {
+	return priorityNames[p]
+}
+
+// parsePriority parses a request's priority: high, normal or low ("" = normal)
+func parsePriority(name string) (priority, error) {
+	if name == "" {
+		return priorityNormal, nil
+	}
+	for p, n := range priorityNames {
+		if n == name {
+			return priority(p), nil
+		}
+	}
+	return 0, fmt.Errorf("priority must be high, normal or low")
+}
+
+// scheduler hands out a fixed number of browser slots (MAX_CONCURRENT_TESTS). When all are
+// busy, a freed slot goes to the oldest waiter of the highest priority, so an interactive
+// test queued behind a large low-priority batch runs next. Running tests are never
+// preempted.
+type scheduler struct {
+	mu      sync.Mutex
+	slots   int
+	running int
+	// queues holds the waiters for each priority, oldest first
+	queues [numPriorities][]chan struct{}
+}
+
+func newScheduler(slots int) *scheduler {
+	return &scheduler{slots: slots}
+}
+
+// acquire blocks until a slot is free for a caller of priority p, or ctx is done. Every
+// successful acquire must be paired with a release.
+func (sc *scheduler) acquire(ctx context.Context, p priority) error {
+	sc.mu.Lock()
+	if sc.running < sc.slots {
+		sc.running++
+		sc.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	sc.queues[p] = append(sc.queues[p], ready)
+	sc.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+		select {
+		case <-ready:
+			// The slot was handed over while cancelling; pass it on
+			sc.releaseLocked()
+		default:
+			sc.removeLocked(p, ready)
+		}
+		return ctx.Err()
+	}
+}
+
+// release frees a slot, handing it straight to the next waiter if there is one
+func (sc *scheduler) release() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.releaseLocked()
+}
+
+func (sc *scheduler) releaseLocked() {
+	for p := numPriorities - 1; p >= 0; p-- {
+		if queue := sc.queues[p]; len(queue) > 0 {
+			sc.queues[p] = queue[1:]
+			close(queue[0])
+			return
+		}
+	}
+	sc.running--
+}
+
+// removeLocked drops a waiter that gave up
+func (sc *scheduler) removeLocked(p priority, ready chan struct{}) {
+	queue := sc.queues[p]
+	for i, c := range queue {
+		if c == ready {
+			sc.queues[p] = append(queue[:i:i], queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// stats returns how many slots are in use and how many callers wait at each priority
+func (sc *scheduler) stats() (running int, queued [numPriorities]int) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for p, queue := range sc.queues {
+		queued[p] = len(queue)
+	}
+	return sc.running, queued
+}
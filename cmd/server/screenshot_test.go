@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+	"github.com/dreamup/qa-agent/internal/reporter"
+)
+
+func TestReportAnnotatedScreenshotLink(t *testing.T) {
+	// handleScreenshot serves from ./data/media
+	t.Chdir(t.TempDir())
+	mediaDir := filepath.Join("data", "media")
+	if err := os.MkdirAll(mediaDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"screenshot_gameplay_1.png":           "original",
+		"annotated_screenshot_gameplay_1.png": "annotated",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(mediaDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report := &reporter.Report{
+		ReportID:  "report-1",
+		GameURL:   "https://example.com/game",
+		Timestamp: time.Now(),
+		Evidence: &reporter.Evidence{
+			Screenshots: []reporter.ScreenshotInfo{{
+				Context:           agent.ContextGameplay,
+				Filepath:          "screenshot_gameplay_1.png",
+				AnnotatedFilepath: "annotated_screenshot_gameplay_1.png",
+				Timestamp:         time.Now(),
+			}},
+		},
+	}
+	html, err := reporter.RenderHTML(report)
+	if err != nil {
+		t.Fatalf("RenderHTML: %v", err)
+	}
+
+	match := regexp.MustCompile(`<a href="([^"]+)">actions</a>`).FindSubmatch(html)
+	if match == nil {
+		t.Fatalf("report has no actions link:\n%s", html)
+	}
+	link := string(match[1])
+
+	s := &Server{}
+	rec := httptest.NewRecorder()
+	s.handleScreenshot(rec, httptest.NewRequest(http.MethodGet, link, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d: %s", link, rec.Code, rec.Body)
+	}
+	if got := rec.Body.String(); got != "annotated" {
+		t.Errorf("GET %s served %q, want the annotated file", link, got)
+	}
+}
+
+func TestHandleScreenshotRejectsOtherFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := &Server{}
+	for _, path := range []string{
+		"/api/screenshots/",
+		"/api/screenshots/report.json",
+		"/api/screenshots/annotated_report.json",
+		"/api/screenshots/..screenshot_x.png",
+	} {
+		rec := httptest.NewRecorder()
+		s.handleScreenshot(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want %d", path, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
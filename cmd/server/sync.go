@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/reporter"
+)
+
+// defaultSyncMaxWait bounds POST /api/tests/sync when SYNC_MAX_WAIT_SECONDS is unset
+const defaultSyncMaxWait = 5 * time.Minute
+
+// runTest executes a test inline and returns its report, or an error describing why
+// the test did not complete
+func (s *Server) runTest(job *TestJob) (*reporter.Report, error) {
+	s.executeTest(job)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if job.Status != "completed" || job.Report == nil {
+		return nil, fmt.Errorf("test %s: %s", job.Status, job.Message)
+	}
+	return job.Report, nil
+}
+
+// Run a test and return its report once done: POST /api/tests/sync.
+// Responds 504 with the test ID if the test outlives the server's max wait; the test keeps
+// running and can still be polled.
+func (s *Server) handleTestSubmitSync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.rejectDraining(w) {
+		return
+	}
+
+	var req TestRequest
+	if err := decodeJSONBody(w, r, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := validateTestRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.rejectDomain(w, req.URL) {
+		return
+	}
+
+	// The server's WriteTimeout is far shorter than a test
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(s.syncMaxWait + 30*time.Second)); err != nil {
+		log.Printf("Warning: Failed to extend write deadline for sync test: %v", err)
+	}
+
+	job := s.newTestJob(req)
+
+	type result struct {
+		report *reporter.Report
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		report, err := s.runTest(job)
+		done <- result{report, err}
+	}()
+
+	timer := time.NewTimer(s.syncMaxWait)
+	defer timer.Stop()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res.report)
+
+	case <-timer.C:
+		s.mu.RLock()
+		status := job.Status
+		s.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusGatewayTimeout)
+		json.NewEncoder(w).Encode(TestResponse{
+			TestID: job.ID,
+			Status: status,
+		})
+
+	case <-r.Context().Done():
+		// Nobody is waiting for the result any more; a test that finished meanwhile keeps
+		// its result
+		if _, cancelled := s.cancelJob(job, "Client disconnected"); cancelled {
+			log.Printf("Test %s cancelled: sync client disconnected", job.ID)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dbStatus returns a test's status as stored in the database
+func dbStatus(t *testing.T, s *Server, testID string) string {
+	t.Helper()
+	record, err := s.db.GetTest(testID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record.Status
+}
+
+func TestSyncClientDisconnectCancelsQueuedTest(t *testing.T) {
+	s := newTestServer(t)
+
+	ctx, disconnect := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/api/tests/sync", strings.NewReader(`{"url": "https://example.com/game"}`)).WithContext(ctx)
+	handled := make(chan struct{})
+	go func() {
+		s.handleTestSubmitSync(httptest.NewRecorder(), req)
+		close(handled)
+	}()
+
+	// The test waits for the browser slot newTestServer holds
+	var job *TestJob
+	for deadline := time.Now().Add(5 * time.Second); job == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("sync test was never created")
+		}
+		s.mu.RLock()
+		for _, j := range s.jobs {
+			job = j
+		}
+		s.mu.RUnlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	disconnect()
+	<-handled
+
+	s.mu.RLock()
+	status, message := job.Status, job.Message
+	s.mu.RUnlock()
+	if status != "cancelled" || message != "Client disconnected" {
+		t.Errorf("test is %s (%s), want cancelled (Client disconnected)", status, message)
+	}
+	if got := dbStatus(t, s, job.ID); got != "cancelled" {
+		t.Errorf("database status %s, want cancelled", got)
+	}
+	if job.ctx.Err() == nil {
+		t.Error("test context wasn't cancelled")
+	}
+}
+
+func TestCancelJobKeepsFinishedResult(t *testing.T) {
+	s := newTestServer(t)
+	for _, finished := range []string{"completed", "failed", "cancelled", "interrupted"} {
+		job := s.newTestJob(TestRequest{URL: "https://example.com/" + finished})
+		s.mu.Lock()
+		job.Status, job.Message = finished, "done"
+		s.mu.Unlock()
+		if err := s.db.UpdateTestStatus(job.ID, finished); err != nil {
+			t.Fatal(err)
+		}
+
+		// The sync client disconnecting just as the test finished
+		status, cancelled := s.cancelJob(job, "Client disconnected")
+		if cancelled || status.Status != finished || status.Message != "done" {
+			t.Errorf("%s: cancelJob = %s (%s), cancelled %v; want the test left %s", finished, status.Status, status.Message, cancelled, finished)
+		}
+		if got := dbStatus(t, s, job.ID); got != finished {
+			t.Errorf("%s: database status %s after cancelJob", finished, got)
+		}
+
+		// DELETE /api/tests/{id} goes through the same check
+		rec := httptest.NewRecorder()
+		s.handleTestCancel(rec, httptest.NewRequest(http.MethodDelete, "/api/tests/"+job.ID, nil))
+		if rec.Code != http.StatusConflict {
+			t.Errorf("%s: DELETE status %d, want 409", finished, rec.Code)
+		}
+	}
+}
@@ -1584,12 +1584,13 @@ fetchTestHistory apiBaseUrl history =
     let
         queryParams =
             [ "status=" ++ (if history.statusFilter == Nothing then "all" else Maybe.withDefault "all" history.statusFilter)
+            , "limit=500"
             ]
                 |> String.join "&"
     in
     getWithCors
         (apiBaseUrl ++ "/tests/list?" ++ queryParams)
-        (Decode.list reportSummaryDecoder)
+        (Decode.field "tests" (Decode.list reportSummaryDecoder))
         TestHistoryFetched
 
 
@@ -3050,7 +3051,13 @@ viewMediaSection model evidence =
                         ]
                         [ source
                             [ Html.Attributes.attribute "src" videoUrl
-                            , Html.Attributes.attribute "type" "video/mp4"
+                            , Html.Attributes.attribute "type"
+                                (if String.endsWith ".webm" videoUrl then
+                                    "video/webm"
+
+                                 else
+                                    "video/mp4"
+                                )
                             ]
                             []
                         , text "Your browser does not support the video tag."
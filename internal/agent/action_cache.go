@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	// DefaultActionCachePath is where gameplay agents persist successful drags between tests
+	DefaultActionCachePath = "./data/action_cache.json"
+	// maxPersistedDragsPerGame caps how many drags are kept per game (newest first)
+	maxPersistedDragsPerGame = 20
+	// similarScreenThreshold is the max mean per-channel difference (0-255) for two screens to match
+	similarScreenThreshold = 12.0
+	// similaritySampleCols and similaritySampleRows set the grid sampled when comparing screens
+	similaritySampleCols = 32
+	similaritySampleRows = 18
+)
+
+// actionCacheFileMu serializes cache file writes from concurrent tests in this process
+var actionCacheFileMu sync.Mutex
+
+// LoadCache merges successful drags persisted at path into the agent's cache.
+// A missing file is not an error.
+func (g *GameplayAgent) LoadCache(path string) error {
+	byGame, err := readActionCacheFile(path)
+	if err != nil {
+		return err
+	}
+
+	for _, drags := range byGame {
+		g.actionCache.SuccessfulDrags = mergeDrags(g.actionCache.SuccessfulDrags, drags)
+	}
+	return nil
+}
+
+// SaveCache writes the agent's successful drags to path, keyed by game name.
+// Entries already in the file (e.g. from other tests) are kept, and each game is
+// pruned to its most recent drags.
+func (g *GameplayAgent) SaveCache(path string) error {
+	actionCacheFileMu.Lock()
+	defer actionCacheFileMu.Unlock()
+
+	byGame, err := readActionCacheFile(path)
+	if err != nil {
+		return err
+	}
+
+	for _, drag := range g.actionCache.SuccessfulDrags {
+		byGame[drag.GameName] = mergeDrags(byGame[drag.GameName], []CachedDrag{drag})
+	}
+	for game, drags := range byGame {
+		sort.Slice(drags, func(i, j int) bool {
+			return drags[i].Timestamp.After(drags[j].Timestamp)
+		})
+		if len(drags) > maxPersistedDragsPerGame {
+			drags = drags[:maxPersistedDragsPerGame]
+		}
+		byGame[game] = drags
+	}
+
+	data, err := json.Marshal(byGame)
+	if err != nil {
+		return fmt.Errorf("failed to marshal action cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create action cache directory: %w", err)
+	}
+
+	// Write to a temp file and rename so readers never see a partial cache
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write action cache: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace action cache: %w", err)
+	}
+	return nil
+}
+
+// findCachedDrag returns the most recent cached drag for the game whose "before" screenshot
+// resembles the current screen, skipping drags in tried. Returns nil if none match.
+func (g *GameplayAgent) findCachedDrag(gameName string, screenshot *Screenshot, tried map[string]bool) *CachedDrag {
+	drags := g.GetCachedDragsForGame(gameName)
+	sort.Slice(drags, func(i, j int) bool {
+		return drags[i].Timestamp.After(drags[j].Timestamp)
+	})
+
+	for i := range drags {
+		drag := &drags[i]
+		if tried[drag.key()] || drag.ScreenshotB64 == "" {
+			continue
+		}
+		cachedData, err := base64.StdEncoding.DecodeString(drag.ScreenshotB64)
+		if err != nil {
+			continue
+		}
+		if screensSimilar(cachedData, screenshot.Data) {
+			return drag
+		}
+	}
+	return nil
+}
+
+// key identifies the drag a cached entry replays, for skipping ones already tried
+func (d *CachedDrag) key() string {
+	return fmt.Sprintf("%s%v>%s%v", d.StartCell, d.StartOffset, d.EndCell, d.EndOffset)
+}
+
+// readActionCacheFile loads a persisted cache, returning an empty map if the file doesn't exist
+func readActionCacheFile(path string) (map[string][]CachedDrag, error) {
+	byGame := make(map[string][]CachedDrag)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return byGame, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read action cache: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &byGame); err != nil {
+		return nil, fmt.Errorf("failed to parse action cache %s: %w", path, err)
+	}
+	return byGame, nil
+}
+
+// mergeDrags appends drags not already present (same game, drag and timestamp)
+func mergeDrags(existing, drags []CachedDrag) []CachedDrag {
+	for _, drag := range drags {
+		duplicate := false
+		for _, e := range existing {
+			if e.GameName == drag.GameName && e.key() == drag.key() && e.Timestamp.Equal(drag.Timestamp) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			existing = append(existing, drag)
+		}
+	}
+	return existing
+}
+
+// screensSimilar compares two PNG or JPEG screenshots on a coarse sample grid and reports
+// whether their mean color difference is under similarScreenThreshold
+func screensSimilar(a, b []byte) bool {
+	imgA, err := decodeImage(a)
+	if err != nil {
+		return false
+	}
+	imgB, err := decodeImage(b)
+	if err != nil {
+		return false
+	}
+	if imgA.Bounds().Size() != imgB.Bounds().Size() {
+		return false
+	}
+
+	var total float64
+	samples := 0
+	for row := 0; row < similaritySampleRows; row++ {
+		for col := 0; col < similaritySampleCols; col++ {
+			total += pixelDiff(imgA, imgB, col, row)
+			samples++
+		}
+	}
+	return total/float64(samples) <= similarScreenThreshold
+}
+
+// pixelDiff returns the mean per-channel difference (0-255) at a sample grid point
+func pixelDiff(a, b image.Image, col, row int) float64 {
+	bounds := a.Bounds()
+	x := bounds.Min.X + (2*col+1)*bounds.Dx()/(2*similaritySampleCols)
+	y := bounds.Min.Y + (2*row+1)*bounds.Dy()/(2*similaritySampleRows)
+
+	r1, g1, b1, _ := a.At(x, y).RGBA()
+	r2, g2, b2, _ := b.At(x, y).RGBA()
+
+	diff := func(p, q uint32) float64 {
+		if p > q {
+			return float64(p-q) / 257
+		}
+		return float64(q-p) / 257
+	}
+	return (diff(r1, r2) + diff(g1, g2) + diff(b1, b2)) / 3
+}
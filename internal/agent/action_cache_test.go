@@ -0,0 +1,235 @@
+package agent
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testFrame encodes a width x height image colored by pixel
+func testFrame(t *testing.T, width, height int, format ImageFormat, pixel func(x, y int) color.RGBA) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, pixel(x, y))
+		}
+	}
+	data, err := encodeImage(img, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// testCacheAgent returns a gameplay agent with the default grid and viewport and no OpenAI client
+func testCacheAgent() *GameplayAgent {
+	return &GameplayAgent{
+		ctx:         context.Background(),
+		actionCache: &ActionCache{},
+		gridCols:    20,
+		gridRows:    12,
+		imageWidth:  1280,
+		imageHeight: 720,
+	}
+}
+
+func TestScreensSimilar(t *testing.T) {
+	sky := func(x, y int) color.RGBA { return color.RGBA{R: 0x40, G: 0x90, B: 0xe0, A: 0xff} }
+	shifted := func(by uint8) func(x, y int) color.RGBA {
+		return func(x, y int) color.RGBA { return color.RGBA{R: 0x40 + by, G: 0x90 + by, B: 0xe0 - by, A: 0xff} }
+	}
+	// 320x180 samples the 32x18 grid at x, y = 5, 15, 25, ...
+	offGrid := func(x, y int) color.RGBA {
+		if x%10 == 0 || y%10 == 0 {
+			return color.RGBA{A: 0xff}
+		}
+		return sky(x, y)
+	}
+	// columns of white sprites over the sky, covering n of the 32 sample columns
+	sprites := func(n int) func(x, y int) color.RGBA {
+		return func(x, y int) color.RGBA {
+			if x < n*10 {
+				return color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			}
+			return sky(x, y)
+		}
+	}
+
+	base := testFrame(t, 320, 180, ImageFormatPNG, sky)
+	tests := []struct {
+		name string
+		b    []byte
+		want bool
+	}{
+		{name: "identical", b: base, want: true},
+		{name: "same screen as JPEG", b: testFrame(t, 320, 180, ImageFormatJPEG, sky), want: true},
+		{name: "difference at the threshold", b: testFrame(t, 320, 180, ImageFormatPNG, shifted(12)), want: true},
+		{name: "difference past the threshold", b: testFrame(t, 320, 180, ImageFormatPNG, shifted(13)), want: false},
+		{name: "changes between sample points", b: testFrame(t, 320, 180, ImageFormatPNG, offGrid), want: true},
+		{name: "small sprite", b: testFrame(t, 320, 180, ImageFormatPNG, sprites(2)), want: true},
+		{name: "large sprite", b: testFrame(t, 320, 180, ImageFormatPNG, sprites(8)), want: false},
+		{name: "different size", b: testFrame(t, 640, 360, ImageFormatPNG, sky), want: false},
+		{name: "undecodable", b: []byte("not an image"), want: false},
+		{name: "empty", b: nil, want: false},
+	}
+	for _, tt := range tests {
+		if got := screensSimilar(base, tt.b); got != tt.want {
+			t.Errorf("%s: screensSimilar = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := screensSimilar(tt.b, base); got != tt.want {
+			t.Errorf("%s (swapped): screensSimilar = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMergeDrags(t *testing.T) {
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	drag := CachedDrag{GameName: "birds", StartCell: "C8", EndCell: "A10", StartOffset: [2]float64{0.2, -0.1}, Power: 0.8, Timestamp: at}
+	with := func(change func(d *CachedDrag)) CachedDrag {
+		d := drag
+		change(&d)
+		return d
+	}
+
+	tests := []struct {
+		name      string
+		add       CachedDrag
+		wantAdded bool
+	}{
+		{name: "same drag", add: drag, wantAdded: false},
+		{name: "same drag, other outcome", add: with(func(d *CachedDrag) { d.Outcome = "missed" }), wantAdded: false},
+		{name: "same instant in another zone", add: with(func(d *CachedDrag) { d.Timestamp = at.In(time.FixedZone("CET", 3600)) }), wantAdded: false},
+		{name: "later", add: with(func(d *CachedDrag) { d.Timestamp = at.Add(time.Second) }), wantAdded: true},
+		{name: "other game", add: with(func(d *CachedDrag) { d.GameName = "tetris" }), wantAdded: true},
+		{name: "other end cell", add: with(func(d *CachedDrag) { d.EndCell = "A11" }), wantAdded: true},
+		{name: "other offset", add: with(func(d *CachedDrag) { d.StartOffset = [2]float64{0.3, -0.1} }), wantAdded: true},
+	}
+	for _, tt := range tests {
+		got := mergeDrags([]CachedDrag{drag}, []CachedDrag{tt.add})
+		if added := len(got) == 2; added != tt.wantAdded {
+			t.Errorf("%s: merged %d drags, want added = %v", tt.name, len(got), tt.wantAdded)
+		}
+	}
+}
+
+func TestSaveCachePrunesAndMerges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "action_cache.json")
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	dragAt := func(game string, minute int) CachedDrag {
+		return CachedDrag{GameName: game, StartCell: "C8", EndCell: "A10", Power: 0.5, Timestamp: start.Add(time.Duration(minute) * time.Minute)}
+	}
+
+	// An earlier test saved 15 drags for one game and 1 for another
+	earlier := testCacheAgent()
+	for minute := 0; minute < 15; minute++ {
+		earlier.actionCache.SuccessfulDrags = append(earlier.actionCache.SuccessfulDrags, dragAt("birds", minute))
+	}
+	earlier.actionCache.SuccessfulDrags = append(earlier.actionCache.SuccessfulDrags, dragAt("tetris", 0))
+	if err := earlier.SaveCache(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// This test loaded those, added 10 more and saves twice
+	agent := testCacheAgent()
+	if err := agent.LoadCache(path); err != nil {
+		t.Fatal(err)
+	}
+	for minute := 15; minute < 25; minute++ {
+		agent.actionCache.SuccessfulDrags = append(agent.actionCache.SuccessfulDrags, dragAt("birds", minute))
+	}
+	for i := 0; i < 2; i++ {
+		if err := agent.SaveCache(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	byGame, err := readActionCacheFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		game        string
+		wantMinutes []int
+	}{
+		{game: "birds", wantMinutes: []int{24, 23, 22, 21, 20, 19, 18, 17, 16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5}},
+		{game: "tetris", wantMinutes: []int{0}},
+	}
+	for _, tt := range tests {
+		var minutes []int
+		for _, drag := range byGame[tt.game] {
+			minutes = append(minutes, int(drag.Timestamp.Sub(start)/time.Minute))
+		}
+		if !reflect.DeepEqual(minutes, tt.wantMinutes) {
+			t.Errorf("%s: saved drags at minutes %v, want %v", tt.game, minutes, tt.wantMinutes)
+		}
+	}
+}
+
+func TestLoadCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "action_cache.json")
+	if err := testCacheAgent().LoadCache(path); err != nil {
+		t.Fatalf("LoadCache with no file: %v", err)
+	}
+
+	// The model estimated more power than the drag distance gives
+	agent := testCacheAgent()
+	start, _ := parseGridCell("C8+0.3,-0.2", agent.gridCols, agent.gridRows)
+	end, _ := parseGridCell("A10-0.5,0.4", agent.gridCols, agent.gridRows)
+	succeeded := agent.dragFromCells(start, end, "aim low")
+	succeeded.Power = 0.95
+	agent.CacheSuccessfulDrag("birds", succeeded, "destroyed_pig", testScreenshot(t, 320, 180, ImageFormatPNG))
+	if err := agent.SaveCache(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := testCacheAgent()
+	if err := loaded.LoadCache(path); err != nil {
+		t.Fatal(err)
+	}
+	drags := loaded.GetCachedDragsForGame("birds")
+	if len(drags) != 1 {
+		t.Fatalf("loaded %d drags, want 1", len(drags))
+	}
+	want := agent.actionCache.SuccessfulDrags[0]
+	if !drags[0].Timestamp.Equal(want.Timestamp) {
+		t.Errorf("loaded timestamp %v, want %v", drags[0].Timestamp, want.Timestamp)
+	}
+	want.Timestamp = drags[0].Timestamp
+	if !reflect.DeepEqual(drags[0], want) {
+		t.Errorf("loaded %s → %s %v → %v power %.2f, want %s → %s %v → %v power %.2f",
+			drags[0].StartCell, drags[0].EndCell, drags[0].StartOffset, drags[0].EndOffset, drags[0].Power,
+			want.StartCell, want.EndCell, want.StartOffset, want.EndOffset, want.Power)
+	}
+
+	replayed, err := loaded.cachedDragAction(&drags[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.SlingshotCell != succeeded.SlingshotCell || replayed.TargetCell != succeeded.TargetCell {
+		t.Errorf("replayed drag %s → %s, want %s → %s",
+			replayed.SlingshotCell, replayed.TargetCell, succeeded.SlingshotCell, succeeded.TargetCell)
+	}
+	if replayed.Power != succeeded.Power || math.Abs(replayed.AngleDegrees-succeeded.AngleDegrees) > 1e-9 {
+		t.Errorf("replayed angle %.2f power %.2f, want angle %.2f power %.2f",
+			replayed.AngleDegrees, replayed.Power, succeeded.AngleDegrees, succeeded.Power)
+	}
+
+	// Drags cached before power and offsets were stored keep their offsets in the cell
+	// and get power from the drag distance
+	legacy := CachedDrag{GameName: "birds", StartCell: "C8+0.3,-0.2", EndCell: "A10-0.5,0.4"}
+	replayed, err = loaded.cachedDragAction(&legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromCells := loaded.dragFromCells(start, end, "")
+	if replayed.SlingshotCell != start || replayed.TargetCell != end || replayed.Power != fromCells.Power {
+		t.Errorf("replayed legacy drag %s → %s power %.2f, want %s → %s power %.2f",
+			replayed.SlingshotCell, replayed.TargetCell, replayed.Power, start, end, fromCells.Power)
+	}
+}
@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RecordedActionKind is the kind of input a RecordedAction sent to the game
+type RecordedActionKind string
+
+const (
+	// RecordedClick is a mouse click at X,Y, on Selector, or on the button labelled Text
+	RecordedClick RecordedActionKind = "click"
+	// RecordedDrag is a mouse drag from X,Y to EndX,EndY
+	RecordedDrag RecordedActionKind = "drag"
+	// RecordedKey is a key press and release
+	RecordedKey RecordedActionKind = "key"
+	// RecordedKeyDown and RecordedKeyUp are a held key's press and release
+	RecordedKeyDown RecordedActionKind = "key_down"
+	RecordedKeyUp   RecordedActionKind = "key_up"
+	// RecordedGamepadButton sets a virtual gamepad button (Value 1 pressed, 0 released)
+	RecordedGamepadButton RecordedActionKind = "gamepad_button"
+	// RecordedGamepadAxis moves a virtual gamepad axis to Value
+	RecordedGamepadAxis RecordedActionKind = "gamepad_axis"
+)
+
+// maxRecordedActions caps an action log; later actions are counted but not kept
+const maxRecordedActions = 10000
+
+// RecordedAction is one input sent to the game. Coordinates are in screenshot pixels
+// (relative to the game iframe, if there is one), so the run can be replayed.
+type RecordedAction struct {
+	Timestamp time.Time `json:"timestamp"`
+	// OffsetMs is the time since the browser started, for replaying with the same pacing
+	OffsetMs int64              `json:"offset_ms"`
+	Kind     RecordedActionKind `json:"kind"`
+	X        int                `json:"x,omitempty"`
+	Y        int                `json:"y,omitempty"`
+	EndX     int                `json:"end_x,omitempty"`
+	EndY     int                `json:"end_y,omitempty"`
+	Selector string             `json:"selector,omitempty"`
+	Text     string             `json:"text,omitempty"`
+	Key      string             `json:"key,omitempty"`
+	// Target is where a key event was dispatched: canvas, window or page
+	Target string `json:"target,omitempty"`
+	// Index is the gamepad button or axis
+	Index int     `json:"index,omitempty"`
+	Value float64 `json:"value,omitempty"`
+	// DurationMs is how long a drag moved, and HoldMs how long it paused before release
+	DurationMs int64 `json:"duration_ms,omitempty"`
+	HoldMs     int64 `json:"hold_ms,omitempty"`
+}
+
+// ActionLog records the input sent during a test. Every context derived from a browser
+// context shares the browser's log, and the interaction helpers (clicks, drags, key and
+// gamepad events) append to it.
+type ActionLog struct {
+	mu      sync.Mutex
+	start   time.Time
+	actions []RecordedAction
+	dropped int
+}
+
+// NewActionLog creates an empty action log whose offsets count from now
+func NewActionLog() *ActionLog {
+	return &ActionLog{start: time.Now()}
+}
+
+// actionLogKey is the context key for the browser's *ActionLog
+type actionLogKey struct{}
+
+// ActionLogFromContext returns the browser's action log, or nil if the context has none
+func ActionLogFromContext(ctx context.Context) *ActionLog {
+	log, _ := ctx.Value(actionLogKey{}).(*ActionLog)
+	return log
+}
+
+// recordAction appends an action to the context's action log, if it has one
+func recordAction(ctx context.Context, action RecordedAction) {
+	if log := ActionLogFromContext(ctx); log != nil {
+		log.Record(action)
+	}
+}
+
+// Record appends an action, stamping its time
+func (l *ActionLog) Record(action RecordedAction) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.actions) >= maxRecordedActions {
+		l.dropped++
+		return
+	}
+	now := time.Now()
+	action.Timestamp = now
+	action.OffsetMs = now.Sub(l.start).Milliseconds()
+	l.actions = append(l.actions, action)
+}
+
+// Actions returns the recorded actions in order. Safe to call on a nil log.
+func (l *ActionLog) Actions() []RecordedAction {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]RecordedAction(nil), l.actions...)
+}
+
+// Dropped returns how many actions weren't kept because the log was full
+func (l *ActionLog) Dropped() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.dropped
+}
@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxLegendActions caps the actions listed in an annotated screenshot's legend
+const maxLegendActions = 8
+
+var (
+	clickMarkerColor = color.RGBA{255, 0, 0, 255}
+	dragMarkerColor  = color.RGBA{0, 160, 255, 255}
+	legendBackground = color.RGBA{0, 0, 0, 180}
+	legendTextColor  = color.RGBA{255, 255, 255, 255}
+)
+
+// AnnotateScreenshots saves, next to each saved screenshot, a copy marked up with the
+// actions taken after it and before the next screenshot: numbered click markers, drag
+// arrows and a legend that also lists key and gamepad input. The copies' paths are set
+// as AnnotatedFilepath. Screenshots that weren't saved are skipped.
+func AnnotateScreenshots(screenshots []*Screenshot, actions []RecordedAction) error {
+	ordered := append([]*Screenshot(nil), screenshots...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Timestamp.Before(ordered[j].Timestamp) })
+
+	for i, s := range ordered {
+		if s.Filepath == "" {
+			continue
+		}
+		var taken []RecordedAction
+		for _, action := range actions {
+			if action.Timestamp.Before(s.Timestamp) {
+				continue
+			}
+			if i+1 < len(ordered) && !action.Timestamp.Before(ordered[i+1].Timestamp) {
+				continue
+			}
+			taken = append(taken, action)
+		}
+		if err := s.saveAnnotated(taken); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveAnnotated draws actions on a copy of the screenshot and saves it in the media
+// directory as annotated_<filename>.png (PNG even for JPEG screenshots, so markers stay sharp)
+func (s *Screenshot) saveAnnotated(actions []RecordedAction) error {
+	img, err := decodeImage(s.Data)
+	if err != nil {
+		return fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	legend := []string{"Actions until the next frame:"}
+	if len(actions) == 0 {
+		legend = []string{"No actions until the next frame"}
+	}
+	for i, action := range actions {
+		n := i + 1
+		switch {
+		case action.Kind == RecordedClick && action.Selector == "" && action.Text == "":
+			drawClickMarker(rgba, action.X, action.Y, clickMarkerColor)
+			drawString(rgba, action.X+22, action.Y-8, fmt.Sprint(n), clickMarkerColor)
+		case action.Kind == RecordedDrag:
+			drawArrow(rgba, action.X, action.Y, action.EndX, action.EndY, dragMarkerColor)
+			drawString(rgba, action.X+10, action.Y-10, fmt.Sprint(n), dragMarkerColor)
+		}
+		if n <= maxLegendActions {
+			legend = append(legend, fmt.Sprintf("%d. %s", n, describeAction(action)))
+		}
+	}
+	if len(actions) > maxLegendActions {
+		legend = append(legend, fmt.Sprintf("... and %d more", len(actions)-maxLegendActions))
+	}
+	legend = append(legend, "red circle = click, blue arrow = drag")
+	drawLegend(rgba, legend)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, rgba); err != nil {
+		return fmt.Errorf("failed to encode annotated screenshot: %w", err)
+	}
+	mediaDir, err := getMediaDir()
+	if err != nil {
+		return err
+	}
+	base := filepath.Base(s.Filepath)
+	filename := "annotated_" + strings.TrimSuffix(base, filepath.Ext(base)) + ".png"
+	if err := os.WriteFile(filepath.Join(mediaDir, filename), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to save annotated screenshot: %w", err)
+	}
+	s.AnnotatedFilepath = filename
+	return nil
+}
+
+// describeAction is a short legend line for an action
+func describeAction(action RecordedAction) string {
+	switch action.Kind {
+	case RecordedClick:
+		switch {
+		case action.Selector != "":
+			return fmt.Sprintf("click %s", action.Selector)
+		case action.Text != "":
+			return fmt.Sprintf("click %q", action.Text)
+		default:
+			return fmt.Sprintf("click (%d,%d)", action.X, action.Y)
+		}
+	case RecordedDrag:
+		return fmt.Sprintf("drag (%d,%d) -> (%d,%d)", action.X, action.Y, action.EndX, action.EndY)
+	case RecordedKey, RecordedKeyDown, RecordedKeyUp:
+		return fmt.Sprintf("%s %s", action.Kind, action.Key)
+	case RecordedGamepadButton, RecordedGamepadAxis:
+		return fmt.Sprintf("%s %d = %g", action.Kind, action.Index, action.Value)
+	default:
+		return string(action.Kind)
+	}
+}
+
+// drawClickMarker draws a circle with a crosshair at x, y
+func drawClickMarker(img *image.RGBA, x, y int, col color.Color) {
+	bounds := img.Bounds()
+	radius := 20
+
+	// Draw circle outline
+	for angle := 0; angle < 360; angle++ {
+		rad := float64(angle) * math.Pi / 180
+		for r := radius - 3; r <= radius; r++ {
+			px := x + int(float64(r)*math.Cos(rad))
+			py := y + int(float64(r)*math.Sin(rad))
+			if image.Pt(px, py).In(bounds) {
+				img.Set(px, py, col)
+			}
+		}
+	}
+
+	// Draw crosshair
+	for i := -25; i <= 25; i++ {
+		if image.Pt(x+i, y).In(bounds) {
+			img.Set(x+i, y, col)
+		}
+		if image.Pt(x, y+i).In(bounds) {
+			img.Set(x, y+i, col)
+		}
+	}
+}
+
+// drawArrow draws a 3px line from (x0,y0) to (x1,y1) with an arrowhead at the end
+func drawArrow(img *image.RGBA, x0, y0, x1, y1 int, col color.Color) {
+	drawThickLine(img, float64(x0), float64(y0), float64(x1), float64(y1), col)
+
+	angle := math.Atan2(float64(y1-y0), float64(x1-x0))
+	for _, side := range []float64{-1, 1} {
+		wing := angle + math.Pi + side*math.Pi/6
+		drawThickLine(img, float64(x1), float64(y1), float64(x1)+14*math.Cos(wing), float64(y1)+14*math.Sin(wing), col)
+	}
+}
+
+// drawThickLine draws a 3px wide line by stamping squares along it
+func drawThickLine(img *image.RGBA, x0, y0, x1, y1 float64, col color.Color) {
+	bounds := img.Bounds()
+	steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		cx := int(math.Round(x0 + t*(x1-x0)))
+		cy := int(math.Round(y0 + t*(y1-y0)))
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				if image.Pt(cx+dx, cy+dy).In(bounds) {
+					img.Set(cx+dx, cy+dy, col)
+				}
+			}
+		}
+	}
+}
+
+// drawLegend draws lines of text on a translucent box in the top-left corner
+func drawLegend(img *image.RGBA, lines []string) {
+	const lineHeight, padding, charWidth = 15, 6, 7
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line)*charWidth)
+	}
+	box := image.Rect(0, 0, width+2*padding, len(lines)*lineHeight+2*padding).Intersect(img.Bounds())
+	draw.Draw(img, box, image.NewUniform(legendBackground), image.Point{}, draw.Over)
+	for i, line := range lines {
+		drawString(img, padding, padding+(i+1)*lineHeight-3, line, legendTextColor)
+	}
+}
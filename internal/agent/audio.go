@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// AudioStatus summarizes audio activity observed on the page
+type AudioStatus struct {
+	// Detected is true if the game played any audio during the test
+	Detected bool `json:"detected"`
+	// AudioContexts is how many Web Audio contexts the page created
+	AudioContexts int `json:"audio_contexts"`
+	// RunningContexts is how many Web Audio contexts were running (not suspended) at check time
+	RunningContexts int `json:"running_contexts"`
+	// SourcesStarted counts Web Audio source nodes (buffers, oscillators) that were started
+	SourcesStarted int `json:"sources_started"`
+	// MediaElementsPlayed counts <audio>/<video> elements that started playback
+	MediaElementsPlayed int `json:"media_elements_played"`
+	// MediaElementsPlaying counts unmuted media elements still playing at check time
+	MediaElementsPlaying int `json:"media_elements_playing"`
+}
+
+// audioProbeScript instruments Web Audio and HTMLMediaElement before any page script runs.
+// Counters are kept on window.__qaAudio and read back by CheckAudio.
+const audioProbeScript = `
+(function() {
+    if (window.__qaAudio) return;
+    const state = window.__qaAudio = { contexts: [], sourcesStarted: 0, mediaPlayed: 0 };
+
+    const wrapContext = function(name) {
+        const Original = window[name];
+        if (!Original) return;
+        const Wrapped = function(...args) {
+            const ctx = new Original(...args);
+            state.contexts.push(ctx);
+            return ctx;
+        };
+        Wrapped.prototype = Original.prototype;
+        Object.setPrototypeOf(Wrapped, Original);
+        window[name] = Wrapped;
+    };
+    wrapContext('AudioContext');
+    wrapContext('webkitAudioContext');
+
+    if (window.AudioScheduledSourceNode) {
+        const originalStart = AudioScheduledSourceNode.prototype.start;
+        AudioScheduledSourceNode.prototype.start = function(...args) {
+            state.sourcesStarted++;
+            return originalStart.apply(this, args);
+        };
+    }
+
+    // 'play' does not bubble, so listen in the capture phase to see every media element
+    document.addEventListener('play', function(e) {
+        if (e.target instanceof HTMLMediaElement) {
+            state.mediaPlayed++;
+        }
+    }, true);
+})();
+`
+
+// audioCheckScript reads the counters collected by audioProbeScript
+const audioCheckScript = `
+(function() {
+    const state = window.__qaAudio;
+    if (!state) return JSON.stringify({ installed: false });
+
+    const media = Array.from(document.querySelectorAll('audio, video'));
+    return JSON.stringify({
+        installed: true,
+        contexts: state.contexts.length,
+        running: state.contexts.filter(c => c.state === 'running').length,
+        sourcesStarted: state.sourcesStarted,
+        mediaPlayed: state.mediaPlayed,
+        mediaPlaying: media.filter(m => !m.paused && !m.muted && m.volume > 0).length
+    });
+})();
+`
+
+// InstallAudioProbe injects audio instrumentation into every document the page loads.
+// It must be called before LoadGame so the hooks are in place before game scripts run.
+func (bm *BrowserManager) InstallAudioProbe() error {
+	err := runWithTimeout(bm.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		_, err := page.AddScriptToEvaluateOnNewDocument(audioProbeScript).Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to install audio probe: %w", err)
+	}
+	return nil
+}
+
+// CheckAudio reports the audio activity recorded since the page loaded.
+// Requires InstallAudioProbe to have been called before navigation.
+func (bm *BrowserManager) CheckAudio() (*AudioStatus, error) {
+	var resultJSON string
+	if err := runWithTimeout(bm.ctx, chromedp.Evaluate(audioCheckScript, &resultJSON)); err != nil {
+		return nil, fmt.Errorf("failed to check audio: %w", err)
+	}
+
+	var result struct {
+		Installed      bool `json:"installed"`
+		Contexts       int  `json:"contexts"`
+		Running        int  `json:"running"`
+		SourcesStarted int  `json:"sourcesStarted"`
+		MediaPlayed    int  `json:"mediaPlayed"`
+		MediaPlaying   int  `json:"mediaPlaying"`
+	}
+	if err := json.Unmarshal([]byte(resultJSON), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse audio status: %w", err)
+	}
+	if !result.Installed {
+		return nil, fmt.Errorf("audio probe not installed on this page")
+	}
+
+	status := &AudioStatus{
+		AudioContexts:        result.Contexts,
+		RunningContexts:      result.Running,
+		SourcesStarted:       result.SourcesStarted,
+		MediaElementsPlayed:  result.MediaPlayed,
+		MediaElementsPlaying: result.MediaPlaying,
+	}
+	// A context that exists but never started a source (e.g. blocked by autoplay policy) is not audio
+	status.Detected = (result.Running > 0 && result.SourcesStarted > 0) || result.MediaPlayed > 0
+
+	return status, nil
+}
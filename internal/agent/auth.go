@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/dreamup/qa-agent/internal/logging"
+)
+
+// WithBasicAuth answers HTTP basic auth challenges from the game's server with the given
+// credentials, e.g. for password-protected staging environments
+func WithBasicAuth(username, password string) BrowserOption {
+	return func(c *browserConfig) {
+		if username != "" {
+			c.basicAuth = url.UserPassword(username, password)
+		}
+	}
+}
+
+// WithExtraHeaders sends the given headers with every request, e.g. a preview
+// environment's access token
+func WithExtraHeaders(headers map[string]string) BrowserOption {
+	return func(c *browserConfig) {
+		c.extraHeaders = headers
+	}
+}
+
+// setExtraHeaders sends headers with every request the browser makes
+func (bm *BrowserManager) setExtraHeaders(headers map[string]string) error {
+	h := make(network.Headers, len(headers))
+	for name, value := range headers {
+		h[name] = value
+	}
+	return runWithTimeout(bm.ctx,
+		network.Enable(),
+		network.SetExtraHTTPHeaders(h),
+	)
+}
+
+// enableAuth answers authentication challenges: proxyAuth for the proxy and serverAuth for
+// the sites being loaded (either may be nil). Enabling auth handling in the Fetch domain
+// pauses every request, so paused requests are continued unchanged.
+func (bm *BrowserManager) enableAuth(proxyAuth, serverAuth *url.Userinfo) error {
+	chromedp.ListenTarget(bm.ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventAuthRequired:
+			// CDP calls can't be made from the listener goroutine
+			go func() {
+				creds := serverAuth
+				if ev.AuthChallenge != nil && ev.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+					creds = proxyAuth
+				}
+
+				response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+				if creds != nil {
+					password, _ := creds.Password()
+					response = &fetch.AuthChallengeResponse{
+						Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+						Username: creds.Username(),
+						Password: password,
+					}
+				}
+				if err := chromedp.Run(bm.ctx, fetch.ContinueWithAuth(ev.RequestID, response)); err != nil {
+					logging.Printf(bm.ctx, "[Auth] Failed to answer auth challenge: %v", err)
+				}
+			}()
+		case *fetch.EventRequestPaused:
+			go func() {
+				if err := chromedp.Run(bm.ctx, fetch.ContinueRequest(ev.RequestID)); err != nil && bm.ctx.Err() == nil {
+					logging.Printf(bm.ctx, "[Auth] Failed to continue request: %v", err)
+				}
+			}()
+		}
+	})
+
+	return runWithTimeout(bm.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		return fetch.Enable().WithHandleAuthRequests(true).Do(ctx)
+	}))
+}
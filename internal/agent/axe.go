@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dreamup/qa-agent/internal/logging"
+)
+
+// axeCoreVersion is the axe-core release used for accessibility audits, embedded or (if
+// the bundle wasn't fetched before building) loaded from the CDN
+const axeCoreVersion = "4.8.2"
+
+// axeCoreURL is the CDN fallback for the axe-core bundle
+const axeCoreURL = "https://cdnjs.cloudflare.com/ajax/libs/axe-core/" + axeCoreVersion + "/axe.min.js"
+
+// DefaultAccessibilityTags are the axe rule tags audited by default: WCAG 2.0 A and AA,
+// and WCAG 2.1 AA
+var DefaultAccessibilityTags = []string{"wcag2a", "wcag2aa", "wcag21aa"}
+
+// axeFS holds axe/axe.min.js when scripts/fetch-axe.sh was run before building. The
+// bundle isn't committed, so builds that skip the script have only the README.
+//
+//go:embed axe
+var axeFS embed.FS
+
+// axeCoreBundle returns the axe-core bundle in fsys, or "" with a warning if there is none
+// and audits will load axe-core from the CDN, which fails offline or under a strict CSP
+func axeCoreBundle(ctx context.Context, fsys fs.FS) string {
+	data, err := fs.ReadFile(fsys, "axe/axe.min.js")
+	if err != nil || len(data) == 0 {
+		logging.Printf(ctx, "[Metrics] Warning: axe-core isn't embedded in this build (run scripts/fetch-axe.sh before building); loading it from %s", axeCoreURL)
+		return ""
+	}
+	return string(data)
+}
+
+// ParseAccessibilityTags parses a comma-separated list of axe rule tags (e.g.
+// "wcag2a,wcag2aa,best-practice"). An empty list returns nil, for the defaults.
+func ParseAccessibilityTags(value string) ([]string, error) {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if strings.ContainsAny(tag, "'\"\\ ") {
+			return nil, fmt.Errorf("invalid accessibility tag %q", tag)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// AccessibilityWeights turn an audit's violation counts into a 0-100 score. Each violated
+// rule adds its impact's weight to a penalty, and the score falls off smoothly as
+// 100 * HalfScorePenalty / (HalfScorePenalty + penalty): it never hits a floor, so a page
+// with 30 critical violations still scores below one with 3.
+type AccessibilityWeights struct {
+	Critical float64 `json:"critical"`
+	Serious  float64 `json:"serious"`
+	Moderate float64 `json:"moderate"`
+	Minor    float64 `json:"minor"`
+	// HalfScorePenalty is the penalty that halves the score
+	HalfScorePenalty float64 `json:"half_score_penalty"`
+}
+
+// DefaultAccessibilityWeights weigh a critical violation like 1.5 serious, 3 moderate or
+// 7.5 minor ones; one critical violation scores 87, ten score 40
+var DefaultAccessibilityWeights = AccessibilityWeights{
+	Critical:         15,
+	Serious:          10,
+	Moderate:         5,
+	Minor:            2,
+	HalfScorePenalty: 100,
+}
+
+// Score returns the score and penalty for an audit's violation counts
+func (w AccessibilityWeights) Score(m *AccessibilityMetrics) (int, float64) {
+	penalty := float64(m.Critical)*w.Critical + float64(m.Serious)*w.Serious +
+		float64(m.Moderate)*w.Moderate + float64(m.Minor)*w.Minor
+	if penalty <= 0 {
+		return 100, 0
+	}
+	half := w.HalfScorePenalty
+	if half <= 0 {
+		half = DefaultAccessibilityWeights.HalfScorePenalty
+	}
+	return int(math.Round(100 * half / (half + penalty))), penalty
+}
+
+// ParseAccessibilityWeights parses weights like "critical=20,serious=10,half=150", where
+// half sets HalfScorePenalty. Unlisted weights keep their defaults; an empty value
+// returns nil, for the defaults.
+func ParseAccessibilityWeights(value string) (*AccessibilityWeights, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	weights := DefaultAccessibilityWeights
+	for _, entry := range strings.Split(value, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid accessibility weight %q (use name=value)", entry)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid accessibility weight %q (must be a non-negative number)", entry)
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "critical":
+			weights.Critical = v
+		case "serious":
+			weights.Serious = v
+		case "moderate":
+			weights.Moderate = v
+		case "minor":
+			weights.Minor = v
+		case "half":
+			if v == 0 {
+				return nil, fmt.Errorf("accessibility weight half must be positive")
+			}
+			weights.HalfScorePenalty = v
+		default:
+			return nil, fmt.Errorf("unknown accessibility weight %q (use critical, serious, moderate, minor or half)", name)
+		}
+	}
+	return &weights, nil
+}
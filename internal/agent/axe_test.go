@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAxeCoreBundle(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	embedded := fstest.MapFS{
+		"axe/README.md":  {Data: []byte("readme")},
+		"axe/axe.min.js": {Data: []byte("window.axe = {}")},
+	}
+	if got := axeCoreBundle(context.Background(), embedded); got != "window.axe = {}" {
+		t.Errorf("axeCoreBundle = %q, want the embedded bundle", got)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("embedded bundle logged %q", logs.String())
+	}
+
+	// A checkout built without scripts/fetch-axe.sh has only the README
+	for name, fsys := range map[string]fstest.MapFS{
+		"missing": {"axe/README.md": {Data: []byte("readme")}},
+		"empty":   {"axe/axe.min.js": {Data: nil}},
+	} {
+		logs.Reset()
+		if got := axeCoreBundle(context.Background(), fsys); got != "" {
+			t.Errorf("%s: axeCoreBundle = %q, want none", name, got)
+		}
+		if warning := logs.String(); !strings.Contains(warning, "scripts/fetch-axe.sh") || !strings.Contains(warning, axeCoreURL) {
+			t.Errorf("%s: fallback warning %q doesn't name the fetch script and CDN URL", name, warning)
+		}
+	}
+}
+
+// TestFetchAxeReadsVersion checks scripts/fetch-axe.sh can still find the pinned version
+func TestFetchAxeReadsVersion(t *testing.T) {
+	source, err := os.ReadFile("axe.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The sed expression in scripts/fetch-axe.sh
+	match := regexp.MustCompile(`(?m)^const axeCoreVersion = "(.*)"$`).FindSubmatch(source)
+	if match == nil || string(match[1]) != axeCoreVersion {
+		t.Errorf("fetch-axe.sh would read version %q, want %q", match, axeCoreVersion)
+	}
+}
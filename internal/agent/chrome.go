@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// WithChromePath runs the Chrome or Chromium binary at path instead of searching PATH
+// (e.g. /usr/bin/chromium on Alpine). An empty path keeps the default lookup.
+func WithChromePath(path string) BrowserOption {
+	return func(c *browserConfig) {
+		c.chromePath = path
+	}
+}
+
+// WithChromeFlags adds extra command-line flags such as "--use-gl=swiftshader".
+// Extra flags are applied last, so they override the built-in defaults.
+func WithChromeFlags(flags ...string) BrowserOption {
+	return func(c *browserConfig) {
+		c.extraFlags = append(c.extraFlags, flags...)
+	}
+}
+
+// WithNoSandbox toggles --no-sandbox (on by default, needed when running as root in containers)
+func WithNoSandbox(enabled bool) BrowserOption {
+	return func(c *browserConfig) {
+		c.noSandbox = enabled
+	}
+}
+
+// WithDisableDevShm toggles --disable-dev-shm-usage (on by default, needed when /dev/shm is small)
+func WithDisableDevShm(enabled bool) BrowserOption {
+	return func(c *browserConfig) {
+		c.disableDevShm = enabled
+	}
+}
+
+// ParseChromeFlags splits a space-separated flag list such as
+// "--use-gl=swiftshader --enable-unsafe-swiftshader" and checks each entry starts with "--"
+func ParseChromeFlags(s string) ([]string, error) {
+	flags := strings.Fields(s)
+	for _, flag := range flags {
+		if !strings.HasPrefix(flag, "--") || len(flag) == 2 {
+			return nil, fmt.Errorf("invalid Chrome flag %q (expected --flag or --flag=value)", flag)
+		}
+	}
+	return flags, nil
+}
+
+// ChromeOptionsFromEnv builds browser options from CHROME_PATH, EXTRA_CHROME_FLAGS,
+// CHROME_NO_SANDBOX and CHROME_DISABLE_DEV_SHM
+func ChromeOptionsFromEnv() ([]BrowserOption, error) {
+	var options []BrowserOption
+
+	if path := os.Getenv("CHROME_PATH"); path != "" {
+		options = append(options, WithChromePath(path))
+	}
+
+	if v := os.Getenv("EXTRA_CHROME_FLAGS"); v != "" {
+		flags, err := ParseChromeFlags(v)
+		if err != nil {
+			return nil, fmt.Errorf("EXTRA_CHROME_FLAGS: %w", err)
+		}
+		options = append(options, WithChromeFlags(flags...))
+	}
+
+	if v := os.Getenv("CHROME_NO_SANDBOX"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("CHROME_NO_SANDBOX: %w", err)
+		}
+		options = append(options, WithNoSandbox(enabled))
+	}
+
+	if v := os.Getenv("CHROME_DISABLE_DEV_SHM"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("CHROME_DISABLE_DEV_SHM: %w", err)
+		}
+		options = append(options, WithDisableDevShm(enabled))
+	}
+
+	return options, nil
+}
+
+// chromeFlagOptions converts "--name=value" and "--name" flags to allocator options
+func chromeFlagOptions(flags []string) []chromedp.ExecAllocatorOption {
+	opts := make([]chromedp.ExecAllocatorOption, 0, len(flags))
+	for _, flag := range flags {
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(flag, "--"), "=")
+		if hasValue {
+			opts = append(opts, chromedp.Flag(name, value))
+		} else {
+			opts = append(opts, chromedp.Flag(name, true))
+		}
+	}
+	return opts
+}
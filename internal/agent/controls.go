@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxKeyHold caps how long keyboard gameplay holds each key down
+const MaxKeyHold = 5 * time.Second
+
+// DefaultGameplayKeys is the key sequence sent per round of keyboard gameplay when no
+// controls are specified
+var DefaultGameplayKeys = []string{
+	"ArrowUp", "ArrowUp",
+	"ArrowRight", "ArrowRight", "ArrowRight",
+	"Space",
+	"ArrowLeft", "ArrowLeft",
+	"ArrowDown",
+	"Space",
+	"ArrowRight",
+}
+
+// ControlPresets are named key sequences for common game genres
+var ControlPresets = map[string][]string{
+	// platformer runs right, jumps and occasionally backtracks
+	"platformer": {
+		"ArrowRight", "ArrowRight", "ArrowRight",
+		"Space",
+		"ArrowRight", "ArrowRight",
+		"ArrowUp",
+		"ArrowLeft",
+		"Space",
+	},
+	// racing mostly accelerates with occasional steering
+	"racing": {
+		"ArrowUp", "ArrowUp", "ArrowUp",
+		"ArrowLeft",
+		"ArrowUp", "ArrowUp",
+		"ArrowRight",
+		"ArrowUp",
+	},
+	// wasd moves in every direction with WASD keys
+	"wasd": {
+		"w", "w", "d", "d", "s", "a", "a", "Space",
+	},
+	// arrows moves in every direction with the arrow keys
+	"arrows": {
+		"ArrowUp", "ArrowRight", "ArrowDown", "ArrowLeft",
+	},
+}
+
+// namedKeys are the multi-character key names accepted in controls
+var namedKeys = map[string]bool{
+	"ArrowUp":    true,
+	"ArrowDown":  true,
+	"ArrowLeft":  true,
+	"ArrowRight": true,
+	"Space":      true,
+	"Enter":      true,
+	"Escape":     true,
+}
+
+// ResolveControls expands a list of keys and preset names into the key sequence for one
+// round of keyboard gameplay. Presets expand in place; single characters (e.g. "w") and
+// named keys (e.g. "ArrowUp", "Space") are used as-is. An empty list returns DefaultGameplayKeys.
+func ResolveControls(controls []string) ([]string, error) {
+	if len(controls) == 0 {
+		return DefaultGameplayKeys, nil
+	}
+
+	var keys []string
+	for _, control := range controls {
+		if preset, ok := ControlPresets[strings.ToLower(control)]; ok {
+			keys = append(keys, preset...)
+			continue
+		}
+		if namedKeys[control] || (utf8.RuneCountInString(control) == 1 && control != " ") {
+			keys = append(keys, control)
+			continue
+		}
+		return nil, fmt.Errorf("unknown control %q (use a single character, a key name such as ArrowUp or Space, or a preset: platformer, racing, wasd, arrows)", control)
+	}
+
+	return keys, nil
+}
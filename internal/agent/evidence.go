@@ -8,8 +8,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/chromedp"
 	"github.com/google/uuid"
@@ -41,17 +44,66 @@ type Screenshot struct {
 	Width int
 	// Height is the screenshot height in pixels
 	Height int
+	// Format is the encoding of Data ("" is PNG)
+	Format ImageFormat
+	// AnnotatedFilepath is the saved copy marked up with the actions taken after it
+	// (see AnnotateScreenshots); empty if it wasn't annotated
+	AnnotatedFilepath string
+
+	// phash caches PerceptualHash once phashDone is set
+	phash     uint64
+	phashDone bool
 }
 
+// DefaultScreenshotInterval is how often gameplay screenshots are kept as evidence
+const DefaultScreenshotInterval = 2 * time.Second
+
+// DefaultMaxScreenshots caps the gameplay screenshots kept in memory during a test
+const DefaultMaxScreenshots = 20
+
 // CaptureScreenshot captures a full-page screenshot using chromedp
-// Resolution: 1280x720, Format: PNG with compression level 6
+// Resolution: the browser's viewport (1280x720 by default), Format: PNG with compression level 6.
+// If the game is embedded in an iframe (see DetectGameFrame), only that iframe is captured.
 func CaptureScreenshot(ctx context.Context, screenshotContext ScreenshotContext) (*Screenshot, error) {
 	var buf []byte
+	viewport := ViewportFromContext(ctx)
+	width, height := viewport.Width, viewport.Height
+	format := ScreenshotFormatFromContext(ctx)
+
+	// FullScreenshot takes PNG at quality 100 and JPEG below it, so JPEG tops out at 99
+	quality := 100
+	cdpFormat := page.CaptureScreenshotFormatPng
+	if format.Format == ImageFormatJPEG {
+		quality = min(format.Quality, 99)
+		cdpFormat = page.CaptureScreenshotFormatJpeg
+	}
+
+	capture := chromedp.FullScreenshot(&buf, quality)
+	if frame := GameFrameFromContext(ctx); frame != nil {
+		width, height = frame.Width, frame.Height
+		capture = chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			shot := page.CaptureScreenshot().WithFormat(cdpFormat)
+			if cdpFormat == page.CaptureScreenshotFormatJpeg {
+				shot = shot.WithQuality(int64(quality))
+			}
+			buf, err = shot.
+				WithClip(&page.Viewport{
+					X:      float64(frame.X),
+					Y:      float64(frame.Y),
+					Width:  float64(frame.Width),
+					Height: float64(frame.Height),
+					Scale:  1,
+				}).
+				Do(ctx)
+			return err
+		})
+	}
 
 	// Capture screenshot with specified settings
-	if err := chromedp.Run(ctx,
-		chromedp.EmulateViewport(1280, 720),
-		chromedp.FullScreenshot(&buf, 100), // 100 quality for PNG
+	if err := runWithTimeout(ctx,
+		chromedp.EmulateViewport(int64(viewport.Width), int64(viewport.Height)),
+		capture,
 	); err != nil {
 		return nil, fmt.Errorf("failed to capture screenshot: %w", err)
 	}
@@ -60,13 +112,47 @@ func CaptureScreenshot(ctx context.Context, screenshotContext ScreenshotContext)
 		Context:   screenshotContext,
 		Timestamp: time.Now(),
 		Data:      buf,
-		Width:     1280,
-		Height:    720,
+		Width:     width,
+		Height:    height,
+		Format:    format.Format,
 	}
 
 	return screenshot, nil
 }
 
+// MaxDOMSnapshotBytes caps the HTML kept by CaptureDOM
+const MaxDOMSnapshotBytes = 256 * 1024
+
+// domSnapshotScript returns the body's HTML with script and style contents removed,
+// since they explain nothing about what is on screen
+const domSnapshotScript = `
+(function() {
+    if (!document.body) return '';
+    const body = document.body.cloneNode(true);
+    body.querySelectorAll('script, style, noscript').forEach(el => el.remove());
+    return body.outerHTML;
+})();
+`
+
+// CaptureDOM returns the page's current <body> HTML without scripts and styles, cut to
+// MaxDOMSnapshotBytes. It shows DOM state screenshots can't explain, such as an HTML
+// error overlay on top of the game.
+func CaptureDOM(ctx context.Context) (string, error) {
+	var html string
+	if err := runWithTimeout(ctx, chromedp.Evaluate(domSnapshotScript, &html)); err != nil {
+		return "", fmt.Errorf("failed to capture DOM: %w", err)
+	}
+	if len(html) > MaxDOMSnapshotBytes {
+		// Back up to a rune boundary so the result stays valid UTF-8
+		cut := MaxDOMSnapshotBytes
+		for cut > 0 && !utf8.RuneStart(html[cut]) {
+			cut--
+		}
+		html = html[:cut] + "\n<!-- truncated -->"
+	}
+	return html, nil
+}
+
 // getMediaDir returns the persistent media directory, creating it if needed
 func getMediaDir() (string, error) {
 	// Use ./data/media for persistent storage (not /tmp which is ephemeral)
@@ -80,10 +166,11 @@ func getMediaDir() (string, error) {
 // SaveToTemp saves the screenshot to a persistent directory with a unique filename
 func (s *Screenshot) SaveToTemp() error {
 	// Generate unique filename
-	filename := fmt.Sprintf("screenshot_%s_%s_%s.png",
+	filename := fmt.Sprintf("screenshot_%s_%s_%s%s",
 		s.Context,
 		s.Timestamp.Format("20060102_150405"),
 		uuid.New().String()[:8],
+		s.fileExtension(),
 	)
 
 	// Get persistent media directory
@@ -104,13 +191,74 @@ func (s *Screenshot) SaveToTemp() error {
 	return nil
 }
 
-// Hash computes a SHA256 hash of the screenshot data for change detection
-// Returns a hex-encoded string of the hash
+// Hash computes a SHA256 hash of the screenshot data for exact deduplication
+// Returns a hex-encoded string of the hash. Use SimilarTo for change detection.
 func (s *Screenshot) Hash() string {
 	hash := sha256.Sum256(s.Data)
 	return hex.EncodeToString(hash[:])
 }
 
+// SampleScreenshots keeps n screenshots spread evenly across the run, always including the first and last
+func SampleScreenshots(screenshots []*Screenshot, n int) []*Screenshot {
+	if n >= len(screenshots) {
+		return screenshots
+	}
+	if n < 2 {
+		n = 2
+	}
+
+	sampled := make([]*Screenshot, 0, n)
+	last := len(screenshots) - 1
+	for i := 0; i < n; i++ {
+		sampled = append(sampled, screenshots[i*last/(n-1)])
+	}
+	return sampled
+}
+
+// WaitForScreenStable captures screenshots every pollInterval until stableFrames consecutive
+// captures have the same hash, or timeout elapses. It returns the last screenshot taken and
+// whether the screen actually stabilized. Games with constant background animation never
+// stabilize, so timeout is an upper bound rather than an error.
+func WaitForScreenStable(ctx context.Context, timeout, pollInterval time.Duration, stableFrames int) (*Screenshot, bool, error) {
+	if stableFrames < 1 {
+		stableFrames = 1
+	}
+
+	deadline := time.Now().Add(timeout)
+	var last *Screenshot
+	var lastHash string
+	matches := 0
+
+	for {
+		screenshot, err := CaptureScreenshot(ctx, ContextGameplay)
+		if err != nil {
+			return last, false, err
+		}
+
+		hash := screenshot.Hash()
+		if hash == lastHash {
+			matches++
+		} else {
+			matches = 0
+		}
+		last = screenshot
+		lastHash = hash
+
+		if matches >= stableFrames {
+			return last, true, nil
+		}
+		if time.Now().Add(pollInterval).After(deadline) {
+			return last, false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return last, false, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
 // LogLevel represents the severity level of a console log
 type LogLevel string
 
@@ -125,8 +273,19 @@ const (
 	LogLevelInfo LogLevel = "info"
 	// LogLevelDebug represents console.debug messages
 	LogLevelDebug LogLevel = "debug"
+	// LogLevelException represents uncaught exceptions and unhandled promise rejections
+	LogLevelException LogLevel = "exception"
 )
 
+// ParseLogLevel checks a console log level name
+func ParseLogLevel(name string) (LogLevel, error) {
+	switch level := LogLevel(name); level {
+	case LogLevelLog, LogLevelWarning, LogLevelError, LogLevelInfo, LogLevelDebug, LogLevelException:
+		return level, nil
+	}
+	return "", fmt.Errorf("unknown log level %q (use log, info, warning, error, debug or exception)", name)
+}
+
 // ConsoleLog represents a single browser console log entry
 type ConsoleLog struct {
 	// Level is the severity level of the log
@@ -139,6 +298,11 @@ type ConsoleLog struct {
 	Source string
 	// Args contains additional arguments passed to the console method
 	Args []interface{}
+	// StackTrace is the call stack of an uncaught exception, one frame per line
+	StackTrace string `json:"StackTrace,omitempty"`
+	// Occurrences is how many times the message was logged, when the report collapsed
+	// repeats of it into this entry (0 = not deduplicated)
+	Occurrences int `json:"Occurrences,omitempty"`
 }
 
 // ConsoleLogger captures browser console logs during test execution
@@ -147,6 +311,8 @@ type ConsoleLogger struct {
 	Logs []ConsoleLog
 	// Filter determines which log levels to capture (nil = capture all)
 	Filter map[LogLevel]bool
+	// onLog is called with each captured log that passes Filter (nil = no forwarding)
+	onLog func(ConsoleLog)
 }
 
 // NewConsoleLogger creates a new console logger with optional level filtering
@@ -166,6 +332,12 @@ func NewConsoleLogger(filterLevels ...LogLevel) *ConsoleLogger {
 	return logger
 }
 
+// SetOnLog forwards each captured log that passes Filter to fn as it arrives, e.g. for
+// live streaming. fn runs on the browser event goroutine and must not block.
+func (cl *ConsoleLogger) SetOnLog(fn func(ConsoleLog)) {
+	cl.onLog = fn
+}
+
 // StartCapture sets up console log event listeners in the browser context
 func (cl *ConsoleLogger) StartCapture(ctx context.Context) error {
 	// Enable console log events
@@ -173,6 +345,8 @@ func (cl *ConsoleLogger) StartCapture(ctx context.Context) error {
 		switch ev := ev.(type) {
 		case *runtime.EventConsoleAPICalled:
 			cl.handleConsoleEvent(ev)
+		case *runtime.EventExceptionThrown:
+			cl.handleException(ev)
 		}
 	})
 
@@ -229,7 +403,60 @@ func (cl *ConsoleLogger) handleConsoleEvent(ev *runtime.EventConsoleAPICalled) {
 		Args:      args,
 	}
 
+	cl.record(log)
+}
+
+// handleException records an uncaught exception or unhandled promise rejection, which
+// never reaches console.error
+func (cl *ConsoleLogger) handleException(ev *runtime.EventExceptionThrown) {
+	if cl.Filter != nil && !cl.Filter[LogLevelException] {
+		return
+	}
+	details := ev.ExceptionDetails
+	if details == nil {
+		return
+	}
+
+	// The description is "TypeError: msg\n    at ..."; its first line is the most useful
+	// message. Text is only a prefix such as "Uncaught" or "Uncaught (in promise)".
+	message := details.Text
+	if details.Exception != nil && details.Exception.Description != "" {
+		firstLine, _, _ := strings.Cut(details.Exception.Description, "\n")
+		message = strings.TrimSpace(details.Text + " " + firstLine)
+	}
+
+	source := details.URL
+	if source != "" {
+		source = fmt.Sprintf("%s:%d:%d", details.URL, details.LineNumber, details.ColumnNumber)
+	}
+
+	var stack []string
+	if details.StackTrace != nil {
+		for _, frame := range details.StackTrace.CallFrames {
+			name := frame.FunctionName
+			if name == "" {
+				name = "(anonymous)"
+			}
+			stack = append(stack, fmt.Sprintf("at %s (%s:%d:%d)", name, frame.URL, frame.LineNumber, frame.ColumnNumber))
+		}
+	}
+
+	cl.record(ConsoleLog{
+		Level:      LogLevelException,
+		Message:    message,
+		Timestamp:  time.Now(),
+		Source:     source,
+		StackTrace: strings.Join(stack, "\n"),
+	})
+}
+
+// record stores a captured log and forwards it
+func (cl *ConsoleLogger) record(log ConsoleLog) {
 	cl.Logs = append(cl.Logs, log)
+
+	if cl.onLog != nil {
+		cl.onLog(log)
+	}
 }
 
 // GetLogs returns all captured logs
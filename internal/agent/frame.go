@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/dreamup/qa-agent/internal/logging"
+)
+
+// minGameFrameCoverage is the fraction of the viewport an iframe must cover for the page
+// to count as a thin wrapper around it
+const minGameFrameCoverage = 0.5
+
+// GameFrame is the iframe a game is embedded in, in page (CSS pixel) coordinates
+type GameFrame struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// URL is the iframe's src
+	URL string `json:"url"`
+	// TargetID is the iframe's out-of-process target, if it is cross-origin
+	TargetID target.ID `json:"target_id,omitempty"`
+}
+
+// gameFrameKey is the context key for the browser's *gameFrameState
+type gameFrameKey struct{}
+
+// gameFrameState holds the detected game frame, shared by every context derived from
+// the browser context
+type gameFrameState struct {
+	mu    sync.RWMutex
+	frame *GameFrame
+}
+
+// GameFrameFromContext returns the game iframe detected by DetectGameFrame, or nil if the
+// game runs in the top document
+func GameFrameFromContext(ctx context.Context) *GameFrame {
+	state, ok := ctx.Value(gameFrameKey{}).(*gameFrameState)
+	if !ok {
+		return nil
+	}
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return state.frame
+}
+
+// toPage converts coordinates relative to the game frame (as seen in screenshots) to
+// page coordinates for input events
+func toPage(ctx context.Context, x, y float64) (float64, float64) {
+	if frame := GameFrameFromContext(ctx); frame != nil {
+		return x + float64(frame.X), y + float64(frame.Y)
+	}
+	return x, y
+}
+
+// gameFrameScript finds the largest visible iframe and whether the top document has a
+// canvas big enough to be the game itself
+const gameFrameScript = `
+(function() {
+    const visibleArea = (el) => {
+        const r = el.getBoundingClientRect();
+        const w = Math.min(r.right, window.innerWidth) - Math.max(r.left, 0);
+        const h = Math.min(r.bottom, window.innerHeight) - Math.max(r.top, 0);
+        return w > 0 && h > 0 ? w * h : 0;
+    };
+
+    let best = null, bestArea = 0;
+    for (const iframe of document.querySelectorAll('iframe')) {
+        const style = getComputedStyle(iframe);
+        if (style.visibility === 'hidden' || style.display === 'none') continue;
+        const area = visibleArea(iframe);
+        if (area > bestArea) {
+            best = iframe;
+            bestArea = area;
+        }
+    }
+
+    let canvasArea = 0;
+    for (const canvas of document.querySelectorAll('canvas')) {
+        canvasArea = Math.max(canvasArea, visibleArea(canvas));
+    }
+
+    if (!best) return JSON.stringify({ found: false, canvasArea: canvasArea });
+
+    // Focus the iframe so keyboard input reaches the game, even across origins
+    try { best.focus(); } catch (e) {}
+
+    const r = best.getBoundingClientRect();
+    return JSON.stringify({
+        found: true,
+        x: Math.max(0, Math.round(r.left)),
+        y: Math.max(0, Math.round(r.top)),
+        width: Math.round(Math.min(r.right, window.innerWidth) - Math.max(r.left, 0)),
+        height: Math.round(Math.min(r.bottom, window.innerHeight) - Math.max(r.top, 0)),
+        src: best.src || '',
+        area: bestArea,
+        canvasArea: canvasArea,
+        viewportArea: window.innerWidth * window.innerHeight
+    });
+})();
+`
+
+// DetectGameFrame checks whether the page is a thin wrapper around a game iframe (as on
+// many game portals) and, if so, routes screenshots and clicks to that iframe: screenshots
+// are clipped to it and click coordinates are offset into it. It returns nil if the game
+// runs in the top document, which stays the target for both.
+//
+// Cross-origin iframes run in their own target, but Chrome can only screenshot top-level
+// targets and routes input events into child frames by position, so routing goes through
+// the top target either way. The iframe's target is looked up for diagnostics.
+func (bm *BrowserManager) DetectGameFrame() (*GameFrame, error) {
+	var resultJSON string
+	if err := runWithTimeout(bm.ctx, chromedp.Evaluate(gameFrameScript, &resultJSON)); err != nil {
+		return nil, fmt.Errorf("failed to detect game frame: %w", err)
+	}
+
+	var result struct {
+		Found        bool    `json:"found"`
+		X            int     `json:"x"`
+		Y            int     `json:"y"`
+		Width        int     `json:"width"`
+		Height       int     `json:"height"`
+		Src          string  `json:"src"`
+		Area         float64 `json:"area"`
+		CanvasArea   float64 `json:"canvasArea"`
+		ViewportArea float64 `json:"viewportArea"`
+	}
+	if err := json.Unmarshal([]byte(resultJSON), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse game frame: %w", err)
+	}
+
+	// A page with its own large canvas is the game, whatever iframes it also has
+	var frame *GameFrame
+	if result.Found && result.ViewportArea > 0 &&
+		result.Area/result.ViewportArea >= minGameFrameCoverage && result.CanvasArea < result.Area {
+		frame = &GameFrame{
+			X:      result.X,
+			Y:      result.Y,
+			Width:  result.Width,
+			Height: result.Height,
+			URL:    result.Src,
+		}
+		if targets, err := chromedp.Targets(bm.ctx); err == nil {
+			for _, t := range targets {
+				if t.Type == "iframe" && t.URL == result.Src {
+					frame.TargetID = t.TargetID
+					break
+				}
+			}
+		}
+		logging.Printf(bm.ctx, "[GameFrame] Game is embedded in an iframe at (%d, %d) %dx%d: %s (out-of-process: %v)",
+			frame.X, frame.Y, frame.Width, frame.Height, frame.URL, frame.TargetID != "")
+	}
+
+	if state, ok := bm.ctx.Value(gameFrameKey{}).(*gameFrameState); ok {
+		state.mu.Lock()
+		state.frame = frame
+		state.mu.Unlock()
+	}
+	return frame, nil
+}
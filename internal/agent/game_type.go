@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/logging"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// GameType is how a game is played, which picks the AI gameplay strategy
+type GameType string
+
+const (
+	GameTypeSlingshot GameType = "slingshot" // Pull back and release to launch (Angry Birds-style)
+	GameTypeKeyboard  GameType = "keyboard"  // Moved with keys (platformers, racing, arcade)
+	GameTypeClick     GameType = "click"     // Played by clicking or tapping (puzzles, idle games)
+	GameTypeDrag      GameType = "drag"      // Played by dragging pieces (match-3, sorting, drawing)
+	GameTypeUnknown   GameType = "unknown"   // Type could not be determined
+)
+
+// ClassifyGameType uses vision to decide how a game is played from a screenshot of it
+// in play and the game mechanics, if known. Returns GameTypeUnknown on error.
+func (g *GameplayAgent) ClassifyGameType(screenshot *Screenshot, gameMechanics string) (GameType, error) {
+	imageURL := scaledForVision(g.ctx, screenshot, g.maxImageDim).DataURL()
+
+	mechanicsContext := ""
+	if gameMechanics != "" {
+		mechanicsContext = fmt.Sprintf("\n\nGAME MECHANICS:\n%s", gameMechanics)
+	}
+
+	prompt := fmt.Sprintf(`How is the game in this screenshot played?%s
+
+Return JSON:
+{
+  "game_type": "keyboard",
+  "reasoning": "A character stands on platforms; arrow keys are shown in the tutorial"
+}
+
+GAME TYPES:
+- slingshot: Pull back a launcher and release to fling a projectile (Angry Birds-style)
+- keyboard: A character or vehicle is moved with keys (platformers, racing, shooters, snake)
+- click: Played by clicking or tapping things (puzzles, idle/clicker games, card games)
+- drag: Played by dragging pieces or drawing (match-3 swaps, sorting, slicing)`, mechanicsContext)
+
+	release, err := AcquireLLMSlot(g.ctx)
+	if err != nil {
+		return GameTypeUnknown, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resp, err := g.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: g.model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role: openai.ChatMessageRoleUser,
+				MultiContent: []openai.ChatMessagePart{
+					{
+						Type: openai.ChatMessagePartTypeText,
+						Text: prompt,
+					},
+					{
+						Type: openai.ChatMessagePartTypeImageURL,
+						ImageURL: &openai.ChatMessageImageURL{
+							URL: imageURL,
+						},
+					},
+				},
+			},
+		},
+		MaxCompletionTokens: visionMaxTokens(g.model, 200),
+	})
+
+	if err != nil {
+		return GameTypeUnknown, fmt.Errorf("game type classification API call failed: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return GameTypeUnknown, fmt.Errorf("no response from vision API")
+	}
+
+	responseText := strings.TrimSpace(resp.Choices[0].Message.Content)
+
+	jsonText := responseText
+	if start, end := strings.Index(responseText, "{"), strings.LastIndex(responseText, "}"); start != -1 && end > start {
+		jsonText = responseText[start : end+1]
+	}
+
+	var result struct {
+		GameType  string `json:"game_type"`
+		Reasoning string `json:"reasoning"`
+	}
+	if err := json.Unmarshal([]byte(jsonText), &result); err != nil {
+		return GameTypeUnknown, fmt.Errorf("failed to parse game type response: %w (response: %s)", err, jsonText)
+	}
+
+	switch gameType := GameType(strings.ToLower(strings.TrimSpace(result.GameType))); gameType {
+	case GameTypeSlingshot, GameTypeKeyboard, GameTypeClick, GameTypeDrag:
+		logging.Printf(g.ctx, "[Gameplay] Classified game as %s: %s", gameType, result.Reasoning)
+		return gameType, nil
+	default:
+		return GameTypeUnknown, fmt.Errorf("unexpected game type %q", result.GameType)
+	}
+}
+
+// SetGameType steers planned gameplay (see PlayWithPlannedActions) toward the game's
+// kind of input
+func (g *GameplayAgent) SetGameType(gameType GameType) {
+	g.gameType = gameType
+}
+
+// gameTypeHints tell the action planner which actions suit each game type
+var gameTypeHints = map[GameType]string{
+	GameTypeSlingshot: "This is a slingshot game: use drag_slingshot to pull back and release.",
+	GameTypeKeyboard:  "This is a keyboard game: use keypress actions, holding movement keys with hold_ms.",
+	GameTypeClick:     "This is a click game: use click actions on the things to interact with.",
+	GameTypeDrag:      "This is a drag game: use drag_slingshot from the piece to where it should go.",
+}
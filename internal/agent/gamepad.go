@@ -0,0 +1,209 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+
+	"github.com/dreamup/qa-agent/internal/logging"
+)
+
+// GamepadButton is a button index in the W3C "standard" gamepad mapping
+type GamepadButton int
+
+const (
+	GamepadButtonA            GamepadButton = 0 // Bottom face button
+	GamepadButtonB            GamepadButton = 1 // Right face button
+	GamepadButtonX            GamepadButton = 2 // Left face button
+	GamepadButtonY            GamepadButton = 3 // Top face button
+	GamepadButtonLeftBumper   GamepadButton = 4
+	GamepadButtonRightBumper  GamepadButton = 5
+	GamepadButtonLeftTrigger  GamepadButton = 6
+	GamepadButtonRightTrigger GamepadButton = 7
+	GamepadButtonSelect       GamepadButton = 8
+	GamepadButtonStart        GamepadButton = 9
+	GamepadButtonLeftStick    GamepadButton = 10
+	GamepadButtonRightStick   GamepadButton = 11
+	GamepadButtonDPadUp       GamepadButton = 12
+	GamepadButtonDPadDown     GamepadButton = 13
+	GamepadButtonDPadLeft     GamepadButton = 14
+	GamepadButtonDPadRight    GamepadButton = 15
+	GamepadButtonHome         GamepadButton = 16
+	gamepadButtonCount                      = 17
+)
+
+// GamepadAxis is an axis index in the standard gamepad mapping; values run from -1
+// (left/up) to 1 (right/down)
+type GamepadAxis int
+
+const (
+	GamepadAxisLeftX  GamepadAxis = 0
+	GamepadAxisLeftY  GamepadAxis = 1
+	GamepadAxisRightX GamepadAxis = 2
+	GamepadAxisRightY GamepadAxis = 3
+	gamepadAxisCount              = 4
+)
+
+// gamepadTapDuration is how long TapButton holds a button; games poll gamepads once per
+// frame, so a press must span a few frames to be seen
+const gamepadTapDuration = 150 * time.Millisecond
+
+// emulateGamepadScript replaces navigator.getGamepads in the page and its same-origin
+// iframes with one returning a single virtual standard-mapping gamepad, then announces it
+// with gamepadconnected. The pad's state lives in window.__dreamupGamepad so later calls
+// can change it. Returns false if the page already has the virtual gamepad.
+const emulateGamepadScript = `
+(function(buttonCount, axisCount) {
+	if (window.__dreamupGamepad) {
+		return false;
+	}
+
+	const pad = {
+		id: 'DreamUp Virtual Gamepad (STANDARD GAMEPAD)',
+		index: 0,
+		connected: true,
+		mapping: 'standard',
+		timestamp: performance.now(),
+		buttons: Array.from({ length: buttonCount }, () => ({ pressed: false, touched: false, value: 0 })),
+		axes: new Array(axisCount).fill(0),
+		vibrationActuator: null
+	};
+	window.__dreamupGamepad = pad;
+
+	const install = (w) => {
+		try {
+			Object.defineProperty(w.navigator, 'getGamepads', {
+				configurable: true,
+				value: () => [pad, null, null, null]
+			});
+			// GamepadEvent only accepts real Gamepad objects, so attach the pad to a plain event
+			const event = new w.Event('gamepadconnected');
+			Object.defineProperty(event, 'gamepad', { value: pad });
+			w.dispatchEvent(event);
+		} catch (e) {
+			// Cross-origin frames can't be reached
+		}
+	};
+
+	install(window);
+	for (const frame of document.querySelectorAll('iframe')) {
+		try {
+			if (frame.contentWindow) {
+				install(frame.contentWindow);
+			}
+		} catch (e) {}
+	}
+	return true;
+})(%d, %d)
+`
+
+// Gamepad is a virtual gamepad installed in the page by EmulateGamepad, for games that
+// only read input through the Gamepad API
+type Gamepad struct {
+	ctx context.Context
+}
+
+// EmulateGamepad connects a virtual standard-mapping gamepad to the current page (and its
+// same-origin iframes). Games see it through navigator.getGamepads and the
+// gamepadconnected event. Calling it again on the same page reuses the installed pad.
+func EmulateGamepad(ctx context.Context) (*Gamepad, error) {
+	var installed bool
+	script := fmt.Sprintf(emulateGamepadScript, gamepadButtonCount, gamepadAxisCount)
+	if err := runWithTimeout(ctx, chromedp.Evaluate(script, &installed)); err != nil {
+		return nil, fmt.Errorf("failed to emulate gamepad: %w", err)
+	}
+	if installed {
+		logging.Printf(ctx, "[Gamepad] Virtual gamepad connected")
+	}
+	return &Gamepad{ctx: ctx}, nil
+}
+
+// update runs a statement against the virtual pad (bound as "pad") and bumps its timestamp,
+// which games compare to skip unchanged input
+func (g *Gamepad) update(statement string) error {
+	script := fmt.Sprintf(`
+(function() {
+	const pad = window.__dreamupGamepad;
+	if (!pad) {
+		return false;
+	}
+	%s;
+	pad.timestamp = performance.now();
+	return true;
+})()
+`, statement)
+
+	var ok bool
+	if err := runWithTimeout(g.ctx, chromedp.Evaluate(script, &ok)); err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("virtual gamepad is not installed (the page may have reloaded)")
+	}
+	return nil
+}
+
+// setButton sets a button's pressed state
+func (g *Gamepad) setButton(button GamepadButton, pressed bool) error {
+	if button < 0 || button >= gamepadButtonCount {
+		return fmt.Errorf("invalid gamepad button %d", button)
+	}
+	value := 0
+	if pressed {
+		value = 1
+	}
+	if err := g.update(fmt.Sprintf("Object.assign(pad.buttons[%d], { pressed: %t, touched: %t, value: %d })", button, pressed, pressed, value)); err != nil {
+		return err
+	}
+	recordAction(g.ctx, RecordedAction{Kind: RecordedGamepadButton, Index: int(button), Value: float64(value)})
+	return nil
+}
+
+// PressButton holds a button down until ReleaseButton
+func (g *Gamepad) PressButton(button GamepadButton) error {
+	if err := g.setButton(button, true); err != nil {
+		return fmt.Errorf("failed to press gamepad button %d: %w", button, err)
+	}
+	return nil
+}
+
+// ReleaseButton releases a button
+func (g *Gamepad) ReleaseButton(button GamepadButton) error {
+	if err := g.setButton(button, false); err != nil {
+		return fmt.Errorf("failed to release gamepad button %d: %w", button, err)
+	}
+	return nil
+}
+
+// TapButton presses a button long enough for the game to see it, then releases it
+func (g *Gamepad) TapButton(button GamepadButton) error {
+	if err := g.PressButton(button); err != nil {
+		return err
+	}
+	time.Sleep(gamepadTapDuration)
+	return g.ReleaseButton(button)
+}
+
+// SetAxis moves an axis to value, clamped to -1..1; it stays there until set again
+func (g *Gamepad) SetAxis(axis GamepadAxis, value float64) error {
+	if axis < 0 || axis >= gamepadAxisCount {
+		return fmt.Errorf("invalid gamepad axis %d", axis)
+	}
+	value = max(-1, min(1, value))
+	if err := g.update(fmt.Sprintf("pad.axes[%d] = %g", axis, value)); err != nil {
+		return fmt.Errorf("failed to set gamepad axis %d: %w", axis, err)
+	}
+	recordAction(g.ctx, RecordedAction{Kind: RecordedGamepadAxis, Index: int(axis), Value: value})
+	return nil
+}
+
+// Reset releases every button and centers every axis
+func (g *Gamepad) Reset() error {
+	statement := "pad.buttons.forEach((b) => { b.pressed = false; b.touched = false; b.value = 0; }); pad.axes.fill(0)"
+	if err := g.update(statement); err != nil {
+		return fmt.Errorf("failed to reset gamepad: %w", err)
+	}
+	return nil
+}
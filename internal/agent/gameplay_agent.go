@@ -5,11 +5,12 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"log"
+	"math"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/dreamup/qa-agent/internal/logging"
 	openai "github.com/sashabaranov/go-openai"
 )
 
@@ -20,12 +21,46 @@ type GameplayAgent struct {
 	vision       *VisionDOMDetector
 	client       *openai.Client
 	actionCache  *ActionCache
-	gridCols     int // 20 columns (A-T)
-	gridRows     int // 12 rows (1-12)
-	imageWidth   int // 1280
-	imageHeight  int // 720
+	gridCols     int // 20 columns (A-T) by default, see SetGridSize
+	gridRows     int // 12 rows (1-12) by default
+	imageWidth   int // Viewport width (1280 by default)
+	imageHeight  int // Viewport height (720 by default)
+
+	// settleTimeout caps how long to wait for the screen to stop changing after a drag
+	settleTimeout time.Duration
+	// settlePollInterval is the delay between stability checks
+	settlePollInterval time.Duration
+	// stuckPatience is how many consecutive attempts may leave the screen unchanged before giving up
+	stuckPatience int
+	// maxLevels is how many levels PlayGameLevel plays before stopping
+	maxLevels int
+	// gameType steers the action planner (see SetGameType; "" gives no hint)
+	gameType GameType
+	// model is the OpenAI vision model used for gameplay decisions (see SetModel)
+	model string
+	// maxImageDim is the longest side of screenshots sent to vision (see SetMaxImageDimension)
+	maxImageDim int
 }
 
+const (
+	// DefaultSettleTimeout is the maximum wait for game physics to settle after an action
+	DefaultSettleTimeout = 5 * time.Second
+	// DefaultSettlePollInterval is how often the screen is compared while settling
+	DefaultSettlePollInterval = 500 * time.Millisecond
+	// DefaultStuckPatience is how many unchanged gameplay intervals are tolerated, once every
+	// way of interacting has been tried, before a test ends early as stuck
+	DefaultStuckPatience = 5
+	// DefaultMaxLevels is how many levels PlayGameLevel plays, continuing past each
+	// level-complete screen, before stopping
+	DefaultMaxLevels = 3
+	// MaxLevelsLimit is the most levels a test may ask PlayGameLevel to play
+	MaxLevelsLimit = 20
+)
+
+// continueButtonTexts are tried, in order, when vision finds a level-complete screen but
+// can't name its continue button
+var continueButtonTexts = []string{"Next Level", "Next", "Continue", "Play Next"}
+
 // GameplayActionType represents different types of gameplay actions
 type GameplayActionType string
 
@@ -35,8 +70,34 @@ const (
 	ActionTypeWait           GameplayActionType = "wait"            // Wait for game state to change
 	ActionTypeObserve        GameplayActionType = "observe"         // Take screenshot and analyze
 	ActionTypeClick          GameplayActionType = "click"           // Single click action
+	ActionTypeKeypress       GameplayActionType = "keypress"        // Press (and optionally hold) a key
+)
+
+// GameOutcome is the vision-classified result of a gameplay action
+type GameOutcome string
+
+const (
+	OutcomeLevelComplete   GameOutcome = "level_complete"   // Win / level cleared screen
+	OutcomeLevelFailed     GameOutcome = "level_failed"     // Lose / retry screen
+	OutcomeDestroyedTarget GameOutcome = "destroyed_target" // Shot hit and destroyed a target, level continues
+	OutcomeInProgress      GameOutcome = "in_progress"      // Level continues with no notable change
+	OutcomeUnknown         GameOutcome = "unknown"          // Outcome could not be determined
 )
 
+// GameplayResult summarizes a PlayGameLevel run
+type GameplayResult struct {
+	// Outcome is the last outcome detected (level_complete if the level was beaten)
+	Outcome GameOutcome
+	// Attempts is how many attempts were made
+	Attempts int
+	// LevelComplete reports whether the agent actually beat the level
+	LevelComplete bool
+	// LevelsCompleted is how many levels were beaten, continuing to the next level after each
+	LevelsCompleted int
+	// Stuck reports whether the loop ended early because the screen stopped changing
+	Stuck bool
+}
+
 // GameplayActionPlan represents a single action in a gameplay sequence
 type GameplayActionPlan struct {
 	Type        GameplayActionType `json:"type"`
@@ -44,6 +105,8 @@ type GameplayActionPlan struct {
 	EndCell     string             `json:"end_cell,omitempty"`     // Grid cell to end (e.g., "C5")
 	TargetCell  string             `json:"target_cell,omitempty"`  // Grid cell to click (e.g., "J10")
 	WaitMs      int                `json:"wait_ms,omitempty"`      // Duration to wait
+	Key         string             `json:"key,omitempty"`          // Key to press (e.g., "ArrowRight", "Space", "w")
+	HoldMs      int                `json:"hold_ms,omitempty"`      // How long to hold Key (0 = tap)
 	Description string             `json:"description"`            // AI reasoning
 	ElementName string             `json:"element_name,omitempty"` // What element to find
 }
@@ -65,12 +128,16 @@ type ActionCache struct {
 
 // CachedDrag represents a successful slingshot drag
 type CachedDrag struct {
-	GameName      string    `json:"game_name"`
-	StartCell     string    `json:"start_cell"`
-	EndCell       string    `json:"end_cell"`
-	Outcome       string    `json:"outcome"`       // "destroyed_pig", "hit_structure", "missed"
-	Timestamp     time.Time `json:"timestamp"`
-	ScreenshotB64 string    `json:"screenshot_b64"` // Optional: before state
+	GameName  string `json:"game_name"`
+	StartCell string `json:"start_cell"`
+	EndCell   string `json:"end_cell"`
+	// StartOffset and EndOffset are the cells' in-cell offsets (see GridCell) as [x, y]
+	StartOffset   [2]float64 `json:"start_offset"`
+	EndOffset     [2]float64 `json:"end_offset"`
+	Power         float64    `json:"power"`
+	Outcome       string     `json:"outcome"` // "destroyed_pig", "hit_structure", "missed"
+	Timestamp     time.Time  `json:"timestamp"`
+	ScreenshotB64 string     `json:"screenshot_b64"` // Optional: before state
 }
 
 // NewGameplayAgent creates a new gameplay agent
@@ -80,28 +147,113 @@ func NewGameplayAgent(ctx context.Context, vision *VisionDOMDetector) (*Gameplay
 		return nil, fmt.Errorf("OPENAI_API_KEY required for gameplay agent")
 	}
 
-	return &GameplayAgent{
+	viewport := ViewportFromContext(ctx)
+	grid, err := GridSizeFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	maxImageDim, err := VisionMaxDimensionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if vision != nil {
+		grid = vision.GridSize()
+		maxImageDim = vision.MaxImageDimension()
+	}
+
+	ga := &GameplayAgent{
 		ctx:         ctx,
 		vision:      vision,
 		client:      openai.NewClient(apiKey),
 		actionCache: &ActionCache{SuccessfulDrags: []CachedDrag{}},
-		gridCols:    20,
-		gridRows:    12,
-		imageWidth:  1280,
-		imageHeight: 720,
-	}, nil
+		gridCols:    grid.Cols,
+		gridRows:    grid.Rows,
+		imageWidth:  viewport.Width,
+		imageHeight: viewport.Height,
+
+		settleTimeout:      DefaultSettleTimeout,
+		settlePollInterval: DefaultSettlePollInterval,
+		stuckPatience:      DefaultStuckPatience,
+		maxLevels:          DefaultMaxLevels,
+		model:              VisionModelFromEnv(),
+		maxImageDim:        maxImageDim,
+	}
+	if vision != nil {
+		ga.model = vision.Model()
+	}
+
+	// Reuse drags that worked in previous tests
+	if err := ga.LoadCache(DefaultActionCachePath); err != nil {
+		logging.Printf(ctx, "[Gameplay Cache] Warning: Failed to load action cache: %v", err)
+	} else if n := len(ga.actionCache.SuccessfulDrags); n > 0 {
+		logging.Printf(ctx, "[Gameplay Cache] Loaded %d cached drags from %s", n, DefaultActionCachePath)
+	}
+
+	return ga, nil
+}
+
+// SetSettleTimeout sets the maximum time to wait for the screen to stabilize after each action.
+// Slow physics games may need longer than the default; fast puzzle games can use much less.
+func (g *GameplayAgent) SetSettleTimeout(timeout time.Duration) {
+	g.settleTimeout = timeout
+}
+
+// SetSettlePollInterval sets how often the screen is checked for changes while settling
+func (g *GameplayAgent) SetSettlePollInterval(interval time.Duration) {
+	g.settlePollInterval = interval
+}
+
+// SetStuckPatience sets how many consecutive attempts may leave the screen unchanged
+// before PlayGameLevel gives up
+func (g *GameplayAgent) SetStuckPatience(attempts int) {
+	g.stuckPatience = attempts
+}
+
+// SetGridSize changes the grid vision aims in (see VisionDOMDetector.SetGridSize)
+func (g *GameplayAgent) SetGridSize(grid GridSize) error {
+	if err := grid.Validate(); err != nil {
+		return err
+	}
+	g.gridCols, g.gridRows = grid.Cols, grid.Rows
+	return nil
+}
+
+// SetMaxImageDimension sets the longest side screenshots are downscaled to for vision
+// (see VisionDOMDetector.SetMaxImageDimension)
+func (g *GameplayAgent) SetMaxImageDimension(maxDim int) error {
+	if err := validateVisionMaxDimension(maxDim); err != nil {
+		return err
+	}
+	g.maxImageDim = maxDim
+	return nil
+}
+
+// SetMaxLevels sets how many levels PlayGameLevel plays before stopping; after each
+// level-complete screen short of it, the agent clicks through to the next level
+func (g *GameplayAgent) SetMaxLevels(levels int) {
+	g.maxLevels = levels
+}
+
+// SetModel changes the OpenAI vision model used for gameplay decisions (e.g. gpt-4o-mini, gpt-5)
+func (g *GameplayAgent) SetModel(model string) error {
+	if err := validateVisionModel(model); err != nil {
+		return err
+	}
+	g.model = model
+	return nil
 }
 
 // DetectSlingshotAndTarget uses vision to find slingshot and determine optimal aim
 func (g *GameplayAgent) DetectSlingshotAndTarget(screenshot *Screenshot, gameMechanics string) (*SlingshotDragAction, error) {
 	// Apply grid overlay to screenshot
-	griddedScreenshot, err := AddGridOverlay(screenshot, g.gridCols, g.gridRows)
+	visionScreenshot := scaledForVision(g.ctx, screenshot, g.maxImageDim)
+	griddedScreenshot, err := AddGridOverlay(visionScreenshot, g.gridCols, g.gridRows)
 	if err != nil {
-		log.Printf("[Gameplay] Warning: Failed to add grid overlay: %v", err)
-		griddedScreenshot = screenshot
+		logging.Printf(g.ctx, "[Gameplay] Warning: Failed to add grid overlay: %v", err)
+		griddedScreenshot = visionScreenshot
 	}
 
-	imageBase64 := base64.StdEncoding.EncodeToString(griddedScreenshot.Data)
+	imageURL := griddedScreenshot.DataURL()
 
 	// Build game mechanics context
 	mechanicsContext := ""
@@ -109,7 +261,7 @@ func (g *GameplayAgent) DetectSlingshotAndTarget(screenshot *Screenshot, gameMec
 		mechanicsContext = fmt.Sprintf("\n\nGAME MECHANICS:\n%s", gameMechanics)
 	}
 
-	prompt := fmt.Sprintf(`Analyze this Angry Birds gameplay screenshot. Grid: %dx%d (columns A-%s, rows 1-%d).
+	prompt := fmt.Sprintf(`Analyze this slingshot game (Angry Birds-style) screenshot. Grid: %dx%d (columns A-%s, rows 1-%d).
 %s
 
 TASK: Identify the slingshot (bird ready to launch) and suggest where to aim.
@@ -124,23 +276,30 @@ Return JSON:
 }
 
 GUIDELINES:
-- slingshot_cell: Grid cell where the bird/slingshot is currently positioned (usually left side, columns A-F)
+- slingshot_cell: Grid cell where the bird/slingshot is currently positioned (usually left side, columns A-%s)
 - target_aim_cell: Where to drag TO (pull back direction, usually left and/or down from slingshot)
 - Power: 0.5 = medium, 0.7 = strong, 1.0 = maximum
 - Angle: degrees from horizontal (0 = straight right, 45 = diagonal up-right, etc.)
+- %s
 
 EXAMPLE:
 If slingshot is at E7, you might drag to C5 (back and down) for a low trajectory shot.`,
-		g.gridCols, g.gridRows, string(rune('A'+g.gridCols-1)), g.gridRows, mechanicsContext)
+		g.gridCols, g.gridRows, gridColumnLabel(g.gridCols-1), g.gridRows, mechanicsContext, gridColumnLabel(g.gridCols*3/10-1), cellOffsetPrompt)
+
+	logging.Printf(g.ctx, "[Gameplay] Sending slingshot detection request to %s...", g.model)
+	logging.Printf(g.ctx, "[Gameplay] Prompt: %s", prompt)
 
-	log.Printf("[Gameplay] Sending slingshot detection request to GPT-4o...")
-	log.Printf("[Gameplay] Prompt: %s", prompt)
+	release, err := AcquireLLMSlot(g.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	resp, err := g.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: openai.GPT4o,
+		Model: g.model,
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role: openai.ChatMessageRoleUser,
@@ -152,13 +311,13 @@ If slingshot is at E7, you might drag to C5 (back and down) for a low trajectory
 					{
 						Type: openai.ChatMessagePartTypeImageURL,
 						ImageURL: &openai.ChatMessageImageURL{
-							URL: fmt.Sprintf("data:image/png;base64,%s", imageBase64),
+							URL: imageURL,
 						},
 					},
 				},
 			},
 		},
-		MaxCompletionTokens: 800,
+		MaxCompletionTokens: visionMaxTokens(g.model, 800),
 	})
 
 	if err != nil {
@@ -170,7 +329,7 @@ If slingshot is at E7, you might drag to C5 (back and down) for a low trajectory
 	}
 
 	responseText := strings.TrimSpace(resp.Choices[0].Message.Content)
-	log.Printf("[Gameplay] Response: %s", responseText)
+	logging.Printf(g.ctx, "[Gameplay] Response: %s", responseText)
 
 	// Parse JSON response
 	var result struct {
@@ -204,27 +363,78 @@ If slingshot is at E7, you might drag to C5 (back and down) for a low trajectory
 	}
 
 	// Parse grid cells
-	slingshotCell, err := parseGridCell(result.SlingshotCell)
+	slingshotCell, err := parseGridCell(result.SlingshotCell, g.gridCols, g.gridRows)
 	if err != nil {
 		return nil, fmt.Errorf("invalid slingshot cell '%s': %w", result.SlingshotCell, err)
 	}
 
-	targetCell, err := parseGridCell(result.TargetAimCell)
+	targetCell, err := parseGridCell(result.TargetAimCell, g.gridCols, g.gridRows)
 	if err != nil {
 		return nil, fmt.Errorf("invalid target aim cell '%s': %w", result.TargetAimCell, err)
 	}
 
-	log.Printf("[Gameplay] Slingshot detected: %s → %s (angle: %.1f°, power: %.2f)",
+	logging.Printf(g.ctx, "[Gameplay] Slingshot detected: %s → %s (angle: %.1f°, power: %.2f)",
 		slingshotCell.String(), targetCell.String(), result.EstimatedAngle, result.EstimatedPower)
-	log.Printf("[Gameplay] Reasoning: %s", result.Reasoning)
+	logging.Printf(g.ctx, "[Gameplay] Reasoning: %s", result.Reasoning)
+
+	// Prefer the model's estimates, falling back to the drag's geometry for any it left out
+	dragAction := g.dragFromCells(slingshotCell, targetCell, result.Reasoning)
+	if result.EstimatedAngle != 0 {
+		dragAction.AngleDegrees = result.EstimatedAngle
+	}
+	if result.EstimatedPower > 0 {
+		dragAction.Power = min(result.EstimatedPower, 1)
+	}
+	return dragAction, nil
+}
+
+// fullPowerDragFraction is the drag distance, as a fraction of the screen's shorter side,
+// that counts as full power
+const fullPowerDragFraction = 0.25
+
+// dragPhysics derives a drag's launch angle and power from its pixel endpoints. The
+// launch is opposite the pull, so the angle is in degrees above horizontal-right of the
+// direction from end back to start; power (0-1) grows with distance up to
+// fullPowerDragFraction of the screen.
+func dragPhysics(startX, startY, endX, endY, imageWidth, imageHeight int) (angle, power float64) {
+	dx := float64(startX - endX)
+	dy := float64(endY - startY) // screen y grows downward
+	angle = math.Atan2(dy, dx) * 180 / math.Pi
+	fullPower := fullPowerDragFraction * float64(min(imageWidth, imageHeight))
+	power = min(math.Hypot(dx, dy)/fullPower, 1)
+	return angle, power
+}
 
+// dragFromCells builds a drag between two cells with angle and power derived from their
+// positions on screen, so every drag path aims with the same physics
+func (g *GameplayAgent) dragFromCells(start, end GridCell, description string) *SlingshotDragAction {
+	startX, startY := start.ToPixelCoordinates(g.gridCols, g.gridRows, g.imageWidth, g.imageHeight)
+	endX, endY := end.ToPixelCoordinates(g.gridCols, g.gridRows, g.imageWidth, g.imageHeight)
+	angle, power := dragPhysics(startX, startY, endX, endY, g.imageWidth, g.imageHeight)
 	return &SlingshotDragAction{
-		SlingshotCell: slingshotCell,
-		TargetCell:    targetCell,
-		AngleDegrees:  result.EstimatedAngle,
-		Power:         result.EstimatedPower,
-		Description:   result.Reasoning,
-	}, nil
+		SlingshotCell: start,
+		TargetCell:    end,
+		AngleDegrees:  angle,
+		Power:         power,
+		Description:   description,
+	}
+}
+
+// DragActionFromPlan turns a planned drag_slingshot step (see PlanGameplaySequence) into
+// a drag for ExecuteDragAction
+func (g *GameplayAgent) DragActionFromPlan(step GameplayActionPlan) (*SlingshotDragAction, error) {
+	if step.Type != ActionTypeDragSlingshot {
+		return nil, fmt.Errorf("plan step is %s, not %s", step.Type, ActionTypeDragSlingshot)
+	}
+	start, err := parseGridCell(step.StartCell, g.gridCols, g.gridRows)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start cell '%s': %w", step.StartCell, err)
+	}
+	end, err := parseGridCell(step.EndCell, g.gridCols, g.gridRows)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end cell '%s': %w", step.EndCell, err)
+	}
+	return g.dragFromCells(start, end, step.Description), nil
 }
 
 // ExecuteDragAction performs the slingshot drag using existing CDP mouse actions
@@ -235,10 +445,10 @@ func (g *GameplayAgent) ExecuteDragAction(dragAction *SlingshotDragAction) error
 	endX, endY := dragAction.TargetCell.ToPixelCoordinates(
 		g.gridCols, g.gridRows, g.imageWidth, g.imageHeight)
 
-	log.Printf("[Gameplay] Executing drag from %s (%d,%d) to %s (%d,%d)",
+	logging.Printf(g.ctx, "[Gameplay] Executing drag from %s (%d,%d) to %s (%d,%d)",
 		dragAction.SlingshotCell.String(), startX, startY,
 		dragAction.TargetCell.String(), endX, endY)
-	log.Printf("[Gameplay] Action: %s", dragAction.Description)
+	logging.Printf(g.ctx, "[Gameplay] Action: %s (angle: %.1f°, power: %.2f)", dragAction.Description, dragAction.AngleDegrees, dragAction.Power)
 
 	// Calculate drag duration based on power (more power = slower drag for better control)
 	baseDuration := 300 * time.Millisecond
@@ -254,91 +464,393 @@ func (g *GameplayAgent) ExecuteDragAction(dragAction *SlingshotDragAction) error
 		return fmt.Errorf("drag execution failed: %w", err)
 	}
 
-	log.Printf("[Gameplay] Drag completed successfully (duration: %v, hold: %v)", dragDuration, holdDuration)
+	logging.Printf(g.ctx, "[Gameplay] Drag completed successfully (duration: %v, hold: %v)", dragDuration, holdDuration)
 
 	return nil
 }
 
-// PlayGameLevel executes a full gameplay loop for one level attempt
-func (g *GameplayAgent) PlayGameLevel(gameName string, gameMechanics string, maxAttempts int) error {
-	log.Printf("[Gameplay] Starting gameplay loop for %s (max attempts: %d)", gameName, maxAttempts)
+// PlayGameLevel executes a full gameplay loop, sharing maxAttempts across levels. When a
+// level is complete it clicks through to the next one, stopping after the max levels
+// (see SetMaxLevels) or if it can't continue.
+func (g *GameplayAgent) PlayGameLevel(gameName string, gameMechanics string, maxAttempts int) (*GameplayResult, error) {
+	logging.Printf(g.ctx, "[Gameplay] Starting gameplay loop for %s (max attempts: %d, max levels: %d)", gameName, maxAttempts, g.maxLevels)
+
+	result := &GameplayResult{Outcome: OutcomeUnknown}
+	// triedCached tracks cached drags already replayed, so a stale one isn't repeated every attempt
+	triedCached := make(map[string]bool)
+	// lastChanged and unchangedAttempts detect a game that no longer responds to drags
+	var lastChanged *Screenshot
+	unchangedAttempts := 0
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		log.Printf("[Gameplay] === Attempt %d/%d ===", attempt, maxAttempts)
+		logging.Printf(g.ctx, "[Gameplay] === Attempt %d/%d ===", attempt, maxAttempts)
+		result.Attempts = attempt
 
 		// 1. Capture current game state
 		screenshot, err := CaptureScreenshot(g.ctx, ContextGameplay)
 		if err != nil {
-			return fmt.Errorf("failed to capture screenshot: %w", err)
+			return result, fmt.Errorf("failed to capture screenshot: %w", err)
+		}
+
+		// Stop if the previous attempts had no visible effect
+		if screenshot.SimilarTo(lastChanged, DefaultSimilarityThreshold) {
+			unchangedAttempts++
+			if unchangedAttempts >= g.stuckPatience {
+				logging.Printf(g.ctx, "[Gameplay] Screen unchanged for %d attempts, ending gameplay early", unchangedAttempts)
+				result.Attempts = attempt - 1
+				result.Stuck = true
+				break
+			}
+		} else {
+			lastChanged = screenshot
+			unchangedAttempts = 0
 		}
 
 		// Save screenshot for debugging
 		timestamp := time.Now().Format("20060102_150405")
-		screenshotPath := fmt.Sprintf("/tmp/gameplay_%s_attempt%d.png", timestamp, attempt)
+		screenshotPath := fmt.Sprintf("/tmp/gameplay_%s_attempt%d%s", timestamp, attempt, screenshot.fileExtension())
 		if err := os.WriteFile(screenshotPath, screenshot.Data, 0644); err != nil {
-			log.Printf("[Gameplay] Warning: Failed to save screenshot: %v", err)
+			logging.Printf(g.ctx, "[Gameplay] Warning: Failed to save screenshot: %v", err)
 		} else {
-			log.Printf("[Gameplay] Screenshot saved: %s", screenshotPath)
+			logging.Printf(g.ctx, "[Gameplay] Screenshot saved: %s", screenshotPath)
 		}
 
-		// 2. Detect slingshot and calculate optimal aim
-		dragAction, err := g.DetectSlingshotAndTarget(screenshot, gameMechanics)
-		if err != nil {
-			log.Printf("[Gameplay] Failed to detect slingshot: %v", err)
-			// Wait and try again
-			time.Sleep(2 * time.Second)
-			continue
+		// 2. Replay a cached drag for a similar screen, otherwise detect slingshot and aim with vision
+		var dragAction *SlingshotDragAction
+		if cached := g.findCachedDrag(gameName, screenshot, triedCached); cached != nil {
+			triedCached[cached.key()] = true
+			dragAction, err = g.cachedDragAction(cached)
+			if err == nil {
+				logging.Printf(g.ctx, "[Gameplay Cache] Replaying cached drag %s → %s (outcome: %s)",
+					cached.StartCell, cached.EndCell, cached.Outcome)
+			}
+		}
+		if dragAction == nil {
+			dragAction, err = g.DetectSlingshotAndTarget(screenshot, gameMechanics)
+			if err != nil {
+				logging.Printf(g.ctx, "[Gameplay] Failed to detect slingshot: %v", err)
+				// Wait and try again
+				time.Sleep(2 * time.Second)
+				continue
+			}
 		}
 
 		// 3. Execute the drag action
 		if err := g.ExecuteDragAction(dragAction); err != nil {
-			log.Printf("[Gameplay] Failed to execute drag: %v", err)
+			logging.Printf(g.ctx, "[Gameplay] Failed to execute drag: %v", err)
 			time.Sleep(2 * time.Second)
 			continue
 		}
 
-		// 4. Wait for game physics to settle
-		log.Printf("[Gameplay] Waiting for game physics to complete...")
-		time.Sleep(5 * time.Second)
+		// 4. Wait for game physics to settle (screen stops changing), then use the
+		// last frame as the result screenshot
+		logging.Printf(g.ctx, "[Gameplay] Waiting for game physics to complete (max %v)...", g.settleTimeout)
+		settleStart := time.Now()
+		resultScreenshot, stable, err := WaitForScreenStable(g.ctx, g.settleTimeout, g.settlePollInterval, 1)
+		if err == nil {
+			if stable {
+				logging.Printf(g.ctx, "[Gameplay] Screen settled after %v", time.Since(settleStart).Round(time.Millisecond))
+			} else {
+				logging.Printf(g.ctx, "[Gameplay] Screen still changing after %v, continuing", g.settleTimeout)
+			}
+		}
 
-		// 5. Capture result screenshot to analyze outcome
-		resultScreenshot, err := CaptureScreenshot(g.ctx, ContextGameplay)
+		// 5. Analyze the result screenshot
 		if err != nil {
-			log.Printf("[Gameplay] Warning: Failed to capture result screenshot: %v", err)
+			logging.Printf(g.ctx, "[Gameplay] Warning: Failed to capture result screenshot: %v", err)
 		} else {
-			resultPath := fmt.Sprintf("/tmp/gameplay_%s_attempt%d_result.png", timestamp, attempt)
+			resultPath := fmt.Sprintf("/tmp/gameplay_%s_attempt%d_result%s", timestamp, attempt, resultScreenshot.fileExtension())
 			if err := os.WriteFile(resultPath, resultScreenshot.Data, 0644); err != nil {
-				log.Printf("[Gameplay] Warning: Failed to save result screenshot: %v", err)
+				logging.Printf(g.ctx, "[Gameplay] Warning: Failed to save result screenshot: %v", err)
 			} else {
-				log.Printf("[Gameplay] Result screenshot saved: %s", resultPath)
+				logging.Printf(g.ctx, "[Gameplay] Result screenshot saved: %s", resultPath)
 			}
 
-			// Analyze outcome (simple version - could be enhanced with vision API)
-			outcome := g.analyzeOutcome(resultScreenshot)
-			log.Printf("[Gameplay] Outcome: %s", outcome)
+			// Classify the result with vision
+			analysis, err := g.analyzeOutcome(resultScreenshot, gameMechanics)
+			if err != nil {
+				logging.Printf(g.ctx, "[Gameplay] Warning: Failed to analyze outcome: %v", err)
+			}
+			outcome := analysis.outcome
+			logging.Printf(g.ctx, "[Gameplay] Outcome: %s", outcome)
+			result.Outcome = outcome
 
 			// Cache successful actions for self-healing
-			if outcome == "success" || strings.Contains(outcome, "destroyed") {
-				g.CacheSuccessfulDrag(gameName, dragAction, outcome, screenshot)
+			if outcome == OutcomeLevelComplete || outcome == OutcomeDestroyedTarget {
+				g.CacheSuccessfulDrag(gameName, dragAction, string(outcome), screenshot)
+			}
+
+			// 6. Continue to the next level, or stop once enough levels are beaten
+			if outcome == OutcomeLevelComplete {
+				result.LevelComplete = true
+				result.LevelsCompleted++
+				logging.Printf(g.ctx, "[Gameplay] 🏆 Level %d complete after %d attempt(s)", result.LevelsCompleted, attempt)
+				if result.LevelsCompleted >= g.maxLevels || attempt == maxAttempts {
+					break
+				}
+				if err := g.continueToNextLevel(resultScreenshot, analysis); err != nil {
+					logging.Printf(g.ctx, "[Gameplay] Could not continue to the next level: %v", err)
+					break
+				}
+
+				// A new level looks different; start stuck detection and cached drags afresh
+				lastChanged = nil
+				unchangedAttempts = 0
+				triedCached = make(map[string]bool)
 			}
 		}
 
-		// 6. Check if level is complete (could use vision API to detect win/lose screens)
-		log.Printf("[Gameplay] Checking if level is complete...")
-		// For now, just continue to next attempt
 		time.Sleep(2 * time.Second)
 	}
 
-	log.Printf("[Gameplay] Completed gameplay loop (%d attempts)", maxAttempts)
+	logging.Printf(g.ctx, "[Gameplay] Completed gameplay loop (%d attempts, %d level(s) complete, outcome: %s)",
+		result.Attempts, result.LevelsCompleted, result.Outcome)
+	return result, nil
+}
+
+// continueToNextLevel clicks a level-complete screen's continue button: by the text vision
+// read from it, then at the grid cell vision placed it in, then by common button texts.
+// It waits for the next level to settle before returning.
+func (g *GameplayAgent) continueToNextLevel(screenshot *Screenshot, analysis outcomeAnalysis) error {
+	clicked := false
+	if analysis.continueText != "" {
+		if err := clickButtonByText(g.ctx, analysis.continueText); err != nil {
+			logging.Printf(g.ctx, "[Gameplay] No DOM button %q: %v", analysis.continueText, err)
+		} else {
+			clicked = true
+		}
+	}
+	if !clicked && analysis.continueCell != "" {
+		if cell, err := parseGridCell(analysis.continueCell, g.gridCols, g.gridRows); err != nil {
+			logging.Printf(g.ctx, "[Gameplay] Ignoring continue button cell: %v", err)
+		} else {
+			x, y := cell.ToPixelCoordinates(g.gridCols, g.gridRows, screenshot.Width, screenshot.Height)
+			if err := clickAt(g.ctx, x, y); err != nil {
+				logging.Printf(g.ctx, "[Gameplay] Click on %s failed: %v", cell, err)
+			} else {
+				clicked = true
+			}
+		}
+	}
+	for _, text := range continueButtonTexts {
+		if clicked {
+			break
+		}
+		clicked = clickButtonByText(g.ctx, text) == nil
+	}
+	if !clicked {
+		return fmt.Errorf("no continue button found")
+	}
+
+	logging.Printf(g.ctx, "[Gameplay] ➡️  Continuing to the next level")
+	if _, _, err := WaitForScreenStable(g.ctx, g.settleTimeout, g.settlePollInterval, 1); err != nil {
+		logging.Printf(g.ctx, "[Gameplay] Warning: Failed to wait for the next level: %v", err)
+	}
 	return nil
 }
 
-// analyzeOutcome performs basic outcome analysis
-// Could be enhanced with vision API to detect specific results
-func (g *GameplayAgent) analyzeOutcome(screenshot *Screenshot) string {
-	// Simple placeholder - in a full implementation, this would use vision API
-	// to analyze if pigs were destroyed, structures collapsed, etc.
-	return "unknown"
+// outcomeAnalysis is vision's reading of the screen after an action
+type outcomeAnalysis struct {
+	outcome GameOutcome
+	// continueText and continueCell locate a level-complete screen's continue button
+	// ("Next", "Continue", ...), if vision saw one
+	continueText string
+	continueCell string
+}
+
+// analyzeOutcome uses vision to classify the screen after an action as level_complete,
+// level_failed, destroyed_target or in_progress, and to find the button that continues
+// past a level-complete screen. The outcome is OutcomeUnknown on error.
+func (g *GameplayAgent) analyzeOutcome(screenshot *Screenshot, gameMechanics string) (outcomeAnalysis, error) {
+	unknown := outcomeAnalysis{outcome: OutcomeUnknown}
+
+	visionScreenshot := scaledForVision(g.ctx, screenshot, g.maxImageDim)
+	griddedScreenshot, err := AddGridOverlay(visionScreenshot, g.gridCols, g.gridRows)
+	if err != nil {
+		griddedScreenshot = visionScreenshot
+	}
+
+	imageURL := griddedScreenshot.DataURL()
+
+	mechanicsContext := ""
+	if gameMechanics != "" {
+		mechanicsContext = fmt.Sprintf("\n\nGAME MECHANICS:\n%s", gameMechanics)
+	}
+
+	prompt := fmt.Sprintf(`Analyze this gameplay screenshot taken just after the player's last action. Grid: %dx%d (columns A-%s, rows 1-%d).
+%s
+
+TASK: Classify the current game state.
+
+Return JSON:
+{
+  "outcome": "in_progress",
+  "reasoning": "Structures still standing, next bird is on the slingshot",
+  "continue_text": "",
+  "continue_cell": ""
+}
+
+OUTCOMES:
+- level_complete: A win / "level cleared" / stars / "next level" screen is shown
+- level_failed: A lose / "level failed" / "try again" / game over screen is shown
+- destroyed_target: No end screen, but the last action visibly destroyed a target (enemy, pig, block)
+- in_progress: No end screen and nothing notable was destroyed
+
+For level_complete, set continue_text to the exact text of the button that goes on to the
+next level (e.g. "NEXT", "Continue") and continue_cell to the grid cell at its center (e.g.
+"K9"). For an icon-only button leave continue_text empty. Otherwise leave both empty.`,
+		g.gridCols, g.gridRows, gridColumnLabel(g.gridCols-1), g.gridRows, mechanicsContext)
+
+	release, err := AcquireLLMSlot(g.ctx)
+	if err != nil {
+		return unknown, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resp, err := g.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: g.model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role: openai.ChatMessageRoleUser,
+				MultiContent: []openai.ChatMessagePart{
+					{
+						Type: openai.ChatMessagePartTypeText,
+						Text: prompt,
+					},
+					{
+						Type: openai.ChatMessagePartTypeImageURL,
+						ImageURL: &openai.ChatMessageImageURL{
+							URL: imageURL,
+						},
+					},
+				},
+			},
+		},
+		MaxCompletionTokens: visionMaxTokens(g.model, 300),
+	})
+
+	if err != nil {
+		return unknown, fmt.Errorf("outcome analysis API call failed: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return unknown, fmt.Errorf("no response from vision API")
+	}
+
+	responseText := strings.TrimSpace(resp.Choices[0].Message.Content)
+
+	jsonText := responseText
+	if start, end := strings.Index(responseText, "{"), strings.LastIndex(responseText, "}"); start != -1 && end > start {
+		jsonText = responseText[start : end+1]
+	}
+
+	var result struct {
+		Outcome      string `json:"outcome"`
+		Reasoning    string `json:"reasoning"`
+		ContinueText string `json:"continue_text"`
+		ContinueCell string `json:"continue_cell"`
+	}
+	if err := json.Unmarshal([]byte(jsonText), &result); err != nil {
+		return unknown, fmt.Errorf("failed to parse outcome response: %w (response: %s)", err, jsonText)
+	}
+	logging.Printf(g.ctx, "[Gameplay] Outcome reasoning: %s", result.Reasoning)
+
+	switch outcome := GameOutcome(strings.ToLower(strings.TrimSpace(result.Outcome))); outcome {
+	case OutcomeLevelComplete:
+		return outcomeAnalysis{
+			outcome:      outcome,
+			continueText: strings.TrimSpace(result.ContinueText),
+			continueCell: strings.ToUpper(strings.TrimSpace(result.ContinueCell)),
+		}, nil
+	case OutcomeLevelFailed, OutcomeDestroyedTarget, OutcomeInProgress:
+		return outcomeAnalysis{outcome: outcome}, nil
+	default:
+		return unknown, fmt.Errorf("unrecognized outcome %q", result.Outcome)
+	}
+}
+
+// ReadGameScore uses vision to locate and read the numeric score shown on screen.
+// Returns found=false (not an error) when the game displays no score.
+func (g *GameplayAgent) ReadGameScore(screenshot *Screenshot) (int, bool, error) {
+	imageURL := scaledForVision(g.ctx, screenshot, g.maxImageDim).DataURL()
+
+	prompt := `Find the player's current score in this game screenshot.
+
+TASK: Locate a numeric score display (e.g. "Score: 1200", "PTS 45", a counter in a corner of the HUD)
+and read its value. Ignore timers, lives, level numbers, coin/ammo counts and high scores.
+
+Return JSON:
+{
+  "found": true,
+  "score": 1200,
+  "region": "top-left HUD"
+}
+
+If no score is visible, return {"found": false, "score": 0, "region": ""}.`
+
+	release, err := AcquireLLMSlot(g.ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resp, err := g.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: g.model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role: openai.ChatMessageRoleUser,
+				MultiContent: []openai.ChatMessagePart{
+					{
+						Type: openai.ChatMessagePartTypeText,
+						Text: prompt,
+					},
+					{
+						Type: openai.ChatMessagePartTypeImageURL,
+						ImageURL: &openai.ChatMessageImageURL{
+							URL: imageURL,
+						},
+					},
+				},
+			},
+		},
+		MaxCompletionTokens: visionMaxTokens(g.model, 200),
+	})
+
+	if err != nil {
+		return 0, false, fmt.Errorf("score reading API call failed: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return 0, false, fmt.Errorf("no response from vision API")
+	}
+
+	responseText := strings.TrimSpace(resp.Choices[0].Message.Content)
+
+	jsonText := responseText
+	if start, end := strings.Index(responseText, "{"), strings.LastIndex(responseText, "}"); start != -1 && end > start {
+		jsonText = responseText[start : end+1]
+	}
+
+	var result struct {
+		Found  bool   `json:"found"`
+		Score  int    `json:"score"`
+		Region string `json:"region"`
+	}
+	if err := json.Unmarshal([]byte(jsonText), &result); err != nil {
+		return 0, false, fmt.Errorf("failed to parse score response: %w (response: %s)", err, jsonText)
+	}
+
+	if !result.Found {
+		return 0, false, nil
+	}
+	logging.Printf(g.ctx, "[Gameplay] Read score %d (%s)", result.Score, result.Region)
+	return result.Score, true, nil
 }
 
 // CacheSuccessfulDrag stores a successful drag action for future reference
@@ -350,18 +862,22 @@ func (g *GameplayAgent) CacheSuccessfulDrag(gameName string, action *SlingshotDr
 		screenshotB64 = base64.StdEncoding.EncodeToString(screenshot.Data)
 	}
 
+	start, end := action.SlingshotCell, action.TargetCell
 	cached := CachedDrag{
 		GameName:      gameName,
-		StartCell:     action.SlingshotCell.String(),
-		EndCell:       action.TargetCell.String(),
+		StartCell:     GridCell{Column: start.Column, Row: start.Row}.String(),
+		EndCell:       GridCell{Column: end.Column, Row: end.Row}.String(),
+		StartOffset:   [2]float64{start.OffsetX, start.OffsetY},
+		EndOffset:     [2]float64{end.OffsetX, end.OffsetY},
+		Power:         action.Power,
 		Outcome:       outcome,
 		Timestamp:     time.Now(),
 		ScreenshotB64: screenshotB64,
 	}
 
 	g.actionCache.SuccessfulDrags = append(g.actionCache.SuccessfulDrags, cached)
-	log.Printf("[Gameplay Cache] Cached successful drag: %s → %s (outcome: %s)",
-		cached.StartCell, cached.EndCell, outcome)
+	logging.Printf(g.ctx, "[Gameplay Cache] Cached successful drag: %s → %s (power: %.2f, outcome: %s)",
+		start, end, action.Power, outcome)
 
 	// Limit cache size to last 50 successful drags
 	if len(g.actionCache.SuccessfulDrags) > 50 {
@@ -369,6 +885,33 @@ func (g *GameplayAgent) CacheSuccessfulDrag(gameName string, action *SlingshotDr
 	}
 }
 
+// cachedDragAction rebuilds the drag that succeeded from a cached drag, with its offsets
+// and power
+func (g *GameplayAgent) cachedDragAction(cached *CachedDrag) (*SlingshotDragAction, error) {
+	start, err := parseGridCell(cached.StartCell, g.gridCols, g.gridRows)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseGridCell(cached.EndCell, g.gridCols, g.gridRows)
+	if err != nil {
+		return nil, err
+	}
+	// Drags cached before offsets were stored separately carry them in the cell
+	clamp := func(offset float64) float64 { return max(-maxCellOffset, min(maxCellOffset, offset)) }
+	if cached.StartOffset != [2]float64{} {
+		start.OffsetX, start.OffsetY = clamp(cached.StartOffset[0]), clamp(cached.StartOffset[1])
+	}
+	if cached.EndOffset != [2]float64{} {
+		end.OffsetX, end.OffsetY = clamp(cached.EndOffset[0]), clamp(cached.EndOffset[1])
+	}
+
+	action := g.dragFromCells(start, end, fmt.Sprintf("cached drag (previous outcome: %s)", cached.Outcome))
+	if cached.Power > 0 {
+		action.Power = min(cached.Power, 1)
+	}
+	return action, nil
+}
+
 // GetCachedDragsForGame returns cached successful drags for a specific game
 func (g *GameplayAgent) GetCachedDragsForGame(gameName string) []CachedDrag {
 	var drags []CachedDrag
@@ -383,17 +926,21 @@ func (g *GameplayAgent) GetCachedDragsForGame(gameName string) []CachedDrag {
 // PlanGameplaySequence generates a sequence of actions using AI
 // Implements Stagehand's action sequencing pattern
 func (g *GameplayAgent) PlanGameplaySequence(screenshot *Screenshot, gameMechanics string) ([]GameplayActionPlan, error) {
-	griddedScreenshot, err := AddGridOverlay(screenshot, g.gridCols, g.gridRows)
+	visionScreenshot := scaledForVision(g.ctx, screenshot, g.maxImageDim)
+	griddedScreenshot, err := AddGridOverlay(visionScreenshot, g.gridCols, g.gridRows)
 	if err != nil {
-		griddedScreenshot = screenshot
+		griddedScreenshot = visionScreenshot
 	}
 
-	imageBase64 := base64.StdEncoding.EncodeToString(griddedScreenshot.Data)
+	imageURL := griddedScreenshot.DataURL()
 
 	mechanicsContext := ""
 	if gameMechanics != "" {
 		mechanicsContext = fmt.Sprintf("\n\nGAME MECHANICS:\n%s", gameMechanics)
 	}
+	if hint := gameTypeHints[g.gameType]; hint != "" {
+		mechanicsContext += "\n\n" + hint
+	}
 
 	prompt := fmt.Sprintf(`Plan a sequence of actions to play this game. Grid: %dx%d (A-%s, 1-%d).
 %s
@@ -406,19 +953,35 @@ Return JSON array of actions:
   {"type": "observe", "description": "check if pigs destroyed"}
 ]
 
+For a keyboard game, e.g.:
+[
+  {"type": "keypress", "key": "ArrowRight", "hold_ms": 800, "description": "run toward the gap"},
+  {"type": "keypress", "key": "Space", "description": "jump over it"}
+]
+
 Available action types:
 - detect_element: Find a game element
-- drag_slingshot: Drag from start_cell to end_cell
+- drag_slingshot: Drag from start_cell to end_cell (any drag or swipe, not just slingshots)
 - click: Single click at target_cell
+- keypress: Press key (a character or a name such as ArrowUp, Space, Enter), holding it for hold_ms
 - wait: Wait for wait_ms milliseconds
-- observe: Analyze current game state`,
-		g.gridCols, g.gridRows, string(rune('A'+g.gridCols-1)), g.gridRows, mechanicsContext)
+- observe: Analyze current game state
+
+Plan only the next few seconds of play (at most %d actions); the screen is shown again after them.
+%s`,
+		g.gridCols, g.gridRows, gridColumnLabel(g.gridCols-1), g.gridRows, mechanicsContext, maxPlannedActions, cellOffsetPrompt)
+
+	release, err := AcquireLLMSlot(g.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	resp, err := g.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: openai.GPT4o,
+		Model: g.model,
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role: openai.ChatMessageRoleUser,
@@ -430,13 +993,13 @@ Available action types:
 					{
 						Type: openai.ChatMessagePartTypeImageURL,
 						ImageURL: &openai.ChatMessageImageURL{
-							URL: fmt.Sprintf("data:image/png;base64,%s", imageBase64),
+							URL: imageURL,
 						},
 					},
 				},
 			},
 		},
-		MaxCompletionTokens: 1000,
+		MaxCompletionTokens: visionMaxTokens(g.model, 1000),
 	})
 
 	if err != nil {
@@ -470,9 +1033,9 @@ Available action types:
 		return nil, fmt.Errorf("failed to parse action sequence: %w (response: %s)", err, jsonText)
 	}
 
-	log.Printf("[Gameplay] Planned %d actions", len(actions))
+	logging.Printf(g.ctx, "[Gameplay] Planned %d actions", len(actions))
 	for i, action := range actions {
-		log.Printf("[Gameplay]   %d. %s: %s", i+1, action.Type, action.Description)
+		logging.Printf(g.ctx, "[Gameplay]   %d. %s: %s", i+1, action.Type, action.Description)
 	}
 
 	return actions, nil
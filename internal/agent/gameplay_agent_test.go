@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDragPhysics(t *testing.T) {
+	// At 1280x720, full power is a 0.25 * 720 = 180px drag
+	tests := []struct {
+		name                 string
+		startX, startY       int
+		endX, endY           int
+		width, height        int
+		wantAngle, wantPower float64
+	}{
+		{name: "no drag", startX: 400, startY: 400, endX: 400, endY: 400, width: 1280, height: 720, wantAngle: 0, wantPower: 0},
+		{name: "half power", startX: 400, startY: 400, endX: 310, endY: 400, width: 1280, height: 720, wantAngle: 0, wantPower: 0.5},
+		{name: "full power", startX: 400, startY: 400, endX: 220, endY: 400, width: 1280, height: 720, wantAngle: 0, wantPower: 1},
+		{name: "clamped past full power", startX: 600, startY: 400, endX: 100, endY: 400, width: 1280, height: 720, wantAngle: 0, wantPower: 1},
+		{name: "full power scales with the screen", startX: 200, startY: 200, endX: 110, endY: 200, width: 640, height: 360, wantAngle: 0, wantPower: 1},
+
+		// The launch is opposite the pull, with y up
+		{name: "pull down-left launches up-right", startX: 400, startY: 400, endX: 310, endY: 490, width: 1280, height: 720, wantAngle: 45, wantPower: math.Sqrt2 / 2},
+		{name: "pull right launches left", startX: 400, startY: 400, endX: 490, endY: 400, width: 1280, height: 720, wantAngle: 180, wantPower: 0.5},
+		{name: "pull up launches down", startX: 400, startY: 400, endX: 400, endY: 310, width: 1280, height: 720, wantAngle: -90, wantPower: 0.5},
+		{name: "pull down launches up", startX: 400, startY: 400, endX: 400, endY: 490, width: 1280, height: 720, wantAngle: 90, wantPower: 0.5},
+	}
+	for _, tt := range tests {
+		angle, power := dragPhysics(tt.startX, tt.startY, tt.endX, tt.endY, tt.width, tt.height)
+		if math.Abs(angle-tt.wantAngle) > 1e-9 || math.Abs(power-tt.wantPower) > 1e-9 {
+			t.Errorf("%s: dragPhysics = (angle %.3f, power %.3f), want (%.3f, %.3f)",
+				tt.name, angle, power, tt.wantAngle, tt.wantPower)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/logging"
+)
+
+const (
+	// maxPlannedActions caps the steps executed from one plan, so a runaway plan can't
+	// play blind for long before the screen is looked at again
+	maxPlannedActions = 8
+	// maxPlannedWait caps a planned wait step
+	maxPlannedWait = 10 * time.Second
+)
+
+// PlayWithPlannedActions plays any game, not just slingshot games: each iteration it
+// plans a short sequence of actions from a screenshot (see PlanGameplaySequence), executes
+// it, waits for the screen to settle and classifies the outcome. Level progression and
+// stuck detection work as in PlayGameLevel.
+func (g *GameplayAgent) PlayWithPlannedActions(gameMechanics string, maxIterations int) (*GameplayResult, error) {
+	logging.Printf(g.ctx, "[Gameplay] Starting planned gameplay loop (max iterations: %d, max levels: %d)", maxIterations, g.maxLevels)
+
+	result := &GameplayResult{Outcome: OutcomeUnknown}
+	detector := NewUIDetector(g.ctx)
+	// lastChanged and unchangedIterations detect a game that no longer responds to the plans
+	var lastChanged *Screenshot
+	unchangedIterations := 0
+
+	for iteration := 1; iteration <= maxIterations && g.ctx.Err() == nil; iteration++ {
+		logging.Printf(g.ctx, "[Gameplay] === Iteration %d/%d ===", iteration, maxIterations)
+		result.Attempts = iteration
+
+		// 1. Observe
+		screenshot, err := CaptureScreenshot(g.ctx, ContextGameplay)
+		if err != nil {
+			return result, fmt.Errorf("failed to capture screenshot: %w", err)
+		}
+		if screenshot.SimilarTo(lastChanged, DefaultSimilarityThreshold) {
+			unchangedIterations++
+			if unchangedIterations >= g.stuckPatience {
+				logging.Printf(g.ctx, "[Gameplay] Screen unchanged for %d iterations, ending gameplay early", unchangedIterations)
+				result.Attempts = iteration - 1
+				result.Stuck = true
+				break
+			}
+		} else {
+			lastChanged = screenshot
+			unchangedIterations = 0
+		}
+
+		// 2. Plan
+		plan, err := g.PlanGameplaySequence(screenshot, gameMechanics)
+		if err != nil {
+			logging.Printf(g.ctx, "[Gameplay] Failed to plan actions: %v", err)
+			time.Sleep(2 * time.Second)
+			continue
+		}
+
+		// 3. Execute
+		if err := g.ExecuteGameplaySequence(detector, plan); err != nil {
+			return result, err
+		}
+
+		// 4. Let the screen settle, then classify the outcome
+		resultScreenshot, _, err := WaitForScreenStable(g.ctx, g.settleTimeout, g.settlePollInterval, 1)
+		if err != nil {
+			logging.Printf(g.ctx, "[Gameplay] Warning: Failed to capture result screenshot: %v", err)
+			continue
+		}
+		analysis, err := g.analyzeOutcome(resultScreenshot, gameMechanics)
+		if err != nil {
+			logging.Printf(g.ctx, "[Gameplay] Warning: Failed to analyze outcome: %v", err)
+		}
+		logging.Printf(g.ctx, "[Gameplay] Outcome: %s", analysis.outcome)
+		result.Outcome = analysis.outcome
+
+		// 5. Continue to the next level, or stop once enough levels are beaten
+		if analysis.outcome == OutcomeLevelComplete {
+			result.LevelComplete = true
+			result.LevelsCompleted++
+			logging.Printf(g.ctx, "[Gameplay] 🏆 Level %d complete after %d iteration(s)", result.LevelsCompleted, iteration)
+			if result.LevelsCompleted >= g.maxLevels || iteration == maxIterations {
+				break
+			}
+			if err := g.continueToNextLevel(resultScreenshot, analysis); err != nil {
+				logging.Printf(g.ctx, "[Gameplay] Could not continue to the next level: %v", err)
+				break
+			}
+			lastChanged = nil
+			unchangedIterations = 0
+		}
+	}
+
+	logging.Printf(g.ctx, "[Gameplay] Completed planned gameplay loop (%d iterations, %d level(s) complete, outcome: %s)",
+		result.Attempts, result.LevelsCompleted, result.Outcome)
+	return result, nil
+}
+
+// ExecuteGameplaySequence executes up to maxPlannedActions steps of a plan. Steps that
+// fail are logged and skipped, since later steps may still make progress; an error is
+// only returned if the test is cancelled.
+func (g *GameplayAgent) ExecuteGameplaySequence(detector *UIDetector, plan []GameplayActionPlan) error {
+	if len(plan) > maxPlannedActions {
+		logging.Printf(g.ctx, "[Gameplay] Executing the first %d of %d planned actions", maxPlannedActions, len(plan))
+		plan = plan[:maxPlannedActions]
+	}
+	for i, step := range plan {
+		if err := g.ctx.Err(); err != nil {
+			return err
+		}
+		if err := g.executePlanStep(detector, step); err != nil {
+			logging.Printf(g.ctx, "[Gameplay] Planned action %d (%s) failed: %v", i+1, step.Type, err)
+		}
+	}
+	return g.ctx.Err()
+}
+
+// executePlanStep executes one planned step. detect_element and observe steps are no-ops:
+// the next iteration's screenshot is the observation.
+func (g *GameplayAgent) executePlanStep(detector *UIDetector, step GameplayActionPlan) error {
+	switch step.Type {
+	case ActionTypeDragSlingshot:
+		dragAction, err := g.DragActionFromPlan(step)
+		if err != nil {
+			return err
+		}
+		return g.ExecuteDragAction(dragAction)
+
+	case ActionTypeClick:
+		cell, err := parseGridCell(step.TargetCell, g.gridCols, g.gridRows)
+		if err != nil {
+			return fmt.Errorf("invalid target cell '%s': %w", step.TargetCell, err)
+		}
+		x, y := cell.ToPixelCoordinates(g.gridCols, g.gridRows, g.imageWidth, g.imageHeight)
+		logging.Printf(g.ctx, "[Gameplay] Clicking %s (%d,%d): %s", cell, x, y, step.Description)
+		return clickAt(g.ctx, x, y)
+
+	case ActionTypeKeypress:
+		if step.Key == "" {
+			return fmt.Errorf("keypress has no key")
+		}
+		keys, err := ResolveControls([]string{step.Key})
+		if err != nil {
+			return err
+		}
+		hold := min(time.Duration(step.HoldMs)*time.Millisecond, MaxKeyHold)
+		logging.Printf(g.ctx, "[Gameplay] Pressing %s (hold: %v): %s", step.Key, hold, step.Description)
+		for _, key := range keys {
+			if err := g.pressKey(detector, key, hold); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case ActionTypeWait:
+		g.sleep(min(time.Duration(step.WaitMs)*time.Millisecond, maxPlannedWait))
+		return nil
+
+	case ActionTypeDetectElement, ActionTypeObserve:
+		return nil
+
+	default:
+		return fmt.Errorf("unknown action type %q", step.Type)
+	}
+}
+
+// pressKey taps key, or holds it for hold. A held key is released even if the press
+// reported failure, so it can't stay stuck down.
+func (g *GameplayAgent) pressKey(detector *UIDetector, key string, hold time.Duration) error {
+	var sent bool
+	var err error
+	if hold <= 0 {
+		sent, err = detector.SendKeyboardEventToWindow(key)
+	} else {
+		sent, err = detector.SendKeyDownToWindow(key)
+		if err == nil && sent {
+			g.sleep(hold)
+		}
+		if _, upErr := detector.SendKeyUpToWindow(key); upErr != nil && err == nil {
+			err = upErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !sent {
+		return fmt.Errorf("key %s was not dispatched", key)
+	}
+	return nil
+}
+
+// sleep waits for d, or until the test is cancelled
+func (g *GameplayAgent) sleep(d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-g.ctx.Done():
+	}
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+)
+
+// ImageFormat is the encoding of a screenshot's Data
+type ImageFormat string
+
+const (
+	// ImageFormatPNG is lossless and the default
+	ImageFormatPNG ImageFormat = "png"
+	// ImageFormatJPEG is several times smaller, so uploads and vision requests are faster
+	ImageFormatJPEG ImageFormat = "jpeg"
+)
+
+// DefaultJPEGQuality is the JPEG quality used when none is given, and for images the
+// agent re-encodes (such as grid overlays of JPEG screenshots)
+const DefaultJPEGQuality = 80
+
+// ScreenshotFormat is how CaptureScreenshot encodes screenshots
+type ScreenshotFormat struct {
+	Format ImageFormat
+	// Quality is the JPEG quality (1-100); ignored for PNG
+	Quality int
+}
+
+// ParseScreenshotFormat validates a format name ("png" or "jpeg"/"jpg"; empty is PNG) and
+// JPEG quality (0 = DefaultJPEGQuality)
+func ParseScreenshotFormat(format string, quality int) (ScreenshotFormat, error) {
+	var f ScreenshotFormat
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "png":
+		f.Format = ImageFormatPNG
+	case "jpeg", "jpg":
+		f.Format = ImageFormatJPEG
+	default:
+		return ScreenshotFormat{}, fmt.Errorf("unknown screenshot format %q (use png or jpeg)", format)
+	}
+	if quality == 0 {
+		quality = DefaultJPEGQuality
+	}
+	if quality < 1 || quality > 100 {
+		return ScreenshotFormat{}, fmt.Errorf("screenshot quality must be between 1 and 100, got %d", quality)
+	}
+	f.Quality = quality
+	return f, nil
+}
+
+// screenshotFormatKey is the context key for the browser's ScreenshotFormat
+type screenshotFormatKey struct{}
+
+// ScreenshotFormatFromContext returns the screenshot format configured for the browser
+// context, or PNG if none was set
+func ScreenshotFormatFromContext(ctx context.Context) ScreenshotFormat {
+	if f, ok := ctx.Value(screenshotFormatKey{}).(ScreenshotFormat); ok {
+		return f
+	}
+	return ScreenshotFormat{Format: ImageFormatPNG}
+}
+
+// WithScreenshotFormat sets how the browser's screenshots are encoded (default PNG)
+func WithScreenshotFormat(format ScreenshotFormat) BrowserOption {
+	return func(c *browserConfig) {
+		c.screenshotFormat = format
+	}
+}
+
+// MIMEType returns the screenshot's content type
+func (s *Screenshot) MIMEType() string {
+	if s.Format == ImageFormatJPEG {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// DataURL returns the screenshot as a data: URL, as vision APIs take images
+func (s *Screenshot) DataURL() string {
+	return fmt.Sprintf("data:%s;base64,%s", s.MIMEType(), base64.StdEncoding.EncodeToString(s.Data))
+}
+
+// fileExtension returns the extension screenshot files are saved with
+func (s *Screenshot) fileExtension() string {
+	if s.Format == ImageFormatJPEG {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+// decodeImage decodes a PNG or JPEG screenshot
+func decodeImage(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// encodeImage encodes an image in format, JPEG at DefaultJPEGQuality
+func encodeImage(img image.Image, format ImageFormat) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == ImageFormatJPEG {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: DefaultJPEGQuality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	return buf.Bytes(), err
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dreamup/qa-agent/internal/logging"
+	xdraw "golang.org/x/image/draw"
+)
+
+// DefaultVisionMaxDimension is the longest side, in pixels, of screenshots sent to vision
+// APIs. OpenAI works on 512px tiles anyway, so 1280x720 viewports scaled to 1024x576 cost
+// fewer tiles and a smaller payload with little loss of accuracy.
+const DefaultVisionMaxDimension = 1024
+
+// MinVisionMaxDimension keeps vision images large enough for the grid labels to be read
+const MinVisionMaxDimension = 256
+
+// VisionMaxDimensionFromEnv returns the VISION_MAX_DIMENSION env var, or
+// DefaultVisionMaxDimension if it's unset. 0 sends screenshots at full size.
+func VisionMaxDimensionFromEnv() (int, error) {
+	s := strings.TrimSpace(os.Getenv("VISION_MAX_DIMENSION"))
+	if s == "" {
+		return DefaultVisionMaxDimension, nil
+	}
+	maxDim, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("VISION_MAX_DIMENSION: %w", err)
+	}
+	if err := validateVisionMaxDimension(maxDim); err != nil {
+		return 0, fmt.Errorf("VISION_MAX_DIMENSION: %w", err)
+	}
+	return maxDim, nil
+}
+
+// validateVisionMaxDimension checks a vision image size (0 = full size)
+func validateVisionMaxDimension(maxDim int) error {
+	if maxDim != 0 && maxDim < MinVisionMaxDimension {
+		return fmt.Errorf("vision image size must be 0 (full size) or at least %d pixels, got %d", MinVisionMaxDimension, maxDim)
+	}
+	return nil
+}
+
+// Scaled returns a copy of the screenshot shrunk so its longest side is at most maxDim
+// pixels, keeping its aspect ratio and format. The screenshot itself is returned if it
+// already fits or maxDim is 0. The copy isn't saved to disk (Filepath is empty).
+func (s *Screenshot) Scaled(maxDim int) (*Screenshot, error) {
+	if maxDim <= 0 {
+		return s, nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(s.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+	if config.Width <= maxDim && config.Height <= maxDim {
+		return s, nil
+	}
+
+	width, height := maxDim, max(1, config.Height*maxDim/config.Width)
+	if config.Height > config.Width {
+		width, height = max(1, config.Width*maxDim/config.Height), maxDim
+	}
+
+	img, err := decodeImage(s.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(scaled, scaled.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+
+	data, err := encodeImage(scaled, s.Format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode scaled screenshot: %w", err)
+	}
+	return &Screenshot{
+		Context:   s.Context,
+		Timestamp: s.Timestamp,
+		Data:      data,
+		Width:     width,
+		Height:    height,
+		Format:    s.Format,
+	}, nil
+}
+
+// scaledForVision returns the screenshot downscaled to maxDim for a vision request, or the
+// original if it can't be scaled. Grid cell answers don't depend on the image size, so
+// they map back to the full-size screenshot unchanged.
+func scaledForVision(ctx context.Context, screenshot *Screenshot, maxDim int) *Screenshot {
+	scaled, err := screenshot.Scaled(maxDim)
+	if err != nil {
+		logging.Printf(ctx, "[Vision] Warning: Failed to downscale screenshot, sending it full size: %v", err)
+		return screenshot
+	}
+	return scaled
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// testScreenshot returns a width x height screenshot drawn like a simple game frame: a sky
+// gradient, ground and a grid of sprites
+func testScreenshot(t testing.TB, width, height int, format ImageFormat) *Screenshot {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{R: 0x40, G: uint8(0x80 + y*0x60/height), B: 0xe0, A: 0xff}
+			switch {
+			case y > height*3/4:
+				c = color.RGBA{R: 0x3a, G: 0x8a, B: 0x2a, A: 0xff}
+			case x%160 > 100 && y%120 > 70:
+				c = color.RGBA{R: 0xff, G: uint8(x * 255 / width), B: 0x33, A: 0xff}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	data, err := encodeImage(img, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Screenshot{Context: ContextGameplay, Filepath: "frame.png", Data: data, Width: width, Height: height, Format: format}
+}
+
+func TestScaled(t *testing.T) {
+	tests := []struct {
+		name                  string
+		width, height         int
+		format                ImageFormat
+		maxDim                int
+		wantWidth, wantHeight int
+		wantOriginal          bool
+	}{
+		{name: "landscape", width: 1280, height: 720, maxDim: 1024, wantWidth: 1024, wantHeight: 576},
+		{name: "portrait", width: 720, height: 1280, maxDim: 1024, wantWidth: 576, wantHeight: 1024},
+		{name: "square", width: 800, height: 800, maxDim: 256, wantWidth: 256, wantHeight: 256},
+		{name: "jpeg", width: 1280, height: 720, format: ImageFormatJPEG, maxDim: 640, wantWidth: 640, wantHeight: 360},
+		{name: "maxDim 0", width: 1280, height: 720, maxDim: 0, wantOriginal: true},
+		{name: "negative maxDim", width: 1280, height: 720, maxDim: -1, wantOriginal: true},
+		{name: "already small", width: 640, height: 360, maxDim: 1024, wantOriginal: true},
+		{name: "exactly maxDim", width: 1024, height: 576, maxDim: 1024, wantOriginal: true},
+	}
+	for _, tt := range tests {
+		original := testScreenshot(t, tt.width, tt.height, tt.format)
+		scaled, err := original.Scaled(tt.maxDim)
+		if err != nil {
+			t.Errorf("%s: Scaled(%d): %v", tt.name, tt.maxDim, err)
+			continue
+		}
+		if tt.wantOriginal {
+			if scaled != original {
+				t.Errorf("%s: Scaled(%d) returned a copy, want the original", tt.name, tt.maxDim)
+			}
+			continue
+		}
+
+		config, format, err := image.DecodeConfig(bytes.NewReader(scaled.Data))
+		if err != nil {
+			t.Errorf("%s: decoding scaled screenshot: %v", tt.name, err)
+			continue
+		}
+		if config.Width != tt.wantWidth || config.Height != tt.wantHeight || scaled.Width != tt.wantWidth || scaled.Height != tt.wantHeight {
+			t.Errorf("%s: Scaled(%d) is %dx%d (image %dx%d), want %dx%d", tt.name, tt.maxDim,
+				scaled.Width, scaled.Height, config.Width, config.Height, tt.wantWidth, tt.wantHeight)
+		}
+		if wantFormat := map[ImageFormat]string{"": "png", ImageFormatPNG: "png", ImageFormatJPEG: "jpeg"}[tt.format]; format != wantFormat {
+			t.Errorf("%s: scaled screenshot is %s, want %s", tt.name, format, wantFormat)
+		}
+		if scaled.Format != original.Format || scaled.Context != original.Context || scaled.Filepath != "" {
+			t.Errorf("%s: scaled screenshot %+v doesn't carry over the original's metadata", tt.name, scaled)
+		}
+	}
+}
+
+func TestScaledRejectsUndecodableData(t *testing.T) {
+	if _, err := (&Screenshot{Data: []byte("not an image")}).Scaled(1024); err == nil {
+		t.Error("Scaled accepted data that isn't an image")
+	}
+}
+
+// BenchmarkScaled compares sending a 1280x720 screenshot to vision at full size with
+// downscaling it first. payload-bytes is the size of the data URL in the request.
+func BenchmarkScaled(b *testing.B) {
+	for _, format := range []ImageFormat{ImageFormatPNG, ImageFormatJPEG} {
+		screenshot := testScreenshot(b, 1280, 720, format)
+
+		b.Run(string(format)+"/full", func(b *testing.B) {
+			var payload string
+			for i := 0; i < b.N; i++ {
+				payload = screenshot.DataURL()
+			}
+			b.ReportMetric(float64(len(payload)), "payload-bytes")
+		})
+		b.Run(string(format)+"/scaled", func(b *testing.B) {
+			var payload string
+			for i := 0; i < b.N; i++ {
+				scaled, err := screenshot.Scaled(DefaultVisionMaxDimension)
+				if err != nil {
+					b.Fatal(err)
+				}
+				payload = scaled.DataURL()
+			}
+			b.ReportMetric(float64(len(payload)), "payload-bytes")
+		})
+	}
+}
@@ -159,6 +159,7 @@ func executeClick(ctx context.Context, action Action) error {
 	if err != nil {
 		return fmt.Errorf("failed to click %s: %w", action.Selector, err)
 	}
+	recordAction(ctx, RecordedAction{Kind: RecordedClick, Selector: action.Selector})
 
 	return nil
 }
@@ -206,6 +207,7 @@ func executeKeypress(ctx context.Context, action Action) error {
 	if err != nil {
 		return fmt.Errorf("failed to press key %s: %w", action.Key, err)
 	}
+	recordAction(ctx, RecordedAction{Kind: RecordedKey, Key: action.Key, Target: "page"})
 
 	return nil
 }
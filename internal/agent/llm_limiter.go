@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultLLMConcurrency is the default number of LLM/vision API calls allowed in flight
+// across all tests in this process
+const DefaultLLMConcurrency = 10
+
+var (
+	llmSlotsMu sync.RWMutex
+	llmSlots   = make(chan struct{}, DefaultLLMConcurrency)
+)
+
+// SetLLMConcurrency sets the process-wide limit on concurrent LLM/vision API calls.
+// This is independent of how many tests (browsers) run at once, so API rate limits can be
+// respected without reducing browser concurrency. Call it once at startup.
+func SetLLMConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	llmSlotsMu.Lock()
+	llmSlots = make(chan struct{}, n)
+	llmSlotsMu.Unlock()
+}
+
+// AcquireLLMSlot blocks until an LLM call slot is available or ctx is done.
+// The returned release function must be called once the API call completes.
+func AcquireLLMSlot(ctx context.Context) (release func(), err error) {
+	llmSlotsMu.RLock()
+	slots := llmSlots
+	llmSlotsMu.RUnlock()
+
+	select {
+	case slots <- struct{}{}:
+		var once sync.Once
+		return func() { once.Do(func() { <-slots }) }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for LLM call slot: %w", ctx.Err())
+	}
+}
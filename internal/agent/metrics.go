@@ -0,0 +1,521 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/chromedp/cdproto/memory"
+	"github.com/chromedp/cdproto/performance"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/dreamup/qa-agent/internal/logging"
+)
+
+// FPSMetrics describes the page's rendering frame rate
+type FPSMetrics struct {
+	// Average is the mean frames per second over the sample window
+	Average float64 `json:"average"`
+	// Min is the lowest per-second frame rate observed
+	Min float64 `json:"min"`
+	// Max is the highest per-second frame rate observed
+	Max float64 `json:"max"`
+	// P1 is the 1st percentile per-second frame rate (the "1% low")
+	P1 float64 `json:"p1"`
+	// P50 is the median per-second frame rate
+	P50 float64 `json:"p50"`
+	// Frames is the number of frames rendered in each second of the window, in order
+	Frames []float64 `json:"frames"`
+	// DurationMs is the length of the sample window in milliseconds
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// LoadTimeMetrics contains page load timings in milliseconds from navigation start
+type LoadTimeMetrics struct {
+	// TimeToFirstByte is when the first response byte arrived
+	TimeToFirstByte float64 `json:"time_to_first_byte_ms"`
+	// DOMContentLoaded is when the DOM was parsed
+	DOMContentLoaded float64 `json:"dom_content_loaded_ms"`
+	// LoadComplete is when the load event finished
+	LoadComplete float64 `json:"load_complete_ms"`
+	// FirstPaint is when the first pixel was painted
+	FirstPaint float64 `json:"first_paint_ms"`
+	// FirstContentfulPaint is when the first text/image was painted
+	FirstContentfulPaint float64 `json:"first_contentful_paint_ms"`
+	// LargestContentfulPaint is when the largest text/image seen so far was painted
+	LargestContentfulPaint float64 `json:"largest_contentful_paint_ms"`
+	// CumulativeLayoutShift is the largest burst of unexpected layout shifts (unitless;
+	// Core Web Vitals rate 0.1 or less as good)
+	CumulativeLayoutShift float64 `json:"cumulative_layout_shift"`
+	// TotalBlockingTime sums the part over 50ms of every long task after first contentful
+	// paint, i.e. how long the main thread blocked input
+	TotalBlockingTime float64 `json:"total_blocking_time_ms"`
+}
+
+// AccessibilityIssue is a single axe-core rule violation
+type AccessibilityIssue struct {
+	// ID is the axe rule ID (e.g. "color-contrast")
+	ID string `json:"id"`
+	// Impact is critical, serious, moderate or minor
+	Impact string `json:"impact"`
+	// Description explains the rule
+	Description string `json:"description"`
+	// Nodes is how many elements violate the rule
+	Nodes int `json:"nodes"`
+}
+
+// AccessibilityMetrics summarizes an axe-core accessibility audit. The counts are the raw
+// result; Score is one policy applied to them (see AccessibilityWeights).
+type AccessibilityMetrics struct {
+	// Score is 0-100, derived from violation impacts
+	Score int `json:"score"`
+	// Penalty is the weighted sum of violations the score was derived from
+	Penalty float64 `json:"penalty"`
+	// Violations is the total number of violated rules
+	Violations int `json:"violations"`
+	// Critical, Serious, Moderate and Minor count violations by impact
+	Critical int `json:"critical"`
+	Serious  int `json:"serious"`
+	Moderate int `json:"moderate"`
+	Minor    int `json:"minor"`
+	// AffectedNodes is how many elements violate any rule (counted once per rule)
+	AffectedNodes int `json:"affected_nodes"`
+	// Issues lists the individual violations
+	Issues []AccessibilityIssue `json:"issues,omitempty"`
+}
+
+// RuntimeStats is a sample of the page's resource use from the CDP Performance and Memory
+// domains. Comparing samples over a test shows leaks, e.g. a JS heap or node count that
+// keeps growing.
+type RuntimeStats struct {
+	// Label says when the sample was taken (e.g. "load", "end_of_gameplay")
+	Label string `json:"label"`
+	// JSHeapUsedBytes and JSHeapTotalBytes are the used and allocated JavaScript heap
+	JSHeapUsedBytes  int64 `json:"js_heap_used_bytes"`
+	JSHeapTotalBytes int64 `json:"js_heap_total_bytes"`
+	// DOMNodes, Documents and JSEventListeners count live objects, detached ones included
+	DOMNodes         int64 `json:"dom_nodes"`
+	Documents        int64 `json:"documents"`
+	JSEventListeners int64 `json:"js_event_listeners"`
+	// LayoutCount and RecalcStyleCount count layouts and style recalculations so far
+	LayoutCount      int64 `json:"layout_count"`
+	RecalcStyleCount int64 `json:"recalc_style_count"`
+	// ScriptDurationMs and TaskDurationMs are the main thread's total time in scripts and tasks
+	ScriptDurationMs float64 `json:"script_duration_ms"`
+	TaskDurationMs   float64 `json:"task_duration_ms"`
+	// CollectedAt is when the sample was taken
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+// PerformanceMetrics groups all performance measurements for a test run
+type PerformanceMetrics struct {
+	FPS           *FPSMetrics           `json:"fps,omitempty"`
+	LoadTime      *LoadTimeMetrics      `json:"load_time,omitempty"`
+	Accessibility *AccessibilityMetrics `json:"accessibility,omitempty"`
+	// Runtime are resource use samples in the order taken (see CollectRuntimeStats)
+	Runtime []*RuntimeStats `json:"runtime,omitempty"`
+	// Errors records sub-collections that failed, so partial results are still reported
+	Errors []string `json:"errors,omitempty"`
+	// CollectedAt is when the metrics were gathered
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+// MetricsCollector gathers performance and accessibility metrics from the page
+type MetricsCollector struct {
+	ctx context.Context
+	// accessibilityTags are the axe rule tags audited (nil = DefaultAccessibilityTags)
+	accessibilityTags []string
+	// accessibilityWeights score the audit (nil = DefaultAccessibilityWeights)
+	accessibilityWeights *AccessibilityWeights
+}
+
+// NewMetricsCollector creates a metrics collector for the given browser context
+func NewMetricsCollector(ctx context.Context) *MetricsCollector {
+	return &MetricsCollector{
+		ctx: ctx,
+	}
+}
+
+// SetAccessibilityTags sets the axe rule tags CollectAccessibility audits (e.g. "wcag2a",
+// "wcag21aa", "best-practice"). Nil restores DefaultAccessibilityTags.
+func (mc *MetricsCollector) SetAccessibilityTags(tags []string) {
+	mc.accessibilityTags = tags
+}
+
+// SetAccessibilityWeights sets how CollectAccessibility scores violations. Nil restores
+// DefaultAccessibilityWeights.
+func (mc *MetricsCollector) SetAccessibilityWeights(weights *AccessibilityWeights) {
+	mc.accessibilityWeights = weights
+}
+
+// evaluateAsync runs a script that returns a promise and waits up to timeout for it to resolve
+func (mc *MetricsCollector) evaluateAsync(script string, res interface{}, timeout time.Duration) error {
+	return runWithDeadline(mc.ctx, timeout, chromedp.Evaluate(script, res, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+		return p.WithAwaitPromise(true)
+	}))
+}
+
+// StartContinuousFPS installs a requestAnimationFrame sampler in the page of ctx's browser
+// tab that records per-second frame rates until StopContinuousFPS is called. It doesn't
+// block, so it can span the whole gameplay session.
+func (mc *MetricsCollector) StartContinuousFPS(ctx context.Context) error {
+	script := `
+(function() {
+    if (window.__qaFPSSampler) return true;
+    const sampler = { buckets: [], start: performance.now(), bucketStart: performance.now(), frames: 0, stopped: false };
+    window.__qaFPSSampler = sampler;
+
+    function tick(now) {
+        if (sampler.stopped) return;
+        sampler.frames++;
+        if (now - sampler.bucketStart >= 1000) {
+            sampler.buckets.push(sampler.frames * 1000 / (now - sampler.bucketStart));
+            sampler.bucketStart = now;
+            sampler.frames = 0;
+        }
+        requestAnimationFrame(tick);
+    }
+    requestAnimationFrame(tick);
+    return true;
+})();
+`
+
+	var ok bool
+	if err := runWithTimeout(ctx, chromedp.Evaluate(script, &ok)); err != nil {
+		return fmt.Errorf("failed to start FPS sampler: %w", err)
+	}
+	return nil
+}
+
+// StopContinuousFPS stops the sampler started by StartContinuousFPS and returns the
+// per-second FPS series with summary statistics
+func (mc *MetricsCollector) StopContinuousFPS() (*FPSMetrics, error) {
+	script := `
+(function() {
+    const sampler = window.__qaFPSSampler;
+    if (!sampler) return '';
+    sampler.stopped = true;
+    delete window.__qaFPSSampler;
+    const now = performance.now();
+    const elapsed = (now - sampler.bucketStart) / 1000;
+    if (sampler.frames > 0 && elapsed > 0) sampler.buckets.push(sampler.frames / elapsed);
+    return JSON.stringify({ buckets: sampler.buckets, duration: now - sampler.start });
+})();
+`
+
+	var resultJSON string
+	if err := runWithTimeout(mc.ctx, chromedp.Evaluate(script, &resultJSON)); err != nil {
+		return nil, fmt.Errorf("failed to stop FPS sampler: %w", err)
+	}
+	if resultJSON == "" {
+		return nil, fmt.Errorf("FPS sampler not running (page may have navigated)")
+	}
+
+	var result struct {
+		Buckets  []float64 `json:"buckets"`
+		Duration float64   `json:"duration"`
+	}
+	if err := json.Unmarshal([]byte(resultJSON), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse FPS result: %w", err)
+	}
+
+	return fpsStats(result.Buckets, result.Duration), nil
+}
+
+// fpsStats summarizes a per-second FPS series
+func fpsStats(buckets []float64, durationMs float64) *FPSMetrics {
+	metrics := &FPSMetrics{
+		Frames:     buckets,
+		DurationMs: durationMs,
+	}
+	if len(buckets) == 0 {
+		return metrics
+	}
+
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	total := 0.0
+	for _, fps := range sorted {
+		total += fps
+	}
+	metrics.Average = total / float64(len(sorted))
+	metrics.Min = sorted[0]
+	metrics.Max = sorted[len(sorted)-1]
+	metrics.P1 = percentile(sorted, 1)
+	metrics.P50 = percentile(sorted, 50)
+
+	return metrics
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// CollectLoadTime reads navigation and paint timings and the Core Web Vitals (LCP, CLS and
+// TBT) from the Performance API. Vitals cover what the browser has buffered so far, so call
+// it once the page has loaded.
+func (mc *MetricsCollector) CollectLoadTime() (*LoadTimeMetrics, error) {
+	script := `
+(function() {
+    const nav = performance.getEntriesByType('navigation')[0];
+    const result = {};
+    if (nav) {
+        result.ttfb = nav.responseStart;
+        result.domContentLoaded = nav.domContentLoadedEventEnd;
+        result.loadComplete = nav.loadEventEnd;
+    }
+    for (const entry of performance.getEntriesByType('paint')) {
+        if (entry.name === 'first-paint') result.firstPaint = entry.startTime;
+        if (entry.name === 'first-contentful-paint') result.firstContentfulPaint = entry.startTime;
+    }
+
+    // Web vitals entries are only exposed to observers; buffered ones are returned at once
+    function buffered(type) {
+        try {
+            const observer = new PerformanceObserver(() => {});
+            observer.observe({ type: type, buffered: true });
+            const entries = observer.takeRecords();
+            observer.disconnect();
+            return entries;
+        } catch (e) {
+            return [];
+        }
+    }
+
+    const lcp = buffered('largest-contentful-paint');
+    if (lcp.length) result.largestContentfulPaint = lcp[lcp.length - 1].startTime;
+
+    // CLS is the largest session window: shifts less than 1s apart, spanning at most 5s,
+    // ignoring shifts right after user input
+    let cls = 0, windowValue = 0, windowStart = 0, last = 0;
+    for (const shift of buffered('layout-shift')) {
+        if (shift.hadRecentInput) continue;
+        if (windowValue && shift.startTime - last < 1000 && shift.startTime - windowStart < 5000) {
+            windowValue += shift.value;
+        } else {
+            windowValue = shift.value;
+            windowStart = shift.startTime;
+        }
+        last = shift.startTime;
+        cls = Math.max(cls, windowValue);
+    }
+    result.cumulativeLayoutShift = cls;
+
+    let tbt = 0;
+    for (const task of buffered('longtask')) {
+        if (task.startTime >= (result.firstContentfulPaint || 0)) tbt += Math.max(0, task.duration - 50);
+    }
+    result.totalBlockingTime = tbt;
+
+    return JSON.stringify(result);
+})();
+`
+
+	var resultJSON string
+	if err := runWithTimeout(mc.ctx, chromedp.Evaluate(script, &resultJSON)); err != nil {
+		return nil, fmt.Errorf("failed to read load timings: %w", err)
+	}
+
+	var result struct {
+		TTFB                   float64 `json:"ttfb"`
+		DOMContentLoaded       float64 `json:"domContentLoaded"`
+		LoadComplete           float64 `json:"loadComplete"`
+		FirstPaint             float64 `json:"firstPaint"`
+		FirstContentfulPaint   float64 `json:"firstContentfulPaint"`
+		LargestContentfulPaint float64 `json:"largestContentfulPaint"`
+		CumulativeLayoutShift  float64 `json:"cumulativeLayoutShift"`
+		TotalBlockingTime      float64 `json:"totalBlockingTime"`
+	}
+	if err := json.Unmarshal([]byte(resultJSON), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse load timings: %w", err)
+	}
+
+	return &LoadTimeMetrics{
+		TimeToFirstByte:        result.TTFB,
+		DOMContentLoaded:       result.DOMContentLoaded,
+		LoadComplete:           result.LoadComplete,
+		FirstPaint:             result.FirstPaint,
+		FirstContentfulPaint:   result.FirstContentfulPaint,
+		LargestContentfulPaint: result.LargestContentfulPaint,
+		CumulativeLayoutShift:  result.CumulativeLayoutShift,
+		TotalBlockingTime:      result.TotalBlockingTime,
+	}, nil
+}
+
+// CollectRuntimeStats samples JS heap size, DOM counters and layout work through the CDP
+// Performance and Memory domains, labelling the sample with when it was taken
+func (mc *MetricsCollector) CollectRuntimeStats(label string) (*RuntimeStats, error) {
+	stats := &RuntimeStats{Label: label, CollectedAt: time.Now()}
+	var metrics []*performance.Metric
+	err := runWithTimeout(mc.ctx,
+		performance.Enable(),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			metrics, err = performance.GetMetrics().Do(ctx)
+			return err
+		}),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			stats.Documents, stats.DOMNodes, stats.JSEventListeners, err = memory.GetDOMCounters().Do(ctx)
+			return err
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runtime stats: %w", err)
+	}
+
+	// Durations are reported in seconds
+	for _, m := range metrics {
+		switch m.Name {
+		case "JSHeapUsedSize":
+			stats.JSHeapUsedBytes = int64(m.Value)
+		case "JSHeapTotalSize":
+			stats.JSHeapTotalBytes = int64(m.Value)
+		case "LayoutCount":
+			stats.LayoutCount = int64(m.Value)
+		case "RecalcStyleCount":
+			stats.RecalcStyleCount = int64(m.Value)
+		case "ScriptDuration":
+			stats.ScriptDurationMs = m.Value * 1000
+		case "TaskDuration":
+			stats.TaskDurationMs = m.Value * 1000
+		}
+	}
+	return stats, nil
+}
+
+// CollectAccessibility runs an axe-core WCAG audit on the page. The embedded axe-core
+// bundle is injected through CDP (so it works offline and despite the page's CSP); builds
+// without it load the pinned version from the CDN.
+// Violations are scored with AccessibilityWeights (see SetAccessibilityWeights).
+func (mc *MetricsCollector) CollectAccessibility() (*AccessibilityMetrics, error) {
+	if bundle := axeCoreBundle(mc.ctx, axeFS); bundle != "" {
+		var loaded bool
+		inject := fmt.Sprintf("(function() { if (!window.axe) { %s\n} return !!window.axe; })()", bundle)
+		if err := runWithTimeout(mc.ctx, chromedp.Evaluate(inject, &loaded)); err != nil || !loaded {
+			logging.Printf(mc.ctx, "[Metrics] Warning: Embedded axe-core failed to load (%v), trying the CDN", err)
+		}
+	}
+
+	tags := mc.accessibilityTags
+	if tags == nil {
+		tags = DefaultAccessibilityTags
+	}
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return nil, fmt.Errorf("invalid accessibility tags: %w", err)
+	}
+
+	script := fmt.Sprintf(`
+new Promise((resolve, reject) => {
+    function run() {
+        axe.run(document, { runOnly: { type: 'tag', values: %s } })
+            .then(results => resolve(JSON.stringify(results.violations.map(v => ({
+                id: v.id,
+                impact: v.impact || 'minor',
+                description: v.description,
+                nodes: v.nodes.length
+            })))))
+            .catch(err => reject(err.toString()));
+    }
+    if (window.axe) {
+        run();
+        return;
+    }
+    const script = document.createElement('script');
+    script.src = %q;
+    script.onload = run;
+    script.onerror = () => reject('failed to load axe-core');
+    document.head.appendChild(script);
+})
+`, tagsJSON, axeCoreURL)
+
+	var resultJSON string
+	if err := mc.evaluateAsync(script, &resultJSON, DefaultOperationTimeout); err != nil {
+		return nil, fmt.Errorf("failed to run accessibility audit: %w", err)
+	}
+
+	var issues []AccessibilityIssue
+	if err := json.Unmarshal([]byte(resultJSON), &issues); err != nil {
+		return nil, fmt.Errorf("failed to parse accessibility results: %w", err)
+	}
+
+	metrics := &AccessibilityMetrics{
+		Violations: len(issues),
+		Issues:     issues,
+	}
+	for _, issue := range issues {
+		switch issue.Impact {
+		case "critical":
+			metrics.Critical++
+		case "serious":
+			metrics.Serious++
+		case "moderate":
+			metrics.Moderate++
+		default:
+			metrics.Minor++
+		}
+		metrics.AffectedNodes += issue.Nodes
+	}
+
+	weights := DefaultAccessibilityWeights
+	if mc.accessibilityWeights != nil {
+		weights = *mc.accessibilityWeights
+	}
+	metrics.Score, metrics.Penalty = weights.Score(metrics)
+
+	return metrics, nil
+}
+
+// CollectAll gathers load time, accessibility and runtime stats; FPS is sampled over
+// gameplay instead (see StartContinuousFPS). Each collection is independent: a failure is logged and recorded in Errors
+// without preventing the others from being reported.
+func (mc *MetricsCollector) CollectAll() *PerformanceMetrics {
+	metrics := &PerformanceMetrics{
+		CollectedAt: time.Now(),
+	}
+
+	if loadTime, err := mc.CollectLoadTime(); err != nil {
+		logging.Printf(mc.ctx, "[Metrics] Warning: %v", err)
+		metrics.Errors = append(metrics.Errors, err.Error())
+	} else {
+		metrics.LoadTime = loadTime
+	}
+
+	if accessibility, err := mc.CollectAccessibility(); err != nil {
+		logging.Printf(mc.ctx, "[Metrics] Warning: %v", err)
+		metrics.Errors = append(metrics.Errors, err.Error())
+	} else {
+		metrics.Accessibility = accessibility
+	}
+
+	if runtimeStats, err := mc.CollectRuntimeStats("load"); err != nil {
+		logging.Printf(mc.ctx, "[Metrics] Warning: %v", err)
+		metrics.Errors = append(metrics.Errors, err.Error())
+	} else {
+		metrics.Runtime = append(metrics.Runtime, runtimeStats)
+	}
+
+	return metrics
+}
+
+// HeapGrowthBytes is how much the used JS heap grew from the first runtime sample to the
+// last, or 0 if there are fewer than two samples
+func (pm *PerformanceMetrics) HeapGrowthBytes() int64 {
+	if pm == nil || len(pm.Runtime) < 2 {
+		return 0
+	}
+	return pm.Runtime[len(pm.Runtime)-1].JSHeapUsedBytes - pm.Runtime[0].JSHeapUsedBytes
+}
@@ -3,12 +3,12 @@ package agent
 import (
 	"context"
 	"fmt"
-	"log"
 	"math/rand"
 	"time"
 
 	"github.com/chromedp/cdproto/input"
 	"github.com/chromedp/chromedp"
+	"github.com/dreamup/qa-agent/internal/logging"
 )
 
 // MouseAction represents different types of mouse interactions
@@ -19,9 +19,9 @@ const (
 	MouseActionDrag  MouseAction = "drag"
 )
 
-// PerformRandomClick clicks at a random position in the game area
+// PerformRandomClick clicks at a random position in the game area, chosen with rng
 // Avoids top navigation (rows 1-3) and edges
-func PerformRandomClick(ctx context.Context, screenWidth, screenHeight int) error {
+func PerformRandomClick(ctx context.Context, rng *rand.Rand, screenWidth, screenHeight int) error {
 	// Click in center 60% of screen to avoid nav/ads
 	// Avoid top 25% (rows 1-3 in 12-row grid)
 	minX := int(float64(screenWidth) * 0.2)   // 20% from left
@@ -29,16 +29,18 @@ func PerformRandomClick(ctx context.Context, screenWidth, screenHeight int) erro
 	minY := int(float64(screenHeight) * 0.25) // 25% from top (skip nav)
 	maxY := int(float64(screenHeight) * 0.8)  // 80% from top
 
-	x := minX + rand.Intn(maxX-minX)
-	y := minY + rand.Intn(maxY-minY)
+	x := minX + rng.Intn(maxX-minX)
+	y := minY + rng.Intn(maxY-minY)
 
-	log.Printf("[Mouse] Random click at (%d, %d)", x, y)
+	logging.Printf(ctx, "[Mouse] Random click at (%d, %d)", x, y)
 
 	// Use chromedp's MouseClickXY for consistent clicking
-	err := chromedp.Run(ctx, chromedp.MouseClickXY(float64(x), float64(y)))
+	pageX, pageY := toPage(ctx, float64(x), float64(y))
+	err := runWithTimeout(ctx, chromedp.MouseClickXY(pageX, pageY))
 	if err != nil {
 		return fmt.Errorf("random click failed: %w", err)
 	}
+	recordAction(ctx, RecordedAction{Kind: RecordedClick, X: x, Y: y})
 
 	return nil
 }
@@ -54,8 +56,9 @@ const (
 	DragPatternDiagonal        DragPattern = "diagonal"         // Diagonal drag
 )
 
-// PerformRandomDrag performs a drag gesture with the specified pattern
-func PerformRandomDrag(ctx context.Context, pattern DragPattern, screenWidth, screenHeight int) error {
+// PerformRandomDrag performs a drag gesture with the specified pattern, from a start point
+// chosen with rng
+func PerformRandomDrag(ctx context.Context, rng *rand.Rand, pattern DragPattern, screenWidth, screenHeight int) error {
 	// For slingshot-style games, start on LEFT side where slingshot typically is
 	// Start in left 20-30% of screen (slingshot area)
 	var startX, startY int
@@ -69,8 +72,8 @@ func PerformRandomDrag(ctx context.Context, pattern DragPattern, screenWidth, sc
 		slingshotMinY := int(float64(screenHeight) * 0.40) // Middle-ish vertically
 		slingshotMaxY := int(float64(screenHeight) * 0.60)
 
-		startX = slingshotMinX + rand.Intn(slingshotMaxX-slingshotMinX)
-		startY = slingshotMinY + rand.Intn(slingshotMaxY-slingshotMinY)
+		startX = slingshotMinX + rng.Intn(slingshotMaxX-slingshotMinX)
+		startY = slingshotMinY + rng.Intn(slingshotMaxY-slingshotMinY)
 	} else {
 		// Other patterns: use center area
 		centerMinX := int(float64(screenWidth) * 0.4)
@@ -78,8 +81,8 @@ func PerformRandomDrag(ctx context.Context, pattern DragPattern, screenWidth, sc
 		centerMinY := int(float64(screenHeight) * 0.4)
 		centerMaxY := int(float64(screenHeight) * 0.6)
 
-		startX = centerMinX + rand.Intn(centerMaxX-centerMinX)
-		startY = centerMinY + rand.Intn(centerMaxY-centerMinY)
+		startX = centerMinX + rng.Intn(centerMaxX-centerMinX)
+		startY = centerMinY + rng.Intn(centerMaxY-centerMinY)
 	}
 
 	// Calculate end position based on pattern
@@ -118,7 +121,7 @@ func PerformRandomDrag(ctx context.Context, pattern DragPattern, screenWidth, sc
 		endY = screenHeight - 50
 	}
 
-	log.Printf("[Mouse] Drag %s from (%d,%d) to (%d,%d)", pattern, startX, startY, endX, endY)
+	logging.Printf(ctx, "[Mouse] Drag %s from (%d,%d) to (%d,%d)", pattern, startX, startY, endX, endY)
 
 	// Perform drag using chromedp CDP events
 	err := PerformDrag(ctx, startX, startY, endX, endY, 300*time.Millisecond, 100*time.Millisecond)
@@ -129,11 +132,15 @@ func PerformRandomDrag(ctx context.Context, pattern DragPattern, screenWidth, sc
 	return nil
 }
 
-// PerformDrag executes a mouse drag from (startX, startY) to (endX, endY)
+// PerformDrag executes a mouse drag from (startX, startY) to (endX, endY), in screenshot
+// coordinates (relative to the game iframe, if there is one)
 func PerformDrag(ctx context.Context, startX, startY, endX, endY int, duration, holdDuration time.Duration) error {
+	startPageX, startPageY := toPage(ctx, float64(startX), float64(startY))
+	endPageX, endPageY := toPage(ctx, float64(endX), float64(endY))
+
 	// Mouse press at start position
-	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
-		return input.DispatchMouseEvent(input.MousePressed, float64(startX), float64(startY)).
+	err := runWithTimeout(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		return input.DispatchMouseEvent(input.MousePressed, startPageX, startPageY).
 			WithButton(input.Left).
 			WithClickCount(1).
 			Do(ctx)
@@ -148,10 +155,10 @@ func PerformDrag(ctx context.Context, startX, startY, endX, endY int, duration,
 	steps := 10
 	for i := 1; i <= steps; i++ {
 		t := float64(i) / float64(steps)
-		x := float64(startX) + float64(endX-startX)*t
-		y := float64(startY) + float64(endY-startY)*t
+		x := startPageX + (endPageX-startPageX)*t
+		y := startPageY + (endPageY-startPageY)*t
 
-		err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		err := runWithTimeout(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
 			return input.DispatchMouseEvent(input.MouseMoved, x, y).Do(ctx)
 		}))
 		if err != nil {
@@ -165,8 +172,8 @@ func PerformDrag(ctx context.Context, startX, startY, endX, endY int, duration,
 	time.Sleep(holdDuration)
 
 	// Mouse release
-	err = chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
-		return input.DispatchMouseEvent(input.MouseReleased, float64(endX), float64(endY)).
+	err = runWithTimeout(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		return input.DispatchMouseEvent(input.MouseReleased, endPageX, endPageY).
 			WithButton(input.Left).
 			WithClickCount(1).
 			Do(ctx)
@@ -175,5 +182,14 @@ func PerformDrag(ctx context.Context, startX, startY, endX, endY int, duration,
 		return fmt.Errorf("mouse release failed: %w", err)
 	}
 
+	recordAction(ctx, RecordedAction{
+		Kind:       RecordedDrag,
+		X:          startX,
+		Y:          startY,
+		EndX:       endX,
+		EndY:       endY,
+		DurationMs: duration.Milliseconds(),
+		HoldMs:     holdDuration.Milliseconds(),
+	})
 	return nil
 }
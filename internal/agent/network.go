@@ -0,0 +1,421 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/google/uuid"
+)
+
+// harRedacted replaces credentials in HARs, which are served with reports
+const harRedacted = "[REDACTED]"
+
+// harSecretHeaders carry credentials in every HAR (lowercase). Basic auth answered for
+// WithBasicAuth is sent as Authorization.
+var harSecretHeaders = []string{"authorization", "proxy-authorization", "cookie", "set-cookie"}
+
+// maxHAREntries caps how many requests a NetworkRecorder keeps, so games that poll
+// constantly can't grow the log without bound
+const maxHAREntries = 5000
+
+// HAR is an HTTP Archive (v1.2) document
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root of a HAR document
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+	// Comment notes entries dropped once the recorder's cap was reached
+	Comment string `json:"comment,omitempty"`
+}
+
+// HARCreator identifies the tool that produced the HAR
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is a single request/response pair
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	// ResourceType is Chrome's resource type (Document, Script, Image, ...)
+	ResourceType string `json:"_resourceType,omitempty"`
+	// Error is Chrome's net error for requests that failed to load
+	Error string `json:"_error,omitempty"`
+}
+
+// HARRequest describes the request half of an entry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse describes the response half of an entry
+type HARResponse struct {
+	Status      int64          `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARContent describes the response body (the body itself is not captured)
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// HARNameValue is a header, cookie or query parameter
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARTimings breaks down the request time in milliseconds (-1 = not applicable)
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// networkRequest accumulates CDP events for one request until it finishes
+type networkRequest struct {
+	started      time.Time
+	startMono    float64
+	endMono      float64
+	request      *network.Request
+	response     *network.Response
+	resourceType string
+	bodySize     int64
+	errorText    string
+}
+
+// NetworkRecorder captures page network traffic and exports it as a HAR
+type NetworkRecorder struct {
+	ctx      context.Context
+	mu       sync.Mutex
+	requests map[network.RequestID]*networkRequest
+	finished []*networkRequest
+	dropped  int
+	redact   map[string]bool // Lowercase names of headers whose values are redacted
+}
+
+// NewNetworkRecorder creates a network recorder for the given browser context. The values
+// of credential headers (Authorization, Cookie, ...), secretHeaders and cookies are
+// redacted from its HARs.
+func NewNetworkRecorder(ctx context.Context, secretHeaders ...string) *NetworkRecorder {
+	redact := make(map[string]bool, len(harSecretHeaders)+len(secretHeaders))
+	for _, name := range harSecretHeaders {
+		redact[name] = true
+	}
+	for _, name := range secretHeaders {
+		redact[strings.ToLower(name)] = true
+	}
+	return &NetworkRecorder{
+		ctx:      ctx,
+		requests: make(map[network.RequestID]*networkRequest),
+		redact:   redact,
+	}
+}
+
+// Start enables the Network domain and begins recording requests.
+// Call before navigation to capture the initial page load.
+func (nr *NetworkRecorder) Start() error {
+	chromedp.ListenTarget(nr.ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			nr.handleRequest(ev)
+		case *network.EventResponseReceived:
+			nr.handleResponse(ev)
+		case *network.EventLoadingFinished:
+			nr.handleFinished(ev.RequestID, ev.Timestamp, ev.EncodedDataLength, "")
+		case *network.EventLoadingFailed:
+			nr.handleFinished(ev.RequestID, ev.Timestamp, 0, ev.ErrorText)
+		}
+	})
+
+	if err := runWithTimeout(nr.ctx, network.Enable()); err != nil {
+		return fmt.Errorf("failed to enable network events: %w", err)
+	}
+	return nil
+}
+
+// handleRequest records a new request. A redirect reuses the request ID, so the
+// previous hop is completed with the redirect response first.
+func (nr *NetworkRecorder) handleRequest(ev *network.EventRequestWillBeSent) {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	if prev, ok := nr.requests[ev.RequestID]; ok && ev.RedirectResponse != nil {
+		prev.response = ev.RedirectResponse
+		prev.endMono = monotonicSeconds(ev.Timestamp)
+		nr.finish(ev.RequestID, prev)
+	}
+
+	req := &networkRequest{
+		started:      time.Now(),
+		startMono:    monotonicSeconds(ev.Timestamp),
+		request:      ev.Request,
+		resourceType: ev.Type.String(),
+	}
+	if ev.WallTime != nil {
+		req.started = ev.WallTime.Time()
+	}
+	nr.requests[ev.RequestID] = req
+}
+
+// handleResponse attaches response headers to an in-flight request
+func (nr *NetworkRecorder) handleResponse(ev *network.EventResponseReceived) {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	if req, ok := nr.requests[ev.RequestID]; ok {
+		req.response = ev.Response
+	}
+}
+
+// handleFinished completes a request that finished loading or failed
+func (nr *NetworkRecorder) handleFinished(id network.RequestID, ts *cdp.MonotonicTime, bodySize float64, errorText string) {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	req, ok := nr.requests[id]
+	if !ok {
+		return
+	}
+	req.endMono = monotonicSeconds(ts)
+	req.bodySize = int64(bodySize)
+	req.errorText = errorText
+	nr.finish(id, req)
+}
+
+// finish moves a request to the finished list, respecting maxHAREntries. Caller holds nr.mu.
+func (nr *NetworkRecorder) finish(id network.RequestID, req *networkRequest) {
+	delete(nr.requests, id)
+	if len(nr.finished) >= maxHAREntries {
+		nr.dropped++
+		return
+	}
+	nr.finished = append(nr.finished, req)
+}
+
+// HAR builds a HAR document from the requests recorded so far.
+// Requests still in flight are included without a response.
+func (nr *NetworkRecorder) HAR() *HAR {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	requests := make([]*networkRequest, 0, len(nr.finished)+len(nr.requests))
+	requests = append(requests, nr.finished...)
+	for _, req := range nr.requests {
+		requests = append(requests, req)
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].started.Before(requests[j].started)
+	})
+
+	har := &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "dreamup-qa-agent", Version: "1.0"},
+		Entries: make([]HAREntry, 0, len(requests)),
+	}}
+	for _, req := range requests {
+		har.Log.Entries = append(har.Log.Entries, req.toEntry(nr.redact))
+	}
+	if nr.dropped > 0 {
+		har.Log.Comment = fmt.Sprintf("%d requests dropped after reaching the %d entry limit", nr.dropped, maxHAREntries)
+	}
+	return har
+}
+
+// SaveToTemp writes the HAR to the persistent media directory and returns its path
+func (nr *NetworkRecorder) SaveToTemp() (string, error) {
+	data, err := json.Marshal(nr.HAR())
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal HAR: %w", err)
+	}
+
+	filename := fmt.Sprintf("network_%s_%s.har",
+		time.Now().Format("20060102_150405"),
+		uuid.New().String()[:8],
+	)
+
+	mediaDir, err := getMediaDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(mediaDir, filename)
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to save HAR to %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// StartNetworkRecording begins capturing the page's network traffic for a HAR, with the
+// browser's credentials redacted. Call before navigation so the initial page load is included.
+func (bm *BrowserManager) StartNetworkRecording() (*NetworkRecorder, error) {
+	recorder := NewNetworkRecorder(bm.ctx, bm.secretHeaders...)
+	if err := recorder.Start(); err != nil {
+		return nil, err
+	}
+	return recorder, nil
+}
+
+// toEntry converts the accumulated events into a HAR entry, redacting the values of the
+// headers in redact and of all cookies
+func (req *networkRequest) toEntry(redact map[string]bool) HAREntry {
+	entry := HAREntry{
+		StartedDateTime: req.started,
+		ResourceType:    req.resourceType,
+		Error:           req.errorText,
+		Timings:         HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+	}
+	if req.endMono > req.startMono {
+		entry.Time = (req.endMono - req.startMono) * 1000
+	}
+
+	entry.Request = HARRequest{
+		Cookies:     []HARNameValue{},
+		Headers:     []HARNameValue{},
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	if req.request != nil {
+		entry.Request.Method = req.request.Method
+		entry.Request.URL = req.request.URL + req.request.URLFragment
+		entry.Request.Headers = harHeaders(req.request.Headers, redact)
+		if u, err := url.Parse(req.request.URL); err == nil {
+			for name, values := range u.Query() {
+				for _, value := range values {
+					entry.Request.QueryString = append(entry.Request.QueryString, HARNameValue{Name: name, Value: value})
+				}
+			}
+		}
+	}
+
+	entry.Response = HARResponse{
+		Cookies:     []HARNameValue{},
+		Headers:     []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	resp := req.response
+	if resp == nil {
+		entry.Timings.Wait = entry.Time
+		return entry
+	}
+
+	entry.Request.HTTPVersion = resp.Protocol
+	entry.Response.Status = resp.Status
+	entry.Response.StatusText = resp.StatusText
+	entry.Response.HTTPVersion = resp.Protocol
+	entry.Response.Headers = harHeaders(resp.Headers, redact)
+	entry.Response.Content = HARContent{Size: req.bodySize, MimeType: resp.MimeType}
+	entry.Response.BodySize = req.bodySize
+	entry.ServerIPAddress = resp.RemoteIPAddress
+	if location, ok := resp.Headers["Location"]; ok {
+		entry.Response.RedirectURL = fmt.Sprint(location)
+	} else if location, ok := resp.Headers["location"]; ok {
+		entry.Response.RedirectURL = fmt.Sprint(location)
+	}
+
+	if t := resp.Timing; t != nil {
+		if t.DNSStart >= 0 {
+			entry.Timings.DNS = t.DNSEnd - t.DNSStart
+		}
+		if t.ConnectStart >= 0 {
+			entry.Timings.Connect = t.ConnectEnd - t.ConnectStart
+		}
+		if t.SslStart >= 0 {
+			entry.Timings.SSL = t.SslEnd - t.SslStart
+		}
+		entry.Timings.Blocked = (t.RequestTime - req.startMono) * 1000
+		entry.Timings.Send = t.SendEnd - t.SendStart
+		entry.Timings.Wait = t.ReceiveHeadersEnd - t.SendEnd
+		if req.endMono > 0 {
+			entry.Timings.Receive = req.endMono*1000 - (t.RequestTime*1000 + t.ReceiveHeadersEnd)
+		}
+		if entry.Timings.Blocked < 0 {
+			entry.Timings.Blocked = 0
+		}
+		if entry.Timings.Receive < 0 {
+			entry.Timings.Receive = 0
+		}
+	} else {
+		entry.Timings.Wait = entry.Time
+	}
+
+	redactCookies(entry.Request.Cookies)
+	redactCookies(entry.Response.Cookies)
+	return entry
+}
+
+// harHeaders converts CDP headers to sorted HAR name/value pairs, redacting the values of
+// the headers in redact
+func harHeaders(headers network.Headers, redact map[string]bool) []HARNameValue {
+	pairs := make([]HARNameValue, 0, len(headers))
+	for name, value := range headers {
+		v := fmt.Sprint(value)
+		if redact[strings.ToLower(name)] {
+			v = harRedacted
+		}
+		pairs = append(pairs, HARNameValue{Name: name, Value: v})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Name < pairs[j].Name
+	})
+	return pairs
+}
+
+// redactCookies replaces cookie values, which are session credentials
+func redactCookies(cookies []HARNameValue) {
+	for i := range cookies {
+		cookies[i].Value = harRedacted
+	}
+}
+
+// monotonicSeconds returns a CDP monotonic timestamp as seconds on the same clock
+// as ResourceTiming.RequestTime
+func monotonicSeconds(ts *cdp.MonotonicTime) float64 {
+	if ts == nil {
+		return 0
+	}
+	return ts.Time().Sub(*cdp.MonotonicTimeEpoch).Seconds()
+}
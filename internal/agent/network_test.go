@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestNetworkRecorderRedactsCredentials(t *testing.T) {
+	const (
+		password    = "hunter2-basic-auth"
+		previewKey  = "preview-token-0451"
+		sessionID   = "session-cookie-9f3e"
+		gameCookie  = "set-cookie-value-77c1"
+		proxySecret = "proxy-pass-5d2a"
+	)
+	basicAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("tester:"+password))
+
+	// The game page is fetched again with the basic auth answer and the custom header
+	// from WithExtraHeaders, and sets a session cookie
+	recorder := NewNetworkRecorder(context.Background(), "X-Preview-Key")
+	recorder.handleRequest(&network.EventRequestWillBeSent{
+		RequestID: "1",
+		Request: &network.Request{
+			URL:    "https://staging.example.com/game",
+			Method: "GET",
+			Headers: network.Headers{
+				"Authorization":       basicAuth,
+				"x-preview-key":       previewKey,
+				"Cookie":              "sid=" + sessionID,
+				"Proxy-Authorization": "Basic " + proxySecret,
+				"Accept":              "text/html",
+			},
+		},
+	})
+	recorder.handleResponse(&network.EventResponseReceived{
+		RequestID: "1",
+		Response: &network.Response{
+			URL:    "https://staging.example.com/game",
+			Status: 200,
+			Headers: network.Headers{
+				"set-cookie":   "sid=" + gameCookie + "; HttpOnly",
+				"Content-Type": "text/html",
+			},
+		},
+	})
+	recorder.handleFinished("1", nil, 512, "")
+
+	har := recorder.HAR()
+	// Cookies are never filled in from CDP today; any that are must be redacted too
+	har.Log.Entries[0].Request.Cookies = []HARNameValue{{Name: "sid", Value: sessionID}}
+	redactCookies(har.Log.Entries[0].Request.Cookies)
+
+	data, err := json.Marshal(har)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{password, basicAuth, previewKey, sessionID, gameCookie, proxySecret} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("HAR contains secret %q:\n%s", secret, data)
+		}
+	}
+
+	entry := har.Log.Entries[0]
+	want := map[string]string{
+		"Authorization":       harRedacted,
+		"x-preview-key":       harRedacted,
+		"Cookie":              harRedacted,
+		"Proxy-Authorization": harRedacted,
+		"Accept":              "text/html",
+	}
+	for _, h := range entry.Request.Headers {
+		if h.Value != want[h.Name] {
+			t.Errorf("request header %s = %q, want %q", h.Name, h.Value, want[h.Name])
+		}
+	}
+	for _, h := range entry.Response.Headers {
+		if h.Name == "set-cookie" && h.Value != harRedacted {
+			t.Errorf("response set-cookie = %q, want it redacted", h.Value)
+		}
+		if h.Name == "Content-Type" && h.Value != "text/html" {
+			t.Errorf("response Content-Type = %q, want it kept", h.Value)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math/bits"
+)
+
+const (
+	// DefaultSimilarityThreshold is how many of the 64 perceptual hash bits may differ for two
+	// screenshots to count as the same screen. It absorbs animation noise (blinking cursors,
+	// idle sprites) while a new menu, level or score change still registers.
+	DefaultSimilarityThreshold = 5
+
+	// phashCols and phashRows size the grayscale grid a difference hash is computed from:
+	// each row compares 9 neighbouring cells, giving 8x8 = 64 bits
+	phashCols = 9
+	phashRows = 8
+	// phashSampleStep is the pixel stride used when averaging each grid cell
+	phashSampleStep = 4
+)
+
+// PerceptualHash computes a 64-bit difference hash (dHash) of the screenshot: the image is
+// reduced to a 9x8 grayscale grid and each bit records whether a cell is brighter than its
+// right neighbour. Visually similar screenshots have hashes a few bits apart, unlike Hash,
+// which changes with any pixel. The result is cached on the screenshot.
+func (s *Screenshot) PerceptualHash() (uint64, error) {
+	if s.phashDone {
+		return s.phash, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(s.Data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() < phashCols || bounds.Dy() < phashRows {
+		return 0, fmt.Errorf("screenshot too small to hash (%dx%d)", bounds.Dx(), bounds.Dy())
+	}
+
+	// Average the luminance of each grid cell
+	var grid [phashRows][phashCols]float64
+	for row := 0; row < phashRows; row++ {
+		y0 := bounds.Min.Y + row*bounds.Dy()/phashRows
+		y1 := bounds.Min.Y + (row+1)*bounds.Dy()/phashRows
+		for col := 0; col < phashCols; col++ {
+			x0 := bounds.Min.X + col*bounds.Dx()/phashCols
+			x1 := bounds.Min.X + (col+1)*bounds.Dx()/phashCols
+
+			var total float64
+			samples := 0
+			for y := y0; y < y1; y += phashSampleStep {
+				for x := x0; x < x1; x += phashSampleStep {
+					r, g, b, _ := img.At(x, y).RGBA()
+					total += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+					samples++
+				}
+			}
+			grid[row][col] = total / float64(samples)
+		}
+	}
+
+	var hash uint64
+	for row := 0; row < phashRows; row++ {
+		for col := 0; col < phashCols-1; col++ {
+			hash <<= 1
+			if grid[row][col] > grid[row][col+1] {
+				hash |= 1
+			}
+		}
+	}
+
+	s.phash, s.phashDone = hash, true
+	return hash, nil
+}
+
+// SimilarTo reports whether two screenshots show the same screen: identical data, or
+// perceptual hashes at most threshold bits apart (see DefaultSimilarityThreshold).
+// Screenshots that can't be decoded are only similar if identical.
+func (s *Screenshot) SimilarTo(other *Screenshot, threshold int) bool {
+	if other == nil {
+		return false
+	}
+	if bytes.Equal(s.Data, other.Data) {
+		return true
+	}
+
+	a, err := s.PerceptualHash()
+	if err != nil {
+		return false
+	}
+	b, err := other.PerceptualHash()
+	if err != nil {
+		return false
+	}
+	return bits.OnesCount64(a^b) <= threshold
+}
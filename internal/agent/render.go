@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"time"
+)
+
+const (
+	// DefaultRenderTimeout is how long a game gets to draw something before the test gives up
+	DefaultRenderTimeout = 15 * time.Second
+
+	// blankSampleStep is the spacing, in pixels, of the grid sampled by IsBlank
+	blankSampleStep = 8
+	// blankTolerance is how far (per 16-bit channel) a sample may differ and still count as blank
+	blankTolerance = 8 * 257
+)
+
+// IsBlank reports whether the screenshot is a single solid color, e.g. an all-black page
+// whose game never rendered
+func (s *Screenshot) IsBlank() (bool, error) {
+	img, _, err := image.Decode(bytes.NewReader(s.Data))
+	if err != nil {
+		return false, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+
+	bounds := img.Bounds()
+	r0, g0, b0, _ := img.At(bounds.Min.X, bounds.Min.Y).RGBA()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += blankSampleStep {
+		for x := bounds.Min.X; x < bounds.Max.X; x += blankSampleStep {
+			r, g, b, _ := img.At(x, y).RGBA()
+			if channelDiff(r, r0) > blankTolerance || channelDiff(g, g0) > blankTolerance || channelDiff(b, b0) > blankTolerance {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+func channelDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Replayer sends recorded actions (see ActionLog) back to a game, so a run can be
+// reproduced without the AI or random choices that produced it
+type Replayer struct {
+	ctx      context.Context
+	detector *UIDetector
+	// gamepad is connected the first time a recorded gamepad action is replayed
+	gamepad *Gamepad
+}
+
+// NewReplayer creates a replayer for the browser context
+func NewReplayer(ctx context.Context) *Replayer {
+	return &Replayer{ctx: ctx, detector: NewUIDetector(ctx)}
+}
+
+// ReplayDelay is how long after the first action a recorded action should be replayed,
+// to keep the original pacing
+func ReplayDelay(first, action RecordedAction) time.Duration {
+	return max(0, time.Duration(action.OffsetMs-first.OffsetMs)*time.Millisecond)
+}
+
+// Execute replays one action. Clicks at coordinates go through the same viewport
+// transform as vision clicks, since recorded coordinates are in screenshot pixels.
+func (p *Replayer) Execute(action RecordedAction) error {
+	switch action.Kind {
+	case RecordedClick:
+		switch {
+		case action.Selector != "":
+			return executeClick(p.ctx, Action{Type: ActionClick, Selector: action.Selector, Timeout: 5 * time.Second})
+		case action.Text != "":
+			return clickButtonByText(p.ctx, action.Text)
+		default:
+			return clickAt(p.ctx, action.X, action.Y)
+		}
+
+	case RecordedDrag:
+		return PerformDrag(p.ctx, action.X, action.Y, action.EndX, action.EndY,
+			time.Duration(action.DurationMs)*time.Millisecond, time.Duration(action.HoldMs)*time.Millisecond)
+
+	case RecordedKey:
+		var sent bool
+		var err error
+		switch action.Target {
+		case "canvas":
+			sent, err = p.detector.SendKeyboardEventToCanvas(action.Key)
+		case "page":
+			return executeKeypress(p.ctx, Action{Type: ActionKeypress, Key: action.Key})
+		default:
+			sent, err = p.detector.SendKeyboardEventToWindow(action.Key)
+		}
+		return keySent(action, sent, err)
+
+	case RecordedKeyDown:
+		sent, err := p.detector.SendKeyDownToWindow(action.Key)
+		return keySent(action, sent, err)
+
+	case RecordedKeyUp:
+		sent, err := p.detector.SendKeyUpToWindow(action.Key)
+		return keySent(action, sent, err)
+
+	case RecordedGamepadButton, RecordedGamepadAxis:
+		if p.gamepad == nil {
+			gamepad, err := EmulateGamepad(p.ctx)
+			if err != nil {
+				return err
+			}
+			p.gamepad = gamepad
+		}
+		if action.Kind == RecordedGamepadAxis {
+			return p.gamepad.SetAxis(GamepadAxis(action.Index), action.Value)
+		}
+		if action.Value > 0 {
+			return p.gamepad.PressButton(GamepadButton(action.Index))
+		}
+		return p.gamepad.ReleaseButton(GamepadButton(action.Index))
+
+	default:
+		return fmt.Errorf("unknown action kind %q", action.Kind)
+	}
+}
+
+// keySent turns a key dispatch result into an error
+func keySent(action RecordedAction, sent bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if !sent {
+		return fmt.Errorf("%s %s was not dispatched (no %s to send it to)", action.Kind, action.Key, action.Target)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"bytes"
+	"image"
+)
+
+// screenshotSignature samples a screenshot's colors (0-255) on the similarity grid, or
+// returns nil if it can't be decoded
+func screenshotSignature(s *Screenshot) []float64 {
+	img, _, err := image.Decode(bytes.NewReader(s.Data))
+	if err != nil {
+		return nil
+	}
+
+	bounds := img.Bounds()
+	sig := make([]float64, 0, similaritySampleRows*similaritySampleCols*3)
+	for row := 0; row < similaritySampleRows; row++ {
+		for col := 0; col < similaritySampleCols; col++ {
+			x := bounds.Min.X + (2*col+1)*bounds.Dx()/(2*similaritySampleCols)
+			y := bounds.Min.Y + (2*row+1)*bounds.Dy()/(2*similaritySampleRows)
+			r, g, b, _ := img.At(x, y).RGBA()
+			sig = append(sig, float64(r)/257, float64(g)/257, float64(b)/257)
+		}
+	}
+	return sig
+}
+
+// signatureDistance is the mean per-channel difference (0-255) between two signatures.
+// Screenshots that couldn't be decoded count as entirely different.
+func signatureDistance(a, b []float64) float64 {
+	if a == nil || b == nil || len(a) != len(b) {
+		return 255
+	}
+	var total float64
+	for i := range a {
+		if a[i] > b[i] {
+			total += a[i] - b[i]
+		} else {
+			total += b[i] - a[i]
+		}
+	}
+	return total / float64(len(a))
+}
+
+// SelectDistinctScreenshots picks up to n screenshots that best cover a run: the first and
+// last always, then, one at a time, the screenshot least like any already picked. Exact
+// duplicates of picked screenshots are never added, so fewer than n may be returned.
+// The result keeps capture order.
+func SelectDistinctScreenshots(screenshots []*Screenshot, n int) []*Screenshot {
+	if n >= len(screenshots) {
+		return screenshots
+	}
+	if n < 2 {
+		n = 2
+	}
+
+	hashes := make([]string, len(screenshots))
+	sigs := make([][]float64, len(screenshots))
+	for i, s := range screenshots {
+		hashes[i] = s.Hash()
+		sigs[i] = screenshotSignature(s)
+	}
+	distance := func(i, j int) float64 {
+		if hashes[i] == hashes[j] {
+			return 0
+		}
+		return signatureDistance(sigs[i], sigs[j])
+	}
+
+	last := len(screenshots) - 1
+	picked := make([]bool, len(screenshots))
+	picked[0], picked[last] = true, true
+	count := 2
+
+	// nearest[i] is how far screenshot i is from the closest picked screenshot
+	nearest := make([]float64, len(screenshots))
+	for i := range screenshots {
+		nearest[i] = min(distance(i, 0), distance(i, last))
+	}
+
+	for count < n {
+		best := -1
+		for i := range screenshots {
+			if !picked[i] && nearest[i] > 0 && (best < 0 || nearest[i] > nearest[best]) {
+				best = i
+			}
+		}
+		if best < 0 {
+			// Everything left duplicates a picked screenshot
+			break
+		}
+		picked[best] = true
+		count++
+		for i := range screenshots {
+			nearest[i] = min(nearest[i], distance(i, best))
+		}
+	}
+
+	selected := make([]*Screenshot, 0, count)
+	for i, s := range screenshots {
+		if picked[i] {
+			selected = append(selected, s)
+		}
+	}
+	return selected
+}
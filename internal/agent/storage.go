@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// localStorageSeedScript writes the seeded entries before any page script runs.
+// %s is a JSON object of key/value pairs.
+const localStorageSeedScript = `
+(function() {
+	const entries = %s;
+	try {
+		for (const [key, value] of Object.entries(entries)) {
+			window.localStorage.setItem(key, value);
+		}
+	} catch (e) {
+		// Storage is unavailable for opaque origins (about:blank, sandboxed frames)
+	}
+})();
+`
+
+// SeedStorage sets cookies and localStorage entries so a game starts with a logged-in
+// session or saved progress. Call before navigation: cookies are set directly, and
+// localStorage is written by a script that runs before the page's own scripts on every load.
+// Each cookie must have a Domain (see CookieDefaults).
+func (bm *BrowserManager) SeedStorage(cookies []http.Cookie, localStorage map[string]string) error {
+	if len(cookies) > 0 {
+		params := make([]*network.CookieParam, 0, len(cookies))
+		for _, c := range cookies {
+			if c.Name == "" || c.Domain == "" {
+				return fmt.Errorf("cookie %q must have a name and domain", c.Name)
+			}
+			param := &network.CookieParam{
+				Name:     c.Name,
+				Value:    c.Value,
+				Domain:   c.Domain,
+				Path:     c.Path,
+				Secure:   c.Secure,
+				HTTPOnly: c.HttpOnly,
+			}
+			if !c.Expires.IsZero() {
+				expires := cdp.TimeSinceEpoch(c.Expires)
+				param.Expires = &expires
+			}
+			params = append(params, param)
+		}
+
+		if err := runWithTimeout(bm.ctx, network.SetCookies(params)); err != nil {
+			return fmt.Errorf("failed to set cookies: %w", err)
+		}
+	}
+
+	if len(localStorage) > 0 {
+		entries, err := json.Marshal(localStorage)
+		if err != nil {
+			return fmt.Errorf("failed to encode localStorage: %w", err)
+		}
+		script := fmt.Sprintf(localStorageSeedScript, entries)
+
+		err = runWithTimeout(bm.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			_, err := page.AddScriptToEvaluateOnNewDocument(script).Do(ctx)
+			return err
+		}))
+		if err != nil {
+			return fmt.Errorf("failed to seed localStorage: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// CookieDefaults fills in a missing Domain and Path from the game URL so callers
+// can pass just a name and value
+func CookieDefaults(cookies []http.Cookie, gameURL string) ([]http.Cookie, error) {
+	u, err := url.Parse(gameURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid game URL: %w", err)
+	}
+
+	result := make([]http.Cookie, len(cookies))
+	for i, c := range cookies {
+		if c.Name == "" {
+			return nil, fmt.Errorf("cookie %d has no name", i)
+		}
+		if c.Domain == "" {
+			c.Domain = u.Hostname()
+		}
+		if c.Path == "" {
+			c.Path = "/"
+		}
+		result[i] = c
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// NetworkProfile is a named network condition to emulate
+type NetworkProfile string
+
+const (
+	// NetworkProfileNone leaves the network unthrottled
+	NetworkProfileNone NetworkProfile = ""
+	// NetworkProfileFast3G matches Chrome DevTools' "Fast 3G" preset
+	NetworkProfileFast3G NetworkProfile = "fast-3g"
+	// NetworkProfileSlow3G matches Chrome DevTools' "Slow 3G" preset
+	NetworkProfileSlow3G NetworkProfile = "slow-3g"
+	// NetworkProfileOffline drops all requests
+	NetworkProfileOffline NetworkProfile = "offline"
+	// NetworkProfileOfflineAfterLoad loads the game normally, then goes offline for gameplay
+	NetworkProfileOfflineAfterLoad NetworkProfile = "offline-after-load"
+)
+
+// networkConditions are the Network.emulateNetworkConditions parameters for a profile
+type networkConditions struct {
+	offline bool
+	// latency is in milliseconds; throughputs are bytes/sec (-1 disables throttling)
+	latency            float64
+	downloadThroughput float64
+	uploadThroughput   float64
+}
+
+// networkProfiles maps profiles to conditions. offline-after-load has none of its own:
+// it is unthrottled while loading and NetworkProfileOffline afterwards.
+var networkProfiles = map[NetworkProfile]networkConditions{
+	NetworkProfileNone:             {latency: 0, downloadThroughput: -1, uploadThroughput: -1},
+	NetworkProfileOfflineAfterLoad: {latency: 0, downloadThroughput: -1, uploadThroughput: -1},
+	NetworkProfileFast3G:           {latency: 562.5, downloadThroughput: 180000, uploadThroughput: 84375},
+	NetworkProfileSlow3G:           {latency: 2000, downloadThroughput: 50000, uploadThroughput: 50000},
+	NetworkProfileOffline:          {offline: true, downloadThroughput: -1, uploadThroughput: -1},
+}
+
+// ParseNetworkProfile validates a profile name such as "slow-3g"
+func ParseNetworkProfile(name string) (NetworkProfile, error) {
+	profile := NetworkProfile(name)
+	if _, ok := networkProfiles[profile]; !ok {
+		return "", fmt.Errorf("unknown network profile %q (use fast-3g, slow-3g, offline or offline-after-load)", name)
+	}
+	return profile, nil
+}
+
+// SetNetworkConditions throttles (or cuts off) the browser's network to match profile.
+// Call before navigation so load-time metrics reflect the profile.
+func (bm *BrowserManager) SetNetworkConditions(profile NetworkProfile) error {
+	conditions, ok := networkProfiles[profile]
+	if !ok {
+		return fmt.Errorf("unknown network profile %q", profile)
+	}
+
+	err := runWithTimeout(bm.ctx,
+		network.Enable(),
+		network.EmulateNetworkConditions(conditions.offline, conditions.latency,
+			conditions.downloadThroughput, conditions.uploadThroughput),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set network conditions: %w", err)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/chromedp"
@@ -12,15 +13,15 @@ import (
 // UIElement represents a detected UI element
 type UIElement struct {
 	// Selector is the CSS selector that uniquely identifies this element
-	Selector string
+	Selector string `json:"selector"`
 	// Type is the type of UI element (button, canvas, input, etc.)
-	Type UIElementType
+	Type UIElementType `json:"type"`
 	// Text is the text content of the element (if any)
-	Text string
+	Text string `json:"text,omitempty"`
 	// Visible indicates whether the element is currently visible
-	Visible bool
+	Visible bool `json:"visible"`
 	// Attributes contains additional element attributes
-	Attributes map[string]string
+	Attributes map[string]string `json:"attributes,omitempty"`
 }
 
 // UIElementType represents the type of UI element
@@ -196,9 +197,9 @@ func (d *UIDetector) DetectPattern(pattern UIPattern) (*UIElement, error) {
 func (d *UIDetector) DetectElement(selector string, elementType UIElementType) (*UIElement, error) {
 	var nodes []*cdp.Node
 
-	// Query for the element
-	err := chromedp.Run(d.ctx,
-		chromedp.Nodes(selector, &nodes, chromedp.ByQuery),
+	// Query for the element; AtLeast(0) returns at once instead of waiting for it to appear
+	err := runWithTimeout(d.ctx,
+		chromedp.Nodes(selector, &nodes, chromedp.ByQuery, chromedp.AtLeast(0)),
 	)
 
 	if err != nil {
@@ -217,7 +218,7 @@ func (d *UIDetector) DetectElement(selector string, elementType UIElementType) (
 
 	// Extract text content if available
 	var text string
-	chromedp.Run(d.ctx,
+	runWithTimeout(d.ctx,
 		chromedp.Text(selector, &text, chromedp.ByQuery),
 	)
 
@@ -265,41 +266,98 @@ func (d *UIDetector) FindBestStartButton() (string, error) {
 	return element.Selector, nil
 }
 
-// ClickStartButton attempts to find and click a start/play button
-// Returns true if a button was found and clicked, false otherwise
-func (d *UIDetector) ClickStartButton() (bool, error) {
-	// Use JavaScript to find and click start/play buttons
-	script := `
-(function() {
-	console.log('[StartButton] Starting detection...');
-
-	// Try finding buttons by text content
-	const buttons = document.querySelectorAll('button, a[role="button"], div[role="button"], a, span[role="button"], input[type="button"], input[type="submit"], div, span, img, area');
-	console.log('[StartButton] Found', buttons.length, 'potential button elements');
+// MinStartButtonScore is the lowest score ClickStartButton will click a button at
+const MinStartButtonScore = 0.5
+
+// StartButtonResult describes what ClickStartButton clicked
+type StartButtonResult struct {
+	// Clicked is whether anything was clicked
+	Clicked bool `json:"clicked"`
+	// Method is "button" for a scored start button, "canvas" for the canvas fallback,
+	// or empty if nothing was clicked
+	Method string `json:"method"`
+	// Selector identifies the clicked element (or the best rejected candidate)
+	Selector string `json:"selector"`
+	// Text is the matched text of the button
+	Text string `json:"text"`
+	// Score rates how likely the button is the real start button (0.0-1.0)
+	Score float64 `json:"score"`
+	// Candidates is how many elements matched start/play text
+	Candidates int `json:"candidates"`
+}
 
-	for (const btn of buttons) {
-		const text = btn.textContent.toLowerCase().trim();
-		const value = (btn.value || '').toLowerCase().trim();
-		const alt = (btn.alt || '').toLowerCase().trim();
-		const title = (btn.title || '').toLowerCase().trim();
-		const ariaLabel = (btn.getAttribute('aria-label') || '').toLowerCase().trim();
-
-		// Match common start/play button text
-		if (text === 'play' || text === 'start' || text === 'begin' ||
-		    text === 'play game' || text === 'start game' ||
-		    text.includes('play now') || text.includes('start now') ||
-		    value === 'play' || value === 'start' ||
-		    alt === 'play' || alt === 'start' ||
-		    title === 'play' || title === 'start' ||
-		    ariaLabel === 'play' || ariaLabel === 'start') {
-			// Check if element is visible
-			const rect = btn.getBoundingClientRect();
-			if (rect.width > 0 && rect.height > 0 && btn.offsetParent !== null) {
-				console.log('[StartButton] Clicking button with text:', text || alt || title || ariaLabel);
-				btn.click();
-				return true;
+// startButtonScript scores every visible element whose text matches a start/play button
+// and clicks the best one scoring at least minScore, falling back to clicking the canvas.
+// Score: exact text match 0.4 (partial 0.2), button-like element 0.2, closeness to the
+// viewport center 0.2, button-sized area 0.2. Elements in the top 15% of the viewport
+// (site navigation, "More Games" links) are never candidates.
+const startButtonScript = `
+(function(minScore) {
+	const exact = ['play', 'start', 'begin', 'play game', 'start game'];
+	const partial = ['play now', 'start now'];
+	const vw = window.innerWidth, vh = window.innerHeight;
+
+	const cssPath = (el) => {
+		if (el.id) return '#' + CSS.escape(el.id);
+		const parts = [];
+		for (; el && el.nodeType === 1 && el !== document.body; el = el.parentElement) {
+			if (el.id) {
+				parts.unshift('#' + CSS.escape(el.id));
+				break;
+			}
+			let index = 1;
+			for (let sib = el.previousElementSibling; sib; sib = sib.previousElementSibling) {
+				if (sib.tagName === el.tagName) index++;
 			}
+			parts.unshift(el.tagName.toLowerCase() + ':nth-of-type(' + index + ')');
 		}
+		return parts.join(' > ');
+	};
+
+	let best = null, candidates = 0;
+	const elements = document.querySelectorAll('button, a[role="button"], div[role="button"], a, span[role="button"], input[type="button"], input[type="submit"], div, span, img, area');
+	for (const el of elements) {
+		const labels = [el.textContent, el.value, el.alt, el.title, el.getAttribute('aria-label')]
+			.map(t => (t || '').toLowerCase().trim());
+		const exactLabel = labels.find(t => exact.includes(t));
+		const partialLabel = labels.find(t => partial.some(p => t.includes(p)));
+		if (!exactLabel && !partialLabel) continue;
+
+		const rect = el.getBoundingClientRect();
+		if (rect.width <= 0 || rect.height <= 0 || el.offsetParent === null) continue;
+		const cx = rect.left + rect.width / 2, cy = rect.top + rect.height / 2;
+		if (cy < vh * 0.15) continue;
+		candidates++;
+
+		let score = exactLabel ? 0.4 : 0.2;
+		const tag = el.tagName.toLowerCase();
+		if (tag === 'button' || tag === 'input' || el.getAttribute('role') === 'button') score += 0.2;
+
+		const dist = Math.hypot(cx - vw / 2, cy - vh / 2) / Math.hypot(vw / 2, vh / 2);
+		score += 0.2 * Math.max(0, 1 - dist);
+
+		// Button-sized: at least 40x20, and not a container covering much of the page
+		const area = rect.width * rect.height;
+		if (rect.width >= 40 && rect.height >= 20 && area <= vw * vh * 0.25) score += 0.2;
+
+		if (!best || score > best.score) {
+			best = { el: el, score: score, text: exactLabel || partialLabel };
+		}
+	}
+
+	const result = { clicked: false, method: '', selector: '', text: '', score: 0, candidates: candidates };
+	if (best) {
+		result.selector = cssPath(best.el);
+		result.text = best.text;
+		result.score = Math.round(best.score * 100) / 100;
+		if (best.score >= minScore) {
+			console.log('[StartButton] Clicking', result.selector, 'score', result.score);
+			best.el.click();
+			result.clicked = true;
+			result.method = 'button';
+			return JSON.stringify(result);
+		}
+		console.log('[StartButton] Best candidate', result.selector, 'scored only', result.score);
 	}
 
 	// Try clicking canvas (many games start on canvas click)
@@ -307,24 +365,37 @@ func (d *UIDetector) ClickStartButton() (bool, error) {
 	if (canvas && canvas.offsetParent !== null) {
 		console.log('[StartButton] No button found, clicking canvas');
 		canvas.click();
-		return true;
+		result.clicked = true;
+		result.method = 'canvas';
+		if (!best) result.selector = cssPath(canvas);
 	}
-
-	console.log('[StartButton] No start button or canvas found');
-	return false;
-})();
+	return JSON.stringify(result);
+})(%f);
 `
 
-	var clicked bool
-	err := chromedp.Run(d.ctx,
-		chromedp.Evaluate(script, &clicked),
-	)
+// ClickStartButton finds the most likely start/play button, scoring each text match
+// (see StartButtonResult), and clicks it if it scores at least MinStartButtonScore.
+// Otherwise it clicks the game canvas, if any. The result says what was clicked and
+// how confident the choice was, so callers can fall back to vision.
+func (d *UIDetector) ClickStartButton() (*StartButtonResult, error) {
+	script := fmt.Sprintf(startButtonScript, MinStartButtonScore)
 
+	var resultJSON string
+	err := runWithTimeout(d.ctx,
+		chromedp.Evaluate(script, &resultJSON),
+	)
 	if err != nil {
-		return false, fmt.Errorf("failed to run start button script: %w", err)
+		return nil, fmt.Errorf("failed to run start button script: %w", err)
 	}
 
-	return clicked, nil
+	var result StartButtonResult
+	if err := json.Unmarshal([]byte(resultJSON), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse start button result: %w", err)
+	}
+	if result.Clicked {
+		recordAction(d.ctx, RecordedAction{Kind: RecordedClick, Selector: result.Selector})
+	}
+	return &result, nil
 }
 
 // HasGameCanvas checks if a game canvas is present
@@ -437,7 +508,7 @@ func (d *UIDetector) AcceptCookieConsent() (bool, error) {
 `
 
 	var clicked bool
-	err := chromedp.Run(d.ctx,
+	err := runWithTimeout(d.ctx,
 		chromedp.Evaluate(script, &clicked),
 	)
 
@@ -509,7 +580,7 @@ func (d *UIDetector) FocusGameCanvas() (bool, error) {
 `
 
 	var resultJSON string
-	err := chromedp.Run(d.ctx,
+	err := runWithTimeout(d.ctx,
 		chromedp.Evaluate(script, &resultJSON),
 	)
 
@@ -607,13 +678,16 @@ func (d *UIDetector) SendKeyboardEventToCanvas(keyCode string) (bool, error) {
 `, keyCode)
 
 	var dispatched bool
-	err := chromedp.Run(d.ctx,
+	err := runWithTimeout(d.ctx,
 		chromedp.Evaluate(script, &dispatched),
 	)
 
 	if err != nil {
 		return false, fmt.Errorf("failed to send keyboard event %s: %w", keyCode, err)
 	}
+	if dispatched {
+		recordAction(d.ctx, RecordedAction{Kind: RecordedKey, Key: keyCode, Target: "canvas"})
+	}
 
 	return dispatched, nil
 }
@@ -678,13 +752,86 @@ func (d *UIDetector) SendKeyboardEventToWindow(keyCode string) (bool, error) {
 `, keyCode, keyCode)
 
 	var dispatched bool
-	err := chromedp.Run(d.ctx,
+	err := runWithTimeout(d.ctx,
 		chromedp.Evaluate(script, &dispatched),
 	)
 
 	if err != nil {
 		return false, fmt.Errorf("failed to send keyboard event %s to window: %w", keyCode, err)
 	}
+	if dispatched {
+		recordAction(d.ctx, RecordedAction{Kind: RecordedKey, Key: keyCode, Target: "window"})
+	}
+
+	return dispatched, nil
+}
+
+// SendKeyDownToWindow presses a key without releasing it, so games that poll held keys
+// (hold Right to run) keep moving until SendKeyUpToWindow. The event reaches window and
+// document, and the canvas too if it has focus.
+func (d *UIDetector) SendKeyDownToWindow(keyCode string) (bool, error) {
+	return d.sendKeyEvent("keydown", keyCode)
+}
+
+// SendKeyUpToWindow releases a key pressed with SendKeyDownToWindow
+func (d *UIDetector) SendKeyUpToWindow(keyCode string) (bool, error) {
+	return d.sendKeyEvent("keyup", keyCode)
+}
+
+// sendKeyEvent dispatches a single keydown or keyup event for keyCode
+func (d *UIDetector) sendKeyEvent(eventType, keyCode string) (bool, error) {
+	script := fmt.Sprintf(`
+(function() {
+	const keyMappings = {
+		'ArrowUp': { key: 'ArrowUp', code: 'ArrowUp', keyCode: 38 },
+		'ArrowDown': { key: 'ArrowDown', code: 'ArrowDown', keyCode: 40 },
+		'ArrowLeft': { key: 'ArrowLeft', code: 'ArrowLeft', keyCode: 37 },
+		'ArrowRight': { key: 'ArrowRight', code: 'ArrowRight', keyCode: 39 },
+		'Space': { key: ' ', code: 'Space', keyCode: 32 },
+		'Enter': { key: 'Enter', code: 'Enter', keyCode: 13 },
+		'Escape': { key: 'Escape', code: 'Escape', keyCode: 27 }
+	};
+
+	const inputKey = %q;
+	const mapping = keyMappings[inputKey] || {
+		key: inputKey,
+		code: 'Key' + inputKey.toUpperCase(),
+		keyCode: inputKey.toUpperCase().charCodeAt(0)
+	};
+
+	const event = new KeyboardEvent(%q, {
+		key: mapping.key,
+		code: mapping.code,
+		keyCode: mapping.keyCode,
+		which: mapping.keyCode,
+		bubbles: true,
+		cancelable: true,
+		composed: true
+	});
+
+	// Dispatched on the focused canvas or the body, the event bubbles up to document and window
+	const active = document.activeElement;
+	const target = active && active.tagName === 'CANVAS' ? active : (document.body || document);
+	target.dispatchEvent(event);
+	return true;
+})();
+`, keyCode, eventType)
+
+	var dispatched bool
+	err := runWithTimeout(d.ctx,
+		chromedp.Evaluate(script, &dispatched),
+	)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to send %s %s: %w", eventType, keyCode, err)
+	}
+	if dispatched {
+		kind := RecordedKeyDown
+		if eventType == "keyup" {
+			kind = RecordedKeyUp
+		}
+		recordAction(d.ctx, RecordedAction{Kind: kind, Key: keyCode, Target: "window"})
+	}
 
 	return dispatched, nil
 }
@@ -752,7 +899,7 @@ func (d *UIDetector) WaitForGameReady(timeoutSeconds int) (bool, error) {
 `, timeoutSeconds)
 
 	var ready bool
-	err := chromedp.Run(d.ctx,
+	err := runWithDeadline(d.ctx, time.Duration(timeoutSeconds)*time.Second+DefaultOperationTimeout,
 		chromedp.Evaluate(script, &ready),
 	)
 
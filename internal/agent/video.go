@@ -3,18 +3,40 @@ package agent
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
+	"github.com/dreamup/qa-agent/internal/logging"
 	"github.com/google/uuid"
 )
 
+// VideoFormat is the container/codec used when saving a recording
+type VideoFormat string
+
+const (
+	// VideoFormatMP4 encodes H.264 in an MP4 container (requires libx264)
+	VideoFormatMP4 VideoFormat = "mp4"
+	// VideoFormatWebM encodes VP9 in a WebM container (requires libvpx-vp9)
+	VideoFormatWebM VideoFormat = "webm"
+)
+
+// ErrFFmpegMissing is returned when ffmpeg can't be found, so callers can skip video work
+var ErrFFmpegMissing = errors.New("ffmpeg not found: install ffmpeg and make sure it is on PATH to record gameplay video")
+
+// FFmpegAvailable reports whether the ffmpeg binary is on PATH
+func FFmpegAvailable() bool {
+	_, err := exec.LookPath("ffmpeg")
+	return err == nil
+}
+
 // VideoRecorder captures browser screencast frames and converts them to video
 type VideoRecorder struct {
 	// Frames stores captured video frames
@@ -37,6 +59,8 @@ type VideoRecorder struct {
 	Quality int
 	// Format frame rate (frames per second)
 	FrameRate int
+	// Format is the output format used by SaveToTemp (mp4 by default)
+	Format VideoFormat
 }
 
 // NewVideoRecorder creates a new video recorder instance
@@ -47,6 +71,7 @@ func NewVideoRecorder(ctx context.Context) *VideoRecorder {
 		ctx:        ctx,
 		Quality:    80,
 		FrameRate:  30,
+		Format:     VideoFormatMP4,
 	}
 }
 
@@ -67,14 +92,33 @@ func (vr *VideoRecorder) StartRecording() error {
 	// Set up listener for screencast frames
 	// This listener will capture all screencast frame events
 	chromedp.ListenTarget(vr.ctx, func(ev interface{}) {
-		if frameEvent, ok := ev.(*page.EventScreencastFrame); ok {
-			vr.handleFrame(frameEvent)
+		switch ev := ev.(type) {
+		case *page.EventScreencastFrame:
+			vr.handleFrame(ev)
+		case *page.EventFrameNavigated:
+			// A top-level navigation (e.g. menu -> game page) can swap the renderer and
+			// silently end the screencast, so re-attach it to the new document.
+			// Listeners must not block, so restart from a goroutine.
+			if ev.Frame.ParentID == "" {
+				go vr.resumeAfterNavigation(ev.Frame.URL)
+			}
 		}
 	})
 
-	// Start screencast with Chrome DevTools Protocol
+	if err := vr.startScreencast(); err != nil {
+		vr.mu.Lock()
+		vr.IsRecording = false
+		vr.mu.Unlock()
+		return fmt.Errorf("failed to start screencast: %w", err)
+	}
+
+	return nil
+}
+
+// startScreencast starts (or restarts) the CDP screencast for the current document
+func (vr *VideoRecorder) startScreencast() error {
 	// Use ActionFunc to capture the execution context for acknowledgments
-	err := chromedp.Run(vr.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+	return chromedp.Run(vr.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
 		// Store the execution context for frame acknowledgments
 		vr.mu.Lock()
 		vr.ackCtx = ctx
@@ -87,15 +131,22 @@ func (vr *VideoRecorder) StartRecording() error {
 			WithEveryNthFrame(1).
 			Do(ctx)
 	}))
+}
 
-	if err != nil {
-		vr.mu.Lock()
-		vr.IsRecording = false
-		vr.mu.Unlock()
-		return fmt.Errorf("failed to start screencast: %w", err)
+// resumeAfterNavigation restarts the screencast after the main frame navigates
+func (vr *VideoRecorder) resumeAfterNavigation(url string) {
+	vr.mu.Lock()
+	recording := vr.IsRecording
+	vr.mu.Unlock()
+	if !recording {
+		return
 	}
 
-	return nil
+	if err := vr.startScreencast(); err != nil {
+		logging.Printf(vr.ctx, "[Video] Warning: Failed to resume screencast after navigation to %s: %v", url, err)
+		return
+	}
+	logging.Printf(vr.ctx, "[Video] Resumed screencast after navigation to %s", url)
 }
 
 // handleFrame processes a screencast frame
@@ -144,7 +195,7 @@ func (vr *VideoRecorder) StopRecording() error {
 
 	// Stop screencast without holding the mutex
 	// This allows handleFrame to complete any pending frame processing
-	if err := chromedp.Run(vr.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+	if err := runWithTimeout(vr.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
 		return page.StopScreencast().Do(ctx)
 	})); err != nil {
 		return fmt.Errorf("failed to stop screencast: %w", err)
@@ -159,14 +210,38 @@ func (vr *VideoRecorder) StopRecording() error {
 	return nil
 }
 
-// SaveAsMP4 converts captured frames to MP4 video using ffmpeg
+// SaveAsMP4 converts captured frames to MP4 video using ffmpeg (libx264)
 func (vr *VideoRecorder) SaveAsMP4(outputPath string) error {
+	return vr.encode(outputPath,
+		"-c:v", "libx264", // H.264 codec
+		"-preset", "fast", // Encoding speed preset
+		"-pix_fmt", "yuv420p", // Pixel format for compatibility
+		"-crf", "23", // Quality (lower is better, 23 is good)
+		"-movflags", "faststart", // Move moov atom to beginning for fast seeking
+	)
+}
+
+// SaveAsWebM converts captured frames to WebM video using ffmpeg (libvpx-vp9)
+func (vr *VideoRecorder) SaveAsWebM(outputPath string) error {
+	return vr.encode(outputPath,
+		"-c:v", "libvpx-vp9", // VP9 codec
+		"-pix_fmt", "yuv420p", // Pixel format for compatibility
+		"-crf", "32", "-b:v", "0", // Constant quality mode
+		"-deadline", "realtime", "-cpu-used", "8", // Favor encoding speed
+	)
+}
+
+// encode writes the captured frames to a temp directory and runs ffmpeg with the given codec args
+func (vr *VideoRecorder) encode(outputPath string, codecArgs ...string) error {
 	vr.mu.Lock()
 	defer vr.mu.Unlock()
 
 	if len(vr.Frames) == 0 {
 		return fmt.Errorf("no frames captured")
 	}
+	if !FFmpegAvailable() {
+		return ErrFFmpegMissing
+	}
 
 	// Create temporary directory for frames
 	tmpDir, err := os.MkdirTemp("", "video_frames_*")
@@ -200,20 +275,15 @@ func (vr *VideoRecorder) SaveAsMP4(outputPath string) error {
 		}
 	}
 
-	// Use ffmpeg to create MP4
-	cmd := exec.Command("ffmpeg",
-		"-y",                                     // Overwrite output file
+	args := []string{
+		"-y",                                       // Overwrite output file
 		"-framerate", fmt.Sprintf("%d", actualFPS), // Input frame rate (calculated from actual capture)
 		"-i", filepath.Join(tmpDir, "frame_%05d.jpg"), // Input pattern
-		"-c:v", "libx264",    // H.264 codec
-		"-preset", "fast",    // Encoding speed preset
-		"-pix_fmt", "yuv420p", // Pixel format for compatibility
-		"-crf", "23",         // Quality (lower is better, 23 is good)
-		"-movflags", "faststart", // Move moov atom to beginning for fast seeking
-		outputPath,
-	)
+	}
+	args = append(args, codecArgs...)
+	args = append(args, outputPath)
 
-	output, err := cmd.CombinedOutput()
+	output, err := exec.Command("ffmpeg", args...).CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, string(output))
 	}
@@ -221,11 +291,17 @@ func (vr *VideoRecorder) SaveAsMP4(outputPath string) error {
 	return nil
 }
 
-// SaveToTemp saves the video to a persistent media directory
+// SaveToTemp saves the video to a persistent media directory in the recorder's Format
 func (vr *VideoRecorder) SaveToTemp() (string, error) {
-	filename := fmt.Sprintf("gameplay_%s_%s.mp4",
+	format := vr.Format
+	if format == "" {
+		format = VideoFormatMP4
+	}
+
+	filename := fmt.Sprintf("gameplay_%s_%s.%s",
 		time.Now().Format("20060102_150405"),
 		uuid.New().String()[:8],
+		format,
 	)
 
 	// Use persistent media directory (defined in evidence.go)
@@ -235,7 +311,15 @@ func (vr *VideoRecorder) SaveToTemp() (string, error) {
 	}
 	filepath := filepath.Join(mediaDir, filename)
 
-	if err := vr.SaveAsMP4(filepath); err != nil {
+	switch format {
+	case VideoFormatMP4:
+		err = vr.SaveAsMP4(filepath)
+	case VideoFormatWebM:
+		err = vr.SaveAsWebM(filepath)
+	default:
+		err = fmt.Errorf("unsupported video format %q", format)
+	}
+	if err != nil {
 		return "", err
 	}
 
@@ -244,6 +328,43 @@ func (vr *VideoRecorder) SaveToTemp() (string, error) {
 	return filename, nil
 }
 
+// videoEncoders maps each output format to the ffmpeg encoder it requires
+var videoEncoders = map[VideoFormat]string{
+	VideoFormatMP4:  "libx264",
+	VideoFormatWebM: "libvpx-vp9",
+}
+
+// SelectVideoFormat checks which encoders the installed ffmpeg supports and returns
+// preferred if available, otherwise the other supported format. It returns ErrFFmpegMissing
+// if ffmpeg isn't on PATH, or an error if it has neither libx264 nor libvpx-vp9.
+func SelectVideoFormat(preferred VideoFormat) (VideoFormat, error) {
+	if preferred == "" {
+		preferred = VideoFormatMP4
+	}
+	if _, ok := videoEncoders[preferred]; !ok {
+		return "", fmt.Errorf("unsupported video format %q (use mp4 or webm)", preferred)
+	}
+
+	if !FFmpegAvailable() {
+		return "", ErrFFmpegMissing
+	}
+	output, err := exec.Command("ffmpeg", "-hide_banner", "-encoders").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list ffmpeg encoders: %w", err)
+	}
+	encoders := string(output)
+
+	if strings.Contains(encoders, videoEncoders[preferred]) {
+		return preferred, nil
+	}
+	for _, format := range []VideoFormat{VideoFormatMP4, VideoFormatWebM} {
+		if strings.Contains(encoders, videoEncoders[format]) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("ffmpeg has neither libx264 (mp4) nor libvpx-vp9 (webm) encoders")
+}
+
 // GetDuration returns the recording duration
 func (vr *VideoRecorder) GetDuration() time.Duration {
 	vr.mu.Lock()
@@ -263,3 +384,98 @@ func (vr *VideoRecorder) GetFrameCount() int {
 
 	return len(vr.Frames)
 }
+
+const (
+	// DefaultGIFFrames is the default number of frames in a GIF thumbnail (~10 fps over 3 seconds)
+	DefaultGIFFrames = 30
+	// gifThumbnailSeconds is the playback length of a GIF thumbnail
+	gifThumbnailSeconds = 3
+	// gifThumbnailWidth is the width of a GIF thumbnail in pixels (height keeps the aspect ratio)
+	gifThumbnailWidth = 320
+)
+
+// gifFilter scales frames down and builds an optimized palette in a single ffmpeg pass
+var gifFilter = fmt.Sprintf(
+	"scale=%d:-1:flags=lanczos,split[s0][s1];[s0]palettegen=stats_mode=diff[p];[s1][p]paletteuse=dither=bayer",
+	gifThumbnailWidth,
+)
+
+// SaveAsGIF writes a short looping GIF thumbnail of the recording. Up to maxFrames frames
+// are sampled evenly across the whole recording and played back over ~3 seconds.
+func (vr *VideoRecorder) SaveAsGIF(outputPath string, maxFrames int) error {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+
+	if len(vr.Frames) == 0 {
+		return fmt.Errorf("no frames captured")
+	}
+	if !FFmpegAvailable() {
+		return ErrFFmpegMissing
+	}
+	if maxFrames <= 0 {
+		maxFrames = DefaultGIFFrames
+	}
+	if maxFrames > len(vr.Frames) {
+		maxFrames = len(vr.Frames)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "gif_frames_*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Downsample to evenly spaced frames, numbered sequentially for ffmpeg's input pattern
+	for i := 0; i < maxFrames; i++ {
+		frame := vr.Frames[i*len(vr.Frames)/maxFrames]
+		framePath := filepath.Join(tmpDir, fmt.Sprintf("frame_%05d.jpg", i))
+		if err := os.WriteFile(framePath, frame, 0644); err != nil {
+			return fmt.Errorf("failed to write frame %d: %w", i, err)
+		}
+	}
+
+	frameRate := maxFrames / gifThumbnailSeconds
+	if frameRate < 1 {
+		frameRate = 1
+	}
+
+	output, err := exec.Command("ffmpeg",
+		"-y",
+		"-framerate", fmt.Sprintf("%d", frameRate),
+		"-i", filepath.Join(tmpDir, "frame_%05d.jpg"),
+		"-filter_complex", gifFilter,
+		"-loop", "0", // Loop forever
+		outputPath,
+	).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, string(output))
+	}
+
+	return nil
+}
+
+// GIFThumbnailName returns the filename of the GIF thumbnail for a saved video
+func GIFThumbnailName(videoFilename string) string {
+	return strings.TrimSuffix(videoFilename, filepath.Ext(videoFilename)) + ".gif"
+}
+
+// GenerateGIFFromVideo builds a GIF thumbnail from an already-encoded video, for recordings
+// whose frames are no longer in memory. It uses the last ~3 seconds, which usually show gameplay.
+func GenerateGIFFromVideo(videoPath, outputPath string) error {
+	if !FFmpegAvailable() {
+		return ErrFFmpegMissing
+	}
+
+	output, err := exec.Command("ffmpeg",
+		"-y",
+		"-sseof", fmt.Sprintf("-%d", gifThumbnailSeconds),
+		"-i", videoPath,
+		"-filter_complex", "fps=10,"+gifFilter,
+		"-loop", "0",
+		outputPath,
+	).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, string(output))
+	}
+	return nil
+}
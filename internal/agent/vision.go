@@ -2,7 +2,6 @@ package agent
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -16,13 +15,15 @@ import (
 type VisionDetector struct {
 	ctx    context.Context
 	client *openai.Client
+	// maxImageDim is the longest side of screenshots sent to vision (0 = full size)
+	maxImageDim int
 }
 
 // ClickTarget represents a detected clickable element with its coordinates
 type ClickTarget struct {
-	// X coordinate (0-1280 range based on screenshot width)
+	// X coordinate (0 to screenshot width)
 	X int
-	// Y coordinate (0-720 range based on screenshot height)
+	// Y coordinate (0 to screenshot height)
 	Y int
 	// Description of what was detected (e.g., "Start Game button")
 	Description string
@@ -38,17 +39,30 @@ func NewVisionDetector(ctx context.Context) (*VisionDetector, error) {
 	}
 
 	client := openai.NewClient(apiKey)
+	maxImageDim, err := VisionMaxDimensionFromEnv()
+	if err != nil {
+		return nil, err
+	}
 
 	return &VisionDetector{
-		ctx:    ctx,
-		client: client,
+		ctx:         ctx,
+		client:      client,
+		maxImageDim: maxImageDim,
 	}, nil
 }
 
 // DetectStartButton uses GPT-4o vision to find the start button and return click coordinates
 func (v *VisionDetector) DetectStartButton(screenshot *Screenshot) (*ClickTarget, error) {
-	// Encode screenshot to base64
-	imageBase64 := base64.StdEncoding.EncodeToString(screenshot.Data)
+	// Downscale and encode screenshot to base64; vision answers in the downscaled image's
+	// pixels, which are mapped back to the screenshot's below
+	sent := scaledForVision(v.ctx, screenshot, v.maxImageDim)
+	imageURL := sent.DataURL()
+
+	release, err := AcquireLLMSlot(v.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
 	// Create vision request
 	resp, err := v.client.CreateChatCompletion(
@@ -61,9 +75,9 @@ func (v *VisionDetector) DetectStartButton(screenshot *Screenshot) (*ClickTarget
 					MultiContent: []openai.ChatMessagePart{
 						{
 							Type: openai.ChatMessagePartTypeText,
-							Text: `You are analyzing a game screenshot to find the start button or play button.
+							Text: fmt.Sprintf(`You are analyzing a game screenshot to find the start button or play button.
 
-The screenshot resolution is 1280x720 pixels with origin (0,0) at TOP-LEFT corner.
+The screenshot resolution is %dx%d pixels with origin (0,0) at TOP-LEFT corner.
 
 CRITICAL: You MUST return the EXACT pixel coordinates where the button appears in the image.
 - Measure from the TOP-LEFT corner (0,0)
@@ -91,14 +105,17 @@ Look for:
 IMPORTANT:
 - Count pixels carefully from top-left
 - If button is in upper-left, x and y should be SMALL numbers (like 100-200)
-- If button is in center, x should be near 640, y near 360
-- If button is in bottom-right, x near 1280, y near 720
+- If button is in center, x should be near %d, y near %d
+- If button is in bottom-right, x near %d, y near %d
 - DO NOT just guess the center - measure the actual button location`,
+								sent.Width, sent.Height,
+								sent.Width/2, sent.Height/2,
+								sent.Width, sent.Height),
 						},
 						{
 							Type: openai.ChatMessagePartTypeImageURL,
 							ImageURL: &openai.ChatMessageImageURL{
-								URL:    fmt.Sprintf("data:image/png;base64,%s", imageBase64),
+								URL:    imageURL,
 								Detail: openai.ImageURLDetailAuto,
 							},
 						},
@@ -142,8 +159,13 @@ IMPORTANT:
 		return nil, fmt.Errorf("no start button detected with sufficient confidence")
 	}
 
+	if sent != screenshot && sent.Width > 0 && sent.Height > 0 {
+		result.X = result.X * screenshot.Width / sent.Width
+		result.Y = result.Y * screenshot.Height / sent.Height
+	}
+
 	// Validate coordinates are within bounds (must be strictly less than width/height)
-	// 1280x720 means valid coords are 0-1279 for X and 0-719 for Y
+	// e.g. 1280x720 means valid coords are 0-1279 for X and 0-719 for Y
 	if result.X < 0 || result.X >= screenshot.Width || result.Y < 0 || result.Y >= screenshot.Height {
 		return nil, fmt.Errorf("detected coordinates out of bounds: (%d, %d) for viewport %dx%d",
 			result.X, result.Y, screenshot.Width, screenshot.Height)
@@ -159,16 +181,18 @@ IMPORTANT:
 
 // ClickAt clicks at specific pixel coordinates using chromedp
 func (v *VisionDetector) ClickAt(x, y int) error {
+	viewport := ViewportFromContext(v.ctx)
+
 	// JavaScript to click at specific coordinates
 	script := fmt.Sprintf(`
 (function() {
     console.log('[VisionClick] Screenshot coordinates:', %d, %d);
     console.log('[VisionClick] Viewport size:', window.innerWidth, 'x', window.innerHeight);
 
-    // CRITICAL: The screenshot was taken with viewport set to 1280x720
+    // CRITICAL: The screenshot was taken with the emulated viewport size
     // We need to check if current viewport matches and calculate scale if needed
-    const screenshotWidth = 1280;
-    const screenshotHeight = 720;
+    const screenshotWidth = %d;
+    const screenshotHeight = %d;
     const currentWidth = window.innerWidth;
     const currentHeight = window.innerHeight;
 
@@ -291,10 +315,10 @@ func (v *VisionDetector) ClickAt(x, y int) error {
         scaleFactor: { x: scaleX, y: scaleY }
     });
 })();
-`, x, y, x, y, x, y)
+`, x, y, viewport.Width, viewport.Height, x, y, x, y)
 
 	var resultJSON string
-	err := chromedp.Run(v.ctx, chromedp.Evaluate(script, &resultJSON))
+	err := runWithTimeout(v.ctx, chromedp.Evaluate(script, &resultJSON))
 	if err != nil {
 		return fmt.Errorf("failed to execute click: %w", err)
 	}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultVisionCacheSize is how many screenshots' gameplay detections a VisionDOMDetector remembers
+const DefaultVisionCacheSize = 32
+
+// visionCache is an LRU of gameplay detections keyed by screenshot hash, so screens the
+// session has already seen (e.g. a static menu between click retries) skip the vision API
+type visionCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front = most recently used
+	entries map[string]*list.Element
+	hits    int
+	misses  int
+}
+
+type visionCacheEntry struct {
+	key    string
+	action GameplayAction
+}
+
+func newVisionCache(size int) *visionCache {
+	return &visionCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns a copy of the cached action for key
+func (c *visionCache) get(key string) (*GameplayAction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+	action := elem.Value.(*visionCacheEntry).action
+	return &action, true
+}
+
+// put caches action for key, evicting the least recently used entry when full
+func (c *visionCache) put(key string, action *GameplayAction) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*visionCacheEntry).action = *action
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&visionCacheEntry{key: key, action: *action})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*visionCacheEntry).key)
+	}
+}
+
+// stats returns the hit and miss counts
+func (c *visionCache) stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
@@ -1,23 +1,22 @@
 package agent
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 	"image/png"
-	"log"
 	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/chromedp/chromedp"
+	"github.com/dreamup/qa-agent/internal/logging"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/math/fixed"
@@ -28,32 +27,118 @@ import (
 type VisionDOMDetector struct {
 	ctx    context.Context
 	client *openai.Client
+	// model is used for gameplay state detection (see SetModel)
+	model string
+	// cache remembers gameplay detections by screenshot hash
+	cache *visionCache
+	// fallback answers when OpenAI fails (nil = no fallback, see VISION_FALLBACK)
+	fallback *visionProvider
+	// grid is the labeled grid overlaid on screenshots for vision to answer in (see SetGridSize)
+	grid GridSize
+	// maxImageDim is the longest side of screenshots sent to vision (see SetMaxImageDimension)
+	maxImageDim int
 }
 
 // NewVisionDOMDetector creates a new vision-based DOM detector
 func NewVisionDOMDetector(ctx context.Context) (*VisionDOMDetector, error) {
 	// Use OpenAI (more accurate for spatial reasoning)
-	// Groq's Llama 4 Scout is faster but less accurate with grid coordinates
+	// Groq's Llama 4 Scout is faster but less accurate with grid coordinates, so it's only
+	// used as a fallback (VISION_FALLBACK=groq)
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return nil, fmt.Errorf("OPENAI_API_KEY environment variable required")
 	}
 	client := openai.NewClient(apiKey)
 
+	fallback, err := visionFallbackFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	grid, err := GridSizeFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	maxImageDim, err := VisionMaxDimensionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
 	return &VisionDOMDetector{
-		ctx:    ctx,
-		client: client,
+		ctx:         ctx,
+		client:      client,
+		model:       VisionModelFromEnv(),
+		cache:       newVisionCache(DefaultVisionCacheSize),
+		fallback:    fallback,
+		grid:        grid,
+		maxImageDim: maxImageDim,
 	}, nil
 }
 
+// SetModel changes the OpenAI model used for gameplay state detection (e.g. gpt-4o-mini, gpt-5)
+func (v *VisionDOMDetector) SetModel(model string) error {
+	if err := validateVisionModel(model); err != nil {
+		return err
+	}
+	v.model = model
+	return nil
+}
+
+// Model returns the OpenAI model used for gameplay state detection
+func (v *VisionDOMDetector) Model() string {
+	return v.model
+}
+
+// SetGridSize changes the grid vision answers in. Finer grids click more precisely on
+// dense menus but make the overlay harder to read and the prompt longer.
+func (v *VisionDOMDetector) SetGridSize(grid GridSize) error {
+	if err := grid.Validate(); err != nil {
+		return err
+	}
+	v.grid = grid
+	return nil
+}
+
+// GridSize returns the grid vision answers in
+func (v *VisionDOMDetector) GridSize() GridSize {
+	return v.grid
+}
+
+// SetMaxImageDimension sets the longest side, in pixels, screenshots are downscaled to
+// before they're sent to vision (0 sends them full size). Smaller images make requests
+// faster and cheaper; the full-size screenshots are still used for reports and clicks.
+func (v *VisionDOMDetector) SetMaxImageDimension(maxDim int) error {
+	if err := validateVisionMaxDimension(maxDim); err != nil {
+		return err
+	}
+	v.maxImageDim = maxDim
+	return nil
+}
+
+// MaxImageDimension returns the longest side screenshots are downscaled to for vision
+func (v *VisionDOMDetector) MaxImageDimension() int {
+	return v.maxImageDim
+}
+
+// CacheStats returns how many DetectGameplayState calls were answered from the
+// screenshot cache (hits) and how many went to the vision API (misses)
+func (v *VisionDOMDetector) CacheStats() (hits, misses int) {
+	return v.cache.stats()
+}
+
 // DetectStartButtonDescription uses vision to describe what the start button looks like
 func (v *VisionDOMDetector) DetectStartButtonDescription(screenshot *Screenshot) (string, error) {
 	// Encode screenshot to base64
-	imageBase64 := base64.StdEncoding.EncodeToString(screenshot.Data)
+	imageURL := scaledForVision(v.ctx, screenshot, v.maxImageDim).DataURL()
+
+	release, err := AcquireLLMSlot(v.ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
 
 	// Create vision request
-	resp, err := v.client.CreateChatCompletion(
-		context.Background(),
+	resp, err := v.createChatCompletion(
+		v.ctx,
 		openai.ChatCompletionRequest{
 			Model: openai.GPT4oMini,
 			Messages: []openai.ChatCompletionMessage{
@@ -84,14 +169,14 @@ Return the EXACT text you see on the button, in the same case.`,
 						{
 							Type: openai.ChatMessagePartTypeImageURL,
 							ImageURL: &openai.ChatMessageImageURL{
-								URL:    fmt.Sprintf("data:image/png;base64,%s", imageBase64),
+								URL:    imageURL,
 								Detail: openai.ImageURLDetailAuto,
 							},
 						},
 					},
 				},
 			},
-			MaxCompletionTokens: 500, // GPT-5 needs more tokens than GPT-4o
+			MaxCompletionTokens: 500,
 		},
 	)
 
@@ -129,6 +214,11 @@ Return the EXACT text you see on the button, in the same case.`,
 
 // ClickButtonByText finds and clicks a button by its text content using DOM queries
 func (v *VisionDOMDetector) ClickButtonByText(buttonText string) error {
+	return clickButtonByText(v.ctx, buttonText)
+}
+
+// clickButtonByText clicks the smallest visible element whose text matches buttonText
+func clickButtonByText(ctx context.Context, buttonText string) error {
 	script := fmt.Sprintf(`
 (function() {
 	const searchText = %q;
@@ -203,7 +293,7 @@ func (v *VisionDOMDetector) ClickButtonByText(buttonText string) error {
 `, buttonText)
 
 	var resultJSON string
-	err := chromedp.Run(v.ctx, chromedp.Evaluate(script, &resultJSON))
+	err := runWithTimeout(ctx, chromedp.Evaluate(script, &resultJSON))
 	if err != nil {
 		return fmt.Errorf("failed to execute click: %w", err)
 	}
@@ -224,6 +314,7 @@ func (v *VisionDOMDetector) ClickButtonByText(buttonText string) error {
 		return fmt.Errorf("click failed: %s", result.Reason)
 	}
 
+	recordAction(ctx, RecordedAction{Kind: RecordedClick, Text: buttonText})
 	return nil
 }
 
@@ -254,64 +345,85 @@ type GameplayAction struct {
 	GridCell     string // Grid cell reference (e.g., "J7") for vision-based clicking
 }
 
-// DetectGameplayState analyzes screenshot to determine if game has started or if action is needed
+// DetectGameplayState analyzes screenshot to determine if game has started or if action is needed.
+// Results are cached by screenshot hash, so a screen seen before in this session costs no API call.
 func (v *VisionDOMDetector) DetectGameplayState(screenshot *Screenshot, gameMechanics string) (*GameplayAction, error) {
+	hash := screenshot.Hash()
+	if action, ok := v.cache.get(hash); ok {
+		hits, misses := v.cache.stats()
+		logging.Printf(v.ctx, "[Vision Cache] Hit for screenshot %.12s (hits: %d, misses: %d)", hash, hits, misses)
+		return action, nil
+	}
+
 	// Apply grid overlay to screenshot for more reliable coordinate detection
-	// Using 20 columns (A-T) and 12 rows (1-12) = 64x60 pixel cells for 1280x720
-	gridCols := 20
-	gridRows := 12
-	griddedScreenshot, err := AddGridOverlay(screenshot, gridCols, gridRows)
+	// The default 20 columns (A-T) and 12 rows (1-12) = 64x60 pixel cells at 1280x720
+	// The overlay is drawn after downscaling so its labels stay sharp
+	gridCols := v.grid.Cols
+	gridRows := v.grid.Rows
+	visionScreenshot := scaledForVision(v.ctx, screenshot, v.maxImageDim)
+	griddedScreenshot, err := AddGridOverlay(visionScreenshot, gridCols, gridRows)
 	if err != nil {
-		log.Printf("[Vision Grid] Warning: Failed to add grid overlay, using original: %v", err)
-		griddedScreenshot = screenshot
+		logging.Printf(v.ctx, "[Vision Grid] Warning: Failed to add grid overlay, using original: %v", err)
+		griddedScreenshot = visionScreenshot
 	} else {
-		log.Printf("[Vision Grid] Grid overlay applied: %d columns x %d rows", gridCols, gridRows)
+		logging.Printf(v.ctx, "[Vision Grid] Grid overlay applied: %d columns x %d rows", gridCols, gridRows)
 	}
 
 	// Encode screenshot with grid to base64
-	imageBase64 := base64.StdEncoding.EncodeToString(griddedScreenshot.Data)
+	imageURL := griddedScreenshot.DataURL()
 
 	// Build game mechanics section if provided
 	var mechanicsSection string
 	if gameMechanics != "" {
 		mechanicsSection = fmt.Sprintf("\n\nGAME MECHANICS:\n%s\n\nUse these mechanics to understand how to interact with the game once gameplay has started.\n", gameMechanics)
-		log.Printf("[Vision Game Mechanics] Provided: %s", gameMechanics)
+		logging.Printf(v.ctx, "[Vision Game Mechanics] Provided: %s", gameMechanics)
 	}
 
-	// Simplified prompt for GPT-5 (uses fewer tokens)
+	// Keep the prompt short to save tokens on every detection attempt
+	// Hints are given as screen regions, so they name the same places at any grid size
+	playCell, playCellRight := v.grid.cellAt(0.47, 0.79), v.grid.cellAt(0.52, 0.79)
 	prompt := fmt.Sprintf(`Game screenshot analysis. Grid overlay: %dx%d (A-%s, 1-%d).
 
 Is game playing? If not, what button to click?
-- ONLY click PLAY/START/level numbers (rows 7-12)
-- IGNORE "MORE GAMES", top nav (rows 1-3)
-- Angry Birds PLAY: use J10 or K10
+- ONLY click PLAY/START/level numbers (rows %d-%d)
+- IGNORE "MORE GAMES", top nav (rows 1-%d)
+- Angry Birds PLAY: use %s or %s
+%s
 %s
 JSON response:
-{"game_started": bool, "action_needed": bool, "button_text": "text", "grid_cell": "J10", "description": "brief"}
+{"game_started": bool, "action_needed": bool, "button_text": "text", "grid_cell": "%s", "description": "brief"}
 
 Examples:
-- Menu: {"game_started": false, "action_needed": true, "button_text": "PLAY", "grid_cell": "J10", "description": "main menu"}
-- Levels: {"game_started": false, "action_needed": true, "button_text": "1", "grid_cell": "D4", "description": "level select"}
+- Menu: {"game_started": false, "action_needed": true, "button_text": "PLAY", "grid_cell": "%s", "description": "main menu"}
+- Levels: {"game_started": false, "action_needed": true, "button_text": "1", "grid_cell": "%s", "description": "level select"}
 - Playing: {"game_started": true, "action_needed": false, "button_text": "", "grid_cell": "", "description": "gameplay active"}`,
-		gridCols, gridRows, string(rune('A'+gridCols-1)), gridRows, mechanicsSection)
+		gridCols, gridRows, gridColumnLabel(gridCols-1), gridRows,
+		gridRows/2+1, gridRows, max(1, gridRows/4), playCell, playCellRight, cellOffsetPrompt, mechanicsSection,
+		playCell, playCell, v.grid.cellAt(0.17, 0.29))
 
 	// ===== DETAILED LOGGING =====
-	log.Printf("[Vision Request] ========================================")
-	log.Printf("[Vision Request] Prompt being sent to LLM:")
-	log.Printf("[Vision Request] %s", prompt)
-	log.Printf("[Vision Request] Screenshot metadata: %dx%d, %d bytes", screenshot.Width, screenshot.Height, len(screenshot.Data))
-	log.Printf("[Vision Request] Base64 image size: %d chars", len(imageBase64))
-	// Use GPT-4o for better spatial accuracy
-	modelName := openai.GPT4o
-
-	log.Printf("[Vision Request] Model: %s", modelName)
-	log.Printf("[Vision Request] ========================================")
+	logging.Printf(v.ctx, "[Vision Request] ========================================")
+	logging.Printf(v.ctx, "[Vision Request] Prompt being sent to LLM:")
+	logging.Printf(v.ctx, "[Vision Request] %s", prompt)
+	logging.Printf(v.ctx, "[Vision Request] Screenshot metadata: %dx%d, %d bytes (sent as %dx%d, %d bytes)",
+		screenshot.Width, screenshot.Height, len(screenshot.Data), griddedScreenshot.Width, griddedScreenshot.Height, len(griddedScreenshot.Data))
+	logging.Printf(v.ctx, "[Vision Request] Image data URL size: %d chars", len(imageURL))
+	modelName := v.model
+
+	logging.Printf(v.ctx, "[Vision Request] Model: %s", modelName)
+	logging.Printf(v.ctx, "[Vision Request] ========================================")
+
+	release, err := AcquireLLMSlot(v.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
 	// Create context with 30 second timeout (vision API with large images can be slow)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(v.ctx, 30*time.Second)
 	defer cancel()
 
-	resp, err := v.client.CreateChatCompletion(
+	resp, err := v.createChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
 			Model: modelName,
@@ -326,39 +438,39 @@ Examples:
 						{
 							Type: openai.ChatMessagePartTypeImageURL,
 							ImageURL: &openai.ChatMessageImageURL{
-								URL: fmt.Sprintf("data:image/png;base64,%s", imageBase64),
+								URL: imageURL,
 							},
 						},
 					},
 				},
 			},
-			MaxCompletionTokens: 800, // GPT-5 needs more tokens than GPT-4o
+			MaxCompletionTokens: visionMaxTokens(modelName, 800),
 		},
 	)
 
 	if err != nil {
-		log.Printf("[Vision Response] ERROR: %v", err)
+		logging.Printf(v.ctx, "[Vision Response] ERROR: %v", err)
 		return nil, fmt.Errorf("vision API call failed: %w", err)
 	}
 
 	if len(resp.Choices) == 0 {
-		log.Printf("[Vision Response] ERROR: No choices in response")
+		logging.Printf(v.ctx, "[Vision Response] ERROR: No choices in response")
 		return nil, fmt.Errorf("no response from vision API")
 	}
 
 	// Debug: log finish reason and refusal
-	log.Printf("[Vision Debug] FinishReason: %s", resp.Choices[0].FinishReason)
+	logging.Printf(v.ctx, "[Vision Debug] FinishReason: %s", resp.Choices[0].FinishReason)
 	if resp.Choices[0].Message.Refusal != "" {
-		log.Printf("[Vision Debug] Refusal: %s", resp.Choices[0].Message.Refusal)
+		logging.Printf(v.ctx, "[Vision Debug] Refusal: %s", resp.Choices[0].Message.Refusal)
 	}
 
 	responseText := strings.TrimSpace(resp.Choices[0].Message.Content)
 
 	// ===== DETAILED RESPONSE LOGGING =====
-	log.Printf("[Vision Response] ========================================")
-	log.Printf("[Vision Response] Raw response from LLM:")
-	log.Printf("[Vision Response] %s", responseText)
-	log.Printf("[Vision Response] ========================================")
+	logging.Printf(v.ctx, "[Vision Response] ========================================")
+	logging.Printf(v.ctx, "[Vision Response] Raw response from LLM:")
+	logging.Printf(v.ctx, "[Vision Response] %s", responseText)
+	logging.Printf(v.ctx, "[Vision Response] ========================================")
 
 	// Parse JSON response
 	var result struct {
@@ -392,8 +504,8 @@ Examples:
 	jsonText = strings.TrimSpace(jsonText)
 
 	if err := json.Unmarshal([]byte(jsonText), &result); err != nil {
-		log.Printf("[Vision Parse] ERROR: Failed to parse JSON: %v", err)
-		log.Printf("[Vision Parse] Attempted to parse: %s", jsonText)
+		logging.Printf(v.ctx, "[Vision Parse] ERROR: Failed to parse JSON: %v", err)
+		logging.Printf(v.ctx, "[Vision Parse] Attempted to parse: %s", jsonText)
 		return nil, fmt.Errorf("failed to parse vision response: %w (response: %s)", err, jsonText)
 	}
 
@@ -401,23 +513,23 @@ Examples:
 	var clickX, clickY int
 	if result.ActionNeeded && result.GridCell != "" {
 		// Parse grid cell (e.g., "J7" -> column="J", row=7)
-		gridCell, parseErr := parseGridCell(result.GridCell)
+		gridCell, parseErr := parseGridCell(result.GridCell, gridCols, gridRows)
 		if parseErr != nil {
-			log.Printf("[Vision Grid] Warning: Failed to parse grid cell '%s': %v", result.GridCell, parseErr)
+			logging.Printf(v.ctx, "[Vision Grid] Warning: Failed to parse grid cell '%s': %v", result.GridCell, parseErr)
 			// Fall back to center of screen
 			clickX = screenshot.Width / 2
 			clickY = screenshot.Height / 2
 		} else {
 			clickX, clickY = gridCell.ToPixelCoordinates(gridCols, gridRows, screenshot.Width, screenshot.Height)
-			log.Printf("[Vision Grid] Converted grid cell %s to pixel coordinates (%d, %d)", result.GridCell, clickX, clickY)
+			logging.Printf(v.ctx, "[Vision Grid] Converted grid cell %s to pixel coordinates (%d, %d)", result.GridCell, clickX, clickY)
 		}
 	}
 
 	// Log the parsed results
-	log.Printf("[Vision Parsed] GameStarted: %v, ActionNeeded: %v, ButtonText: '%s', GridCell: '%s', Coords: (%d, %d), Description: '%s'",
+	logging.Printf(v.ctx, "[Vision Parsed] GameStarted: %v, ActionNeeded: %v, ButtonText: '%s', GridCell: '%s', Coords: (%d, %d), Description: '%s'",
 		result.GameStarted, result.ActionNeeded, result.ButtonText, result.GridCell, clickX, clickY, result.Description)
 
-	return &GameplayAction{
+	action := &GameplayAction{
 		GameStarted:  result.GameStarted,
 		ActionNeeded: result.ActionNeeded,
 		ButtonText:   result.ButtonText,
@@ -425,13 +537,15 @@ Examples:
 		ClickY:       clickY,
 		GridCell:     result.GridCell,
 		Description:  result.Description,
-	}, nil
+	}
+	v.cache.put(hash, action)
+	return action, nil
 }
 
 // SaveScreenshotWithClickMarker saves a screenshot with a visual marker showing where we clicked
 func SaveScreenshotWithClickMarker(screenshot *Screenshot, x, y int, label string) (string, error) {
-	// Decode PNG image
-	img, err := png.Decode(bytes.NewReader(screenshot.Data))
+	// Decode PNG or JPEG image
+	img, err := decodeImage(screenshot.Data)
 	if err != nil {
 		return "", fmt.Errorf("failed to decode screenshot: %w", err)
 	}
@@ -441,35 +555,8 @@ func SaveScreenshotWithClickMarker(screenshot *Screenshot, x, y int, label strin
 	rgba := image.NewRGBA(bounds)
 	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
 
-	// Draw a red circle at the click point
-	red := color.RGBA{255, 0, 0, 255}
-	radius := 20
-
-	// Draw circle outline
-	for angle := 0; angle < 360; angle++ {
-		rad := float64(angle) * math.Pi / 180
-		for r := radius - 3; r <= radius; r++ {
-			dx := int(float64(r) * math.Cos(rad))
-			dy := int(float64(r) * math.Sin(rad))
-			px := x + dx
-			py := y + dy
-			if px >= 0 && px < bounds.Max.X && py >= 0 && py < bounds.Max.Y {
-				rgba.Set(px, py, red)
-			}
-		}
-	}
-
-	// Draw crosshair
-	for i := -25; i <= 25; i++ {
-		// Horizontal line
-		if x+i >= 0 && x+i < bounds.Max.X {
-			rgba.Set(x+i, y, red)
-		}
-		// Vertical line
-		if y+i >= 0 && y+i < bounds.Max.Y {
-			rgba.Set(x, y+i, red)
-		}
-	}
+	// Draw a red circle and crosshair at the click point
+	drawClickMarker(rgba, x, y, clickMarkerColor)
 
 	// Save to temp file
 	filename := fmt.Sprintf("click_marker_%s_%d_%d.png", label, x, y)
@@ -488,76 +575,219 @@ func SaveScreenshotWithClickMarker(screenshot *Screenshot, x, y int, label strin
 	return filepath, nil
 }
 
+// GridSize is the resolution of the labeled grid overlaid on screenshots, in which vision
+// models give coordinates
+type GridSize struct {
+	Cols int
+	Rows int
+}
+
+// DefaultGridSize is 20 columns (A-T) by 12 rows, 64x60 pixel cells at 1280x720
+var DefaultGridSize = GridSize{Cols: 20, Rows: 12}
+
+// Grid size limits: smaller grids can't express the prompts' example cells, and larger
+// ones have labels too small to read (52 columns ends at AZ)
+const (
+	MinGridCols = 10
+	MinGridRows = 8
+	MaxGridCols = 52
+	MaxGridRows = 40
+)
+
+// Validate checks the grid is within the supported limits
+func (s GridSize) Validate() error {
+	if s.Cols < MinGridCols || s.Cols > MaxGridCols {
+		return fmt.Errorf("grid columns must be between %d and %d, got %d", MinGridCols, MaxGridCols, s.Cols)
+	}
+	if s.Rows < MinGridRows || s.Rows > MaxGridRows {
+		return fmt.Errorf("grid rows must be between %d and %d, got %d", MinGridRows, MaxGridRows, s.Rows)
+	}
+	return nil
+}
+
+// String returns the grid size in "20x12" format
+func (s GridSize) String() string {
+	return fmt.Sprintf("%dx%d", s.Cols, s.Rows)
+}
+
+// cellAt returns the label of the cell containing a point given as fractions of the
+// screen's width and height, e.g. (0.5, 0.5) for the center
+func (s GridSize) cellAt(fx, fy float64) string {
+	col := min(int(fx*float64(s.Cols)), s.Cols-1)
+	row := min(int(fy*float64(s.Rows)), s.Rows-1)
+	return GridCell{Column: gridColumnLabel(col), Row: row + 1}.String()
+}
+
+// ParseGridSize parses a grid size like "32x18" (columns x rows)
+func ParseGridSize(s string) (GridSize, error) {
+	cols, rows, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	if !ok {
+		return GridSize{}, fmt.Errorf("invalid grid size %q (want COLSxROWS, e.g. 32x18)", s)
+	}
+	var grid GridSize
+	var err error
+	if grid.Cols, err = strconv.Atoi(strings.TrimSpace(cols)); err != nil {
+		return GridSize{}, fmt.Errorf("invalid grid columns in %q: %w", s, err)
+	}
+	if grid.Rows, err = strconv.Atoi(strings.TrimSpace(rows)); err != nil {
+		return GridSize{}, fmt.Errorf("invalid grid rows in %q: %w", s, err)
+	}
+	if err := grid.Validate(); err != nil {
+		return GridSize{}, err
+	}
+	return grid, nil
+}
+
+// GridSizeFromEnv returns the VISION_GRID env var (e.g. "32x18"), or DefaultGridSize if
+// it's unset
+func GridSizeFromEnv() (GridSize, error) {
+	s := os.Getenv("VISION_GRID")
+	if strings.TrimSpace(s) == "" {
+		return DefaultGridSize, nil
+	}
+	grid, err := ParseGridSize(s)
+	if err != nil {
+		return GridSize{}, fmt.Errorf("VISION_GRID: %w", err)
+	}
+	return grid, nil
+}
+
 // GridCell represents a cell in the coordinate grid system
 type GridCell struct {
-	Column string // A, B, C, etc.
+	Column string // A, B, C, etc., continuing AA, AB, etc. past Z
 	Row    int    // 1, 2, 3, etc.
+	// OffsetX and OffsetY aim away from the cell's center, in fractions of a cell
+	// (-0.5 to 0.5; positive is right and down), e.g. "J10+0.3,-0.2"
+	OffsetX float64
+	OffsetY float64
+}
+
+// maxCellOffset is how far an offset can move from a cell's center: to its edge
+const maxCellOffset = 0.5
+
+// cellOffsetPrompt tells vision models how to aim within a cell
+const cellOffsetPrompt = `Cells may add an offset from the cell's center in fractions of a cell (-0.5 to 0.5, + is right/down): "J10+0.3,-0.2" is right of and above J10's center.`
+
+// gridColumnLabel converts a 0-based column index to its letters (0=A, 25=Z, 26=AA, ...)
+func gridColumnLabel(index int) string {
+	label := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		label = string(rune('A'+(index-1)%26)) + label
+	}
+	return label
+}
+
+// gridColumnIndex converts column letters to a 0-based index (A=0, Z=25, AA=26, ...),
+// the inverse of gridColumnLabel. Returns -1 if column isn't all uppercase letters or is
+// longer than any label of a MaxGridCols grid, which also keeps the index from overflowing.
+func gridColumnIndex(column string) int {
+	if column == "" || len(column) > len(gridColumnLabel(MaxGridCols-1)) {
+		return -1
+	}
+	index := 0
+	for _, ch := range column {
+		if ch < 'A' || ch > 'Z' {
+			return -1
+		}
+		index = index*26 + int(ch-'A'+1)
+	}
+	return index - 1
 }
 
-// String returns the grid cell in "A1" format
+// String returns the grid cell in "A1" format, or "A1+0.3,-0.2" if it has an offset
 func (g GridCell) String() string {
-	return fmt.Sprintf("%s%d", g.Column, g.Row)
+	if g.OffsetX == 0 && g.OffsetY == 0 {
+		return fmt.Sprintf("%s%d", g.Column, g.Row)
+	}
+	return fmt.Sprintf("%s%d%+g,%g", g.Column, g.Row, g.OffsetX, g.OffsetY)
 }
 
-// ToPixelCoordinates converts a grid cell to pixel coordinates (returns center of cell)
+// ToPixelCoordinates converts a grid cell to pixel coordinates (the center of the cell,
+// moved by its offset)
 func (g GridCell) ToPixelCoordinates(gridCols, gridRows, imageWidth, imageHeight int) (int, int) {
-	// Convert column letter to index (A=0, B=1, etc.)
-	colIndex := int(g.Column[0] - 'A')
+	// Convert column letters to index (A=0, B=1, ..., AA=26, etc.)
+	colIndex := gridColumnIndex(g.Column)
 	rowIndex := g.Row - 1 // Row numbers are 1-based
 
 	// Calculate cell size
 	cellWidth := float64(imageWidth) / float64(gridCols)
 	cellHeight := float64(imageHeight) / float64(gridRows)
 
-	// Calculate center of cell
-	x := int(float64(colIndex)*cellWidth + cellWidth/2)
-	y := int(float64(rowIndex)*cellHeight + cellHeight/2)
+	// Calculate center of cell, then aim within it
+	x := int((float64(colIndex) + 0.5 + g.OffsetX) * cellWidth)
+	y := int((float64(rowIndex) + 0.5 + g.OffsetY) * cellHeight)
 
 	return x, y
 }
 
-// parseGridCell parses a grid cell string like "J7" into a GridCell struct
-func parseGridCell(cellStr string) (GridCell, error) {
+// parseGridCell parses a grid cell string like "J7", optionally with an offset within the
+// cell like "J7+0.3,-0.2", into a GridCell struct, rejecting cells outside a gridCols x
+// gridRows grid. Offsets past the cell's edge are clamped to it.
+func parseGridCell(cellStr string, gridCols, gridRows int) (GridCell, error) {
 	cellStr = strings.ToUpper(strings.TrimSpace(cellStr))
-	if len(cellStr) < 2 {
-		return GridCell{}, fmt.Errorf("invalid grid cell format: %s", cellStr)
+	if cellStr == "" {
+		return GridCell{}, fmt.Errorf("empty grid cell")
 	}
 
 	// Extract column letter(s) - could be A-Z or AA, AB, etc.
-	colEnd := 0
-	for i, ch := range cellStr {
-		if ch < 'A' || ch > 'Z' {
-			colEnd = i
-			break
-		}
-	}
+	colEnd := strings.IndexFunc(cellStr, func(ch rune) bool { return ch < 'A' || ch > 'Z' })
 	if colEnd == 0 {
+		return GridCell{}, fmt.Errorf("no column letter found in grid cell: %s", cellStr)
+	}
+	if colEnd < 0 {
 		return GridCell{}, fmt.Errorf("no row number found in grid cell: %s", cellStr)
 	}
 
 	column := cellStr[:colEnd]
-	rowStr := cellStr[colEnd:]
+	if index := gridColumnIndex(column); index < 0 || index >= gridCols {
+		return GridCell{}, fmt.Errorf("column %s is outside the grid (A-%s): %s", column, gridColumnLabel(gridCols-1), cellStr)
+	}
+
+	// Split off the offset, which starts with its sign
+	rowStr, offsetStr := cellStr[colEnd:], ""
+	if i := strings.IndexAny(rowStr, "+-"); i > 0 {
+		rowStr, offsetStr = rowStr[:i], rowStr[i:]
+	}
 
 	// Parse row number
-	row, err := fmt.Sscanf(rowStr, "%d", new(int))
-	if err != nil || row == 0 {
+	row, err := strconv.Atoi(rowStr)
+	if err != nil {
 		return GridCell{}, fmt.Errorf("invalid row number in grid cell: %s", cellStr)
 	}
+	if row < 1 || row > gridRows {
+		return GridCell{}, fmt.Errorf("row %d is outside the grid (1-%d): %s", row, gridRows, cellStr)
+	}
 
-	var rowNum int
-	fmt.Sscanf(rowStr, "%d", &rowNum)
-
-	return GridCell{
+	cell := GridCell{
 		Column: column,
-		Row:    rowNum,
-	}, nil
+		Row:    row,
+	}
+	if offsetStr != "" {
+		dx, dy, ok := strings.Cut(offsetStr, ",")
+		if !ok {
+			return GridCell{}, fmt.Errorf("invalid offset in grid cell (want e.g. J7+0.3,-0.2): %s", cellStr)
+		}
+		if cell.OffsetX, err = strconv.ParseFloat(strings.TrimSpace(dx), 64); err != nil {
+			return GridCell{}, fmt.Errorf("invalid x offset in grid cell: %s", cellStr)
+		}
+		if cell.OffsetY, err = strconv.ParseFloat(strings.TrimSpace(dy), 64); err != nil {
+			return GridCell{}, fmt.Errorf("invalid y offset in grid cell: %s", cellStr)
+		}
+		if math.IsNaN(cell.OffsetX) || math.IsNaN(cell.OffsetY) {
+			return GridCell{}, fmt.Errorf("invalid offset in grid cell: %s", cellStr)
+		}
+		cell.OffsetX = max(-maxCellOffset, min(maxCellOffset, cell.OffsetX))
+		cell.OffsetY = max(-maxCellOffset, min(maxCellOffset, cell.OffsetY))
+	}
+
+	return cell, nil
 }
 
 // AddGridOverlay adds a labeled grid overlay to a screenshot
 // gridCols and gridRows define the grid dimensions (e.g., 20x12 for 20 columns, 12 rows)
 func AddGridOverlay(screenshot *Screenshot, gridCols, gridRows int) (*Screenshot, error) {
-	// Decode PNG image
-	img, err := png.Decode(bytes.NewReader(screenshot.Data))
+	// Decode PNG or JPEG image
+	img, err := decodeImage(screenshot.Data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode screenshot: %w", err)
 	}
@@ -590,13 +820,13 @@ func AddGridOverlay(screenshot *Screenshot, gridCols, gridRows int) (*Screenshot
 
 		// Add column label (A, B, C, etc.) at top and bottom
 		if col < gridCols {
-			label := string(rune('A' + col))
-			labelX := int(float64(col)*cellWidth + cellWidth/2)
+			label := gridColumnLabel(col)
+			labelX := int(float64(col)*cellWidth+cellWidth/2) - 3*len(label)
 
 			// Draw label at top
-			drawString(rgba, labelX-3, 12, label, textColor)
+			drawString(rgba, labelX, 12, label, textColor)
 			// Draw label at bottom
-			drawString(rgba, labelX-3, height-5, label, textColor)
+			drawString(rgba, labelX, height-5, label, textColor)
 		}
 	}
 
@@ -623,9 +853,9 @@ func AddGridOverlay(screenshot *Screenshot, gridCols, gridRows int) (*Screenshot
 		}
 	}
 
-	// Encode the modified image back to PNG
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, rgba); err != nil {
+	// Encode the modified image back to the screenshot's format
+	data, err := encodeImage(rgba, screenshot.Format)
+	if err != nil {
 		return nil, fmt.Errorf("failed to encode gridded image: %w", err)
 	}
 
@@ -633,9 +863,10 @@ func AddGridOverlay(screenshot *Screenshot, gridCols, gridRows int) (*Screenshot
 	griddedScreenshot := &Screenshot{
 		Context:   screenshot.Context,
 		Timestamp: screenshot.Timestamp,
-		Data:      buf.Bytes(),
+		Data:      data,
 		Width:     screenshot.Width,
 		Height:    screenshot.Height,
+		Format:    screenshot.Format,
 	}
 
 	return griddedScreenshot, nil
@@ -659,17 +890,24 @@ func drawString(img *image.RGBA, x, y int, label string, col color.Color) {
 
 // ClickAt clicks at specific pixel coordinates using native CDP mouse events
 func (v *VisionDOMDetector) ClickAt(x, y int) error {
+	return clickAt(v.ctx, x, y)
+}
+
+// clickAt clicks at screenshot coordinates, scaling them to the current viewport and
+// offsetting them into the game iframe
+func clickAt(ctx context.Context, x, y int) error {
 	// Use chromedp's native MouseClickXY for real browser input events
 	// This sends actual Input.dispatchMouseEvent through Chrome DevTools Protocol,
 	// which games properly respond to (unlike JavaScript dispatchEvent)
-	log.Printf("[VisionClick] Screenshot coordinates: (%d, %d)", x, y)
+	logging.Printf(ctx, "[VisionClick] Screenshot coordinates: (%d, %d)", x, y)
 
 	// CRITICAL: Transform coordinates from screenshot space to actual viewport space
-	// The screenshot was taken at 1280x720, but the actual viewport might be different
+	// The screenshot was taken at the emulated viewport size, but the actual viewport might be different
+	viewport := ViewportFromContext(ctx)
 	script := fmt.Sprintf(`
 (function() {
-	const screenshotWidth = 1280;
-	const screenshotHeight = 720;
+	const screenshotWidth = %d;
+	const screenshotHeight = %d;
 	const currentWidth = window.innerWidth;
 	const currentHeight = window.innerHeight;
 
@@ -684,10 +922,10 @@ func (v *VisionDOMDetector) ClickAt(x, y int) error {
 
 	return JSON.stringify({ x: viewportX, y: viewportY, scaleX: scaleX, scaleY: scaleY });
 })();
-`, x, y, x, y)
+`, viewport.Width, viewport.Height, x, y, x, y)
 
 	var resultJSON string
-	err := chromedp.Run(v.ctx, chromedp.Evaluate(script, &resultJSON))
+	err := runWithTimeout(ctx, chromedp.Evaluate(script, &resultJSON))
 	if err != nil {
 		return fmt.Errorf("failed to calculate transformed coordinates: %w", err)
 	}
@@ -702,19 +940,21 @@ func (v *VisionDOMDetector) ClickAt(x, y int) error {
 		return fmt.Errorf("failed to parse coordinate transformation: %w", err)
 	}
 
-	log.Printf("[VisionClick] Transformed coordinates: (%d, %d) with scale (%.2f, %.2f)",
+	logging.Printf(ctx, "[VisionClick] Transformed coordinates: (%d, %d) with scale (%.2f, %.2f)",
 		result.X, result.Y, result.ScaleX, result.ScaleY)
 
-	// Now click at the TRANSFORMED coordinates
-	err = chromedp.Run(v.ctx,
-		chromedp.MouseClickXY(float64(result.X), float64(result.Y)),
+	// Now click at the TRANSFORMED coordinates, offset into the game iframe if there is one
+	pageX, pageY := toPage(ctx, float64(result.X), float64(result.Y))
+	err = runWithTimeout(ctx,
+		chromedp.MouseClickXY(pageX, pageY),
 	)
 
 	if err != nil {
 		return fmt.Errorf("CDP mouse click failed: %w", err)
 	}
+	recordAction(ctx, RecordedAction{Kind: RecordedClick, X: x, Y: y})
 
-	log.Printf("[VisionClick] ✓ Successfully clicked using native CDP mouse event")
+	logging.Printf(ctx, "[VisionClick] ✓ Successfully clicked using native CDP mouse event")
 	return nil
 }
 
@@ -757,7 +997,7 @@ func (v *VisionDOMDetector) InspectCanvasCoordinates() error {
 `
 
 	var resultJSON string
-	err := chromedp.Run(v.ctx, chromedp.Evaluate(script, &resultJSON))
+	err := runWithTimeout(v.ctx, chromedp.Evaluate(script, &resultJSON))
 	if err != nil {
 		return fmt.Errorf("failed to inspect canvas: %w", err)
 	}
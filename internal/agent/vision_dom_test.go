@@ -0,0 +1,99 @@
+package agent
+
+import "testing"
+
+func TestParseGridCell(t *testing.T) {
+	tests := []struct {
+		cell       string
+		cols, rows int
+		want       GridCell
+		wantErr    bool
+	}{
+		{cell: "A1", cols: 20, rows: 12, want: GridCell{Column: "A", Row: 1}},
+		{cell: "T12", cols: 20, rows: 12, want: GridCell{Column: "T", Row: 12}},
+		{cell: " j7 ", cols: 20, rows: 12, want: GridCell{Column: "J", Row: 7}},
+		{cell: "AA7", cols: 32, rows: 18, want: GridCell{Column: "AA", Row: 7}},
+		{cell: "J10+0.3,-0.2", cols: 20, rows: 12, want: GridCell{Column: "J", Row: 10, OffsetX: 0.3, OffsetY: -0.2}},
+		{cell: "J10-2,0.9", cols: 20, rows: 12, want: GridCell{Column: "J", Row: 10, OffsetX: -0.5, OffsetY: 0.5}},
+
+		{cell: "", cols: 20, rows: 12, wantErr: true},
+		{cell: "99", cols: 20, rows: 12, wantErr: true},
+		{cell: "J", cols: 20, rows: 12, wantErr: true},
+		{cell: "J0", cols: 20, rows: 12, wantErr: true},
+		{cell: "J13", cols: 20, rows: 12, wantErr: true},
+		{cell: "U1", cols: 20, rows: 12, wantErr: true},
+		{cell: "AA7", cols: 20, rows: 12, wantErr: true},
+		{cell: "J7x", cols: 20, rows: 12, wantErr: true},
+		{cell: "J7+0.3", cols: 20, rows: 12, wantErr: true},
+		{cell: "J7+NaN,0", cols: 20, rows: 12, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseGridCell(tt.cell, tt.cols, tt.rows)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseGridCell(%q, %d, %d) = %v, want error", tt.cell, tt.cols, tt.rows, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseGridCell(%q, %d, %d): %v", tt.cell, tt.cols, tt.rows, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseGridCell(%q, %d, %d) = %+v, want %+v", tt.cell, tt.cols, tt.rows, got, tt.want)
+		}
+	}
+}
+
+func TestGridCellStringRoundTrip(t *testing.T) {
+	for _, s := range []string{"A1", "T12", "AA7", "J10+0.3,-0.2", "B2-0.5,0.5"} {
+		cell, err := parseGridCell(s, 32, 18)
+		if err != nil {
+			t.Fatalf("parseGridCell(%q): %v", s, err)
+		}
+		if got := cell.String(); got != s {
+			t.Errorf("parseGridCell(%q).String() = %q", s, got)
+		}
+	}
+}
+
+func TestGridColumnLabelRoundTrip(t *testing.T) {
+	tests := []struct {
+		index int
+		label string
+	}{
+		{0, "A"},
+		{9, "J"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{51, "AZ"},
+	}
+	for _, tt := range tests {
+		if got := gridColumnLabel(tt.index); got != tt.label {
+			t.Errorf("gridColumnLabel(%d) = %q, want %q", tt.index, got, tt.label)
+		}
+		if got := gridColumnIndex(tt.label); got != tt.index {
+			t.Errorf("gridColumnIndex(%q) = %d, want %d", tt.label, got, tt.index)
+		}
+	}
+	for index := 0; index < MaxGridCols; index++ {
+		if got := gridColumnIndex(gridColumnLabel(index)); got != index {
+			t.Errorf("gridColumnIndex(gridColumnLabel(%d)) = %d", index, got)
+		}
+	}
+}
+
+func TestGridColumnIndexRejects(t *testing.T) {
+	for _, column := range []string{"", "a", "A1", "É", "AAA", "ZZZZZZZZZZZZZZZZZZZZ"} {
+		if got := gridColumnIndex(column); got != -1 {
+			t.Errorf("gridColumnIndex(%q) = %d, want -1", column, got)
+		}
+	}
+
+	// Before the length cap, this overflowed int to a negative index that passed the
+	// bounds check
+	if cell, err := parseGridCell("ZZZZZZZZZZZZZZZZZZZZ1", MaxGridCols, MaxGridRows); err == nil {
+		t.Errorf("parseGridCell accepted an overflowing column: %+v", cell)
+	}
+}
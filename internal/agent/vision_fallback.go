@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/logging"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	// DefaultGroqBaseURL is Groq's OpenAI-compatible API endpoint
+	DefaultGroqBaseURL = "https://api.groq.com/openai/v1"
+	// DefaultGroqVisionModel is the Groq model used when GROQ_VISION_MODEL is unset
+	DefaultGroqVisionModel = "meta-llama/llama-4-scout-17b-16e-instruct"
+
+	// visionFallbackTimeout bounds the fallback request; the caller's deadline, if sooner,
+	// still applies
+	visionFallbackTimeout = 30 * time.Second
+)
+
+// visionProvider is an OpenAI-compatible chat completion endpoint
+type visionProvider struct {
+	name   string
+	client *openai.Client
+	model  string
+}
+
+// visionFallbackFromEnv returns the provider to retry vision requests against when OpenAI
+// fails, per VISION_FALLBACK. It returns nil if no fallback is configured.
+func visionFallbackFromEnv() (*visionProvider, error) {
+	switch fallback := strings.ToLower(strings.TrimSpace(os.Getenv("VISION_FALLBACK"))); fallback {
+	case "":
+		return nil, nil
+	case "groq":
+		apiKey := os.Getenv("GROQ_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("GROQ_API_KEY environment variable required for VISION_FALLBACK=groq")
+		}
+		config := openai.DefaultConfig(apiKey)
+		config.BaseURL = DefaultGroqBaseURL
+		if baseURL := os.Getenv("GROQ_BASE_URL"); baseURL != "" {
+			config.BaseURL = baseURL
+		}
+		model := DefaultGroqVisionModel
+		if m := os.Getenv("GROQ_VISION_MODEL"); m != "" {
+			model = m
+		}
+		return &visionProvider{name: "groq", client: openai.NewClientWithConfig(config), model: model}, nil
+	default:
+		return nil, fmt.Errorf("unknown VISION_FALLBACK %q (use groq)", fallback)
+	}
+}
+
+// createChatCompletion sends a vision request to OpenAI, retrying the same prompt against
+// the fallback provider (if configured) when OpenAI fails. There's no retry once ctx is
+// cancelled or past its deadline: nobody is waiting for the answer.
+func (v *VisionDOMDetector) createChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	resp, err := v.client.CreateChatCompletion(ctx, req)
+	if err == nil || v.fallback == nil || ctx.Err() != nil {
+		if err == nil {
+			logging.Printf(v.ctx, "[Vision] Answered by openai (%s)", req.Model)
+		}
+		return resp, err
+	}
+
+	logging.Printf(v.ctx, "[Vision] OpenAI request failed, retrying with %s: %v", v.fallback.name, err)
+	fallbackCtx, cancel := context.WithTimeout(ctx, visionFallbackTimeout)
+	defer cancel()
+
+	req.Model = v.fallback.model
+	resp, fallbackErr := v.fallback.client.CreateChatCompletion(fallbackCtx, req)
+	if fallbackErr != nil {
+		return resp, fmt.Errorf("%w (%s fallback also failed: %w)", err, v.fallback.name, fallbackErr)
+	}
+	logging.Printf(v.ctx, "[Vision] Answered by %s (%s)", v.fallback.name, req.Model)
+	return resp, nil
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// testVisionEndpoint serves chat completions with handler and counts the requests
+func testVisionEndpoint(t *testing.T, handler http.HandlerFunc) (*openai.Client, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = server.URL + "/v1"
+	return openai.NewClientWithConfig(config), &requests
+}
+
+func answer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "groq answer"}}]}`))
+}
+
+func serverError(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, `{"error": {"message": "overloaded"}}`, http.StatusInternalServerError)
+}
+
+// hang answers only once the client gives up
+func hang(w http.ResponseWriter, r *http.Request) {
+	// The server notices the client leaving once the body is read
+	io.Copy(io.Discard, r.Body)
+	<-r.Context().Done()
+}
+
+func TestCreateChatCompletionFallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		primary       http.HandlerFunc
+		fallback      http.HandlerFunc
+		timeout       time.Duration // Caller's deadline (0 = none)
+		cancelAfter   time.Duration // When the caller cancels (0 = never)
+		wantFallbacks int32
+		wantErr       error
+	}{
+		{name: "openai fails", primary: serverError, fallback: answer, wantFallbacks: 1},
+		{name: "caller cancelled", primary: hang, fallback: answer, cancelAfter: 50 * time.Millisecond, wantErr: context.Canceled},
+		{name: "caller deadline passed", primary: hang, fallback: answer, timeout: 50 * time.Millisecond, wantErr: context.DeadlineExceeded},
+		// The fallback gets what's left of the caller's deadline, not a fresh 30s
+		{name: "fallback within the caller's deadline", primary: serverError, fallback: hang, timeout: 200 * time.Millisecond,
+			wantFallbacks: 1, wantErr: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		primary, _ := testVisionEndpoint(t, tt.primary)
+		fallback, fallbacks := testVisionEndpoint(t, tt.fallback)
+		v := &VisionDOMDetector{
+			ctx:      context.Background(),
+			client:   primary,
+			fallback: &visionProvider{name: "groq", client: fallback, model: "llama"},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if tt.timeout > 0 {
+			ctx, cancel = context.WithTimeout(context.Background(), tt.timeout)
+		}
+		if tt.cancelAfter > 0 {
+			time.AfterFunc(tt.cancelAfter, cancel)
+		}
+		started := time.Now()
+		resp, err := v.createChatCompletion(ctx, openai.ChatCompletionRequest{Model: openai.GPT4oMini})
+		cancel()
+
+		if elapsed := time.Since(started); elapsed > 5*time.Second {
+			t.Errorf("%s: took %v", tt.name, elapsed)
+		}
+		if got := fallbacks.Load(); got != tt.wantFallbacks {
+			t.Errorf("%s: %d fallback requests, want %d", tt.name, got, tt.wantFallbacks)
+		}
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "groq answer" {
+			t.Errorf("%s: got %+v, %v, want the fallback's answer", tt.name, resp, err)
+		}
+	}
+}
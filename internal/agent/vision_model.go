@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// DefaultVisionModel is the OpenAI model used for gameplay vision when VISION_MODEL is unset
+const DefaultVisionModel = openai.GPT4o
+
+// reasoningTokenFactor scales completion limits for reasoning models (gpt-5, o-series),
+// whose hidden reasoning tokens count against MaxCompletionTokens
+const reasoningTokenFactor = 4
+
+// VisionModelFromEnv returns the VISION_MODEL env var, or DefaultVisionModel if it's unset
+func VisionModelFromEnv() string {
+	if model := strings.TrimSpace(os.Getenv("VISION_MODEL")); model != "" {
+		return model
+	}
+	return DefaultVisionModel
+}
+
+// validateVisionModel checks a model name passed to SetModel
+func validateVisionModel(model string) error {
+	if strings.TrimSpace(model) == "" {
+		return fmt.Errorf("vision model must not be empty")
+	}
+	return nil
+}
+
+// isReasoningModel reports whether model spends completion tokens on hidden reasoning
+func isReasoningModel(model string) bool {
+	model = strings.ToLower(model)
+	for _, prefix := range []string{"gpt-5", "o1", "o3", "o4"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// visionMaxTokens adjusts a completion limit sized for gpt-4o to the model's family
+func visionMaxTokens(model string, tokens int) int {
+	if isReasoningModel(model) {
+		return tokens * reasoningTokenFactor
+	}
+	return tokens
+}
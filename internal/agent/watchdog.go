@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// DefaultOperationTimeout bounds a single browser operation (evaluate, click, screenshot).
+// A wedged renderer otherwise blocks chromedp.Run forever, and the test never
+// releases its concurrency slot.
+const DefaultOperationTimeout = 30 * time.Second
+
+// pingTimeout is how long a liveness probe may take before the browser is considered hung
+const pingTimeout = 5 * time.Second
+
+// runWithTimeout runs chromedp actions with the default per-operation timeout
+func runWithTimeout(ctx context.Context, actions ...chromedp.Action) error {
+	return runWithDeadline(ctx, DefaultOperationTimeout, actions...)
+}
+
+// runWithDeadline runs chromedp actions, aborting them if they exceed the given timeout.
+// A timeout caused by this watchdog (rather than the caller's context) is returned as a
+// retryable timeout error so callers can tell a hung browser apart from a cancelled test.
+func runWithDeadline(ctx context.Context, timeout time.Duration, actions ...chromedp.Action) error {
+	// The first Run on a chromedp context allocates the browser and ties its lifetime
+	// to the context passed in, so make sure that happens on the parent context.
+	if err := chromedp.Run(ctx); err != nil {
+		return err
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := chromedp.Run(opCtx, actions...)
+	if err != nil && ctx.Err() == nil && errors.Is(opCtx.Err(), context.DeadlineExceeded) {
+		return NewTimeoutError(fmt.Sprintf("browser operation did not complete within %v", timeout), err)
+	}
+	return err
+}
+
+// IsTimeoutError reports whether err (or any error it wraps) is a timeout error
+func IsTimeoutError(err error) bool {
+	var catErr *CategorizedError
+	if errors.As(err, &catErr) {
+		return catErr.Category == ErrorCategoryTimeout
+	}
+	return false
+}
+
+// Ping checks that the browser is still responsive by evaluating a trivial expression.
+// Returns an error if the page does not answer within a few seconds.
+func (bm *BrowserManager) Ping() error {
+	var ok bool
+	if err := runWithDeadline(bm.ctx, pingTimeout, chromedp.Evaluate(`true`, &ok)); err != nil {
+		return NewBrowserError("browser is not responding", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// BaselineRecord is the golden final screenshot a game's later runs are compared against
+type BaselineRecord struct {
+	GameURL string `json:"gameUrl"`
+	// ReportID is the report the screenshot came from
+	ReportID string `json:"reportId"`
+	// Screenshot is the PNG image
+	Screenshot []byte    `json:"-"`
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// SaveBaseline stores b as its game's baseline, replacing any previous one. Baselines live
+// in the database rather than the media directory so retention cleanup doesn't remove them.
+func (d *Database) SaveBaseline(b BaselineRecord) error {
+	query := `
+		INSERT OR REPLACE INTO baselines (game_url, report_id, screenshot, width, height, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	_, err := d.db.Exec(query, b.GameURL, b.ReportID, b.Screenshot, b.Width, b.Height, time.Now())
+	return err
+}
+
+// GetBaseline returns the baseline for a game URL, or nil if it has none
+func (d *Database) GetBaseline(gameURL string) (*BaselineRecord, error) {
+	query := `
+		SELECT game_url, report_id, screenshot, width, height, created_at
+		FROM baselines
+		WHERE game_url = ?
+	`
+
+	var b BaselineRecord
+	var reportID sql.NullString
+	err := d.db.QueryRow(query, gameURL).Scan(&b.GameURL, &reportID, &b.Screenshot, &b.Width, &b.Height, &b.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.ReportID = reportID.String
+	return &b, nil
+}
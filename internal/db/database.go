@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -23,7 +25,17 @@ type TestRecord struct {
 	Duration    int       `json:"duration"`
 	ReportID    string    `json:"reportId"`
 	ReportData  string    `json:"reportData"` // JSON string
-	CreatedAt   time.Time `json:"createdAt"`
+	// MaxDuration, Headless and GameMechanics are the test's main request parameters
+	MaxDuration   int    `json:"maxDuration"`
+	Headless      bool   `json:"headless"`
+	GameMechanics string `json:"gameMechanics,omitempty"`
+	// RequestData is the test's full request as JSON, without credentials (see CreateTest)
+	RequestData string `json:"requestData,omitempty"`
+	// StatusMessage explains a status the server set on its own, e.g. why a test was interrupted
+	StatusMessage string `json:"statusMessage,omitempty"`
+	// Tags group tests by project, environment, campaign, ...
+	Tags        []string   `json:"tags"`
+	CreatedAt   time.Time  `json:"createdAt"`
 	CompletedAt *time.Time `json:"completedAt,omitempty"`
 }
 
@@ -67,8 +79,24 @@ func initSchema(db *sql.DB) error {
 	CREATE INDEX IF NOT EXISTS idx_tests_game_url ON tests(game_url);
 	`
 
-	_, err := db.Exec(schema)
-	return err
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Later schema changes are applied as migrations (see migrate.go)
+	return migrate(db)
+}
+
+// TestParams are the request parameters stored with a test
+type TestParams struct {
+	MaxDuration   int
+	Headless      bool
+	GameMechanics string
+	// Tags are stored in test_tags for filtering
+	Tags []string
+	// Request is the full request, stored as JSON so the test can be re-run later.
+	// Callers must strip credentials from it first.
+	Request interface{}
 }
 
 // Close closes the database connection
@@ -76,14 +104,32 @@ func (d *Database) Close() error {
 	return d.db.Close()
 }
 
-// CreateTest inserts a new test record
-func (d *Database) CreateTest(id, gameURL, status string) error {
+// CreateTest inserts a new test record with its request parameters
+func (d *Database) CreateTest(id, gameURL, status string, params TestParams) error {
+	requestJSON, err := json.Marshal(params.Request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO tests (id, game_url, status, created_at)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO tests (id, game_url, status, max_duration, headless, game_mechanics, request_data, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := d.db.Exec(query, id, gameURL, status, time.Now())
-	return err
+	if _, err := tx.Exec(query, id, gameURL, status, params.MaxDuration, params.Headless, params.GameMechanics, string(requestJSON), time.Now()); err != nil {
+		return err
+	}
+	for _, tag := range params.Tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO test_tags (test_id, tag) VALUES (?, ?)`, id, tag); err != nil {
+			return fmt.Errorf("failed to store tag %q: %w", tag, err)
+		}
+	}
+	return tx.Commit()
 }
 
 // UpdateTestStatus updates the status of a test
@@ -93,6 +139,66 @@ func (d *Database) UpdateTestStatus(id, status string) error {
 	return err
 }
 
+// UpdateTestStatusMessage updates the status of a test and records why it changed
+func (d *Database) UpdateTestStatusMessage(id, status, message string) error {
+	query := `UPDATE tests SET status = ?, status_message = ? WHERE id = ?`
+	_, err := d.db.Exec(query, status, message, id)
+	return err
+}
+
+// InterruptUnfinishedTests marks pending and running tests as interrupted with message,
+// for tests left behind by a server that stopped without draining. Returns the tests
+// it marked, oldest first.
+func (d *Database) InterruptUnfinishedTests(message string) ([]TestRecord, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id FROM tests WHERE status IN ('pending', 'running') ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	for _, id := range ids {
+		if _, err := tx.Exec(`UPDATE tests SET status = 'interrupted', status_message = ? WHERE id = ?`, message, id); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	tests := make([]TestRecord, 0, len(ids))
+	for _, id := range ids {
+		test, err := d.GetTest(id)
+		if err != nil {
+			return nil, err
+		}
+		if test != nil {
+			tests = append(tests, *test)
+		}
+	}
+	return tests, nil
+}
+
 // CompleteTest marks a test as complete with final data
 func (d *Database) CompleteTest(id, status string, score, duration int, reportID string, reportData interface{}) error {
 	// Convert report data to JSON
@@ -114,13 +220,21 @@ func (d *Database) CompleteTest(id, status string, score, duration int, reportID
 // GetTest retrieves a test by ID
 func (d *Database) GetTest(id string) (*TestRecord, error) {
 	query := `
-		SELECT id, game_url, status, score, duration, report_id, report_data, created_at, completed_at
+		SELECT id, game_url, status, score, duration, report_id, report_data,
+			max_duration, headless, game_mechanics, request_data, status_message, ` + tagsColumn + `, created_at, completed_at
 		FROM tests
 		WHERE id = ?
 	`
 
 	var test TestRecord
+	var reportID sql.NullString
 	var reportData sql.NullString
+	var maxDuration sql.NullInt64
+	var headless sql.NullBool
+	var gameMechanics sql.NullString
+	var requestData sql.NullString
+	var statusMessage sql.NullString
+	var tags sql.NullString
 	var completedAt sql.NullTime
 
 	err := d.db.QueryRow(query, id).Scan(
@@ -129,8 +243,14 @@ func (d *Database) GetTest(id string) (*TestRecord, error) {
 		&test.Status,
 		&test.Score,
 		&test.Duration,
-		&test.ReportID,
+		&reportID,
 		&reportData,
+		&maxDuration,
+		&headless,
+		&gameMechanics,
+		&requestData,
+		&statusMessage,
+		&tags,
 		&test.CreatedAt,
 		&completedAt,
 	)
@@ -142,9 +262,20 @@ func (d *Database) GetTest(id string) (*TestRecord, error) {
 		return nil, err
 	}
 
+	if reportID.Valid {
+		test.ReportID = reportID.String
+	}
 	if reportData.Valid {
 		test.ReportData = reportData.String
 	}
+	test.MaxDuration = int(maxDuration.Int64)
+	test.Headless = headless.Bool
+	test.GameMechanics = gameMechanics.String
+	if requestData.Valid {
+		test.RequestData = requestData.String
+	}
+	test.StatusMessage = statusMessage.String
+	test.Tags = splitTags(tags)
 	if completedAt.Valid {
 		test.CompletedAt = &completedAt.Time
 	}
@@ -195,19 +326,18 @@ func (d *Database) GetTestByReportID(reportID string) (*TestRecord, error) {
 
 // ListTests retrieves all tests with optional filtering
 func (d *Database) ListTests(status string, limit, offset int) ([]TestRecord, error) {
+	return d.SearchTests("", status, nil, limit, offset)
+}
+
+// SearchTests retrieves tests whose game URL contains urlSubstring and that have every
+// one of tags, newest first. An empty urlSubstring and no tags match every test.
+func (d *Database) SearchTests(urlSubstring, status string, tags []string, limit, offset int) ([]TestRecord, error) {
+	where, args := testFilter(urlSubstring, status, tags)
 	query := `
-		SELECT id, game_url, status, score, duration, report_id, report_data, created_at, completed_at
+		SELECT id, game_url, status, score, duration, report_id, report_data,
+			max_duration, headless, game_mechanics, status_message, ` + tagsColumn + `, created_at, completed_at
 		FROM tests
-		WHERE 1=1
-	`
-	args := []interface{}{}
-
-	if status != "" && status != "all" {
-		query += ` AND status = ?`
-		args = append(args, status)
-	}
-
-	query += ` ORDER BY created_at DESC LIMIT ? OFFSET ?`
+	` + where + ` ORDER BY created_at DESC LIMIT ? OFFSET ?`
 	args = append(args, limit, offset)
 
 	rows, err := d.db.Query(query, args...)
@@ -221,6 +351,11 @@ func (d *Database) ListTests(status string, limit, offset int) ([]TestRecord, er
 		var test TestRecord
 		var reportID sql.NullString
 		var reportData sql.NullString
+		var maxDuration sql.NullInt64
+		var headless sql.NullBool
+		var gameMechanics sql.NullString
+		var statusMessage sql.NullString
+		var tags sql.NullString
 		var completedAt sql.NullTime
 
 		err := rows.Scan(
@@ -231,6 +366,11 @@ func (d *Database) ListTests(status string, limit, offset int) ([]TestRecord, er
 			&test.Duration,
 			&reportID,
 			&reportData,
+			&maxDuration,
+			&headless,
+			&gameMechanics,
+			&statusMessage,
+			&tags,
 			&test.CreatedAt,
 			&completedAt,
 		)
@@ -238,6 +378,12 @@ func (d *Database) ListTests(status string, limit, offset int) ([]TestRecord, er
 			return nil, err
 		}
 
+		test.MaxDuration = int(maxDuration.Int64)
+		test.Headless = headless.Bool
+		test.GameMechanics = gameMechanics.String
+		test.StatusMessage = statusMessage.String
+		test.Tags = splitTags(tags)
+
 		if reportID.Valid {
 			test.ReportID = reportID.String
 		}
@@ -256,15 +402,174 @@ func (d *Database) ListTests(status string, limit, offset int) ([]TestRecord, er
 
 // CountTests returns the total number of tests
 func (d *Database) CountTests(status string) (int, error) {
-	query := `SELECT COUNT(*) FROM tests WHERE 1=1`
+	return d.CountSearchTests("", status, nil)
+}
+
+// CountSearchTests returns the number of tests matching SearchTests' filters
+func (d *Database) CountSearchTests(urlSubstring, status string, tags []string) (int, error) {
+	where, args := testFilter(urlSubstring, status, tags)
+
+	var count int
+	err := d.db.QueryRow(`SELECT COUNT(*) FROM tests `+where, args...).Scan(&count)
+	return count, err
+}
+
+// testFilter builds the WHERE clause shared by SearchTests and CountSearchTests.
+// Values are always bound as parameters; LIKE wildcards in urlSubstring match literally.
+func testFilter(urlSubstring, status string, tags []string) (string, []interface{}) {
+	where := `WHERE 1=1`
 	args := []interface{}{}
 
 	if status != "" && status != "all" {
-		query += ` AND status = ?`
+		where += ` AND status = ?`
 		args = append(args, status)
 	}
 
-	var count int
-	err := d.db.QueryRow(query, args...).Scan(&count)
-	return count, err
+	if urlSubstring != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(urlSubstring)
+		where += ` AND game_url LIKE ? ESCAPE '\'`
+		args = append(args, "%"+escaped+"%")
+	}
+
+	for _, tag := range tags {
+		where += ` AND EXISTS (SELECT 1 FROM test_tags WHERE test_tags.test_id = tests.id AND test_tags.tag = ?)`
+		args = append(args, tag)
+	}
+
+	return where, args
+}
+
+// tagsSeparator joins a test's tags in tagsColumn; it can't appear in a tag
+const tagsSeparator = "\x1f"
+
+// tagsColumn selects a test's tags joined by tagsSeparator (NULL if it has none)
+const tagsColumn = `(SELECT GROUP_CONCAT(tag, char(31)) FROM test_tags WHERE test_tags.test_id = tests.id)`
+
+// splitTags parses a tagsColumn value into sorted tags
+func splitTags(joined sql.NullString) []string {
+	if !joined.Valid || joined.String == "" {
+		return []string{}
+	}
+	tags := strings.Split(joined.String, tagsSeparator)
+	sort.Strings(tags)
+	return tags
+}
+
+// DeleteTestsOlderThan deletes tests created more than age ago and returns them, with
+// only ID, ReportID and ReportData set, so their media can be deleted too
+func (d *Database) DeleteTestsOlderThan(age time.Duration) ([]*TestRecord, error) {
+	cutoff := time.Now().Add(-age)
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, report_id, report_data FROM tests WHERE created_at < ?`, cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	var tests []*TestRecord
+	for rows.Next() {
+		var test TestRecord
+		var reportID, reportData sql.NullString
+		if err := rows.Scan(&test.ID, &reportID, &reportData); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		test.ReportID = reportID.String
+		test.ReportData = reportData.String
+		tests = append(tests, &test)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM test_tags WHERE test_id IN (SELECT id FROM tests WHERE created_at < ?)`, cutoff); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM tests WHERE created_at < ?`, cutoff); err != nil {
+		return nil, err
+	}
+
+	return tests, tx.Commit()
+}
+
+// TestStats summarizes tests for the dashboard
+type TestStats struct {
+	TotalTests int `json:"totalTests"`
+	// StatusCounts is the number of tests per run status (completed, failed, running, ...)
+	StatusCounts map[string]int `json:"statusCounts"`
+	// Passed and Failed count completed tests by their report verdict
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+	// AverageScore and AverageDuration (seconds) cover completed tests only
+	AverageScore    float64 `json:"averageScore"`
+	AverageDuration float64 `json:"averageDuration"`
+}
+
+// StatsFilter narrows Stats to a creation date range, game URL and/or tags (zero values match everything)
+type StatsFilter struct {
+	Since        time.Time
+	Until        time.Time
+	URLSubstring string
+	Tags         []string
+}
+
+// Stats computes aggregate statistics with SQL aggregates rather than loading rows
+func (d *Database) Stats(filter StatsFilter) (*TestStats, error) {
+	where, args := testFilter(filter.URLSubstring, "", filter.Tags)
+	if !filter.Since.IsZero() {
+		where += ` AND created_at >= ?`
+		args = append(args, filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		where += ` AND created_at < ?`
+		args = append(args, filter.Until)
+	}
+
+	stats := &TestStats{StatusCounts: make(map[string]int)}
+
+	rows, err := d.db.Query(`SELECT status, COUNT(*) FROM tests `+where+` GROUP BY status`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		stats.StatusCounts[status] = count
+		stats.TotalTests += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The pass/fail verdict lives in the stored report's summary
+	query := `
+		SELECT
+			AVG(CASE WHEN status = 'completed' THEN score END),
+			AVG(CASE WHEN status = 'completed' THEN duration END),
+			SUM(CASE WHEN json_extract(report_data, '$.summary.status') IN ('passed', 'passed_with_warnings') THEN 1 ELSE 0 END),
+			SUM(CASE WHEN json_extract(report_data, '$.summary.status') = 'failed' THEN 1 ELSE 0 END)
+		FROM tests
+	` + where
+
+	var avgScore, avgDuration sql.NullFloat64
+	var passed, failed sql.NullInt64
+	if err := d.db.QueryRow(query, args...).Scan(&avgScore, &avgDuration, &passed, &failed); err != nil {
+		return nil, err
+	}
+	stats.AverageScore = avgScore.Float64
+	stats.AverageDuration = avgDuration.Float64
+	stats.Passed = int(passed.Int64)
+	stats.Failed = int(failed.Int64)
+
+	return stats, nil
 }
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// migration is one schema change, applied once per database in version order
+type migration struct {
+	version     int
+	description string
+	apply       func(tx *sql.Tx) error
+}
+
+// migrations change the schema created by initSchema. Append new ones with the next
+// version number; never edit or reorder applied ones. Each must be safe to re-run on
+// a database that already has the change.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "store test request parameters",
+		apply: func(tx *sql.Tx) error {
+			return addColumns(tx, "tests", []column{
+				{"max_duration", "INTEGER DEFAULT 0"},
+				{"headless", "BOOLEAN DEFAULT 0"},
+				{"game_mechanics", "TEXT"},
+				{"request_data", "TEXT"},
+			})
+		},
+	},
+	{
+		version:     2,
+		description: "store visual regression baselines",
+		apply: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				CREATE TABLE IF NOT EXISTS baselines (
+					game_url TEXT PRIMARY KEY,
+					report_id TEXT,
+					screenshot BLOB NOT NULL,
+					width INTEGER,
+					height INTEGER,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				)
+			`)
+			return err
+		},
+	},
+	{
+		version:     3,
+		description: "store test tags",
+		apply: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				CREATE TABLE IF NOT EXISTS test_tags (
+					test_id TEXT NOT NULL,
+					tag TEXT NOT NULL,
+					PRIMARY KEY (test_id, tag)
+				);
+				CREATE INDEX IF NOT EXISTS idx_test_tags_tag ON test_tags(tag);
+			`)
+			return err
+		},
+	},
+	{
+		version:     4,
+		description: "store test status messages",
+		apply: func(tx *sql.Tx) error {
+			return addColumns(tx, "tests", []column{
+				{"status_message", "TEXT"},
+			})
+		},
+	},
+}
+
+// migrate applies the migrations newer than the database's schema_version, each in its
+// own transaction
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_meta (key TEXT PRIMARY KEY, value TEXT NOT NULL)`); err != nil {
+		return fmt.Errorf("failed to create schema_meta table: %w", err)
+	}
+
+	current, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err := m.apply(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+		}
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO schema_meta (key, value) VALUES ('schema_version', ?)`, strconv.Itoa(m.version)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record schema version %d: %w", m.version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("📦 Applied database migration %d: %s", m.version, m.description)
+	}
+	return nil
+}
+
+// schemaVersion returns the last migration applied to the database (0 if none)
+func schemaVersion(db *sql.DB) (int, error) {
+	var value string
+	err := db.QueryRow(`SELECT value FROM schema_meta WHERE key = 'schema_version'`).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version %q: %w", value, err)
+	}
+	return version, nil
+}
+
+// column is a column name and its SQL definition
+type column struct {
+	name       string
+	definition string
+}
+
+// addColumns adds the columns the table doesn't have yet
+func addColumns(tx *sql.Tx, table string, columns []column) error {
+	for _, c := range columns {
+		var exists bool
+		if err := tx.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?`, table, c.name).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, c.name, c.definition)); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", table, c.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+	"github.com/dreamup/qa-agent/internal/logging"
+)
+
+// MaxEnsembleRuns caps how many evaluations an ensemble may run
+const MaxEnsembleRuns = 7
+
+// EnsembleStats describes the individual runs behind an ensemble evaluation
+type EnsembleStats struct {
+	// Runs is how many evaluations succeeded
+	Runs int `json:"runs"`
+	// Failed is how many evaluations failed and were left out
+	Failed int `json:"failed,omitempty"`
+	// Models lists the model used for each successful run, if models were rotated
+	Models []string `json:"models,omitempty"`
+	// OverallScores are each run's overall score
+	OverallScores []int `json:"overall_scores"`
+	// Variance and StdDev measure how much the overall scores disagreed
+	Variance float64 `json:"variance"`
+	StdDev   float64 `json:"std_dev"`
+}
+
+// SetEnsemble makes EvaluateGame run runs evaluations and aggregate them (see
+// EvaluateGameEnsemble). With models, runs rotate through them in order; this has no
+// effect if the provider doesn't support model selection.
+func (ge *GameEvaluator) SetEnsemble(runs int, models ...string) {
+	ge.ensembleRuns = runs
+	ge.ensembleModels = models
+}
+
+// EvaluateGameEnsemble evaluates the game n times and aggregates the results: each score
+// is the median across runs, LoadsCorrectly is the majority verdict, and issues and
+// recommendations are the union of all runs. The score's Ensemble field reports the
+// spread of overall scores. Failed runs are left out unless every run fails.
+func (ge *GameEvaluator) EvaluateGameEnsemble(ctx context.Context, screenshots []*agent.Screenshot, logs []agent.ConsoleLog, n int) (*PlayabilityScore, error) {
+	if n < 1 {
+		n = 1
+	}
+	if n > MaxEnsembleRuns {
+		return nil, fmt.Errorf("ensemble of %d runs exceeds the maximum of %d", n, MaxEnsembleRuns)
+	}
+
+	// Rotate models only if the provider can report and switch its model
+	type modelSelector interface {
+		Model() string
+		SetModel(string)
+	}
+	selector, canRotate := ge.provider.(modelSelector)
+	rotate := canRotate && len(ge.ensembleModels) > 0
+	if rotate {
+		defer selector.SetModel(selector.Model())
+	}
+
+	var scores []*PlayabilityScore
+	var models []string
+	var lastErr error
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if rotate {
+			selector.SetModel(ge.ensembleModels[i%len(ge.ensembleModels)])
+		}
+
+		score, err := ge.evaluateOnce(ctx, screenshots, logs)
+		if err != nil {
+			logging.Printf(ctx, "Warning: Ensemble run %d/%d failed: %v", i+1, n, err)
+			lastErr = err
+			continue
+		}
+		scores = append(scores, score)
+		if rotate {
+			models = append(models, selector.Model())
+		}
+	}
+	if len(scores) == 0 {
+		return nil, fmt.Errorf("all %d ensemble runs failed: %w", n, lastErr)
+	}
+
+	result := aggregateScores(scores)
+	result.Ensemble.Failed = n - len(scores)
+	result.Ensemble.Models = models
+	return result, nil
+}
+
+// aggregateScores combines several evaluations into one
+func aggregateScores(scores []*PlayabilityScore) *PlayabilityScore {
+	pick := func(f func(*PlayabilityScore) int) []int {
+		values := make([]int, len(scores))
+		for i, s := range scores {
+			values[i] = f(s)
+		}
+		return values
+	}
+
+	overall := pick(func(s *PlayabilityScore) int { return s.OverallScore })
+	result := &PlayabilityScore{
+		OverallScore:       medianInt(overall),
+		InteractivityScore: medianInt(pick(func(s *PlayabilityScore) int { return s.InteractivityScore })),
+		VisualQuality:      medianInt(pick(func(s *PlayabilityScore) int { return s.VisualQuality })),
+		ErrorSeverity:      medianInt(pick(func(s *PlayabilityScore) int { return s.ErrorSeverity })),
+		Issues:             []string{},
+		Recommendations:    []string{},
+		IssueEvidence:      []IssueEvidence{},
+	}
+
+	loads := 0
+	var confidence float64
+	seenIssues := make(map[string]bool)
+	seenRecommendations := make(map[string]bool)
+	for _, s := range scores {
+		if s.LoadsCorrectly {
+			loads++
+		}
+		confidence += s.Confidence
+		for _, issue := range s.Issues {
+			if key := normalizeFinding(issue); !seenIssues[key] {
+				seenIssues[key] = true
+				result.Issues = append(result.Issues, issue)
+			}
+		}
+		for _, ev := range s.IssueEvidence {
+			if key := normalizeFinding(ev.Issue); !seenIssues["evidence:"+key] {
+				seenIssues["evidence:"+key] = true
+				result.IssueEvidence = append(result.IssueEvidence, ev)
+			}
+		}
+		for _, rec := range s.Recommendations {
+			if key := normalizeFinding(rec); !seenRecommendations[key] {
+				seenRecommendations[key] = true
+				result.Recommendations = append(result.Recommendations, rec)
+			}
+		}
+	}
+	result.LoadsCorrectly = loads*2 > len(scores)
+	result.Confidence = confidence / float64(len(scores))
+
+	// Use the reasoning of the run that best matches the median verdict
+	closest := scores[0]
+	for _, s := range scores[1:] {
+		if absInt(s.OverallScore-result.OverallScore) < absInt(closest.OverallScore-result.OverallScore) {
+			closest = s
+		}
+	}
+	result.Reasoning = closest.Reasoning
+
+	variance := varianceInt(overall)
+	result.Ensemble = &EnsembleStats{
+		Runs:          len(scores),
+		OverallScores: overall,
+		Variance:      variance,
+		StdDev:        math.Sqrt(variance),
+	}
+	return result
+}
+
+// normalizeFinding is the key used to merge identical issues and recommendations across runs
+func normalizeFinding(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// medianInt returns the median, rounding the mean of the middle two values for even counts
+func medianInt(values []int) int {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return int(math.Round(float64(sorted[mid-1]+sorted[mid]) / 2))
+}
+
+// varianceInt returns the population variance
+func varianceInt(values []int) float64 {
+	var mean float64
+	for _, v := range values {
+		mean += float64(v)
+	}
+	mean /= float64(len(values))
+
+	var sum float64
+	for _, v := range values {
+		d := float64(v) - mean
+		sum += d * d
+	}
+	return sum / float64(len(values))
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
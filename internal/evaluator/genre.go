@@ -0,0 +1,53 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Genre selects genre-specific evaluation criteria
+type Genre string
+
+const (
+	// GenreGeneric uses only the standard criteria
+	GenreGeneric Genre = ""
+	// GenrePhysics is for physics games (e.g. slingshot, stacking, ragdoll games)
+	GenrePhysics Genre = "physics"
+	// GenrePuzzle is for puzzle games
+	GenrePuzzle Genre = "puzzle"
+	// GenreIdle is for idle/incremental games
+	GenreIdle Genre = "idle"
+	// GenrePlatformer is for platformers
+	GenrePlatformer Genre = "platformer"
+	// GenreArcade is for fast-paced arcade games
+	GenreArcade Genre = "arcade"
+)
+
+// genreCriteria are added to the evaluation prompt for each genre
+var genreCriteria = map[Genre]string{
+	GenreGeneric: "",
+	GenrePhysics: `- Weight interactivity heavily: objects should react immediately and plausibly to input (launches, collisions, falling).
+- Objects passing through each other, jittering at rest or never settling are significant issues.
+- Frozen or identical frames after an action suggest the physics simulation is stuck.`,
+	GenrePuzzle: `- Weight visual clarity heavily: pieces, goals and the current state of the puzzle must be easy to read.
+- Fast motion matters little; a static screen between moves is normal, not a sign of unresponsiveness.
+- Missing feedback when a move is made (no highlight, no change) is a significant issue.`,
+	GenreIdle: `- Weight progression heavily: counters, resources or upgrades should visibly increase between screenshots.
+- Little change from player input is acceptable if numbers grow on their own.
+- Counters that never change, or overlapping/unreadable numbers, are significant issues.`,
+	GenrePlatformer: `- Weight responsiveness heavily: the character should move and jump in response to keyboard input.
+- The character clipping through platforms, or falling out of the level, is a significant issue.
+- Check that the camera follows the character and the level stays visible.`,
+	GenreArcade: `- Weight responsiveness and frame-to-frame change heavily: the game should be visibly in motion.
+- A visible score, lives or timer is expected; one that never updates is an issue.
+- A game-over screen soon after the start is acceptable and not a failure to load.`,
+}
+
+// ParseGenre validates a genre name such as "puzzle". An empty name is GenreGeneric.
+func ParseGenre(name string) (Genre, error) {
+	genre := Genre(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := genreCriteria[genre]; !ok {
+		return "", fmt.Errorf("unknown genre %q (use physics, puzzle, idle, platformer or arcade)", name)
+	}
+	return genre, nil
+}
@@ -2,7 +2,6 @@ package evaluator
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -12,7 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/dreamup/qa-agent/internal/agent"
-	openai "github.com/sashabaranov/go-openai"
+	"github.com/dreamup/qa-agent/internal/logging"
 )
 
 // PlayabilityScore represents the evaluation result from the LLM
@@ -33,12 +32,34 @@ type PlayabilityScore struct {
 	Issues []string `json:"issues"`
 	// Recommendations suggests improvements
 	Recommendations []string `json:"recommendations"`
+	// IssueEvidence points each issue at the screenshot or console log it's based on
+	IssueEvidence []IssueEvidence `json:"issue_evidence"`
+	// Confidence is how sure the evaluator is of its scores (0-1, 0 if not reported)
+	Confidence float64 `json:"confidence"`
+	// Ensemble describes the runs behind an ensemble evaluation (nil for a single run)
+	Ensemble *EnsembleStats `json:"ensemble,omitempty"`
+}
+
+// IssueEvidence records what an issue in PlayabilityScore.Issues was concluded from
+type IssueEvidence struct {
+	// Issue is the issue text, as in Issues
+	Issue string `json:"issue"`
+	// Screenshot is the screenshot context it's based on: initial, gameplay or final
+	// (empty if it comes from the console logs alone)
+	Screenshot string `json:"screenshot"`
+	// LogSnippet quotes the console log it's based on, if any
+	LogSnippet string `json:"log_snippet"`
 }
 
 // GameEvaluator handles LLM-based game evaluation
 type GameEvaluator struct {
-	client *openai.Client
-	model  string
+	provider    VisionProvider
+	retryConfig agent.RetryConfig
+	// genre adds genre-specific criteria to the prompt (see SetGenre)
+	genre Genre
+	// ensembleRuns and ensembleModels make EvaluateGame an ensemble (see SetEnsemble)
+	ensembleRuns   int
+	ensembleModels []string
 }
 
 // getAPIKeyFromSecretsManager fetches the OpenAI API key from AWS Secrets Manager
@@ -65,55 +86,80 @@ func getAPIKeyFromSecretsManager(ctx context.Context, secretName string) (string
 	return *result.SecretString, nil
 }
 
-// NewGameEvaluator creates a new game evaluator with OpenAI client
+// resolveOpenAIKey returns the OpenAI API key
 // API key resolution order:
 // 1. Provided apiKey parameter
 // 2. OPENAI_SECRET_NAME environment variable (fetches from Secrets Manager)
 // 3. OPENAI_API_KEY environment variable (legacy, direct key)
-func NewGameEvaluator(apiKey string) (*GameEvaluator, error) {
-	if apiKey == "" {
-		// Check if we should fetch from Secrets Manager
-		secretName := os.Getenv("OPENAI_SECRET_NAME")
-		if secretName != "" {
-			var err error
-			apiKey, err = getAPIKeyFromSecretsManager(context.Background(), secretName)
-			if err != nil {
-				return nil, fmt.Errorf("failed to fetch API key from Secrets Manager: %w", err)
-			}
-		} else {
-			// Fall back to direct environment variable (legacy support)
-			apiKey = os.Getenv("OPENAI_API_KEY")
-			if apiKey == "" {
-				return nil, fmt.Errorf("OPENAI_API_KEY or OPENAI_SECRET_NAME not provided")
-			}
+func resolveOpenAIKey(apiKey string) (string, error) {
+	if apiKey != "" {
+		return apiKey, nil
+	}
+
+	// Check if we should fetch from Secrets Manager
+	secretName := os.Getenv("OPENAI_SECRET_NAME")
+	if secretName != "" {
+		apiKey, err := getAPIKeyFromSecretsManager(context.Background(), secretName)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch API key from Secrets Manager: %w", err)
 		}
+		return apiKey, nil
+	}
+
+	// Fall back to direct environment variable (legacy support)
+	apiKey = os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return "", fmt.Errorf("OPENAI_API_KEY or OPENAI_SECRET_NAME not provided")
 	}
+	return apiKey, nil
+}
 
-	client := openai.NewClient(apiKey)
+// MaxEvaluationImages is how many screenshots an evaluation sends to the model; longer
+// runs send the first, the last and the most visually distinct in between
+const MaxEvaluationImages = 5
 
+// NewGameEvaluator creates a new game evaluator using the provider selected by
+// LLM_PROVIDER ("openai" by default, or "anthropic").
+// apiKey is the key for the selected provider; if empty it is read from the environment
+// (OPENAI_SECRET_NAME / OPENAI_API_KEY for OpenAI, ANTHROPIC_API_KEY for Anthropic).
+func NewGameEvaluator(apiKey string) (*GameEvaluator, error) {
+	provider, err := newProviderFromEnv(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewGameEvaluatorWithProvider(provider), nil
+}
+
+// NewGameEvaluatorWithProvider creates a game evaluator backed by the given provider
+func NewGameEvaluatorWithProvider(provider VisionProvider) *GameEvaluator {
 	return &GameEvaluator{
-		client: client,
-		model:  "gpt-4o", // GPT-4o has vision capabilities
-	}, nil
+		provider:    provider,
+		retryConfig: DefaultEvaluationRetryConfig(),
+	}
 }
 
-// SetModel allows changing the model (useful for testing or using different models)
-func (ge *GameEvaluator) SetModel(model string) {
-	ge.model = model
+// SetRetryConfig sets how transient LLM failures (rate limits, 5xx, timeouts) are retried.
+// Callers with a hard time budget (e.g. Lambda) should use fewer attempts and shorter delays.
+func (ge *GameEvaluator) SetRetryConfig(config agent.RetryConfig) {
+	ge.retryConfig = config
 }
 
-// encodeScreenshotToBase64 encodes a screenshot to base64 string
-func encodeScreenshotToBase64(screenshot *agent.Screenshot) (string, error) {
-	if screenshot.Data == nil || len(screenshot.Data) == 0 {
-		return "", fmt.Errorf("screenshot data is empty")
+// SetModel allows changing the model (useful for testing or using different models).
+// It has no effect if the provider doesn't support model selection.
+func (ge *GameEvaluator) SetModel(model string) {
+	if p, ok := ge.provider.(interface{ SetModel(string) }); ok {
+		p.SetModel(model)
 	}
+}
 
-	encoded := base64.StdEncoding.EncodeToString(screenshot.Data)
-	return encoded, nil
+// SetGenre steers the evaluation with criteria specific to the game's genre
+// (GenreGeneric, the default, uses only the standard criteria)
+func (ge *GameEvaluator) SetGenre(genre Genre) {
+	ge.genre = genre
 }
 
 // buildEvaluationPrompt constructs the prompt for LLM evaluation
-func buildEvaluationPrompt(screenshots []*agent.Screenshot, logs []agent.ConsoleLog) string {
+func buildEvaluationPrompt(screenshots []*agent.Screenshot, logs []agent.ConsoleLog, genre Genre) string {
 	prompt := `You are a QA expert evaluating a web-based game's playability. Analyze the provided screenshots and console logs to assess the game's quality.
 
 Evaluation Criteria:
@@ -121,10 +167,14 @@ Evaluation Criteria:
 2. **Interactivity**: Does the game appear responsive and functional?
 3. **Visual Quality**: Are visuals rendering correctly (no broken images, proper layout)?
 4. **Errors**: Are there console errors that impact gameplay?
-
-Screenshots Context:
 `
 
+	if criteria := genreCriteria[genre]; criteria != "" {
+		prompt += fmt.Sprintf("\nThis is a %s game. Apply these genre-specific criteria when scoring and recommending:\n%s\n", genre, criteria)
+	}
+
+	prompt += "\nScreenshots Context:\n"
+
 	for i, screenshot := range screenshots {
 		prompt += fmt.Sprintf("- Image %d: %s phase (captured at %s)\n",
 			i+1,
@@ -140,18 +190,34 @@ Screenshots Context:
 	} else {
 		errorCount := 0
 		warningCount := 0
+		exceptionCount := 0
 		for _, log := range logs {
 			if log.Level == agent.LogLevelError {
 				errorCount++
 			} else if log.Level == agent.LogLevelWarning {
 				warningCount++
+			} else if log.Level == agent.LogLevelException {
+				exceptionCount++
 			}
 		}
 
 		prompt += fmt.Sprintf("- Total logs: %d\n", len(logs))
+		prompt += fmt.Sprintf("- Uncaught exceptions: %d\n", exceptionCount)
 		prompt += fmt.Sprintf("- Errors: %d\n", errorCount)
 		prompt += fmt.Sprintf("- Warnings: %d\n", warningCount)
 
+		// Uncaught exceptions usually mean game code stopped running
+		if exceptionCount > 0 {
+			prompt += "\nUncaught Exceptions (weigh these heavily in error_severity):\n"
+			count := 0
+			for _, log := range logs {
+				if log.Level == agent.LogLevelException && count < 3 {
+					prompt += fmt.Sprintf("- %s\n", log.Message)
+					count++
+				}
+			}
+		}
+
 		// Include first few errors for context
 		if errorCount > 0 {
 			prompt += "\nSample Errors:\n"
@@ -175,79 +241,77 @@ Provide your evaluation as a JSON object with this structure:
   "error_severity": <0-100, where 0=no errors, 100=critical errors>,
   "reasoning": "<explanation of scores>",
   "issues": ["<issue 1>", "<issue 2>"],
-  "recommendations": ["<recommendation 1>", "<recommendation 2>"]
+  "recommendations": ["<recommendation 1>", "<recommendation 2>"],
+  "issue_evidence": [
+    {"issue": "<issue 1, as above>", "screenshot": "<initial|gameplay|final, or empty>", "log_snippet": "<console log it's based on, or empty>"}
+  ],
+  "confidence": <0.0-1.0, how sure you are of these scores>
 }
 
+Give one issue_evidence entry per issue, naming the screenshot phase (and quoting the console log, if any) that shows it.
+Lower your confidence when the screenshots are ambiguous, nearly identical, or don't show gameplay.
+
 Analyze the images and logs carefully, then respond with ONLY the JSON object.`
 
 	return prompt
 }
 
-// EvaluateGame evaluates a game using screenshots and console logs
+// EvaluateGame evaluates a game using screenshots and console logs.
+// If SetEnsemble asked for more than one run, it evaluates with EvaluateGameEnsemble.
 func (ge *GameEvaluator) EvaluateGame(ctx context.Context, screenshots []*agent.Screenshot, logs []agent.ConsoleLog) (*PlayabilityScore, error) {
+	if ge.ensembleRuns > 1 {
+		return ge.EvaluateGameEnsemble(ctx, screenshots, logs, ge.ensembleRuns)
+	}
+	return ge.evaluateOnce(ctx, screenshots, logs)
+}
+
+// evaluateOnce runs a single evaluation
+func (ge *GameEvaluator) evaluateOnce(ctx context.Context, screenshots []*agent.Screenshot, logs []agent.ConsoleLog) (*PlayabilityScore, error) {
 	if len(screenshots) == 0 {
 		return nil, fmt.Errorf("no screenshots provided for evaluation")
 	}
 
-	// Build prompt
-	textPrompt := buildEvaluationPrompt(screenshots, logs)
-
-	// Build message content with text and images
-	messageParts := []openai.ChatMessagePart{
-		{
-			Type: openai.ChatMessagePartTypeText,
-			Text: textPrompt,
-		},
+	// Keep requests within provider image limits, preferring distinct frames over
+	// near-identical menu or loading screens
+	if len(screenshots) > MaxEvaluationImages {
+		total := len(screenshots)
+		screenshots = agent.SelectDistinctScreenshots(screenshots, MaxEvaluationImages)
+		logging.Printf(ctx, "Selected %d of %d screenshots for evaluation", len(screenshots), total)
 	}
 
-	// Add up to 5 screenshots as images (GPT-4 Vision limit)
-	maxImages := 5
-	if len(screenshots) > maxImages {
-		screenshots = screenshots[:maxImages]
-	}
+	// Build prompt
+	textPrompt := buildEvaluationPrompt(screenshots, logs, ge.genre)
 
-	for _, screenshot := range screenshots {
-		base64Image, err := encodeScreenshotToBase64(screenshot)
-		if err != nil {
-			return nil, fmt.Errorf("failed to encode screenshot: %w", err)
+	images := make([][]byte, 0, len(screenshots))
+	for i, screenshot := range screenshots {
+		if len(screenshot.Data) == 0 {
+			return nil, fmt.Errorf("screenshot %d has no image data", i+1)
 		}
-
-		messageParts = append(messageParts, openai.ChatMessagePart{
-			Type: openai.ChatMessagePartTypeImageURL,
-			ImageURL: &openai.ChatMessageImageURL{
-				URL:    fmt.Sprintf("data:image/png;base64,%s", base64Image),
-				Detail: openai.ImageURLDetailAuto,
-			},
-		})
+		images = append(images, screenshot.Data)
 	}
 
-	// Create chat completion request
-	req := openai.ChatCompletionRequest{
-		Model: ge.model,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:         openai.ChatMessageRoleUser,
-				MultiContent: messageParts,
-			},
-		},
-		MaxTokens:   1500,
-		Temperature: 0.3, // Lower temperature for more consistent evaluations
-	}
+	// Call the provider (bounded by the shared LLM concurrency limit), retrying transient failures.
+	// The slot is released between attempts so backoff doesn't block other tests.
+	var responseText string
+	err := agent.Retry(ctx, ge.retryConfig, func() error {
+		release, err := agent.AcquireLLMSlot(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
 
-	// Call OpenAI API
-	resp, err := ge.client.CreateChatCompletion(ctx, req)
+		responseText, err = ge.provider.Evaluate(ctx, textPrompt, images)
+		if err != nil {
+			return classifyLLMError(err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create chat completion: %w", err)
+		return nil, err
 	}
 
-	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("no response choices returned from API")
-	}
-
-	// Parse JSON response
-	responseText := resp.Choices[0].Message.Content
-
-	// Strip markdown code fences if present
+	// Strip markdown code fences if present (providers without structured output
+	// sometimes wrap the JSON)
 	responseText = stripMarkdownCodeFence(responseText)
 
 	var score PlayabilityScore
@@ -0,0 +1,74 @@
+package evaluator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+)
+
+const scoreJSON = `{
+  "overall_score": 72,
+  "loads_correctly": true,
+  "interactivity_score": 65,
+  "visual_quality": 80,
+  "error_severity": 10,
+  "reasoning": "Loads and responds to input",
+  "issues": ["Sound is missing"],
+  "recommendations": ["Add sound effects"],
+  "issue_evidence": [{"issue": "Sound is missing", "screenshot": "gameplay", "log_snippet": ""}],
+  "confidence": 0.8
+}`
+
+// testScreenshots returns screenshots with stand-in image data; providers under test
+// never decode it
+func testScreenshots() []*agent.Screenshot {
+	return []*agent.Screenshot{
+		{Context: agent.ContextInitial, Data: []byte("initial")},
+		{Context: agent.ContextGameplay, Data: []byte("gameplay")},
+	}
+}
+
+func TestEvaluateGameParsesFencedAndCleanResponses(t *testing.T) {
+	responses := map[string]string{
+		"clean":         scoreJSON,
+		"json fence":    "```json\n" + scoreJSON + "\n```",
+		"generic fence": "```\n" + scoreJSON + "\n```",
+		"padded fence":  "\n  ```json\n" + scoreJSON + "\n```\n",
+	}
+
+	var want *PlayabilityScore
+	for _, name := range []string{"clean", "json fence", "generic fence", "padded fence"} {
+		ge := NewGameEvaluatorWithProvider(&FakeProvider{Response: responses[name]})
+		score, err := ge.EvaluateGame(context.Background(), testScreenshots(), nil)
+		if err != nil {
+			t.Fatalf("%s: EvaluateGame: %v", name, err)
+		}
+		if want == nil {
+			want = score
+			if score.OverallScore != 72 || !score.LoadsCorrectly || len(score.IssueEvidence) != 1 {
+				t.Fatalf("%s: unexpected score %+v", name, score)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(score, want) {
+			t.Errorf("%s: score = %+v, want %+v (as parsed from the clean response)", name, score, want)
+		}
+	}
+}
+
+func TestStripMarkdownCodeFence(t *testing.T) {
+	tests := map[string]string{
+		`{"a":1}`:                 `{"a":1}`,
+		"```json\n{\"a\":1}\n```": `{"a":1}`,
+		"```\n{\"a\":1}\n```":     `{"a":1}`,
+		"```json{\"a\":1}```":     `{"a":1}`,
+		"  {\"a\":1}\n":           `{"a":1}`,
+	}
+	for in, want := range tests {
+		if got := stripMarkdownCodeFence(in); got != want {
+			t.Errorf("stripMarkdownCodeFence(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package evaluator
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// imageMediaType sniffs whether a screenshot is JPEG or PNG (the formats screenshots are
+// captured in)
+func imageMediaType(image []byte) string {
+	if http.DetectContentType(image) == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// VisionProvider sends a prompt plus screenshots to a vision-capable LLM and returns its text reply
+type VisionProvider interface {
+	// Evaluate sends the prompt and PNG images to the model and returns the raw response text
+	Evaluate(ctx context.Context, prompt string, images [][]byte) (string, error)
+}
+
+const (
+	// ProviderOpenAI selects OpenAI (default)
+	ProviderOpenAI = "openai"
+	// ProviderAnthropic selects Anthropic Claude
+	ProviderAnthropic = "anthropic"
+
+	// DefaultOpenAIModel is the OpenAI model used for evaluation
+	DefaultOpenAIModel = "gpt-4o"
+	// DefaultAnthropicModel is the Claude model used for evaluation
+	DefaultAnthropicModel = "claude-3-5-sonnet-latest"
+
+	// evaluationMaxTokens caps the length of the evaluation response
+	evaluationMaxTokens = 1500
+	// evaluationTemperature is kept low for more consistent evaluations
+	evaluationTemperature = 0.3
+)
+
+// OpenAIProvider evaluates with OpenAI chat completions, using structured outputs
+// so the response is guaranteed to match the PlayabilityScore schema
+type OpenAIProvider struct {
+	client         *openai.Client
+	model          string
+	responseFormat *openai.ChatCompletionResponseFormat
+}
+
+// NewOpenAIProvider creates an OpenAI provider. An empty model uses DefaultOpenAIModel.
+func NewOpenAIProvider(apiKey, model string) *OpenAIProvider {
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+	return &OpenAIProvider{
+		client:         openai.NewClient(apiKey),
+		model:          model,
+		responseFormat: playabilityScoreFormat(),
+	}
+}
+
+// playabilityScoreFormat builds a strict JSON schema response format for PlayabilityScore.
+// Returns nil (plain text responses) if the schema can't be generated.
+func playabilityScoreFormat() *openai.ChatCompletionResponseFormat {
+	schema, err := jsonschema.GenerateSchemaForType(PlayabilityScore{})
+	if err != nil {
+		return nil
+	}
+	// Ensemble stats are filled in by EvaluateGameEnsemble, not the model
+	delete(schema.Properties, "ensemble")
+	return &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   "playability_score",
+			Schema: schema,
+			Strict: true,
+		},
+	}
+}
+
+// SetModel changes the OpenAI model
+func (p *OpenAIProvider) SetModel(model string) {
+	p.model = model
+}
+
+// Model returns the OpenAI model in use
+func (p *OpenAIProvider) Model() string {
+	return p.model
+}
+
+// Evaluate implements VisionProvider
+func (p *OpenAIProvider) Evaluate(ctx context.Context, prompt string, images [][]byte) (string, error) {
+	messageParts := []openai.ChatMessagePart{
+		{
+			Type: openai.ChatMessagePartTypeText,
+			Text: prompt,
+		},
+	}
+	for _, image := range images {
+		messageParts = append(messageParts, openai.ChatMessagePart{
+			Type: openai.ChatMessagePartTypeImageURL,
+			ImageURL: &openai.ChatMessageImageURL{
+				URL:    fmt.Sprintf("data:%s;base64,%s", imageMediaType(image), base64.StdEncoding.EncodeToString(image)),
+				Detail: openai.ImageURLDetailAuto,
+			},
+		})
+	}
+
+	resp, err := p.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: p.model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:         openai.ChatMessageRoleUser,
+				MultiContent: messageParts,
+			},
+		},
+		MaxTokens:      evaluationMaxTokens,
+		Temperature:    evaluationTemperature,
+		ResponseFormat: p.responseFormat,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create chat completion: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response choices returned from API")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+// anthropicMessagesURL is the Anthropic Messages API endpoint
+const anthropicMessagesURL = "https://api.anthropic.com/v1/messages"
+
+// AnthropicProvider evaluates with the Anthropic Messages API
+type AnthropicProvider struct {
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewAnthropicProvider creates a Claude provider. An empty model uses DefaultAnthropicModel.
+func NewAnthropicProvider(apiKey, model string) *AnthropicProvider {
+	if model == "" {
+		model = DefaultAnthropicModel
+	}
+	return &AnthropicProvider{
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+// SetModel changes the Claude model
+func (p *AnthropicProvider) SetModel(model string) {
+	p.model = model
+}
+
+// Model returns the Claude model in use
+func (p *AnthropicProvider) Model() string {
+	return p.model
+}
+
+// anthropicContent is a text or image block in an Anthropic message
+type anthropicContent struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Source *anthropicImageSource `json:"source,omitempty"`
+}
+
+// anthropicImageSource is an inline base64 image
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// Evaluate implements VisionProvider
+func (p *AnthropicProvider) Evaluate(ctx context.Context, prompt string, images [][]byte) (string, error) {
+	// Claude recommends images before the text that refers to them
+	content := make([]anthropicContent, 0, len(images)+1)
+	for _, image := range images {
+		content = append(content, anthropicContent{
+			Type: "image",
+			Source: &anthropicImageSource{
+				Type:      "base64",
+				MediaType: imageMediaType(image),
+				Data:      base64.StdEncoding.EncodeToString(image),
+			},
+		})
+	}
+	content = append(content, anthropicContent{Type: "text", Text: prompt})
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model":       p.model,
+		"max_tokens":  evaluationMaxTokens,
+		"temperature": evaluationTemperature,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": content},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, anthropicMessagesURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call Anthropic API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Anthropic response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", &ProviderError{Provider: "Anthropic", StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(respBody))}
+	}
+
+	var result struct {
+		Content []anthropicContent `json:"content"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("failed to parse Anthropic response: %w", err)
+	}
+
+	var text strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no text content returned from API")
+	}
+
+	return text.String(), nil
+}
+
+// FakeProvider returns a canned response without calling any API, for tests
+type FakeProvider struct {
+	// Response is returned from every Evaluate call
+	Response string
+	// Err, if set, is returned instead of Response
+	Err error
+	// Prompts records each prompt received
+	Prompts []string
+	// ImageCounts records how many images each call received
+	ImageCounts []int
+}
+
+// Evaluate implements VisionProvider
+func (p *FakeProvider) Evaluate(ctx context.Context, prompt string, images [][]byte) (string, error) {
+	p.Prompts = append(p.Prompts, prompt)
+	p.ImageCounts = append(p.ImageCounts, len(images))
+	if p.Err != nil {
+		return "", p.Err
+	}
+	return p.Response, nil
+}
+
+// newProviderFromEnv creates the provider selected by LLM_PROVIDER (openai by default).
+// apiKey overrides the provider's key lookup when non-empty.
+func newProviderFromEnv(apiKey string) (VisionProvider, error) {
+	switch provider := strings.ToLower(os.Getenv("LLM_PROVIDER")); provider {
+	case "", ProviderOpenAI:
+		key, err := resolveOpenAIKey(apiKey)
+		if err != nil {
+			return nil, err
+		}
+		return NewOpenAIProvider(key, os.Getenv("OPENAI_MODEL")), nil
+	case ProviderAnthropic:
+		if apiKey == "" {
+			apiKey = os.Getenv("ANTHROPIC_API_KEY")
+			if apiKey == "" {
+				return nil, fmt.Errorf("ANTHROPIC_API_KEY not provided")
+			}
+		}
+		return NewAnthropicProvider(apiKey, os.Getenv("ANTHROPIC_MODEL")), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM_PROVIDER %q (use %s or %s)", provider, ProviderOpenAI, ProviderAnthropic)
+	}
+}
@@ -0,0 +1,125 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+)
+
+// fastRetries retries like the default config without the backoff delays
+func fastRetries() agent.RetryConfig {
+	config := DefaultEvaluationRetryConfig()
+	config.InitialDelay = time.Millisecond
+	config.MaxDelay = time.Millisecond
+	return config
+}
+
+func TestEvaluateGameWithFakeProvider(t *testing.T) {
+	provider := &FakeProvider{Response: scoreJSON}
+	ge := NewGameEvaluatorWithProvider(provider)
+
+	score, err := ge.EvaluateGame(context.Background(), testScreenshots(), []agent.ConsoleLog{
+		{Level: agent.LogLevelError, Message: "Uncaught TypeError: x is undefined"},
+	})
+	if err != nil {
+		t.Fatalf("EvaluateGame: %v", err)
+	}
+	if score.OverallScore != 72 || score.InteractivityScore != 65 || score.VisualQuality != 80 ||
+		score.ErrorSeverity != 10 || !score.LoadsCorrectly || score.Confidence != 0.8 {
+		t.Errorf("unexpected score: %+v", score)
+	}
+	if len(score.Issues) != 1 || score.Issues[0] != "Sound is missing" {
+		t.Errorf("Issues = %v", score.Issues)
+	}
+
+	if len(provider.Prompts) != 1 {
+		t.Fatalf("provider called %d times, want 1", len(provider.Prompts))
+	}
+	if provider.ImageCounts[0] != 2 {
+		t.Errorf("provider got %d images, want 2", provider.ImageCounts[0])
+	}
+	if !strings.Contains(provider.Prompts[0], "x is undefined") {
+		t.Errorf("prompt doesn't include the console error:\n%s", provider.Prompts[0])
+	}
+}
+
+func TestEvaluateGameErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    *FakeProvider
+		screenshots []*agent.Screenshot
+		wantCalls   int
+		wantErr     string
+	}{
+		{
+			name:        "no screenshots",
+			provider:    &FakeProvider{Response: scoreJSON},
+			screenshots: nil,
+			wantCalls:   0,
+			wantErr:     "no screenshots",
+		},
+		{
+			name:        "screenshot without data",
+			provider:    &FakeProvider{Response: scoreJSON},
+			screenshots: []*agent.Screenshot{{Context: agent.ContextInitial}},
+			wantCalls:   0,
+			wantErr:     "no image data",
+		},
+		{
+			name:        "unparseable response",
+			provider:    &FakeProvider{Response: "The game looks great!"},
+			screenshots: testScreenshots(),
+			wantCalls:   1,
+			wantErr:     "failed to parse LLM response",
+		},
+		{
+			name:        "rejected request is not retried",
+			provider:    &FakeProvider{Err: &ProviderError{Provider: "fake", StatusCode: http.StatusBadRequest, Message: "bad image"}},
+			screenshots: testScreenshots(),
+			wantCalls:   1,
+			wantErr:     "bad image",
+		},
+		{
+			name:        "rate limit is retried",
+			provider:    &FakeProvider{Err: &ProviderError{Provider: "fake", StatusCode: http.StatusTooManyRequests, Message: "slow down"}},
+			screenshots: testScreenshots(),
+			wantCalls:   DefaultEvaluationRetryConfig().MaxAttempts,
+			wantErr:     "max retry attempts",
+		},
+	}
+	for _, tt := range tests {
+		ge := NewGameEvaluatorWithProvider(tt.provider)
+		ge.SetRetryConfig(fastRetries())
+
+		score, err := ge.EvaluateGame(context.Background(), tt.screenshots, nil)
+		if err == nil {
+			t.Errorf("%s: EvaluateGame = %+v, want error", tt.name, score)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error %q doesn't mention %q", tt.name, err, tt.wantErr)
+		}
+		if got := len(tt.provider.Prompts); got != tt.wantCalls {
+			t.Errorf("%s: provider called %d times, want %d", tt.name, got, tt.wantCalls)
+		}
+	}
+}
+
+func TestEvaluateGameStopsWhenCancelled(t *testing.T) {
+	provider := &FakeProvider{Err: context.Canceled}
+	ge := NewGameEvaluatorWithProvider(provider)
+	ge.SetRetryConfig(fastRetries())
+
+	_, err := ge.EvaluateGame(context.Background(), testScreenshots(), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if len(provider.Prompts) != 1 {
+		t.Errorf("provider called %d times, want 1", len(provider.Prompts))
+	}
+}
@@ -0,0 +1,66 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ProviderError is returned by providers that call their API over plain HTTP
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s API error (%d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// DefaultEvaluationRetryConfig retries rate limits, server errors and timeouts a few times
+func DefaultEvaluationRetryConfig() agent.RetryConfig {
+	return agent.DefaultRetryConfig()
+}
+
+// classifyLLMError wraps provider errors for agent.Retry: rate limits (429), server
+// errors (5xx) and network timeouts are retryable LLM errors, other 4xx responses are not.
+// Context cancellation is returned unwrapped so retries stop immediately.
+func classifyLLMError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	status := 0
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	var provErr *ProviderError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	case errors.As(err, &provErr):
+		status = provErr.StatusCode
+	}
+
+	switch {
+	case status == http.StatusTooManyRequests || status >= 500:
+		return agent.NewLLMError(fmt.Sprintf("LLM API returned %d", status), err)
+	case status >= 400:
+		llmErr := agent.NewLLMError(fmt.Sprintf("LLM API rejected request (%d)", status), err)
+		llmErr.Retryable = false
+		return llmErr
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return agent.NewLLMError("LLM API request timed out", err)
+	}
+
+	return err
+}
@@ -0,0 +1,78 @@
+// Package logging tags log lines with the test they belong to, so concurrent runs can
+// be told apart, and optionally emits them as JSON lines (LOG_FORMAT=json).
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// FormatJSON selects JSON line output
+const FormatJSON = "json"
+
+// Setup configures the process-wide logger. With format "json" every line, including
+// plain log.Printf output, is written as a JSON object; otherwise text output is kept.
+func Setup(format string) {
+	if strings.ToLower(format) == FormatJSON {
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+	}
+}
+
+// Logger writes printf-style lines tagged with fixed attributes (e.g. test_id) and the
+// current phase of the test. It is safe for concurrent use.
+type Logger struct {
+	base  *slog.Logger
+	phase atomic.Value // string
+}
+
+// New creates a Logger with the given key/value attributes
+func New(attrs ...any) *Logger {
+	l := &Logger{base: slog.Default().With(attrs...)}
+	l.phase.Store("")
+	return l
+}
+
+// SetPhase changes the phase attached to subsequent lines
+func (l *Logger) SetPhase(phase string) {
+	l.phase.Store(phase)
+}
+
+// Printf logs a formatted message at info level
+func (l *Logger) Printf(format string, args ...any) {
+	logger := l.base
+	if phase := l.phase.Load().(string); phase != "" {
+		logger = logger.With("phase", phase)
+	}
+	logger.Info(strings.TrimSuffix(fmt.Sprintf(format, args...), "\n"))
+}
+
+// loggerKey is the context key for a *Logger
+type loggerKey struct{}
+
+// WithLogger returns a context carrying l
+func WithLogger(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the context's Logger, or nil if it has none
+func FromContext(ctx context.Context) *Logger {
+	if ctx == nil {
+		return nil
+	}
+	l, _ := ctx.Value(loggerKey{}).(*Logger)
+	return l
+}
+
+// Printf logs through the context's Logger, falling back to the standard logger
+func Printf(ctx context.Context, format string, args ...any) {
+	if l := FromContext(ctx); l != nil {
+		l.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,143 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+)
+
+const (
+	// StorageBackendS3 stores artifacts in Amazon S3 (default)
+	StorageBackendS3 = "s3"
+	// StorageBackendGCS stores artifacts in Google Cloud Storage
+	StorageBackendGCS = "gcs"
+)
+
+// ArtifactStore uploads report artifacts to object storage
+type ArtifactStore interface {
+	// UploadFile uploads a local file under key and returns its URL
+	UploadFile(ctx context.Context, filePath, key string) (string, error)
+	// GetURL returns the URL of an object key without uploading anything
+	GetURL(key string) string
+}
+
+// NewArtifactStore creates the store selected by STORAGE_BACKEND (s3 by default).
+// An empty bucketName falls back to the backend's bucket env var.
+func NewArtifactStore(bucketName string) (ArtifactStore, error) {
+	switch backend := strings.ToLower(os.Getenv("STORAGE_BACKEND")); backend {
+	case "", StorageBackendS3:
+		return NewS3Uploader(bucketName, "")
+	case StorageBackendGCS:
+		return NewGCSUploader(bucketName)
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q (use %s or %s)", backend, StorageBackendS3, StorageBackendGCS)
+	}
+}
+
+// reportKey is the object key of a report JSON
+func reportKey(reportID string) string {
+	return fmt.Sprintf("reports/%s/report.json", reportID)
+}
+
+// screenshotKey is the object key of a report screenshot, keeping the file's extension
+// (.png or .jpg)
+func screenshotKey(screenshot *agent.Screenshot, reportID string) string {
+	ext := strings.ToLower(filepath.Ext(screenshot.Filepath))
+	if ext == "" {
+		ext = ".png"
+	}
+	return fmt.Sprintf("reports/%s/screenshots/%s_%s%s",
+		reportID,
+		screenshot.Context,
+		screenshot.Timestamp.Format("20060102_150405"),
+		ext,
+	)
+}
+
+// consoleLogsKey is the object key of a report's console logs
+func consoleLogsKey(reportID string) string {
+	return fmt.Sprintf("reports/%s/console_logs.json", reportID)
+}
+
+// videoKey is the object key of a report's gameplay video, keeping the container extension
+func videoKey(videoPath, reportID string) string {
+	return fmt.Sprintf("reports/%s/gameplay%s", reportID, strings.ToLower(filepath.Ext(videoPath)))
+}
+
+// ReportURL returns the URL of a report JSON in store
+func ReportURL(store ArtifactStore, reportID string) string {
+	return store.GetURL(reportKey(reportID))
+}
+
+// getContentType determines content type from file extension
+func getContentType(filePath string) string {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	switch ext {
+	case ".json":
+		return "application/json"
+	case ".png":
+		return "image/png"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".txt":
+		return "text/plain"
+	case ".mp4":
+		return "video/mp4"
+	case ".webm":
+		return "video/webm"
+	case ".gif":
+		return "image/gif"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// UploadReportWithArtifacts uploads a complete report with all artifacts.
+// videoPath is optional; when set the video is uploaded and Evidence.VideoURL points at it.
+func UploadReportWithArtifacts(ctx context.Context, store ArtifactStore, report *Report, screenshots []*agent.Screenshot, logPath, videoPath string) error {
+	// Upload screenshots and update report
+	for i, screenshot := range screenshots {
+		url, err := store.UploadFile(ctx, screenshot.Filepath, screenshotKey(screenshot, report.ReportID))
+		if err != nil {
+			return fmt.Errorf("failed to upload screenshot %d: %w", i, err)
+		}
+		// Update storage URL in report
+		if i < len(report.Evidence.Screenshots) {
+			report.Evidence.Screenshots[i].S3URL = url
+		}
+	}
+
+	// Upload video before the report so the report references its URL
+	if videoPath != "" {
+		videoURL, err := store.UploadFile(ctx, videoPath, videoKey(videoPath, report.ReportID))
+		if err != nil {
+			return fmt.Errorf("failed to upload video: %w", err)
+		}
+		report.Evidence.VideoURL = videoURL
+	}
+
+	// Save updated report to temp file
+	reportPath, err := report.SaveToTemp()
+	if err != nil {
+		return fmt.Errorf("failed to save report: %w", err)
+	}
+	defer os.Remove(reportPath)
+
+	// Upload report
+	if _, err := store.UploadFile(ctx, reportPath, reportKey(report.ReportID)); err != nil {
+		return fmt.Errorf("failed to upload report: %w", err)
+	}
+
+	// Upload console logs if provided
+	if logPath != "" {
+		if _, err := store.UploadFile(ctx, logPath, consoleLogsKey(report.ReportID)); err != nil {
+			return fmt.Errorf("failed to upload console logs: %w", err)
+		}
+	}
+
+	return nil
+}
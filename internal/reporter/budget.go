@@ -0,0 +1,130 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+)
+
+// EvidenceBudget limits how much evidence a report carries. Zero values mean unlimited.
+type EvidenceBudget struct {
+	// MaxBytes caps screenshot bytes plus serialized console logs
+	MaxBytes int64
+	// MaxScreenshots caps the number of screenshots kept (first and last are always kept)
+	MaxScreenshots int
+	// MaxConsoleLogs caps the number of console log entries kept
+	MaxConsoleLogs int
+}
+
+// DefaultEvidenceBudget keeps reports small enough to store in the database and serve quickly
+var DefaultEvidenceBudget = EvidenceBudget{
+	MaxBytes:       50 * 1024 * 1024,
+	MaxConsoleLogs: 2000,
+}
+
+// TruncationInfo records what was dropped to keep a report within its evidence budget
+type TruncationInfo struct {
+	// OriginalScreenshots is how many screenshots were captured
+	OriginalScreenshots int `json:"original_screenshots"`
+	// ScreenshotsDropped is how many were sampled out of the report
+	ScreenshotsDropped int `json:"screenshots_dropped"`
+	// OriginalConsoleLogs is how many console logs were captured
+	OriginalConsoleLogs int `json:"original_console_logs"`
+	// ConsoleLogsDropped is how many were removed from the report
+	ConsoleLogsDropped int `json:"console_logs_dropped"`
+	// Reason describes which limits were exceeded
+	Reason string `json:"reason"`
+}
+
+// applyBudget samples screenshots and trims console logs to fit the budget.
+// Returns nil truncation info if nothing had to be dropped.
+func applyBudget(budget EvidenceBudget, screenshots []*agent.Screenshot, logs []agent.ConsoleLog) ([]*agent.Screenshot, []agent.ConsoleLog, *TruncationInfo) {
+	info := &TruncationInfo{
+		OriginalScreenshots: len(screenshots),
+		OriginalConsoleLogs: len(logs),
+	}
+	var reasons []string
+
+	if budget.MaxScreenshots > 0 && len(screenshots) > budget.MaxScreenshots {
+		screenshots = agent.SampleScreenshots(screenshots, budget.MaxScreenshots)
+		reasons = append(reasons, fmt.Sprintf("more than %d screenshots", budget.MaxScreenshots))
+	}
+
+	if budget.MaxConsoleLogs > 0 && len(logs) > budget.MaxConsoleLogs {
+		logs = trimLogs(logs, budget.MaxConsoleLogs)
+		reasons = append(reasons, fmt.Sprintf("more than %d console logs", budget.MaxConsoleLogs))
+	}
+
+	if budget.MaxBytes > 0 && evidenceSize(screenshots, logs) > budget.MaxBytes {
+		reasons = append(reasons, fmt.Sprintf("evidence larger than %d MB", budget.MaxBytes/(1024*1024)))
+
+		// Halve logs first (cheap to lose), then screenshots, until under budget
+		for evidenceSize(screenshots, logs) > budget.MaxBytes {
+			if len(logs) > 100 {
+				logs = trimLogs(logs, len(logs)/2)
+			} else if len(screenshots) > 2 {
+				screenshots = agent.SampleScreenshots(screenshots, len(screenshots)/2)
+			} else {
+				// Nothing left worth dropping
+				break
+			}
+		}
+	}
+
+	if len(reasons) == 0 {
+		return screenshots, logs, nil
+	}
+
+	info.ScreenshotsDropped = info.OriginalScreenshots - len(screenshots)
+	info.ConsoleLogsDropped = info.OriginalConsoleLogs - len(logs)
+	info.Reason = strings.Join(reasons, ", ")
+	return screenshots, logs, info
+}
+
+// trimLogs keeps at most n logs, preferring errors and warnings, then the most recent entries
+func trimLogs(logs []agent.ConsoleLog, n int) []agent.ConsoleLog {
+	if n >= len(logs) {
+		return logs
+	}
+
+	keep := make([]bool, len(logs))
+	kept := 0
+	for i, l := range logs {
+		if kept == n {
+			break
+		}
+		if l.Level == agent.LogLevelException || l.Level == agent.LogLevelError || l.Level == agent.LogLevelWarning {
+			keep[i] = true
+			kept++
+		}
+	}
+	for i := len(logs) - 1; i >= 0 && kept < n; i-- {
+		if !keep[i] {
+			keep[i] = true
+			kept++
+		}
+	}
+
+	// Preserve original order
+	trimmed := make([]agent.ConsoleLog, 0, n)
+	for i, l := range logs {
+		if keep[i] {
+			trimmed = append(trimmed, l)
+		}
+	}
+	return trimmed
+}
+
+// evidenceSize estimates the stored size of the evidence in bytes
+func evidenceSize(screenshots []*agent.Screenshot, logs []agent.ConsoleLog) int64 {
+	var size int64
+	for _, ss := range screenshots {
+		size += int64(len(ss.Data))
+	}
+	if data, err := json.Marshal(logs); err == nil {
+		size += int64(len(data))
+	}
+	return size
+}
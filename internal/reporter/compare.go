@@ -0,0 +1,167 @@
+package reporter
+
+import (
+	"strings"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+)
+
+const (
+	// VerdictImproved means report B scored higher or resolved errors without adding new ones
+	VerdictImproved = "improved"
+	// VerdictRegressed means report B scored lower or introduced new errors without resolving any
+	VerdictRegressed = "regressed"
+	// VerdictUnchanged means no meaningful difference was found
+	VerdictUnchanged = "unchanged"
+)
+
+// Diff describes how report B changed relative to report A (typically an older run of the same game).
+// All deltas are B minus A.
+type Diff struct {
+	// ReportA and ReportB are the compared report IDs
+	ReportA string `json:"report_a"`
+	ReportB string `json:"report_b"`
+	// SameGame is true when both reports tested the same URL
+	SameGame bool `json:"same_game"`
+	// Verdict summarizes the change: improved, regressed or unchanged
+	Verdict string `json:"verdict"`
+	// Score compares LLM scores (nil if either report has no score)
+	Score *ScoreDiff `json:"score,omitempty"`
+	// NewErrors are console errors present in B but not A
+	NewErrors []string `json:"new_errors"`
+	// ResolvedErrors are console errors present in A but not B
+	ResolvedErrors []string `json:"resolved_errors"`
+	// FPS compares average frame rate (nil if either report lacks FPS metrics)
+	FPS *Delta `json:"fps,omitempty"`
+	// LoadTime compares page load time in milliseconds (nil if either report lacks it)
+	LoadTime *Delta `json:"load_time_ms,omitempty"`
+	// Notes explains comparisons that were skipped because data was missing
+	Notes []string `json:"notes,omitempty"`
+}
+
+// ScoreDiff compares the individual playability scores
+type ScoreDiff struct {
+	Overall       Delta `json:"overall"`
+	Interactivity Delta `json:"interactivity"`
+	VisualQuality Delta `json:"visual_quality"`
+	// ErrorSeverity is lower-is-better, so a negative change is an improvement
+	ErrorSeverity Delta `json:"error_severity"`
+}
+
+// Delta is a before/after value pair
+type Delta struct {
+	A      float64 `json:"a"`
+	B      float64 `json:"b"`
+	Change float64 `json:"change"`
+}
+
+// newDelta builds a Delta from two values
+func newDelta(a, b float64) Delta {
+	return Delta{A: a, B: b, Change: b - a}
+}
+
+// Compare reports score, console error and performance changes between two reports
+func Compare(a, b *Report) *Diff {
+	diff := &Diff{
+		ReportA:  a.ReportID,
+		ReportB:  b.ReportID,
+		SameGame: a.GameURL == b.GameURL,
+	}
+
+	if a.Score != nil && b.Score != nil {
+		diff.Score = &ScoreDiff{
+			Overall:       newDelta(float64(a.Score.OverallScore), float64(b.Score.OverallScore)),
+			Interactivity: newDelta(float64(a.Score.InteractivityScore), float64(b.Score.InteractivityScore)),
+			VisualQuality: newDelta(float64(a.Score.VisualQuality), float64(b.Score.VisualQuality)),
+			ErrorSeverity: newDelta(float64(a.Score.ErrorSeverity), float64(b.Score.ErrorSeverity)),
+		}
+	} else {
+		diff.Notes = append(diff.Notes, "score comparison skipped: a report has no score")
+	}
+
+	errorsA, errorsB := consoleErrors(a), consoleErrors(b)
+	diff.NewErrors = missingFrom(errorsB, errorsA)
+	diff.ResolvedErrors = missingFrom(errorsA, errorsB)
+
+	perfA, perfB := performanceMetrics(a), performanceMetrics(b)
+	if perfA != nil && perfB != nil && perfA.FPS != nil && perfB.FPS != nil {
+		fps := newDelta(perfA.FPS.Average, perfB.FPS.Average)
+		diff.FPS = &fps
+	} else {
+		diff.Notes = append(diff.Notes, "FPS comparison skipped: a report has no FPS metrics")
+	}
+	if perfA != nil && perfB != nil && perfA.LoadTime != nil && perfB.LoadTime != nil {
+		loadTime := newDelta(perfA.LoadTime.LoadComplete, perfB.LoadTime.LoadComplete)
+		diff.LoadTime = &loadTime
+	} else {
+		diff.Notes = append(diff.Notes, "load time comparison skipped: a report has no load time metrics")
+	}
+
+	diff.Verdict = verdict(diff)
+	return diff
+}
+
+// verdict decides the overall direction of a diff, preferring the overall score when available
+func verdict(diff *Diff) string {
+	if diff.Score != nil && diff.Score.Overall.Change != 0 {
+		if diff.Score.Overall.Change > 0 {
+			return VerdictImproved
+		}
+		return VerdictRegressed
+	}
+
+	switch {
+	case len(diff.NewErrors) > 0 && len(diff.ResolvedErrors) == 0:
+		return VerdictRegressed
+	case len(diff.ResolvedErrors) > 0 && len(diff.NewErrors) == 0:
+		return VerdictImproved
+	default:
+		return VerdictUnchanged
+	}
+}
+
+// consoleErrors returns the report's distinct console error messages in order
+func consoleErrors(r *Report) []string {
+	if r.Evidence == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var messages []string
+	for _, entry := range r.Evidence.ConsoleLogs {
+		if entry.Level != agent.LogLevelError && entry.Level != agent.LogLevelException {
+			continue
+		}
+		message := strings.TrimSpace(entry.Message)
+		if message == "" || seen[message] {
+			continue
+		}
+		seen[message] = true
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// missingFrom returns the entries of list that don't appear in other
+func missingFrom(list, other []string) []string {
+	present := make(map[string]bool, len(other))
+	for _, s := range other {
+		present[s] = true
+	}
+
+	missing := []string{}
+	for _, s := range list {
+		if !present[s] {
+			missing = append(missing, s)
+		}
+	}
+	return missing
+}
+
+// performanceMetrics returns the report's performance metrics, or nil if none were collected
+func performanceMetrics(r *Report) *agent.PerformanceMetrics {
+	if r.Evidence == nil {
+		return nil
+	}
+	return r.Evidence.PerformanceMetrics
+}
@@ -0,0 +1,64 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"cloud.google.com/go/storage"
+)
+
+// GCSUploader handles uploading artifacts to Google Cloud Storage
+type GCSUploader struct {
+	client     *storage.Client
+	bucketName string
+}
+
+// NewGCSUploader creates a new GCS uploader using Application Default Credentials
+func NewGCSUploader(bucketName string) (*GCSUploader, error) {
+	if bucketName == "" {
+		bucketName = os.Getenv("GCS_BUCKET_NAME")
+		if bucketName == "" {
+			bucketName = "dreamup-qa-artifacts"
+		}
+	}
+
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	return &GCSUploader{
+		client:     client,
+		bucketName: bucketName,
+	}, nil
+}
+
+// UploadFile streams a file to GCS
+func (u *GCSUploader) UploadFile(ctx context.Context, filePath, key string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	writer := u.client.Bucket(u.bucketName).Object(key).NewWriter(ctx)
+	writer.ContentType = getContentType(filePath)
+
+	if _, err := io.Copy(writer, file); err != nil {
+		writer.Close()
+		return "", fmt.Errorf("failed to upload to GCS: %w", err)
+	}
+	// The object is only committed once the writer is closed
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to upload to GCS: %w", err)
+	}
+
+	return u.GetURL(key), nil
+}
+
+// GetURL returns the public GCS URL for an object key
+func (u *GCSUploader) GetURL(key string) string {
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", u.bucketName, key)
+}
@@ -0,0 +1,111 @@
+package reporter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// junitTestSuite is the root <testsuite> element of a JUnit XML report
+type junitTestSuite struct {
+	XMLName    xml.Name        `xml:"testsuite"`
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+// junitProperty is a name/value pair CI systems display alongside the suite
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitTestCase is a single check in the report
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+// junitFailure marks a test case as failed
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML so CI systems (Jenkins, GitLab) can fail builds on it.
+// Passed checks become passing test cases, failed checks and critical issues become failures.
+// The game URL is used as the classname and the overall score is exported as a property.
+func WriteJUnit(r *Report, w io.Writer) error {
+	if r == nil {
+		return fmt.Errorf("report is nil")
+	}
+
+	suite := junitTestSuite{
+		Name:      "dreamup-qa",
+		Time:      strconv.FormatFloat(r.Duration.Seconds(), 'f', 3, 64),
+		Timestamp: r.Timestamp.Format("2006-01-02T15:04:05"),
+		Properties: []junitProperty{
+			{Name: "report_id", Value: r.ReportID},
+			{Name: "game_url", Value: r.GameURL},
+		},
+	}
+
+	if r.Score != nil {
+		suite.Properties = append(suite.Properties,
+			junitProperty{Name: "overall_score", Value: strconv.Itoa(r.Score.OverallScore)},
+		)
+	}
+
+	if r.Summary != nil {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "status", Value: r.Summary.Status})
+
+		for _, check := range r.Summary.PassedChecks {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				ClassName: r.GameURL,
+				Name:      check,
+				Time:      "0",
+			})
+		}
+		for _, check := range r.Summary.FailedChecks {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				ClassName: r.GameURL,
+				Name:      check,
+				Time:      "0",
+				Failure:   &junitFailure{Message: check, Type: "failed_check", Text: check},
+			})
+			suite.Failures++
+		}
+		for _, issue := range r.Summary.CriticalIssues {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				ClassName: r.GameURL,
+				Name:      "Critical: " + issue,
+				Time:      "0",
+				Failure:   &junitFailure{Message: issue, Type: "critical_issue", Text: issue},
+			})
+			suite.Failures++
+		}
+	}
+	suite.Tests = len(suite.TestCases)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write junit header: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return fmt.Errorf("failed to encode junit report: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write junit report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package reporter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+)
+
+// maxTopRepeated is how many repeated messages LogSummary.TopRepeated lists
+const maxTopRepeated = 5
+
+// volatileNumbers matches the parts of a log message that change between repeats of the
+// same problem: counters, frame numbers, coordinates, timings, addresses
+var volatileNumbers = regexp.MustCompile(`0x[0-9a-fA-F]+|\d+`)
+
+// RepeatedLog is a console message that was logged more than once
+type RepeatedLog struct {
+	Level agent.LogLevel `json:"level"`
+	// Message is the first occurrence's text
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// logSignature identifies a log message independent of the numbers in it, so a warning
+// repeated every frame with a different frame count is one problem
+func logSignature(log agent.ConsoleLog) string {
+	return string(log.Level) + "\x00" + volatileNumbers.ReplaceAllString(strings.TrimSpace(log.Message), "#")
+}
+
+// dedupeLogs collapses logs with the same signature into their first occurrence, with
+// Occurrences counting how many were seen. Order of first occurrence is kept.
+func dedupeLogs(logs []agent.ConsoleLog) []agent.ConsoleLog {
+	index := make(map[string]int, len(logs))
+	deduped := make([]agent.ConsoleLog, 0, len(logs))
+	for _, log := range logs {
+		signature := logSignature(log)
+		if i, ok := index[signature]; ok {
+			deduped[i].Occurrences++
+			continue
+		}
+		index[signature] = len(deduped)
+		log.Occurrences = 1
+		deduped = append(deduped, log)
+	}
+	return deduped
+}
+
+// repeatSuffix notes how often a deduplicated log was repeated, for summary messages
+func repeatSuffix(log agent.ConsoleLog) string {
+	if log.Occurrences > 1 {
+		return fmt.Sprintf(" (logged %d times)", log.Occurrences)
+	}
+	return ""
+}
+
+// topRepeated returns the n most repeated deduplicated logs, most frequent first
+func topRepeated(deduped []agent.ConsoleLog, n int) []RepeatedLog {
+	var repeated []RepeatedLog
+	for _, log := range deduped {
+		if log.Occurrences > 1 {
+			repeated = append(repeated, RepeatedLog{Level: log.Level, Message: log.Message, Count: log.Occurrences})
+		}
+	}
+	sort.SliceStable(repeated, func(i, j int) bool {
+		return repeated[i].Count > repeated[j].Count
+	})
+	if len(repeated) > n {
+		repeated = repeated[:n]
+	}
+	return repeated
+}
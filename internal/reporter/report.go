@@ -44,6 +44,22 @@ type Evidence struct {
 	LogSummary LogSummary `json:"log_summary"`
 	// DetectedElements are UI elements found
 	DetectedElements map[string]string `json:"detected_elements,omitempty"`
+	// Audio describes whether the game produced sound (nil if not checked)
+	Audio *agent.AudioStatus `json:"audio,omitempty"`
+	// PerformanceMetrics contains FPS, load time and accessibility results
+	PerformanceMetrics *agent.PerformanceMetrics `json:"performance_metrics,omitempty"`
+	// Truncated is set when evidence was sampled down to fit the evidence budget
+	Truncated *TruncationInfo `json:"truncated,omitempty"`
+	// NetworkLog is the filename of the HAR network capture (if captured)
+	NetworkLog string `json:"network_log,omitempty"`
+	// DOMSnapshot is the page's final <body> HTML, without scripts and styles
+	DOMSnapshot string `json:"dom_snapshot,omitempty"`
+	// VisualRegression compares the final screen with the game's baseline (nil if it has none)
+	VisualRegression *VisualRegression `json:"visual_regression,omitempty"`
+	// Actions are the clicks, drags, key and gamepad events sent during the test, in order
+	Actions []agent.RecordedAction `json:"actions,omitempty"`
+	// ActionsDropped counts actions past the action log's cap that weren't recorded
+	ActionsDropped int `json:"actions_dropped,omitempty"`
 }
 
 // ScreenshotInfo contains metadata about a screenshot
@@ -54,6 +70,8 @@ type ScreenshotInfo struct {
 	Filepath string `json:"filepath"`
 	// S3URL is the S3 URL (if uploaded)
 	S3URL string `json:"s3_url,omitempty"`
+	// AnnotatedFilepath is a copy marked up with the actions taken after this screenshot
+	AnnotatedFilepath string `json:"annotated_filepath,omitempty"`
 	// Timestamp is when it was captured
 	Timestamp time.Time `json:"timestamp"`
 	// Width in pixels
@@ -74,8 +92,21 @@ type LogSummary struct {
 	Info int `json:"info"`
 	// Debug count
 	Debug int `json:"debug"`
+	// Exceptions counts uncaught exceptions and unhandled promise rejections
+	Exceptions int `json:"exceptions"`
+	// UniqueErrors, UniqueWarnings and UniqueExceptions count distinct messages (numbers in
+	// them ignored), so a message logged every frame counts once
+	UniqueErrors     int `json:"unique_errors"`
+	UniqueWarnings   int `json:"unique_warnings"`
+	UniqueExceptions int `json:"unique_exceptions"`
+	// TopRepeated are the most repeated messages, most frequent first
+	TopRepeated []RepeatedLog `json:"top_repeated,omitempty"`
 }
 
+// LowConfidenceThreshold is the evaluation confidence below which the LLM's verdict is only
+// a warning: its critical findings are reported as failed checks instead of failing the test
+const LowConfidenceThreshold = 0.5
+
 // Summary provides a high-level test overview
 type Summary struct {
 	// Status is the overall test status (passed, failed, error)
@@ -94,10 +125,19 @@ type ReportBuilder struct {
 	startTime  time.Time
 	screenshots []*agent.Screenshot
 	videoURL   string
+	networkLog string
+	dom        string
 	logs       []agent.ConsoleLog
 	score      *evaluator.PlayabilityScore
 	detected   map[string]string
 	metadata   map[string]string
+	audio      *agent.AudioStatus
+	perf       *agent.PerformanceMetrics
+	budget     EvidenceBudget
+	visual     *VisualRegression
+	actions    *agent.ActionLog
+	// critical are problems found by the test itself rather than the evaluation
+	critical []string
 }
 
 // NewReportBuilder creates a new report builder
@@ -120,6 +160,21 @@ func (rb *ReportBuilder) SetVideoURL(videoURL string) {
 	rb.videoURL = videoURL
 }
 
+// SetNetworkLog sets the filename of the HAR network capture
+func (rb *ReportBuilder) SetNetworkLog(filename string) {
+	rb.networkLog = filename
+}
+
+// SetVisualRegression sets the comparison of the final screen with its baseline
+func (rb *ReportBuilder) SetVisualRegression(v *VisualRegression) {
+	rb.visual = v
+}
+
+// SetDOMSnapshot sets the final DOM snapshot (see agent.CaptureDOM)
+func (rb *ReportBuilder) SetDOMSnapshot(html string) {
+	rb.dom = html
+}
+
 // SetConsoleLogs sets the console logs for the report
 func (rb *ReportBuilder) SetConsoleLogs(logs []agent.ConsoleLog) {
 	rb.logs = logs
@@ -135,6 +190,32 @@ func (rb *ReportBuilder) SetDetectedElements(detected map[string]string) {
 	rb.detected = detected
 }
 
+// SetActionLog sets the input actions recorded during the test
+func (rb *ReportBuilder) SetActionLog(actions *agent.ActionLog) {
+	rb.actions = actions
+}
+
+// SetAudioStatus sets the audio detection result
+func (rb *ReportBuilder) SetAudioStatus(audio *agent.AudioStatus) {
+	rb.audio = audio
+}
+
+// SetPerformanceMetrics sets the performance metrics for the report
+func (rb *ReportBuilder) SetPerformanceMetrics(metrics *agent.PerformanceMetrics) {
+	rb.perf = metrics
+}
+
+// SetEvidenceBudget limits the screenshots and logs included in the report
+func (rb *ReportBuilder) SetEvidenceBudget(budget EvidenceBudget) {
+	rb.budget = budget
+}
+
+// AddCriticalIssue records a blocking problem found outside the LLM evaluation
+// (e.g. the game never rendered), which fails the report
+func (rb *ReportBuilder) AddCriticalIssue(issue string) {
+	rb.critical = append(rb.critical, issue)
+}
+
 // AddMetadata adds a metadata key-value pair
 func (rb *ReportBuilder) AddMetadata(key, value string) {
 	rb.metadata[key] = value
@@ -148,15 +229,21 @@ func (rb *ReportBuilder) Build() (*Report, error) {
 	// Calculate duration
 	duration := time.Since(rb.startTime)
 
+	// Store each distinct message once, then sample evidence down to the budget (the log
+	// summary below still counts every log)
+	dedupedLogs := dedupeLogs(rb.logs)
+	screenshots, keptLogs, truncated := applyBudget(rb.budget, rb.screenshots, dedupedLogs)
+
 	// Build screenshot info
-	screenshotInfos := make([]ScreenshotInfo, 0, len(rb.screenshots))
-	for _, ss := range rb.screenshots {
+	screenshotInfos := make([]ScreenshotInfo, 0, len(screenshots))
+	for _, ss := range screenshots {
 		screenshotInfos = append(screenshotInfos, ScreenshotInfo{
-			Context:   ss.Context,
-			Filepath:  ss.Filepath,
-			Timestamp: ss.Timestamp,
-			Width:     ss.Width,
-			Height:    ss.Height,
+			Context:           ss.Context,
+			Filepath:          ss.Filepath,
+			AnnotatedFilepath: ss.AnnotatedFilepath,
+			Timestamp:         ss.Timestamp,
+			Width:             ss.Width,
+			Height:            ss.Height,
 		})
 	}
 
@@ -174,20 +261,41 @@ func (rb *ReportBuilder) Build() (*Report, error) {
 			logSummary.Info++
 		case agent.LogLevelDebug:
 			logSummary.Debug++
+		case agent.LogLevelException:
+			logSummary.Exceptions++
 		}
 	}
+	for _, log := range dedupedLogs {
+		switch log.Level {
+		case agent.LogLevelError:
+			logSummary.UniqueErrors++
+		case agent.LogLevelWarning:
+			logSummary.UniqueWarnings++
+		case agent.LogLevelException:
+			logSummary.UniqueExceptions++
+		}
+	}
+	logSummary.TopRepeated = topRepeated(dedupedLogs, maxTopRepeated)
 
 	// Build evidence
 	evidence := &Evidence{
-		Screenshots:      screenshotInfos,
-		VideoURL:         rb.videoURL,
-		ConsoleLogs:      rb.logs,
-		LogSummary:       logSummary,
-		DetectedElements: rb.detected,
+		Screenshots:        screenshotInfos,
+		VideoURL:           rb.videoURL,
+		NetworkLog:         rb.networkLog,
+		DOMSnapshot:        rb.dom,
+		VisualRegression:   rb.visual,
+		ConsoleLogs:        keptLogs,
+		LogSummary:         logSummary,
+		DetectedElements:   rb.detected,
+		Audio:              rb.audio,
+		PerformanceMetrics: rb.perf,
+		Truncated:          truncated,
+		Actions:            rb.actions.Actions(),
+		ActionsDropped:     rb.actions.Dropped(),
 	}
 
 	// Build summary
-	summary := rb.buildSummary()
+	summary := rb.buildSummary(dedupedLogs)
 
 	// Create report
 	report := &Report{
@@ -204,21 +312,37 @@ func (rb *ReportBuilder) Build() (*Report, error) {
 	return report, nil
 }
 
-// buildSummary constructs the test summary
-func (rb *ReportBuilder) buildSummary() *Summary {
+// buildSummary constructs the test summary; dedupedLogs are the console logs with repeats
+// collapsed (see dedupeLogs)
+func (rb *ReportBuilder) buildSummary(dedupedLogs []agent.ConsoleLog) *Summary {
 	summary := &Summary{
 		PassedChecks:   make([]string, 0),
 		FailedChecks:   make([]string, 0),
 		CriticalIssues: make([]string, 0),
 	}
+	summary.CriticalIssues = append(summary.CriticalIssues, rb.critical...)
+
+	// Visual regressions are critical even if the evaluation rates the game as fine
+	if rb.visual != nil {
+		if rb.visual.Regressed {
+			summary.CriticalIssues = append(summary.CriticalIssues,
+				fmt.Sprintf("Visual regression: %.1f%% of the final screen differs from the baseline", rb.visual.DiffRatio*100))
+		} else {
+			summary.PassedChecks = append(summary.PassedChecks, "Final screen matches baseline")
+		}
+	}
 
 	// Determine status based on score and logs
 	if rb.score != nil {
+		// A confidence of 0 means the evaluator didn't report one
+		lowConfidence := rb.score.Confidence > 0 && rb.score.Confidence < LowConfidenceThreshold
+		scoreIssues := make([]string, 0)
+
 		if rb.score.LoadsCorrectly {
 			summary.PassedChecks = append(summary.PassedChecks, "Game loads successfully")
 		} else {
 			summary.FailedChecks = append(summary.FailedChecks, "Game failed to load")
-			summary.CriticalIssues = append(summary.CriticalIssues, "Game does not load correctly")
+			scoreIssues = append(scoreIssues, "Game does not load correctly")
 		}
 
 		if rb.score.OverallScore >= 70 {
@@ -234,25 +358,53 @@ func (rb *ReportBuilder) buildSummary() *Summary {
 		}
 
 		if rb.score.ErrorSeverity > 50 {
-			summary.CriticalIssues = append(summary.CriticalIssues, "High severity errors detected")
+			scoreIssues = append(scoreIssues, "High severity errors detected")
 		}
 
 		// Add issues from score
-		summary.CriticalIssues = append(summary.CriticalIssues, rb.score.Issues...)
+		scoreIssues = append(scoreIssues, rb.score.Issues...)
+
+		// Don't fail a test on a verdict the evaluator itself isn't sure of
+		if lowConfidence {
+			summary.FailedChecks = append(summary.FailedChecks,
+				fmt.Sprintf("Low-confidence evaluation (%.2f), review manually", rb.score.Confidence))
+			summary.FailedChecks = append(summary.FailedChecks, scoreIssues...)
+		} else {
+			summary.CriticalIssues = append(summary.CriticalIssues, scoreIssues...)
+		}
 	}
 
-	// Check for console errors
+	// Check for console errors, counting distinct messages so one error logged every frame
+	// doesn't outweigh several different ones. Uncaught exceptions are counted separately:
+	// they usually mean game code stopped running, so any of them is critical.
 	errorCount := 0
-	for _, log := range rb.logs {
-		if log.Level == agent.LogLevelError {
+	var exceptions []agent.ConsoleLog
+	for _, log := range dedupedLogs {
+		switch log.Level {
+		case agent.LogLevelError:
 			errorCount++
+		case agent.LogLevelException:
+			exceptions = append(exceptions, log)
 		}
 	}
 
+	if len(exceptions) > 0 {
+		summary.CriticalIssues = append(summary.CriticalIssues,
+			fmt.Sprintf("%d distinct uncaught JavaScript exception(s), first: %s%s",
+				len(exceptions), exceptions[0].Message, repeatSuffix(exceptions[0])))
+	} else {
+		summary.PassedChecks = append(summary.PassedChecks, "No uncaught exceptions")
+	}
+
 	if errorCount == 0 {
 		summary.PassedChecks = append(summary.PassedChecks, "No console errors")
 	} else if errorCount > 5 {
-		summary.FailedChecks = append(summary.FailedChecks, fmt.Sprintf("%d console errors found", errorCount))
+		summary.FailedChecks = append(summary.FailedChecks, fmt.Sprintf("%d distinct console errors found", errorCount))
+	}
+
+	// Audio is informational only - plenty of games are silent by design
+	if rb.audio != nil && rb.audio.Detected {
+		summary.PassedChecks = append(summary.PassedChecks, "Audio detected")
 	}
 
 	// Determine overall status
@@ -297,3 +449,33 @@ func (r *Report) SaveToTemp() (string, error) {
 
 	return filepath, nil
 }
+
+// MediaFiles returns the names of the files in the media directory the report refers to:
+// its screenshots and their annotated copies, the gameplay video and its GIF thumbnail,
+// the visual regression diff and the HAR network capture
+func (r *Report) MediaFiles() []string {
+	if r.Evidence == nil {
+		return nil
+	}
+
+	var files []string
+	add := func(path string) {
+		// Only the filename is stored locally (e.g. VideoURL is /api/videos/{filename})
+		if name := filepath.Base(path); path != "" && name != "." && name != ".." && name != string(filepath.Separator) {
+			files = append(files, name)
+		}
+	}
+	for _, ss := range r.Evidence.Screenshots {
+		add(ss.Filepath)
+		add(ss.AnnotatedFilepath)
+	}
+	if r.Evidence.VideoURL != "" {
+		add(r.Evidence.VideoURL)
+		add(agent.GIFThumbnailName(filepath.Base(r.Evidence.VideoURL)))
+	}
+	if v := r.Evidence.VisualRegression; v != nil {
+		add(v.DiffImage)
+	}
+	add(r.Evidence.NetworkLog)
+	return files
+}
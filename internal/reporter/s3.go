@@ -4,8 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -13,11 +13,40 @@ import (
 	"github.com/dreamup/qa-agent/internal/agent"
 )
 
+// MaxPresignTTL is the longest a SigV4 presigned URL can be valid
+const MaxPresignTTL = 7 * 24 * time.Hour
+
+// DefaultPresignTTL is how long presigned artifact URLs are requested to stay valid. A
+// presigned URL also stops working when the credentials that signed it expire, so under
+// temporary credentials (Lambda roles, STS) the TTL is cut to their remaining lifetime.
+const DefaultPresignTTL = MaxPresignTTL
+
+// PresignTTLFromEnv returns the S3_PRESIGN_TTL env var (a duration such as "24h"), or
+// DefaultPresignTTL if it's unset
+func PresignTTLFromEnv() (time.Duration, error) {
+	v := strings.TrimSpace(os.Getenv("S3_PRESIGN_TTL"))
+	if v == "" {
+		return DefaultPresignTTL, nil
+	}
+	ttl, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("S3_PRESIGN_TTL: %w", err)
+	}
+	if ttl <= 0 || ttl > MaxPresignTTL {
+		return 0, fmt.Errorf("S3_PRESIGN_TTL must be positive and at most %v, got %v", MaxPresignTTL, ttl)
+	}
+	return ttl, nil
+}
+
 // S3Uploader handles uploading artifacts to S3
 type S3Uploader struct {
-	client     *s3.Client
-	bucketName string
-	region     string
+	client      *s3.Client
+	presigner   *s3.PresignClient
+	credentials aws.CredentialsProvider
+	bucketName  string
+	region      string
+	// presignTTL, when non-zero, makes uploads return presigned URLs instead of public ones
+	presignTTL time.Duration
 }
 
 // NewS3Uploader creates a new S3 uploader
@@ -47,129 +76,128 @@ func NewS3Uploader(bucketName, region string) (*S3Uploader, error) {
 	client := s3.NewFromConfig(cfg)
 
 	return &S3Uploader{
-		client:     client,
-		bucketName: bucketName,
-		region:     region,
+		client:      client,
+		presigner:   s3.NewPresignClient(client),
+		credentials: cfg.Credentials,
+		bucketName:  bucketName,
+		region:      region,
 	}, nil
 }
 
+// SetPresign makes uploaded artifact URLs (screenshots, video) presigned for ttl so they
+// work from a private bucket. A ttl of 0 returns to public URLs.
+func (u *S3Uploader) SetPresign(ttl time.Duration) {
+	u.presignTTL = ttl
+}
+
+// PresignURL returns a presigned GET URL for an object key, valid for ttl or until the
+// signing credentials expire, whichever is sooner
+func (u *S3Uploader) PresignURL(ctx context.Context, s3Key string, ttl time.Duration) (string, error) {
+	if u.credentials != nil {
+		// A failure here fails the presign below with the same error
+		if creds, err := u.credentials.Retrieve(ctx); err == nil {
+			ttl = presignTTL(ttl, creds, time.Now())
+		}
+	}
+	req, err := u.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(u.bucketName),
+		Key:    aws.String(s3Key),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign %s: %w", s3Key, err)
+	}
+	return req.URL, nil
+}
+
+// presignTTL cuts ttl to the remaining lifetime of temporary credentials, past which a
+// URL they signed stops working
+func presignTTL(ttl time.Duration, creds aws.Credentials, now time.Time) time.Duration {
+	if !creds.CanExpire {
+		return ttl
+	}
+	if remaining := creds.Expires.Sub(now); remaining > 0 && remaining < ttl {
+		return remaining
+	}
+	return ttl
+}
+
+// PresignReportURL returns a presigned URL for a report JSON, valid for ttl
+func (u *S3Uploader) PresignReportURL(ctx context.Context, reportID string, ttl time.Duration) (string, error) {
+	return u.PresignURL(ctx, reportKey(reportID), ttl)
+}
+
 // UploadFile uploads a file to S3
 func (u *S3Uploader) UploadFile(ctx context.Context, filepath, s3Key string) (string, error) {
-	// Read file
-	data, err := os.ReadFile(filepath)
+	// Open file so large artifacts (videos) are streamed rather than held in memory
+	file, err := os.Open(filepath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file %s: %w", filepath, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
 	if err != nil {
-		return "", fmt.Errorf("failed to read file %s: %w", filepath, err)
+		return "", fmt.Errorf("failed to stat file %s: %w", filepath, err)
 	}
 
 	// Determine content type
-	contentType := u.getContentType(filepath)
+	contentType := getContentType(filepath)
 
 	// Upload to S3
 	_, err = u.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(u.bucketName),
-		Key:         aws.String(s3Key),
-		Body:        strings.NewReader(string(data)),
-		ContentType: aws.String(contentType),
+		Bucket:        aws.String(u.bucketName),
+		Key:           aws.String(s3Key),
+		Body:          file,
+		ContentLength: aws.Int64(info.Size()),
+		ContentType:   aws.String(contentType),
 	})
 
 	if err != nil {
 		return "", fmt.Errorf("failed to upload to S3: %w", err)
 	}
 
-	// Construct S3 URL
-	s3URL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s",
+	if u.presignTTL > 0 {
+		return u.PresignURL(ctx, s3Key, u.presignTTL)
+	}
+
+	return u.GetURL(s3Key), nil
+}
+
+// GetURL returns the public S3 URL for an object key
+func (u *S3Uploader) GetURL(s3Key string) string {
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s",
 		u.bucketName,
 		u.region,
 		s3Key,
 	)
-
-	return s3URL, nil
-}
-
-// getContentType determines content type from file extension
-func (u *S3Uploader) getContentType(filePath string) string {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	switch ext {
-	case ".json":
-		return "application/json"
-	case ".png":
-		return "image/png"
-	case ".jpg", ".jpeg":
-		return "image/jpeg"
-	case ".txt":
-		return "text/plain"
-	default:
-		return "application/octet-stream"
-	}
 }
 
 // UploadScreenshot uploads a screenshot to S3
 func (u *S3Uploader) UploadScreenshot(ctx context.Context, screenshot *agent.Screenshot, reportID string) (string, error) {
-	// Generate S3 key
-	s3Key := fmt.Sprintf("reports/%s/screenshots/%s_%s.png",
-		reportID,
-		screenshot.Context,
-		screenshot.Timestamp.Format("20060102_150405"),
-	)
-
-	return u.UploadFile(ctx, screenshot.Filepath, s3Key)
+	return u.UploadFile(ctx, screenshot.Filepath, screenshotKey(screenshot, reportID))
 }
 
 // UploadReport uploads a report JSON to S3
 func (u *S3Uploader) UploadReport(ctx context.Context, reportPath, reportID string) (string, error) {
-	s3Key := fmt.Sprintf("reports/%s/report.json", reportID)
-	return u.UploadFile(ctx, reportPath, s3Key)
+	return u.UploadFile(ctx, reportPath, reportKey(reportID))
 }
 
 // UploadConsoleLogs uploads console logs to S3
 func (u *S3Uploader) UploadConsoleLogs(ctx context.Context, logPath, reportID string) (string, error) {
-	s3Key := fmt.Sprintf("reports/%s/console_logs.json", reportID)
-	return u.UploadFile(ctx, logPath, s3Key)
+	return u.UploadFile(ctx, logPath, consoleLogsKey(reportID))
 }
 
-// UploadReportWithArtifacts uploads a complete report with all artifacts
-func (u *S3Uploader) UploadReportWithArtifacts(ctx context.Context, report *Report, screenshots []*agent.Screenshot, logPath string) error {
-	// Upload screenshots and update report
-	for i, screenshot := range screenshots {
-		s3URL, err := u.UploadScreenshot(ctx, screenshot, report.ReportID)
-		if err != nil {
-			return fmt.Errorf("failed to upload screenshot %d: %w", i, err)
-		}
-		// Update S3 URL in report
-		if i < len(report.Evidence.Screenshots) {
-			report.Evidence.Screenshots[i].S3URL = s3URL
-		}
-	}
-
-	// Save updated report to temp file
-	reportPath, err := report.SaveToTemp()
-	if err != nil {
-		return fmt.Errorf("failed to save report: %w", err)
-	}
-	defer os.Remove(reportPath)
-
-	// Upload report
-	_, err = u.UploadReport(ctx, reportPath, report.ReportID)
-	if err != nil {
-		return fmt.Errorf("failed to upload report: %w", err)
-	}
-
-	// Upload console logs if provided
-	if logPath != "" {
-		_, err = u.UploadConsoleLogs(ctx, logPath, report.ReportID)
-		if err != nil {
-			return fmt.Errorf("failed to upload console logs: %w", err)
-		}
-	}
+// UploadVideo uploads a gameplay video to S3
+func (u *S3Uploader) UploadVideo(ctx context.Context, videoPath, reportID string) (string, error) {
+	return u.UploadFile(ctx, videoPath, videoKey(videoPath, reportID))
+}
 
-	return nil
+// UploadReportWithArtifacts uploads a complete report with all artifacts to S3
+func (u *S3Uploader) UploadReportWithArtifacts(ctx context.Context, report *Report, screenshots []*agent.Screenshot, logPath, videoPath string) error {
+	return UploadReportWithArtifacts(ctx, u, report, screenshots, logPath, videoPath)
 }
 
 // GetReportURL returns the S3 URL for a report
 func (u *S3Uploader) GetReportURL(reportID string) string {
-	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/reports/%s/report.json",
-		u.bucketName,
-		u.region,
-		reportID,
-	)
+	return ReportURL(u, reportID)
 }
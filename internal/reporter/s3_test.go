@@ -0,0 +1,58 @@
+package reporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestPresignTTL(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name  string
+		ttl   time.Duration
+		creds aws.Credentials
+		want  time.Duration
+	}{
+		{name: "long-lived keys", ttl: DefaultPresignTTL, creds: aws.Credentials{}, want: DefaultPresignTTL},
+		{name: "role credentials expiring first", ttl: DefaultPresignTTL, creds: aws.Credentials{CanExpire: true, Expires: now.Add(6 * time.Hour)}, want: 6 * time.Hour},
+		{name: "TTL shorter than credentials", ttl: time.Hour, creds: aws.Credentials{CanExpire: true, Expires: now.Add(6 * time.Hour)}, want: time.Hour},
+		{name: "already expired credentials are refreshed when signing", ttl: time.Hour, creds: aws.Credentials{CanExpire: true, Expires: now.Add(-time.Minute)}, want: time.Hour},
+	}
+	for _, tt := range tests {
+		if got := presignTTL(tt.ttl, tt.creds, now); got != tt.want {
+			t.Errorf("%s: presignTTL = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPresignTTLFromEnv(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: DefaultPresignTTL},
+		{value: "24h", want: 24 * time.Hour},
+		{value: " 90m ", want: 90 * time.Minute},
+		{value: "168h", want: MaxPresignTTL},
+		{value: "169h", wantErr: true},
+		{value: "0s", wantErr: true},
+		{value: "-1h", wantErr: true},
+		{value: "7d", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Setenv("S3_PRESIGN_TTL", tt.value)
+		got, err := PresignTTLFromEnv()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("S3_PRESIGN_TTL=%q: got %v, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("S3_PRESIGN_TTL=%q: got %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// snapshotTemplate renders a report as a single HTML page. Media sources are resolved
+// before rendering so the same layout can either inline or link assets.
+var snapshotTemplate = template.Must(template.New("snapshot").Funcs(template.FuncMap{
+	"pct": func(v int) string { return fmt.Sprintf("%d/100", v) },
+	// ratio formats a 0-1 fraction as a percentage
+	"ratio": func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) },
+	// mb formats a byte count in megabytes
+	"mb": func(v int64) string { return fmt.Sprintf("%.1f MB", float64(v)/(1<<20)) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>QA Report - {{.Report.GameURL}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; margin: 2rem auto; max-width: 960px; color: #1f2933; }
+h1 { font-size: 1.5rem; margin-bottom: 0.25rem; }
+.meta { color: #616e7c; font-size: 0.9rem; margin-bottom: 1.5rem; }
+.status { display: inline-block; padding: 0.2rem 0.6rem; border-radius: 4px; font-weight: 600; }
+.status-passed { background: #e3f9e5; color: #207227; }
+.status-passed_with_warnings { background: #fffbea; color: #8d2b0b; }
+.status-failed { background: #ffe3e3; color: #a61b1b; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1.5rem; }
+td, th { border-bottom: 1px solid #e4e7eb; padding: 0.4rem; text-align: left; vertical-align: top; }
+figure { margin: 0 0 1.5rem 0; }
+figure img, video { max-width: 100%; border: 1px solid #cbd2d9; }
+figcaption { color: #616e7c; font-size: 0.85rem; }
+figure.thumb { display: inline-block; width: 300px; margin: 0 1rem 1rem 0; vertical-align: top; }
+table.logs td { font-family: monospace; font-size: 0.8rem; }
+tr.log-error td { background: #ffe3e3; }
+tr.log-warning td { background: #fffbea; }
+tr.log-info td { color: #0b69a3; }
+tr.log-debug td { color: #7b8794; }
+</style>
+</head>
+<body>
+<h1>{{.Report.GameURL}}</h1>
+<div class="meta">Report {{.Report.ReportID}} &middot; {{.Report.Timestamp.Format "2006-01-02 15:04:05 MST"}} &middot; {{.Duration}}</div>
+{{with .Report.Summary}}<p><span class="status status-{{.Status}}">{{.Status}}</span></p>{{end}}
+
+{{with .Report.Score}}
+<h2>Score</h2>
+<table>
+<tr><th>Overall</th><td>{{pct .OverallScore}}</td></tr>
+<tr><th>Loads correctly</th><td>{{if .LoadsCorrectly}}yes{{else}}no{{end}}</td></tr>
+<tr><th>Interactivity</th><td>{{pct .InteractivityScore}}</td></tr>
+<tr><th>Visual quality</th><td>{{pct .VisualQuality}}</td></tr>
+<tr><th>Error severity</th><td>{{pct .ErrorSeverity}}</td></tr>
+{{if .Confidence}}<tr><th>Confidence</th><td>{{printf "%.2f" .Confidence}}</td></tr>{{end}}
+</table>
+{{if .Reasoning}}<p>{{.Reasoning}}</p>{{end}}
+{{if .IssueEvidence}}<h3>Issues</h3><ul>{{range .IssueEvidence}}<li>{{.Issue}}{{if .Screenshot}} <small>({{.Screenshot}} screenshot)</small>{{end}}{{if .LogSnippet}}<br><code>{{.LogSnippet}}</code>{{end}}</li>{{end}}</ul>
+{{else if .Issues}}<h3>Issues</h3><ul>{{range .Issues}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .Recommendations}}<h3>Recommendations</h3><ul>{{range .Recommendations}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{end}}
+
+{{with .Report.Summary}}
+{{if .PassedChecks}}<h3>Passed checks</h3><ul>{{range .PassedChecks}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .FailedChecks}}<h3>Failed checks</h3><ul>{{range .FailedChecks}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{end}}
+
+{{with .Report.Evidence}}{{with .PerformanceMetrics}}
+<h2>Performance</h2>
+<table>
+{{with .FPS}}<tr><th>FPS</th><td>avg {{printf "%.1f" .Average}} &middot; min {{printf "%.1f" .Min}} &middot; max {{printf "%.1f" .Max}}</td></tr>
+<tr><th>FPS percentiles</th><td>p1 {{printf "%.1f" .P1}} &middot; p50 {{printf "%.1f" .P50}} ({{len .Frames}} one-second samples)</td></tr>{{end}}
+{{with .LoadTime}}<tr><th>First contentful paint</th><td>{{printf "%.0f" .FirstContentfulPaint}} ms</td></tr>
+<tr><th>DOM content loaded</th><td>{{printf "%.0f" .DOMContentLoaded}} ms</td></tr>
+<tr><th>Load complete</th><td>{{printf "%.0f" .LoadComplete}} ms</td></tr>
+<tr><th>Largest contentful paint</th><td>{{printf "%.0f" .LargestContentfulPaint}} ms</td></tr>
+<tr><th>Cumulative layout shift</th><td>{{printf "%.3f" .CumulativeLayoutShift}}</td></tr>
+<tr><th>Total blocking time</th><td>{{printf "%.0f" .TotalBlockingTime}} ms</td></tr>{{end}}
+{{range .Runtime}}<tr><th>Runtime ({{.Label}})</th><td>JS heap {{mb .JSHeapUsedBytes}} of {{mb .JSHeapTotalBytes}} &middot; {{.DOMNodes}} DOM nodes &middot; {{.JSEventListeners}} listeners &middot; {{.LayoutCount}} layouts &middot; {{.RecalcStyleCount}} style recalcs</td></tr>
+{{end}}{{with .Accessibility}}<tr><th>Accessibility</th><td>{{pct .Score}} ({{.Critical}} critical, {{.Serious}} serious, {{.Moderate}} moderate, {{.Minor}} minor; {{.AffectedNodes}} elements affected)</td></tr>{{end}}
+</table>
+{{end}}{{end}}
+
+{{if .Screenshots}}
+<h2>Screenshots</h2>
+{{range .Screenshots}}
+<figure{{if $.Thumbnails}} class="thumb"{{end}}>
+<img src="{{.Src}}" alt="{{.Context}} screenshot">
+<figcaption>{{.Context}} &middot; {{.Timestamp.Format "15:04:05"}}{{if .AnnotatedSrc}} &middot; <a href="{{.AnnotatedSrc}}">actions</a>{{end}}</figcaption>
+</figure>
+{{end}}
+{{end}}
+
+{{with .Report.Evidence}}{{with .VisualRegression}}
+<h2>Visual regression</h2>
+<p>{{if .Regressed}}Regressed{{else}}Matches baseline{{end}}: {{ratio .DiffRatio}} of the final screen changed (threshold {{ratio .Threshold}}), baseline from {{.BaselineCapturedAt.Format "2006-01-02 15:04"}}.</p>
+{{end}}{{end}}
+{{if .VisualDiffSrc}}
+<figure>
+<img src="{{.VisualDiffSrc}}" alt="visual diff against baseline">
+<figcaption>Changed pixels in red</figcaption>
+</figure>
+{{end}}
+
+{{if .VideoSrc}}
+<h2>Gameplay video</h2>
+<video controls src="{{.VideoSrc}}"></video>
+{{end}}
+
+{{with .Report.Evidence}}{{with .DetectedElements}}
+<h2>Detected UI elements</h2>
+<table>
+{{range $name, $selector := .}}<tr><th>{{$name}}</th><td><code>{{$selector}}</code></td></tr>
+{{end}}</table>
+{{end}}{{end}}
+
+{{with .Report.Evidence}}
+{{with .Truncated}}<p><em>Evidence truncated ({{.Reason}}): {{.ScreenshotsDropped}} of {{.OriginalScreenshots}} screenshots and {{.ConsoleLogsDropped}} of {{.OriginalConsoleLogs}} console logs omitted.</em></p>{{end}}
+<h2>Console</h2>
+{{with .Audio}}<p>Audio detected: {{if .Detected}}yes{{else}}no{{end}}</p>{{end}}
+<p>{{.LogSummary.Total}} logs &middot; {{.LogSummary.Exceptions}} uncaught exceptions &middot; {{.LogSummary.Errors}} errors &middot; {{.LogSummary.Warnings}} warnings
+({{.LogSummary.UniqueExceptions}}, {{.LogSummary.UniqueErrors}} and {{.LogSummary.UniqueWarnings}} distinct)</p>
+{{with .LogSummary.TopRepeated}}<p>Most repeated:</p>
+<ul>
+{{range .}}<li>{{.Level}} &times;{{.Count}}: {{.Message}}</li>
+{{end}}</ul>
+{{end}}
+{{if .ConsoleLogs}}<table class="logs">
+<tr><th>Time</th><th>Level</th><th>Message</th><th>Count</th></tr>
+{{range .ConsoleLogs}}<tr class="log-{{.Level}}"><td>{{.Timestamp.Format "15:04:05.000"}}</td><td>{{.Level}}</td><td>{{.Message}}</td><td>{{if .Occurrences}}{{.Occurrences}}{{else}}1{{end}}</td></tr>
+{{end}}</table>{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// snapshotScreenshot is a screenshot with its resolved image source
+type snapshotScreenshot struct {
+	ScreenshotInfo
+	Src template.URL
+	// AnnotatedSrc is the copy marked up with the actions taken, if there is one
+	AnnotatedSrc template.URL
+}
+
+// snapshotView is the data passed to snapshotTemplate
+type snapshotView struct {
+	Report      *Report
+	Duration    string
+	Screenshots []snapshotScreenshot
+	VideoSrc    template.URL
+	// VisualDiffSrc is the highlighted diff against the visual baseline, if any
+	VisualDiffSrc template.URL
+	// Thumbnails lays screenshots out as a grid instead of full width
+	Thumbnails bool
+}
+
+// RenderSnapshot renders the report as a self-contained HTML document.
+// Screenshots and the gameplay video are read from mediaDir and inlined as base64
+// data URIs, so the output can be shared without access to the server.
+// Media that cannot be read is skipped rather than failing the whole export.
+func RenderSnapshot(report *Report, mediaDir string) ([]byte, error) {
+	return renderReportHTML(report, false, func(filename string) (template.URL, error) {
+		return inlineMedia(mediaDir, filename)
+	})
+}
+
+// RenderHTML renders the report as an HTML page for viewing from the server.
+// Screenshots and video are referenced through the /api/screenshots/ and /api/videos/
+// endpoints rather than inlined, so the page stays small. Sections for a missing
+// score or missing performance metrics are omitted.
+func RenderHTML(r *Report) ([]byte, error) {
+	return renderReportHTML(r, true, func(filename string) (template.URL, error) {
+		if strings.HasSuffix(filename, ".mp4") || strings.HasSuffix(filename, ".webm") {
+			return template.URL("/api/videos/" + url.PathEscape(filename)), nil
+		}
+		return template.URL("/api/screenshots/" + url.PathEscape(filename)), nil
+	})
+}
+
+// renderReportHTML renders the report, resolving each media filename with resolve
+func renderReportHTML(report *Report, thumbnails bool, resolve func(filename string) (template.URL, error)) ([]byte, error) {
+	if report == nil {
+		return nil, fmt.Errorf("report is nil")
+	}
+
+	view := snapshotView{
+		Report:     report,
+		Duration:   report.Duration.Round(time.Second).String(),
+		Thumbnails: thumbnails,
+	}
+
+	if report.Evidence != nil {
+		for _, ss := range report.Evidence.Screenshots {
+			if ss.Filepath == "" {
+				continue
+			}
+			src, err := resolve(filepath.Base(ss.Filepath))
+			if err != nil {
+				continue
+			}
+			shot := snapshotScreenshot{ScreenshotInfo: ss, Src: src}
+			if ss.AnnotatedFilepath != "" {
+				if annotated, err := resolve(filepath.Base(ss.AnnotatedFilepath)); err == nil {
+					shot.AnnotatedSrc = annotated
+				}
+			}
+			view.Screenshots = append(view.Screenshots, shot)
+		}
+
+		if report.Evidence.VideoURL != "" {
+			// VideoURL is a server path like /api/videos/{filename}; only the filename is stored locally
+			if src, err := resolve(filepath.Base(report.Evidence.VideoURL)); err == nil {
+				view.VideoSrc = src
+			}
+		}
+
+		if v := report.Evidence.VisualRegression; v != nil && v.DiffImage != "" {
+			if src, err := resolve(filepath.Base(v.DiffImage)); err == nil {
+				view.VisualDiffSrc = src
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := snapshotTemplate.Execute(&buf, view); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// inlineMedia reads a media file and returns it as a base64 data URI
+func inlineMedia(mediaDir, filename string) (template.URL, error) {
+	if filename == "" || filename == "." || strings.Contains(filename, "..") {
+		return "", fmt.Errorf("invalid media filename: %q", filename)
+	}
+
+	data, err := os.ReadFile(filepath.Join(mediaDir, filename))
+	if err != nil {
+		return "", fmt.Errorf("failed to read media %s: %w", filename, err)
+	}
+
+	// mime's built-in table has no entry for mp4/webm, so map the formats we produce explicitly
+	var contentType string
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".png":
+		contentType = "image/png"
+	case ".jpg", ".jpeg":
+		contentType = "image/jpeg"
+	case ".mp4":
+		contentType = "video/mp4"
+	case ".webm":
+		contentType = "video/webm"
+	default:
+		contentType = "application/octet-stream"
+	}
+
+	return template.URL("data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)), nil
+}
@@ -0,0 +1,114 @@
+package reporter
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+)
+
+const (
+	// VisualRegressionThreshold is the fraction of changed pixels above which the final
+	// screen counts as a visual regression from its baseline
+	VisualRegressionThreshold = 0.1
+
+	// visualDiffTolerance is how far (0-255) a pixel's channels may differ from the
+	// baseline before it counts as changed, absorbing antialiasing and compression noise
+	visualDiffTolerance = 32
+)
+
+// Baseline is a known-good final screenshot of a game that later runs are compared against
+type Baseline struct {
+	// ReportID is the report the screenshot came from
+	ReportID string
+	// Screenshot is the baseline image; its Timestamp is when the baseline was captured
+	Screenshot *agent.Screenshot
+}
+
+// VisualRegression compares a run's final screen with the game's baseline
+type VisualRegression struct {
+	// BaselineReportID is the report the baseline came from
+	BaselineReportID string `json:"baseline_report_id,omitempty"`
+	// BaselineCapturedAt is when the baseline was captured
+	BaselineCapturedAt time.Time `json:"baseline_captured_at"`
+	// DiffRatio is the fraction of pixels that changed (0-1)
+	DiffRatio float64 `json:"diff_ratio"`
+	// Threshold is the DiffRatio above which the run counts as regressed
+	Threshold float64 `json:"threshold"`
+	// Regressed is set when DiffRatio exceeds Threshold
+	Regressed bool `json:"regressed"`
+	// DiffImage is the filename of the highlighted diff image
+	DiffImage string `json:"diff_image,omitempty"`
+}
+
+// VisualDiff compares current with baseline pixel by pixel and returns the fraction of
+// pixels that changed along with a diff image: the current screen faded to grayscale with
+// changed pixels in red. Both screenshots must have the same dimensions.
+func VisualDiff(baseline, current *agent.Screenshot) (float64, *agent.Screenshot, error) {
+	baseImg, _, err := image.Decode(bytes.NewReader(baseline.Data))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to decode baseline: %w", err)
+	}
+	curImg, _, err := image.Decode(bytes.NewReader(current.Data))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+
+	baseBounds, curBounds := baseImg.Bounds(), curImg.Bounds()
+	if baseBounds.Size() != curBounds.Size() {
+		return 0, nil, fmt.Errorf("baseline is %dx%d but screenshot is %dx%d",
+			baseBounds.Dx(), baseBounds.Dy(), curBounds.Dx(), curBounds.Dy())
+	}
+
+	width, height := curBounds.Dx(), curBounds.Dy()
+	if width == 0 || height == 0 {
+		return 0, nil, fmt.Errorf("screenshot is empty")
+	}
+	diffImg := image.NewRGBA(image.Rect(0, 0, width, height))
+	highlight := color.RGBA{R: 255, A: 255}
+	changed := 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r1, g1, b1, _ := baseImg.At(baseBounds.Min.X+x, baseBounds.Min.Y+y).RGBA()
+			r2, g2, b2, _ := curImg.At(curBounds.Min.X+x, curBounds.Min.Y+y).RGBA()
+
+			if channelDelta(r1, r2) > visualDiffTolerance || channelDelta(g1, g2) > visualDiffTolerance ||
+				channelDelta(b1, b2) > visualDiffTolerance {
+				changed++
+				diffImg.SetRGBA(x, y, highlight)
+				continue
+			}
+
+			// Faded grayscale keeps the layout readable behind the highlights
+			gray := uint8((0.299*float64(r2) + 0.587*float64(g2) + 0.114*float64(b2)) / 257 / 3)
+			diffImg.SetRGBA(x, y, color.RGBA{R: 170 + gray, G: 170 + gray, B: 170 + gray, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, diffImg); err != nil {
+		return 0, nil, fmt.Errorf("failed to encode diff image: %w", err)
+	}
+
+	ratio := float64(changed) / float64(width*height)
+	diff := &agent.Screenshot{
+		Context:   current.Context,
+		Timestamp: time.Now(),
+		Data:      buf.Bytes(),
+		Width:     width,
+		Height:    height,
+	}
+	return ratio, diff, nil
+}
+
+// channelDelta returns the difference between two 16-bit color channels on a 0-255 scale
+func channelDelta(a, b uint32) uint32 {
+	if a > b {
+		return (a - b) / 257
+	}
+	return (b - a) / 257
+}
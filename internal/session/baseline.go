@@ -0,0 +1,41 @@
+package session
+
+import (
+	"github.com/dreamup/qa-agent/internal/agent"
+	"github.com/dreamup/qa-agent/internal/reporter"
+)
+
+// compareBaseline diffs the final screenshot against opts.Baseline. It returns nil if the
+// game has no baseline or the screenshots can't be compared (e.g. the viewport changed).
+func (r *runner) compareBaseline(final *agent.Screenshot) *reporter.VisualRegression {
+	baseline := r.opts.Baseline
+	if baseline == nil || baseline.Screenshot == nil {
+		return nil
+	}
+
+	ratio, diff, err := reporter.VisualDiff(baseline.Screenshot, final)
+	if err != nil {
+		r.logf("Warning: Could not compare with baseline: %v", err)
+		return nil
+	}
+
+	regression := &reporter.VisualRegression{
+		BaselineReportID:   baseline.ReportID,
+		BaselineCapturedAt: baseline.Screenshot.Timestamp,
+		DiffRatio:          ratio,
+		Threshold:          reporter.VisualRegressionThreshold,
+		Regressed:          ratio > reporter.VisualRegressionThreshold,
+	}
+	if err := diff.SaveToTemp(); err != nil {
+		r.logf("Warning: Failed to save baseline diff: %v", err)
+	} else {
+		regression.DiffImage = diff.Filepath
+	}
+
+	if regression.Regressed {
+		r.logf("⚠ Visual regression: %.1f%% of the final screen differs from the baseline", ratio*100)
+	} else {
+		r.logf("✓ Final screen matches baseline (%.1f%% changed)", ratio*100)
+	}
+	return regression
+}
@@ -0,0 +1,29 @@
+package session
+
+import (
+	"github.com/dreamup/qa-agent/internal/agent"
+)
+
+// elementDetector is implemented by detectors that can look for common UI patterns
+// (*agent.UIDetector does)
+type elementDetector interface {
+	DetectAllPatterns() map[string]*agent.UIElement
+}
+
+// detectElements records which common UI elements (cookie consent, start, pause and reset
+// buttons, the game canvas) the page has, as pattern name to selector. This shows in the
+// report what automation could have clicked. Returns nil if the detector can't tell.
+func (r *runner) detectElements() map[string]string {
+	detector, ok := r.detector.(elementDetector)
+	if !ok {
+		return nil
+	}
+
+	found := detector.DetectAllPatterns()
+	detected := make(map[string]string, len(found))
+	for name, element := range found {
+		detected[name] = element.Selector
+	}
+	r.logf("Detected %d of %d common UI elements", len(detected), len(agent.AllCommonPatterns()))
+	return detected
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+	"github.com/dreamup/qa-agent/internal/evaluator"
+)
+
+// FakeDetector is a Detector that touches no DOM, for running sessions against games
+// whose controls are known (or in tests). It reports fixed results and records the keys sent.
+type FakeDetector struct {
+	// StartButton is whether ClickStartButton reports a click
+	StartButton bool
+	// Canvas is whether the game renders to a canvas that can be focused
+	Canvas bool
+	// Err, if set, is returned by every call, as from a detector whose page went away
+	Err error
+
+	mu   sync.Mutex
+	keys []string
+}
+
+// NewFakeDetector returns a constructor for Options.NewDetector that always hands out fake
+func NewFakeDetector(fake *FakeDetector) func(ctx context.Context) Detector {
+	return func(ctx context.Context) Detector {
+		return fake
+	}
+}
+
+// ClickStartButton reports whether a start button was "clicked"
+func (f *FakeDetector) ClickStartButton() (*agent.StartButtonResult, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if !f.StartButton {
+		return &agent.StartButtonResult{}, nil
+	}
+	return &agent.StartButtonResult{Clicked: true, Method: "button", Score: 1}, nil
+}
+
+// FocusGameCanvas reports whether the game has a canvas
+func (f *FakeDetector) FocusGameCanvas() (bool, error) {
+	if f.Err != nil {
+		return false, f.Err
+	}
+	return f.Canvas, nil
+}
+
+// SendKeyboardEventToCanvas records key
+func (f *FakeDetector) SendKeyboardEventToCanvas(key string) (bool, error) {
+	if f.Err != nil {
+		return false, f.Err
+	}
+	f.record(key)
+	return true, nil
+}
+
+// SendKeyboardEventToWindow records key
+func (f *FakeDetector) SendKeyboardEventToWindow(key string) (bool, error) {
+	if f.Err != nil {
+		return false, f.Err
+	}
+	f.record(key)
+	return true, nil
+}
+
+// Keys returns the keys sent so far, in order
+func (f *FakeDetector) Keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.keys...)
+}
+
+func (f *FakeDetector) record(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys = append(f.keys, key)
+}
+
+// FakeEvaluator is an Evaluator that calls no API, for running sessions without an LLM
+// key (or in tests). It returns Score, or Err if set, and records the evidence it got.
+type FakeEvaluator struct {
+	Score *evaluator.PlayabilityScore
+	Err   error
+
+	mu          sync.Mutex
+	screenshots int
+	logs        int
+}
+
+// EvaluateGame returns the canned score or error
+func (f *FakeEvaluator) EvaluateGame(ctx context.Context, screenshots []*agent.Screenshot, logs []agent.ConsoleLog) (*evaluator.PlayabilityScore, error) {
+	f.mu.Lock()
+	f.screenshots, f.logs = len(screenshots), len(logs)
+	f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	return f.Score, nil
+}
+
+// Evidence returns how many screenshots and console logs the last evaluation received
+func (f *FakeEvaluator) Evidence() (screenshots, logs int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.screenshots, f.logs
+}
@@ -0,0 +1,431 @@
+package session
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+)
+
+// unchangedThreshold is how many unchanged intervals make standard gameplay switch input mode
+const unchangedThreshold = 5
+
+// playResult is what the gameplay stage hands on to evidence collection
+type playResult struct {
+	screenshots []*agent.Screenshot
+	result      *agent.GameplayResult
+	endedReason string
+	// gameType is how AI-guided gameplay judged the game is played ("" if it didn't run)
+	gameType agent.GameType
+
+	// replayed and replayFailed count the actions sent and failed when replaying
+	replayed     int
+	replayFailed int
+
+	// scoreReader reads the on-screen score; nil when vision is unavailable
+	scoreReader      *agent.GameplayAgent
+	scoreBefore      int
+	scoreBeforeFound bool
+}
+
+// play reads the starting score, then plays with AI-guided actions when game mechanics
+// are known, falling back to adaptive keyboard/mouse input
+func (r *runner) play() *playResult {
+	res := &playResult{}
+
+	stuckPatience := agent.DefaultStuckPatience
+	if r.opts.StuckPatience > 0 {
+		stuckPatience = r.opts.StuckPatience
+	}
+
+	// Score reading and AI-guided gameplay need the real vision detector
+	visionDOMDetector, _ := r.vision.(*agent.VisionDOMDetector)
+
+	// Read the on-screen score so the report can tell whether play increased it
+	if visionDOMDetector != nil {
+		var err error
+		if res.scoreReader, err = agent.NewGameplayAgent(r.bm.GetContext(), visionDOMDetector); err != nil {
+			r.logf("Warning: Score reading disabled: %v", err)
+			res.scoreReader = nil
+		} else if screenshot, err := r.capture(agent.ContextGameplay); err != nil {
+			r.logf("Warning: Failed to capture screenshot for score: %v", err)
+		} else if res.scoreBefore, res.scoreBeforeFound, err = res.scoreReader.ReadGameScore(screenshot); err != nil {
+			r.logf("Warning: Failed to read score before gameplay: %v", err)
+		} else if !res.scoreBeforeFound {
+			r.logf("No visible score found before gameplay")
+		}
+	}
+
+	// === INTELLIGENT GAMEPLAY MODE ===
+	// If game mechanics are provided, use AI-powered gameplay agent
+	// This is inspired by Stagehand's action sequencing and self-healing patterns
+	if r.opts.GameMechanics != "" && visionDOMDetector != nil {
+		if r.playIntelligent(res, visionDOMDetector, stuckPatience) {
+			return res
+		}
+	}
+
+	r.playStandard(res, stuckPatience)
+	return res
+}
+
+// playIntelligent runs the AI gameplay agent. It returns false if the agent couldn't be
+// created, so the caller should fall back to standard gameplay.
+func (r *runner) playIntelligent(res *playResult, visionDOMDetector *agent.VisionDOMDetector, stuckPatience int) bool {
+	r.logf("🎮 Starting intelligent gameplay mode (game mechanics provided)")
+	r.logf("Game mechanics: %s", r.opts.GameMechanics)
+
+	// Create gameplay agent
+	gameplayAgent, err := agent.NewGameplayAgent(r.bm.GetContext(), visionDOMDetector)
+	if err != nil {
+		r.logf("Warning: Could not create gameplay agent: %v", err)
+		r.logf("Falling back to standard gameplay mode...")
+		return false
+	}
+	r.progress(65, "Playing game with AI-guided actions...")
+
+	if r.opts.SettleTimeout > 0 {
+		gameplayAgent.SetSettleTimeout(r.opts.SettleTimeout)
+	}
+	gameplayAgent.SetStuckPatience(stuckPatience)
+	if r.opts.MaxLevels > 0 {
+		gameplayAgent.SetMaxLevels(r.opts.MaxLevels)
+	}
+
+	// Name the game after its URL, to keep its cached drags apart from other games'
+	gameName := "unknown"
+	if u, err := url.Parse(r.opts.URL); err == nil && u.Host != "" {
+		gameName = strings.ToLower(u.Host + strings.TrimSuffix(u.Path, "/"))
+	}
+
+	// Pick a strategy from how the game is played: slingshot games get the aim-and-cache
+	// loop below, everything else the planned-action loop
+	gameType := agent.GameTypeUnknown
+	if screenshot, err := r.capture(agent.ContextGameplay); err != nil {
+		r.logf("Warning: Failed to capture screenshot to classify the game: %v", err)
+	} else if gameType, err = gameplayAgent.ClassifyGameType(screenshot, r.opts.GameMechanics); err != nil {
+		r.logf("Warning: Failed to classify the game, planning generic actions: %v", err)
+	}
+	r.logf("Game type: %s", gameType)
+	gameplayAgent.SetGameType(gameType)
+	res.gameType = gameType
+
+	// Execute AI-powered gameplay loop
+	// For slingshot games this will:
+	// 1. Use vision to detect slingshot and targets
+	// 2. Calculate optimal aim using GPT-4o
+	// 3. Execute precise CDP mouse drags
+	// 4. Cache successful actions for self-healing
+	// Other games plan and execute a few clicks, drags or keypresses per iteration.
+	// Estimate ~10-15s per attempt (Vision API call + drag action), and ~20s per planned
+	// iteration (planning and outcome calls plus the plan's actions)
+	estimatedSecondsPerAttempt := 12
+	if gameType != agent.GameTypeSlingshot {
+		estimatedSecondsPerAttempt = 20
+	}
+	maxDurationSeconds := int(r.opts.MaxDuration.Seconds())
+	maxGameplayAttempts := maxDurationSeconds / estimatedSecondsPerAttempt
+	if maxGameplayAttempts < 1 {
+		maxGameplayAttempts = 1 // At least one attempt
+	}
+	r.logf("Executing up to %d AI-guided gameplay attempts (duration: %ds)...", maxGameplayAttempts, maxDurationSeconds)
+
+	if gameType == agent.GameTypeSlingshot {
+		res.result, err = gameplayAgent.PlayGameLevel(gameName, r.opts.GameMechanics, maxGameplayAttempts)
+	} else {
+		res.result, err = gameplayAgent.PlayWithPlannedActions(r.opts.GameMechanics, maxGameplayAttempts)
+	}
+	if err != nil {
+		r.logf("Warning: Gameplay agent failed: %v", err)
+		r.logf("Continuing with test anyway...")
+	} else {
+		r.logf("✓ AI-guided gameplay completed (outcome: %s, levels complete: %d)",
+			res.result.Outcome, res.result.LevelsCompleted)
+		if res.result.Stuck {
+			res.endedReason = "stuck"
+		}
+
+		// Show cached successful actions
+		cachedDrags := gameplayAgent.GetCachedDragsForGame(gameName)
+		if len(cachedDrags) > 0 {
+			r.logf("📦 Cached %d successful actions for future self-healing", len(cachedDrags))
+		}
+	}
+
+	// Persist cached drags for future tests of the same game
+	if err := gameplayAgent.SaveCache(agent.DefaultActionCachePath); err != nil {
+		r.logf("Warning: Failed to save gameplay action cache: %v", err)
+	}
+
+	r.progress(85, "Finalizing test...")
+	return true
+}
+
+// playGamepadRound pushes the left stick in a random direction while pressing the face
+// buttons and start, then recenters the stick
+func (r *runner) playGamepadRound(gamepad *agent.Gamepad) error {
+	directions := [][2]float64{{1, 0}, {1, 0}, {-1, 0}, {0, -1}, {0, 1}}
+	dir := directions[r.rng.Intn(len(directions))]
+	if err := gamepad.SetAxis(agent.GamepadAxisLeftX, dir[0]); err != nil {
+		return err
+	}
+	if err := gamepad.SetAxis(agent.GamepadAxisLeftY, dir[1]); err != nil {
+		return err
+	}
+
+	for _, button := range []agent.GamepadButton{agent.GamepadButtonA, agent.GamepadButtonB, agent.GamepadButtonStart, agent.GamepadButtonA} {
+		if err := gamepad.TapButton(button); err != nil {
+			return err
+		}
+		time.Sleep(150 * time.Millisecond)
+	}
+	return gamepad.Reset()
+}
+
+// keyHolder is implemented by detectors that can press and release keys separately
+// (*agent.UIDetector does)
+type keyHolder interface {
+	SendKeyDownToWindow(key string) (bool, error)
+	SendKeyUpToWindow(key string) (bool, error)
+}
+
+// holdKey presses key, holds it for d (or until the test is cancelled) and releases it.
+// The key is released even if the press reported failure, so it can't stay stuck down.
+func (r *runner) holdKey(holder keyHolder, key string, d time.Duration) (bool, error) {
+	sent, err := holder.SendKeyDownToWindow(key)
+	if err == nil && sent {
+		select {
+		case <-time.After(d):
+		case <-r.ctx.Done():
+		}
+	}
+	if _, upErr := holder.SendKeyUpToWindow(key); upErr != nil && err == nil {
+		err = upErr
+	}
+	return sent, err
+}
+
+// playStandard plays for MaxDuration, switching between keyboard, mouse clicks, mouse
+// drags and gamepad input whenever the current input mode stops changing the screen
+func (r *runner) playStandard(res *playResult, stuckPatience int) {
+	// Controls were validated by the caller; fall back to the defaults if they weren't
+	gameplayKeys, err := agent.ResolveControls(r.opts.Controls)
+	if err != nil {
+		r.logf("Warning: Ignoring controls: %v", err)
+		gameplayKeys = agent.DefaultGameplayKeys
+	}
+
+	// Detect if game uses canvas or DOM rendering
+	r.logf("Detecting game rendering type...")
+	var useCanvasMode bool
+	focused, err := r.detector.FocusGameCanvas()
+	if err != nil || !focused {
+		r.logf("No canvas detected or focus failed - using DOM/window event mode")
+	} else {
+		r.logf("Canvas detected and focused - using canvas event mode")
+		useCanvasMode = true
+	}
+
+	// Keys are tapped unless a hold is requested and the detector can hold them
+	keyHold := min(r.opts.KeyHold, agent.MaxKeyHold)
+	var holder keyHolder
+	if keyHold > 0 {
+		if h, ok := r.detector.(keyHolder); ok {
+			holder = h
+			r.logf("Holding each key for %v", keyHold)
+		} else {
+			r.logf("Warning: Detector can't hold keys; tapping them instead")
+		}
+	}
+
+	// Add small delay after detection
+	time.Sleep(200 * time.Millisecond)
+
+	r.progress(60, "Playing game with keyboard controls...")
+
+	viewport := agent.ViewportFromContext(r.bm.GetContext())
+	screenWidth := viewport.Width
+	screenHeight := viewport.Height
+	gameplayDuration := r.opts.MaxDuration
+	screenshotInterval := agent.DefaultScreenshotInterval
+	if r.opts.ScreenshotInterval > 0 {
+		screenshotInterval = r.opts.ScreenshotInterval
+	}
+	maxScreenshots := agent.DefaultMaxScreenshots
+	if r.opts.MaxScreenshots > 0 {
+		maxScreenshots = r.opts.MaxScreenshots
+	}
+	gameplayMode := "keyboard"
+	unchangedCount := 0
+	// lastChanged is the screenshot from the last time the screen changed
+	var lastChanged *agent.Screenshot
+	// gamepad is the virtual gamepad, connected the first time gamepad mode is used
+	var gamepad *agent.Gamepad
+	// Once every input mode has failed, stuckCount counts further unchanged intervals
+	var modesExhausted bool
+	var stuckCount int
+
+	// Simulate realistic gameplay with varied interactions over time
+	gameplayStart := time.Now()
+	lastScreenshotTime := time.Now()
+
+	r.logf("Starting %v of adaptive gameplay (starting with keyboard, seed %d)...", gameplayDuration, r.opts.Seed)
+
+	// Gameplay loop - adaptive input mode
+	for time.Since(gameplayStart) < gameplayDuration && r.ctx.Err() == nil {
+		progress := 60 + int(25*time.Since(gameplayStart).Seconds()/gameplayDuration.Seconds())
+		r.progress(progress, fmt.Sprintf("Playing game... %.0fs elapsed", time.Since(gameplayStart).Seconds()))
+
+		// Capture screenshot for both saving and change detection
+		screenshot, err := r.capture(agent.ContextGameplay)
+		if err == nil && screenshot != nil {
+			screenWidth = screenshot.Width
+			screenHeight = screenshot.Height
+
+			// Save a screenshot every interval
+			if time.Since(lastScreenshotTime) >= screenshotInterval {
+				if err := screenshot.SaveToTemp(); err != nil {
+					r.logf("Warning: Failed to save gameplay screenshot: %v", err)
+				} else {
+					res.screenshots = append(res.screenshots, screenshot)
+					r.logf("✓ Captured gameplay screenshot (%d total)", len(res.screenshots))
+				}
+				lastScreenshotTime = time.Now()
+
+				// Over the cap, keep every other screenshot and capture half as often, so the
+				// kept ones stay evenly spread from the start of play
+				if len(res.screenshots) > maxScreenshots {
+					res.screenshots = agent.SampleScreenshots(res.screenshots, (len(res.screenshots)+1)/2)
+					screenshotInterval *= 2
+					r.logf("Screenshot cap (%d) reached: kept %d, now capturing every %v",
+						maxScreenshots, len(res.screenshots), screenshotInterval)
+				}
+			}
+
+			// Check if screen changed since it last changed. Comparing against that rather
+			// than the previous frame lets slow but real change add up.
+			if screenshot.SimilarTo(lastChanged, agent.DefaultSimilarityThreshold) {
+				unchangedCount++
+				r.logf("[Adaptive] Screen unchanged (%d/%d) in %s mode", unchangedCount, unchangedThreshold, gameplayMode)
+				if modesExhausted {
+					stuckCount++
+				}
+			} else {
+				if unchangedCount > 0 {
+					r.logf("[Adaptive] Screen changed! %s mode is working", gameplayMode)
+				}
+				unchangedCount = 0
+				modesExhausted = false
+				stuckCount = 0
+				lastChanged = screenshot
+			}
+		}
+
+		// Give up once every input mode has failed and the screen still hasn't changed,
+		// freeing the browser slot for queued tests
+		if stuckCount >= stuckPatience {
+			r.logf("⏹ Screen unchanged for %d intervals after trying every input mode, ending gameplay early", stuckCount)
+			res.endedReason = "stuck"
+			break
+		}
+
+		// Adaptive mode switching based on effectiveness
+		if unchangedCount >= unchangedThreshold {
+			switch gameplayMode {
+			case "keyboard":
+				r.logf("🔄 Keyboard not effective, switching to mouse clicks")
+				gameplayMode = "mouse-click"
+				unchangedCount = 0
+			case "mouse-click":
+				r.logf("🔄 Mouse clicks not effective, switching to mouse drags")
+				gameplayMode = "mouse-drag"
+				unchangedCount = 0
+			case "mouse-drag":
+				r.logf("🔄 Mouse drags not effective, switching to gamepad")
+				gameplayMode = "gamepad"
+				unchangedCount = 0
+			case "gamepad":
+				r.logf("🔄 Gamepad not effective, cycling back to keyboard")
+				gameplayMode = "keyboard"
+				unchangedCount = 0
+				modesExhausted = true
+			}
+		}
+
+		// Perform actions based on current mode
+		switch gameplayMode {
+		case "keyboard":
+			// Send the requested controls (or the default varied key presses)
+			for _, key := range gameplayKeys {
+				var sent bool
+				var err error
+
+				if holder != nil {
+					sent, err = r.holdKey(holder, key, keyHold)
+				} else if useCanvasMode {
+					sent, err = r.detector.SendKeyboardEventToCanvas(key)
+				} else {
+					sent, err = r.detector.SendKeyboardEventToWindow(key)
+				}
+
+				if err != nil {
+					r.logf("Error sending key %s: %v", key, err)
+				} else if !sent {
+					r.logf("Warning: Failed to send key %s", key)
+				}
+				time.Sleep(150 * time.Millisecond)
+			}
+			time.Sleep(200 * time.Millisecond)
+
+		case "mouse-click":
+			// Perform 3-4 random clicks in game area
+			clickCount := 3 + r.rng.Intn(2) // 3 or 4 clicks
+			for i := 0; i < clickCount; i++ {
+				if r.vision != nil {
+					err := agent.PerformRandomClick(r.bm.GetContext(), r.rng, screenWidth, screenHeight)
+					if err != nil {
+						r.logf("Random click %d failed: %v", i+1, err)
+					}
+				}
+				time.Sleep(300 * time.Millisecond)
+			}
+			time.Sleep(500 * time.Millisecond)
+
+		case "mouse-drag":
+			// Try different drag patterns
+			patterns := []agent.DragPattern{
+				agent.DragPatternHorizontalLeft,  // Slingshot style
+				agent.DragPatternVerticalUp,      // Upward swipe
+				agent.DragPatternHorizontalRight, // Right swipe
+			}
+			pattern := patterns[r.rng.Intn(len(patterns))]
+
+			if r.vision != nil {
+				err := agent.PerformRandomDrag(r.bm.GetContext(), r.rng, pattern, screenWidth, screenHeight)
+				if err != nil {
+					r.logf("Drag %s failed: %v", pattern, err)
+				}
+			}
+			time.Sleep(1 * time.Second) // Wait longer after drags
+
+		case "gamepad":
+			// Some console-style games only read the Gamepad API; the virtual pad is
+			// connected on first use and stays connected
+			if r.vision != nil && gamepad == nil {
+				if gamepad, err = agent.EmulateGamepad(r.bm.GetContext()); err != nil {
+					r.logf("Gamepad emulation failed: %v", err)
+				}
+			}
+			if gamepad != nil {
+				if err := r.playGamepadRound(gamepad); err != nil {
+					r.logf("Gamepad input failed: %v", err)
+				}
+			}
+			time.Sleep(200 * time.Millisecond)
+		}
+	}
+
+	r.logf("Gameplay simulation completed after %v", time.Since(gameplayStart))
+}
@@ -0,0 +1,115 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+)
+
+// Inspection is what a dry run found on a game page, to help write a GameMechanics
+// description before committing to a full test
+type Inspection struct {
+	URL string `json:"url"`
+	// Elements are the common UI patterns found on the page, keyed by pattern name
+	Elements map[string]*agent.UIElement `json:"elements"`
+	// GameFrame is the iframe the game is embedded in, if any
+	GameFrame *agent.GameFrame `json:"game_frame,omitempty"`
+	// StartButton is the start button text vision found, if any
+	StartButton string `json:"start_button,omitempty"`
+	// VisionError explains why StartButton is empty when vision failed or was unavailable
+	VisionError string `json:"vision_error,omitempty"`
+	// Screenshot is the captured screenshot
+	Screenshot *agent.Screenshot `json:"-"`
+	// SuggestedMechanics is a GameMechanics skeleton to fill in
+	SuggestedMechanics string `json:"suggested_mechanics"`
+	// Duration is how long the inspection took
+	Duration time.Duration `json:"duration"`
+}
+
+// Inspect loads opts.URL and reports the UI elements and start button it detects, without
+// playing or evaluating the game. Only the browser, network and storage options apply.
+func Inspect(ctx context.Context, opts Options) (*Inspection, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("URL is required")
+	}
+	start := time.Now()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r := &runner{opts: opts, ctx: ctx}
+	if err := r.startBrowser(); err != nil {
+		return nil, err
+	}
+	defer r.bm.Close()
+
+	if _, err := r.prepareBrowser(); err != nil {
+		return nil, err
+	}
+	if _, _, err := r.loadGame(); err != nil {
+		return nil, err
+	}
+
+	if err := r.phase("inspection"); err != nil {
+		return nil, err
+	}
+	r.progress(60, "Detecting UI elements...")
+
+	inspection := &Inspection{
+		URL:       opts.URL,
+		Elements:  agent.NewUIDetector(r.bm.GetContext()).DetectAllPatterns(),
+		GameFrame: agent.GameFrameFromContext(r.bm.GetContext()),
+	}
+
+	screenshot, err := r.capture(agent.ContextInitial)
+	if err != nil {
+		return nil, fmt.Errorf("screenshot failed: %w", err)
+	}
+	if err := screenshot.SaveToTemp(); err != nil {
+		return nil, fmt.Errorf("failed to save screenshot: %w", err)
+	}
+	inspection.Screenshot = screenshot
+
+	r.progress(80, "Looking for the start button...")
+	vision, err := agent.NewVisionDOMDetector(r.bm.GetContext())
+	if err == nil {
+		inspection.StartButton, err = vision.DetectStartButtonDescription(screenshot)
+	}
+	if err != nil {
+		r.logf("Warning: Vision start button detection failed: %v", err)
+		inspection.VisionError = err.Error()
+	}
+
+	inspection.SuggestedMechanics = suggestMechanics(inspection)
+	inspection.Duration = time.Since(start)
+	return inspection, nil
+}
+
+// suggestMechanics drafts a GameMechanics description from what the inspection found,
+// with placeholders for what only the user knows
+func suggestMechanics(in *Inspection) string {
+	var b strings.Builder
+
+	switch {
+	case in.StartButton != "":
+		fmt.Fprintf(&b, "Start: click the %q button.\n", in.StartButton)
+	case in.Elements[agent.StartButtonPattern.Name] != nil:
+		fmt.Fprintf(&b, "Start: click the %q button.\n", in.Elements[agent.StartButtonPattern.Name].Text)
+	default:
+		b.WriteString("Start: <how the game starts, e.g. click anywhere or press Space>.\n")
+	}
+
+	if in.Elements[agent.GameCanvasPattern.Name] != nil || in.GameFrame != nil {
+		b.WriteString("Controls: <keys or mouse actions that control the game, e.g. arrow keys to move, click to jump>.\n")
+	} else {
+		b.WriteString("Controls: <which buttons or elements to click, e.g. click tiles to match them>.\n")
+	}
+	b.WriteString("Goal: <what the player is trying to do, e.g. reach the flag without falling>.\n")
+	if in.Elements[agent.PauseButtonPattern.Name] != nil {
+		b.WriteString("Avoid the pause button during play.\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package session
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+	"github.com/dreamup/qa-agent/internal/reporter"
+)
+
+// renderWaiter is implemented by detectors that can wait for a canvas to draw
+// (*agent.UIDetector does)
+type renderWaiter interface {
+	HasGameCanvas() bool
+	WaitForGameReady(timeoutSeconds int) (bool, error)
+}
+
+// checkRendered waits for the game to draw something. It returns a screenshot of the
+// blank page if the game never rendered, or nil if it did (or this can't be determined).
+func (r *runner) checkRendered() (*agent.Screenshot, error) {
+	timeout := r.opts.RenderTimeout
+	if timeout < 0 {
+		return nil, nil
+	}
+	if timeout == 0 {
+		timeout = agent.DefaultRenderTimeout
+	}
+
+	if err := r.phase("render check"); err != nil {
+		return nil, err
+	}
+	r.progress(45, "Checking that the game renders...")
+
+	// Canvas games get up to the timeout to draw; DOM games are judged by the screenshot alone
+	if waiter, ok := r.detector.(renderWaiter); ok && waiter.HasGameCanvas() {
+		ready, err := waiter.WaitForGameReady(int(math.Ceil(timeout.Seconds())))
+		if err != nil {
+			r.logf("Warning: Render check failed: %v", err)
+			return nil, nil
+		}
+		if ready {
+			return nil, nil
+		}
+		r.logf("Canvas still blank after %v", timeout)
+	}
+
+	// Only a solid-color page confirms the game failed: a blank canvas may sit behind a DOM menu
+	screenshot, err := r.capture(agent.ContextFinal)
+	if err != nil {
+		r.logf("Warning: Could not capture screenshot for render check: %v", err)
+		return nil, nil
+	}
+	blank, err := screenshot.IsBlank()
+	if err != nil {
+		r.logf("Warning: Render check failed: %v", err)
+		return nil, nil
+	}
+	if !blank {
+		return nil, nil
+	}
+
+	r.logf("⚠ Game failed to render: the page is blank")
+	if err := screenshot.SaveToTemp(); err != nil {
+		return nil, fmt.Errorf("failed to save screenshot: %w", err)
+	}
+	return screenshot, nil
+}
+
+// renderFailureReport builds the report for a game that never rendered, skipping gameplay,
+// video and evaluation
+func (r *runner) renderFailureReport(screenshots []*agent.Screenshot, logs []agent.ConsoleLog,
+	perfMetrics *agent.PerformanceMetrics, networkRecorder *agent.NetworkRecorder) (*reporter.Report, error) {
+	viewport := agent.ViewportFromContext(r.bm.GetContext())
+	reportBuilder := reporter.NewReportBuilder(r.opts.URL)
+	for k, v := range r.opts.Metadata {
+		reportBuilder.AddMetadata(k, v)
+	}
+	reportBuilder.AddMetadata("headless", fmt.Sprintf("%v", r.opts.Headless))
+	reportBuilder.AddMetadata("viewport", fmt.Sprintf("%dx%d", viewport.Width, viewport.Height))
+	reportBuilder.AddMetadata("ended_reason", "failed_to_render")
+	reportBuilder.AddCriticalIssue("Game failed to render")
+	reportBuilder.SetScreenshots(screenshots)
+	reportBuilder.SetConsoleLogs(logs)
+	if domSnapshot, err := agent.CaptureDOM(r.bm.GetContext()); err != nil {
+		r.logf("Warning: DOM snapshot failed: %v", err)
+	} else {
+		reportBuilder.SetDOMSnapshot(domSnapshot)
+	}
+	reportBuilder.SetPerformanceMetrics(perfMetrics)
+	reportBuilder.SetEvidenceBudget(r.opts.EvidenceBudget)
+
+	var harPath string
+	if networkRecorder != nil {
+		var err error
+		if harPath, err = networkRecorder.SaveToTemp(); err != nil {
+			r.logf("Warning: Failed to save network log: %v", err)
+		} else {
+			reportBuilder.SetNetworkLog(filepath.Base(harPath))
+		}
+	}
+
+	report, err := reportBuilder.Build()
+	if err != nil {
+		return nil, fmt.Errorf("report build failed: %w", err)
+	}
+
+	if r.opts.Artifacts != nil {
+		r.opts.Artifacts.Screenshots = screenshots
+		r.opts.Artifacts.NetworkLogPath = harPath
+	}
+	return report, nil
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+)
+
+// replay sends Options.Replay back to the game with its original pacing instead of
+// playing, capturing gameplay screenshots as standard gameplay would. Actions that fail
+// are logged and skipped, so one stale selector doesn't end the replay.
+func (r *runner) replay() *playResult {
+	res := &playResult{endedReason: "replay_complete"}
+	actions := r.opts.Replay
+	replayer := agent.NewReplayer(r.bm.GetContext())
+
+	screenshotInterval := agent.DefaultScreenshotInterval
+	if r.opts.ScreenshotInterval > 0 {
+		screenshotInterval = r.opts.ScreenshotInterval
+	}
+	maxScreenshots := agent.DefaultMaxScreenshots
+	if r.opts.MaxScreenshots > 0 {
+		maxScreenshots = r.opts.MaxScreenshots
+	}
+
+	r.logf("▶ Replaying %d recorded actions...", len(actions))
+	r.progress(60, "Replaying recorded actions...")
+
+	start := time.Now()
+	lastScreenshotTime := start
+	for i, action := range actions {
+		due := start.Add(agent.ReplayDelay(actions[0], action))
+
+		// Capture screenshots while waiting for the action's turn
+		for {
+			if r.ctx.Err() != nil {
+				res.endedReason = "cancelled"
+				return res
+			}
+			wait := time.Until(due)
+			if wait <= 0 {
+				break
+			}
+			if next := time.Until(lastScreenshotTime.Add(screenshotInterval)); next > 0 {
+				time.Sleep(min(wait, next))
+				continue
+			}
+			lastScreenshotTime = time.Now()
+			if screenshot, err := r.capture(agent.ContextGameplay); err != nil {
+				r.logf("Warning: Failed to capture replay screenshot: %v", err)
+			} else if err := screenshot.SaveToTemp(); err != nil {
+				r.logf("Warning: Failed to save replay screenshot: %v", err)
+			} else {
+				res.screenshots = append(res.screenshots, screenshot)
+				if len(res.screenshots) > maxScreenshots {
+					res.screenshots = agent.SampleScreenshots(res.screenshots, (len(res.screenshots)+1)/2)
+					screenshotInterval *= 2
+				}
+			}
+		}
+
+		if err := replayer.Execute(action); err != nil {
+			res.replayFailed++
+			r.logf("Warning: Replayed action %d (%s) failed: %v", i+1, action.Kind, err)
+		}
+		res.replayed++
+		r.progress(60+25*(i+1)/len(actions), fmt.Sprintf("Replayed %d of %d actions", i+1, len(actions)))
+	}
+
+	r.logf("Replay completed after %v (%d of %d actions failed)", time.Since(start), res.replayFailed, len(actions))
+	return res
+}
@@ -0,0 +1,879 @@
+// Package session runs a complete game QA test: browser setup, loading, starting the game,
+// gameplay, evidence collection and LLM evaluation. The server, Lambda and CLI share it.
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+	"github.com/dreamup/qa-agent/internal/evaluator"
+	"github.com/dreamup/qa-agent/internal/logging"
+	"github.com/dreamup/qa-agent/internal/reporter"
+)
+
+// Detector finds and operates a game's DOM controls. *agent.UIDetector implements it.
+type Detector interface {
+	ClickStartButton() (*agent.StartButtonResult, error)
+	FocusGameCanvas() (bool, error)
+	SendKeyboardEventToCanvas(key string) (bool, error)
+	SendKeyboardEventToWindow(key string) (bool, error)
+}
+
+// VisionDetector uses screenshots to decide what to click. *agent.VisionDOMDetector implements it.
+type VisionDetector interface {
+	DetectAndClickStartButton(screenshot *agent.Screenshot) error
+	DetectGameplayState(screenshot *agent.Screenshot, gameMechanics string) (*agent.GameplayAction, error)
+	InspectCanvasCoordinates() error
+	ClickAt(x, y int) error
+	ClickButtonByText(buttonText string) error
+}
+
+// Evaluator scores a game from its evidence. *evaluator.GameEvaluator implements it.
+type Evaluator interface {
+	EvaluateGame(ctx context.Context, screenshots []*agent.Screenshot, logs []agent.ConsoleLog) (*evaluator.PlayabilityScore, error)
+}
+
+// Options configures a test session. Only URL is required.
+type Options struct {
+	// URL is the game to test
+	URL string
+	// Headless runs the browser without a window
+	Headless bool
+	// MaxDuration is how long to play (default 60s)
+	MaxDuration time.Duration
+	// GameMechanics describes how to play; when set, AI-guided gameplay is used
+	GameMechanics string
+	// SettleTimeout caps the wait for physics to settle after AI-guided actions (0 = default)
+	SettleTimeout time.Duration
+	// MaxLevels is how many levels AI-guided gameplay plays through, clicking past each
+	// level-complete screen (0 = agent.DefaultMaxLevels)
+	MaxLevels int
+	// Controls are the keys and presets for keyboard gameplay (see agent.ResolveControls)
+	Controls []string
+	// KeyHold is how long keyboard gameplay holds each key before releasing it, for games
+	// that need sustained input (0 = tap; at most agent.MaxKeyHold)
+	KeyHold time.Duration
+	// Replay, when set, is sent to the game with its recorded pacing instead of playing it;
+	// it should start from the page as loaded, so the game isn't started first
+	Replay []agent.RecordedAction
+	// Seed seeds gameplay's random clicks, drags and gamepad directions, so a run can be
+	// reproduced; 0 picks a time-based seed. The seed used is reported as seed metadata.
+	Seed int64
+	// StuckPatience is how many unchanged intervals end gameplay early (0 = default)
+	StuckPatience int
+	// ScreenshotInterval is how often gameplay screenshots are kept (0 = agent.DefaultScreenshotInterval)
+	ScreenshotInterval time.Duration
+	// MaxScreenshots caps the gameplay screenshots kept (0 = agent.DefaultMaxScreenshots); past
+	// it, every other screenshot is dropped and the interval doubles, so they stay evenly spaced
+	MaxScreenshots int
+	// RenderTimeout is how long the game gets to draw something before the test ends as
+	// failed to render (0 = agent.DefaultRenderTimeout, negative skips the check)
+	RenderTimeout time.Duration
+	// AccessibilityTags are the axe rule tags audited after load (nil = agent.DefaultAccessibilityTags)
+	AccessibilityTags []string
+	// AccessibilityWeights score the accessibility audit (nil = agent.DefaultAccessibilityWeights)
+	AccessibilityWeights *agent.AccessibilityWeights
+	// Cookies and LocalStorage are seeded before the game loads
+	Cookies      []http.Cookie
+	LocalStorage map[string]string
+	// NetworkProfile throttles the connection (default unthrottled)
+	NetworkProfile agent.NetworkProfile
+	// CaptureHAR records network traffic as a HAR file
+	CaptureHAR bool
+	// AnnotateScreenshots saves a copy of each screenshot marked up with the clicks, drags
+	// and keys sent before the next one, as a visual trace of what gameplay did
+	AnnotateScreenshots bool
+	// Genre selects genre-specific criteria for the default evaluator and is reported as
+	// metadata (a custom Evaluator must apply it itself, e.g. GameEvaluator.SetGenre)
+	Genre evaluator.Genre
+	// BrowserOptions configure the browser (viewport, proxy, Chrome flags, ...)
+	BrowserOptions []agent.BrowserOption
+
+	// VideoFormat enables gameplay recording in that format (empty = no recording)
+	VideoFormat agent.VideoFormat
+	// VideoUnavailable explains why recording is off, reported as video_unavailable metadata
+	VideoUnavailable string
+	// VideoURL maps the saved video's path to the URL stored in the report (nil = no URL)
+	VideoURL func(videoPath string) string
+
+	// Baseline is the game's known-good final screenshot; if set, the final screen is diffed
+	// against it and a significant difference is reported as a visual regression
+	Baseline *reporter.Baseline
+
+	// EvidenceBudget caps screenshots and logs kept in the report (zero = unlimited)
+	EvidenceBudget reporter.EvidenceBudget
+	// Metadata is added to the report as-is
+	Metadata map[string]string
+
+	// Logger tags log lines (e.g. with the test ID); nil uses the standard logger
+	Logger *logging.Logger
+	// Progress is called as the session moves through its stages
+	Progress func(percent int, message string)
+	// ConsoleLogger captures the game's console logs (nil = a new one capturing every level)
+	ConsoleLogger *agent.ConsoleLogger
+	// OnConsoleLog is called for each captured console log
+	OnConsoleLog func(agent.ConsoleLog)
+
+	// NewDetector and NewVisionDetector replace the real detectors (e.g. with FakeDetector)
+	NewDetector       func(ctx context.Context) Detector
+	NewVisionDetector func(ctx context.Context) (VisionDetector, error)
+	// Evaluator replaces the default GPT-4o evaluator
+	Evaluator Evaluator
+
+	// Artifacts, if set, is filled with the local files behind the report for uploading
+	Artifacts *Artifacts
+}
+
+// Artifacts are the local files a session produced
+type Artifacts struct {
+	Screenshots    []*agent.Screenshot
+	ConsoleLogPath string
+	VideoPath      string
+	NetworkLogPath string
+}
+
+// ErrCancelled is returned when the session's context is cancelled mid-run
+var ErrCancelled = errors.New("test cancelled")
+
+// runner holds the state shared by a session's stages
+type runner struct {
+	opts     Options
+	ctx      context.Context
+	bm       *agent.BrowserManager
+	detector Detector
+	vision   VisionDetector
+	// capture takes a screenshot of the game
+	capture func(agent.ScreenshotContext) (*agent.Screenshot, error)
+	// rng makes gameplay's random choices, so a seeded session can be reproduced
+	rng *rand.Rand
+}
+
+// RunSession runs a full test of opts.URL and returns its report
+func RunSession(ctx context.Context, opts Options) (*reporter.Report, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("URL is required")
+	}
+	if opts.MaxDuration <= 0 {
+		opts.MaxDuration = 60 * time.Second
+	}
+
+	// Cancelling ctx also stops the browser watcher below
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	r := &runner{opts: opts, ctx: ctx, rng: rand.New(rand.NewSource(opts.Seed))}
+
+	if err := r.startBrowser(); err != nil {
+		return nil, err
+	}
+	bm := r.bm
+	defer bm.Close()
+
+	// Start console logger
+	consoleLogger := opts.ConsoleLogger
+	if consoleLogger == nil {
+		consoleLogger = agent.NewConsoleLogger()
+	}
+	if opts.OnConsoleLog != nil {
+		consoleLogger.SetOnLog(opts.OnConsoleLog)
+	}
+	if err := consoleLogger.StartCapture(bm.GetContext()); err != nil {
+		return nil, fmt.Errorf("failed to start console logger: %w", err)
+	}
+
+	networkRecorder, err := r.prepareBrowser()
+	if err != nil {
+		return nil, err
+	}
+
+	perfMetrics, initialScreenshot, err := r.loadGame()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.NewDetector != nil {
+		r.detector = opts.NewDetector(bm.GetContext())
+	} else {
+		r.detector = agent.NewUIDetector(bm.GetContext())
+	}
+
+	// A game that never draws anything isn't worth playing; report it straight away
+	blankScreenshot, err := r.checkRendered()
+	if err != nil {
+		return nil, err
+	}
+	if blankScreenshot != nil {
+		return r.renderFailureReport([]*agent.Screenshot{initialScreenshot, blankScreenshot},
+			consoleLogger.GetLogs(), perfMetrics, networkRecorder)
+	}
+
+	// Record what automation can click before starting the game changes the page
+	detectedElements := r.detectElements()
+
+	// A replay starts the game itself, with the recorded start clicks
+	if opts.Replay == nil {
+		if err := r.startGame(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Initialize video recorder (needed for both intelligent and standard gameplay)
+	r.logf("Initializing video recorder...")
+	videoRecorder := agent.NewVideoRecorder(bm.GetContext())
+	videoRecorder.Format = opts.VideoFormat
+	videoUnavailable := opts.VideoUnavailable
+
+	// Start video recording early to capture all gameplay
+	if opts.VideoFormat == "" {
+		if videoUnavailable != "" {
+			r.logf("Skipping video recording (%s)", videoUnavailable)
+		}
+	} else {
+		r.logf("Starting video recording...")
+		if err := videoRecorder.StartRecording(); err != nil {
+			r.logf("Warning: Failed to start video recording: %v", err)
+			r.logf("Continuing without video recording...")
+		} else {
+			r.logf("✓ Video recording started")
+		}
+	}
+
+	// Sample FPS for the whole gameplay session so it reflects real in-game frame rates
+	// and catches games that start smooth but degrade
+	metricsCollector := agent.NewMetricsCollector(bm.GetContext())
+	if err := metricsCollector.StartContinuousFPS(bm.GetContext()); err != nil {
+		r.logf("Warning: FPS sampling failed to start: %v", err)
+	}
+
+	var play *playResult
+	if opts.Replay != nil {
+		play = r.replay()
+	} else {
+		play = r.play()
+	}
+
+	// Stop video recording
+	var videoPath string
+	if videoRecorder.IsRecording {
+		r.logf("Stopping video recording...")
+		if err := videoRecorder.StopRecording(); err != nil {
+			r.logf("Warning: Failed to stop video recording: %v", err)
+		} else {
+			r.logf("✓ Video recording stopped")
+			r.logf("Recorded %d frames over %v", videoRecorder.GetFrameCount(), videoRecorder.GetDuration())
+
+			// Save video to temp file
+			r.logf("Saving video as %s...", strings.ToUpper(string(videoRecorder.Format)))
+			videoPath, err = videoRecorder.SaveToTemp()
+			if errors.Is(err, agent.ErrFFmpegMissing) {
+				r.logf("Warning: Video not saved: %v", err)
+				videoUnavailable = "ffmpeg not found"
+			} else if err != nil {
+				r.logf("Warning: Failed to save video: %v", err)
+			} else {
+				r.logf("✓ Video saved to: %s", videoPath)
+
+				// Pre-generate the GIF thumbnail while frames are still in memory
+				gifPath := filepath.Join(filepath.Dir(videoPath), agent.GIFThumbnailName(filepath.Base(videoPath)))
+				if err := videoRecorder.SaveAsGIF(gifPath, agent.DefaultGIFFrames); err != nil {
+					r.logf("Warning: Failed to save GIF thumbnail: %v", err)
+				}
+			}
+		}
+	}
+
+	if err := r.phase("final screenshot"); err != nil {
+		return nil, err
+	}
+	r.progress(70, "Capturing final screenshot...")
+
+	// Stop FPS sampling and record the series alongside the load-time metrics
+	if fps, err := metricsCollector.StopContinuousFPS(); err != nil {
+		r.logf("Warning: FPS sampling failed: %v", err)
+	} else {
+		perfMetrics.FPS = fps
+		r.logf("FPS: avg %.1f, min %.1f, max %.1f, p1 %.1f, p50 %.1f over %d samples",
+			fps.Average, fps.Min, fps.Max, fps.P1, fps.P50, len(fps.Frames))
+	}
+
+	// Sample resource use again; heap or DOM growth since load suggests a leak
+	if stats, err := metricsCollector.CollectRuntimeStats("end_of_gameplay"); err != nil {
+		r.logf("Warning: Runtime stats failed: %v", err)
+	} else {
+		perfMetrics.Runtime = append(perfMetrics.Runtime, stats)
+		r.logf("Runtime: JS heap %.1f MB, %d DOM nodes, %d layouts",
+			float64(stats.JSHeapUsedBytes)/(1<<20), stats.DOMNodes, stats.LayoutCount)
+		if len(perfMetrics.Runtime) > 1 {
+			r.logf("JS heap grew %+.1f MB since load", float64(perfMetrics.HeapGrowthBytes())/(1<<20))
+		}
+	}
+
+	// Wait for game state to settle
+	time.Sleep(200 * time.Millisecond)
+
+	// Capture final screenshot
+	finalScreenshot, err := r.capture(agent.ContextFinal)
+	if err != nil {
+		return nil, fmt.Errorf("final screenshot failed: %w", err)
+	}
+	if err := finalScreenshot.SaveToTemp(); err != nil {
+		return nil, fmt.Errorf("failed to save final screenshot: %w", err)
+	}
+
+	visualRegression := r.compareBaseline(finalScreenshot)
+
+	// Keep the DOM too: errors are sometimes shown in HTML rather than on the canvas
+	domSnapshot, err := agent.CaptureDOM(bm.GetContext())
+	if err != nil {
+		r.logf("Warning: DOM snapshot failed: %v", err)
+	}
+
+	// Compare the on-screen score with the one read before gameplay
+	var scoreDelta string
+	if play.scoreReader != nil && play.scoreBeforeFound {
+		if scoreAfter, found, err := play.scoreReader.ReadGameScore(finalScreenshot); err != nil {
+			r.logf("Warning: Failed to read score after gameplay: %v", err)
+		} else if found {
+			scoreDelta = fmt.Sprintf("%d", scoreAfter-play.scoreBefore)
+			r.logf("Game score: %d -> %d", play.scoreBefore, scoreAfter)
+		}
+	}
+
+	// Check whether the game produced any sound
+	audioStatus, err := bm.CheckAudio()
+	if err != nil {
+		r.logf("Warning: Audio check failed: %v", err)
+	} else {
+		r.logf("Audio detected: %v (contexts: %d, sources: %d, media: %d)",
+			audioStatus.Detected, audioStatus.AudioContexts, audioStatus.SourcesStarted, audioStatus.MediaElementsPlayed)
+	}
+
+	r.progress(80, "Getting console logs...")
+
+	// Get console logs
+	logs := consoleLogger.GetLogs()
+
+	if err := r.phase("evaluation"); err != nil {
+		return nil, err
+	}
+	r.progress(90, "Evaluating with AI...")
+
+	// Evaluate with LLM
+	gameEval := opts.Evaluator
+	if gameEval == nil {
+		defaultEval, err := evaluator.NewGameEvaluator("")
+		if err != nil {
+			return nil, fmt.Errorf("evaluator initialization failed: %w", err)
+		}
+		defaultEval.SetGenre(opts.Genre)
+		gameEval = defaultEval
+	}
+
+	// Combine all screenshots: initial, gameplay screenshots, final
+	screenshots := []*agent.Screenshot{initialScreenshot}
+	screenshots = append(screenshots, play.screenshots...)
+	screenshots = append(screenshots, finalScreenshot)
+	score, err := gameEval.EvaluateGame(ctx, screenshots, logs)
+	if err != nil {
+		return nil, fmt.Errorf("evaluation failed: %w", err)
+	}
+
+	// Build report
+	viewport := agent.ViewportFromContext(bm.GetContext())
+	reportBuilder := reporter.NewReportBuilder(opts.URL)
+	for k, v := range opts.Metadata {
+		reportBuilder.AddMetadata(k, v)
+	}
+	reportBuilder.AddMetadata("headless", fmt.Sprintf("%v", opts.Headless))
+	reportBuilder.AddMetadata("viewport", fmt.Sprintf("%dx%d", viewport.Width, viewport.Height))
+	reportBuilder.AddMetadata("seed", fmt.Sprintf("%d", opts.Seed))
+	if videoUnavailable != "" {
+		reportBuilder.AddMetadata("video_unavailable", videoUnavailable)
+	}
+	if scoreDelta != "" {
+		reportBuilder.AddMetadata("score_delta", scoreDelta)
+	}
+	if play.endedReason != "" {
+		reportBuilder.AddMetadata("ended_reason", play.endedReason)
+	}
+	if opts.NetworkProfile != agent.NetworkProfileNone {
+		reportBuilder.AddMetadata("network_profile", string(opts.NetworkProfile))
+	}
+	if opts.Genre != evaluator.GenreGeneric {
+		reportBuilder.AddMetadata("genre", string(opts.Genre))
+	}
+	if opts.Replay != nil {
+		reportBuilder.AddMetadata("replayed_actions", fmt.Sprintf("%d", play.replayed))
+		reportBuilder.AddMetadata("replay_failed_actions", fmt.Sprintf("%d", play.replayFailed))
+	}
+	if play.gameType != "" {
+		reportBuilder.AddMetadata("game_type", string(play.gameType))
+	}
+	if play.result != nil {
+		reportBuilder.AddMetadata("gameplay_outcome", string(play.result.Outcome))
+		reportBuilder.AddMetadata("gameplay_attempts", fmt.Sprintf("%d", play.result.Attempts))
+		reportBuilder.AddMetadata("level_complete", fmt.Sprintf("%v", play.result.LevelComplete))
+		reportBuilder.AddMetadata("levels_completed", fmt.Sprintf("%d", play.result.LevelsCompleted))
+	}
+	if opts.AnnotateScreenshots {
+		if err := agent.AnnotateScreenshots(screenshots, agent.ActionLogFromContext(bm.GetContext()).Actions()); err != nil {
+			r.logf("Warning: Failed to annotate screenshots: %v", err)
+		}
+	}
+	reportBuilder.SetScreenshots(screenshots)
+	reportBuilder.SetConsoleLogs(logs)
+	reportBuilder.SetDOMSnapshot(domSnapshot)
+	reportBuilder.SetDetectedElements(detectedElements)
+	reportBuilder.SetActionLog(agent.ActionLogFromContext(bm.GetContext()))
+	reportBuilder.SetVisualRegression(visualRegression)
+	reportBuilder.SetScore(score)
+	reportBuilder.SetAudioStatus(audioStatus)
+	reportBuilder.SetPerformanceMetrics(perfMetrics)
+	reportBuilder.SetEvidenceBudget(opts.EvidenceBudget)
+
+	var harPath string
+	if networkRecorder != nil {
+		harPath, err = networkRecorder.SaveToTemp()
+		if err != nil {
+			r.logf("Warning: Failed to save network log: %v", err)
+		} else {
+			reportBuilder.SetNetworkLog(filepath.Base(harPath))
+			r.logf("✓ Network log saved to: %s", harPath)
+		}
+	}
+
+	// Set video URL if video was recorded
+	if videoPath != "" && opts.VideoURL != nil {
+		videoURL := opts.VideoURL(videoPath)
+		reportBuilder.SetVideoURL(videoURL)
+		r.logf("Video URL set to: %s", videoURL)
+	}
+
+	report, err := reportBuilder.Build()
+	if err != nil {
+		return nil, fmt.Errorf("report build failed: %w", err)
+	}
+	if t := report.Evidence.Truncated; t != nil {
+		r.logf("Evidence truncated (%s): dropped %d/%d screenshots, %d/%d console logs",
+			t.Reason, t.ScreenshotsDropped, t.OriginalScreenshots, t.ConsoleLogsDropped, t.OriginalConsoleLogs)
+	}
+
+	if opts.Artifacts != nil {
+		opts.Artifacts.Screenshots = screenshots
+		opts.Artifacts.VideoPath = videoPath
+		opts.Artifacts.NetworkLogPath = harPath
+		if logPath, err := consoleLogger.SaveToTemp(); err != nil {
+			r.logf("Warning: Failed to save console logs: %v", err)
+		} else {
+			opts.Artifacts.ConsoleLogPath = logPath
+		}
+	}
+
+	return report, nil
+}
+
+// startBrowser launches the session's browser. The caller must close r.bm.
+func (r *runner) startBrowser() error {
+	if err := r.phase("browser start"); err != nil {
+		return err
+	}
+	r.progress(10, "Initializing browser...")
+
+	browserOptions := r.opts.BrowserOptions
+	if r.opts.Logger != nil {
+		browserOptions = append(browserOptions, agent.WithLogger(r.opts.Logger))
+	}
+	bm, err := agent.NewBrowserManager(r.opts.Headless, browserOptions...)
+	if err != nil {
+		return fmt.Errorf("failed to create browser: %w", err)
+	}
+	r.bm = bm
+	r.capture = func(sc agent.ScreenshotContext) (*agent.Screenshot, error) {
+		return agent.CaptureScreenshot(bm.GetContext(), sc)
+	}
+
+	// Tear the browser down as soon as the test is cancelled so any in-flight
+	// chromedp call fails fast instead of running to completion
+	go func() {
+		<-r.ctx.Done()
+		bm.Close()
+	}()
+	return nil
+}
+
+// prepareBrowser installs probes, seeds storage and applies network settings before navigation
+func (r *runner) prepareBrowser() (*agent.NetworkRecorder, error) {
+	bm := r.bm
+
+	// Hook Web Audio / media playback before the game's scripts run
+	if err := bm.InstallAudioProbe(); err != nil {
+		r.logf("Warning: Could not install audio probe: %v", err)
+	}
+
+	// Seed cookies and localStorage so the game loads with the caller's session or progress
+	if len(r.opts.Cookies) > 0 || len(r.opts.LocalStorage) > 0 {
+		if err := bm.SeedStorage(r.opts.Cookies, r.opts.LocalStorage); err != nil {
+			return nil, fmt.Errorf("failed to seed browser storage: %w", err)
+		}
+		r.logf("Seeded %d cookies and %d localStorage entries", len(r.opts.Cookies), len(r.opts.LocalStorage))
+	}
+
+	// Record network traffic from the first request when the caller asked for a HAR
+	var networkRecorder *agent.NetworkRecorder
+	if r.opts.CaptureHAR {
+		var err error
+		if networkRecorder, err = bm.StartNetworkRecording(); err != nil {
+			r.logf("Warning: Could not start network recording: %v", err)
+		}
+	}
+
+	// Throttle the network before loading so load-time metrics reflect the profile
+	profile := r.opts.NetworkProfile
+	if profile != agent.NetworkProfileNone && profile != agent.NetworkProfileOfflineAfterLoad {
+		if err := bm.SetNetworkConditions(profile); err != nil {
+			return nil, fmt.Errorf("failed to set network profile: %w", err)
+		}
+		r.logf("Emulating %s network", profile)
+	}
+
+	return networkRecorder, nil
+}
+
+// loadGame navigates to the game, collects load metrics and captures the initial screenshot
+func (r *runner) loadGame() (*agent.PerformanceMetrics, *agent.Screenshot, error) {
+	bm := r.bm
+
+	if err := r.phase("navigation"); err != nil {
+		return nil, nil, err
+	}
+	r.progress(20, "Navigating to URL...")
+
+	// Navigate to URL (proxy connection failures are retried as network errors)
+	if err := agent.WithRetry(r.ctx, func() error {
+		return bm.LoadGame(r.opts.URL)
+	}); err != nil {
+		return nil, nil, fmt.Errorf("navigation failed: %w", err)
+	}
+
+	if r.opts.NetworkProfile == agent.NetworkProfileOfflineAfterLoad {
+		if err := bm.SetNetworkConditions(agent.NetworkProfileOffline); err != nil {
+			r.logf("Warning: Failed to go offline after load: %v", err)
+		} else {
+			r.logf("Game loaded, network is now offline")
+		}
+	}
+
+	// Collect load time and accessibility now; FPS is sampled during gameplay instead
+	metricsCollector := agent.NewMetricsCollector(bm.GetContext())
+	metricsCollector.SetAccessibilityTags(r.opts.AccessibilityTags)
+	metricsCollector.SetAccessibilityWeights(r.opts.AccessibilityWeights)
+	perfMetrics := metricsCollector.CollectAll()
+
+	if err := r.phase("initial screenshot"); err != nil {
+		return nil, nil, err
+	}
+	r.progress(30, "Capturing initial screenshot...")
+
+	// Capture initial screenshot
+	initialScreenshot, err := r.capture(agent.ContextInitial)
+	if err != nil {
+		return nil, nil, fmt.Errorf("screenshot failed: %w", err)
+	}
+	if err := initialScreenshot.SaveToTemp(); err != nil {
+		return nil, nil, fmt.Errorf("failed to save screenshot: %w", err)
+	}
+
+	if err := r.phase("page setup"); err != nil {
+		return nil, nil, err
+	}
+	r.progress(40, "Loading game page...")
+
+	// Wait for page load
+	time.Sleep(2 * time.Second)
+
+	// Remove ads and handle cookie consent with improved logic
+	r.logf("Removing ads and handling cookie consent...")
+	if err := bm.RemoveAdsAndCookieConsent(); err != nil {
+		r.logf("Warning: Ad blocking/cookie consent failed: %v", err)
+		if agent.IsTimeoutError(err) {
+			// A hung evaluate usually means the renderer is wedged; bail out rather than
+			// holding the test slot while every later browser call times out too
+			if pingErr := bm.Ping(); pingErr != nil {
+				return nil, nil, fmt.Errorf("browser became unresponsive: %w", pingErr)
+			}
+		}
+	} else {
+		r.logf("Ad blocking and cookie consent handling completed")
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	// Portal pages often wrap the game in an iframe; from here on, screenshots and
+	// clicks target the game rather than the wrapper
+	if frame, err := bm.DetectGameFrame(); err != nil {
+		r.logf("Warning: Game frame detection failed: %v", err)
+	} else if frame != nil {
+		r.logf("Game runs in an iframe (%dx%d); routing screenshots and clicks to it", frame.Width, frame.Height)
+	}
+
+	return perfMetrics, initialScreenshot, nil
+}
+
+// startGame clicks the start button and waits until vision confirms gameplay has begun
+func (r *runner) startGame() error {
+	bm := r.bm
+
+	if err := r.phase("game start"); err != nil {
+		return err
+	}
+	r.progress(50, "Starting game...")
+
+	// Use vision + DOM to detect and click start button
+	r.logf("Using GPT-4o vision + DOM to detect and click start button...")
+	var err error
+	if r.opts.NewVisionDetector != nil {
+		r.vision, err = r.opts.NewVisionDetector(bm.GetContext())
+	} else {
+		var visionDOMDetector *agent.VisionDOMDetector
+		if visionDOMDetector, err = agent.NewVisionDOMDetector(bm.GetContext()); err == nil {
+			r.vision = visionDOMDetector
+		}
+	}
+	startButtonClicked := false
+	if err != nil {
+		r.vision = nil
+		r.logf("Warning: Could not create vision DOM detector: %v", err)
+		r.logf("Falling back to DOM-only start button detection...")
+	} else {
+		// Take screenshot for vision analysis
+		visionScreenshot, err := r.capture(agent.ContextInitial)
+		if err != nil {
+			r.logf("Warning: Could not capture screenshot for vision: %v", err)
+		} else {
+			// Detect and click start button
+			err := r.vision.DetectAndClickStartButton(visionScreenshot)
+			if err != nil {
+				r.logf("Warning: Vision+DOM start button click failed: %v", err)
+				r.logf("Falling back to DOM-only start button detection...")
+			} else {
+				r.logf("✓ Vision+DOM successfully clicked start button")
+				startButtonClicked = true
+				time.Sleep(300 * time.Millisecond) // Wait for click to register
+			}
+		}
+	}
+
+	// Fallback: Try simple DOM-based start button detection
+	if !startButtonClicked {
+		r.logf("Trying DOM-based start button detection...")
+		result, err := r.detector.ClickStartButton()
+		if err != nil {
+			r.logf("Warning: DOM start button detection failed: %v", err)
+			r.logf("Game may require manual start or will auto-start")
+		} else if result.Clicked {
+			if result.Method == "button" {
+				r.logf("✓ DOM clicked start button %q (%s, score %.2f of %d candidates)",
+					result.Text, result.Selector, result.Score, result.Candidates)
+			} else {
+				r.logf("✓ DOM clicked the game %s (no start button scored %.2f or more)", result.Method, agent.MinStartButtonScore)
+			}
+			time.Sleep(300 * time.Millisecond) // Wait for click to register
+		} else if result.Candidates > 0 {
+			r.logf("No confident start button (best %s scored %.2f) - game may auto-start", result.Selector, result.Score)
+		} else {
+			r.logf("No start button found - game may auto-start")
+		}
+	}
+
+	if err := bm.Ping(); err != nil {
+		return fmt.Errorf("browser became unresponsive: %w", err)
+	}
+
+	if err := r.phase("gameplay"); err != nil {
+		return err
+	}
+	r.progress(55, "Waiting for game to load...")
+
+	r.waitForGameplay()
+	return nil
+}
+
+// waitForGameplay keeps checking whether the game has started, using vision to suggest
+// (and click) the next action if not
+func (r *runner) waitForGameplay() {
+	maxAttempts := 10
+	gameStarted := false
+	var lastDescription string
+	// lastAnalyzed is the last screenshot sent to vision
+	var lastAnalyzed *agent.Screenshot
+	repeatedScreenCount := 0
+
+	for attempt := 1; attempt <= maxAttempts && !gameStarted && r.ctx.Err() == nil; attempt++ {
+		r.logf("Gameplay detection attempt %d/%d...", attempt, maxAttempts)
+
+		// Wait for UI to settle (reduced for faster detection)
+		waitTime := 300 * time.Millisecond
+		if repeatedScreenCount > 0 {
+			// If we're seeing the same screen repeatedly, wait a bit longer
+			waitTime = 500 * time.Millisecond
+			r.logf("Seeing repeated screen, waiting %v for animations...", waitTime)
+		}
+		time.Sleep(waitTime)
+
+		// Take screenshot for vision analysis
+		screenshot, err := r.capture(agent.ContextInitial)
+		if err != nil {
+			r.logf("Warning: Could not capture screenshot for gameplay detection: %v", err)
+			break
+		}
+
+		// No vision available, assume game started after first attempt
+		if r.vision == nil {
+			gameStarted = true
+			break
+		}
+
+		// Skip vision API if the screen looks the same as the last one analyzed (animation
+		// noise aside)
+		if screenshot.SimilarTo(lastAnalyzed, agent.DefaultSimilarityThreshold) {
+			r.logf("⚡ Screenshot unchanged (perceptual match), skipping vision API call")
+			repeatedScreenCount++
+			continue
+		}
+		lastAnalyzed = screenshot
+
+		// Ask vision AI: "Is the game actively playing, or do we need to click something?"
+		action, err := r.vision.DetectGameplayState(screenshot, r.opts.GameMechanics)
+		if err != nil {
+			r.logf("Warning: Vision gameplay detection failed: %v", err)
+			// Continue anyway - might be playing
+			gameStarted = true
+			break
+		}
+
+		if action.GameStarted {
+			r.logf("✓ Vision confirmed game is playing!")
+			gameStarted = true
+			break
+		}
+		if !action.ActionNeeded {
+			r.logf("Vision suggests waiting for game to initialize...")
+			continue
+		}
+
+		r.logf("Vision detected action needed: %s", action.Description)
+
+		// Track repeated screens to detect stuck states
+		if action.Description == lastDescription {
+			repeatedScreenCount++
+			r.logf("⚠ Same screen detected %d times in a row", repeatedScreenCount)
+		} else {
+			repeatedScreenCount = 0
+			lastDescription = action.Description
+		}
+
+		// Inspect canvas coordinates on first attempt for debugging
+		if attempt == 1 {
+			if err := r.vision.InspectCanvasCoordinates(); err != nil {
+				r.logf("Canvas inspection failed: %v", err)
+			}
+		}
+
+		// Try coordinate-based click first (works for canvas-rendered buttons)
+		if action.ClickX > 0 && action.ClickY > 0 {
+			// If we've seen the same screen multiple times, try small coordinate variations
+			clickX := action.ClickX
+			clickY := action.ClickY
+
+			if repeatedScreenCount > 2 {
+				// Add random variation of ±10 pixels to try hitting the button from different angles
+				variation := 10
+				offsetX := (repeatedScreenCount % 3) - 1 // -1, 0, or 1
+				offsetY := ((repeatedScreenCount / 3) % 3) - 1
+				clickX += offsetX * variation
+				clickY += offsetY * variation
+				r.logf("Trying coordinate variation: (%d, %d) -> (%d, %d)", action.ClickX, action.ClickY, clickX, clickY)
+			}
+
+			r.logf("Attempting to click at coordinates: (%d, %d)", clickX, clickY)
+
+			// Save screenshot with visual marker showing where we're clicking
+			markerLabel := fmt.Sprintf("attempt%d", attempt)
+			markerPath, markerErr := agent.SaveScreenshotWithClickMarker(screenshot, clickX, clickY, markerLabel)
+			if markerErr != nil {
+				r.logf("Warning: Could not save click marker screenshot: %v", markerErr)
+			} else {
+				r.logf("📍 Saved screenshot with click marker: %s", markerPath)
+			}
+			if err := r.vision.ClickAt(clickX, clickY); err != nil {
+				r.logf("Warning: Coordinate click failed: %v", err)
+			} else {
+				r.logf("✓ Clicked at vision-suggested coordinates")
+				continue // Continue to next iteration to check if game started
+			}
+		}
+
+		// Fallback to DOM text-based click (works for HTML buttons)
+		if action.ButtonText != "" {
+			r.logf("Attempting DOM click for button text: %s", action.ButtonText)
+			if err := r.vision.ClickButtonByText(action.ButtonText); err != nil {
+				r.logf("Warning: Could not click suggested button: %v", err)
+				// Try clicking the canvas as final fallback
+				r.logf("Fallback: clicking canvas center...")
+				if focused, focusErr := r.detector.FocusGameCanvas(); focusErr == nil && focused {
+					time.Sleep(200 * time.Millisecond)
+				}
+			} else {
+				r.logf("✓ Clicked suggested button: %s", action.ButtonText)
+			}
+		}
+	}
+
+	if !gameStarted {
+		r.logf("Could not confirm game started after %d attempts, proceeding anyway...", maxAttempts)
+	}
+
+	if cached, ok := r.vision.(interface{ CacheStats() (hits, misses int) }); ok {
+		hits, misses := cached.CacheStats()
+		r.logf("Vision cache: %d hits, %d misses", hits, misses)
+	}
+}
+
+// phase records the stage the session is entering, or returns ErrCancelled if the
+// session was cancelled before it
+func (r *runner) phase(name string) error {
+	if r.ctx.Err() != nil {
+		r.logf("Test cancelled, aborting before %s", name)
+		return fmt.Errorf("%w before %s", ErrCancelled, name)
+	}
+	if r.opts.Logger != nil {
+		r.opts.Logger.SetPhase(name)
+	}
+	return nil
+}
+
+// progress reports a stage change to the caller
+func (r *runner) progress(percent int, message string) {
+	if r.opts.Progress != nil {
+		r.opts.Progress(percent, message)
+	}
+}
+
+// logf logs through the session's logger
+func (r *runner) logf(format string, args ...any) {
+	if r.opts.Logger != nil {
+		r.opts.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,158 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/agent"
+	"github.com/dreamup/qa-agent/internal/evaluator"
+)
+
+// testGamePage draws on a canvas and logs to the console, like a minimal game
+const testGamePage = `<!DOCTYPE html>
+<html><body style="margin:0">
+<canvas id="game" width="640" height="360"></canvas>
+<script>
+const ctx = document.getElementById('game').getContext('2d');
+ctx.fillStyle = '#2a6'; ctx.fillRect(0, 0, 640, 360);
+ctx.fillStyle = '#fc3'; ctx.fillRect(260, 140, 120, 80);
+console.log('game loaded');
+window.addEventListener('keydown', e => console.log('key ' + e.key));
+</script>
+</body></html>`
+
+// browserOptions returns the options to launch Chrome with, skipping the test if there
+// is no Chrome to launch
+func browserOptions(t *testing.T) []agent.BrowserOption {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("launches Chrome")
+	}
+	if os.Getenv("CHROME_PATH") == "" {
+		found := false
+		for _, name := range []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome", "headless_shell"} {
+			if _, err := exec.LookPath(name); err == nil {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Skip("Chrome not found (set CHROME_PATH)")
+		}
+	}
+	options, err := agent.ChromeOptionsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return options
+}
+
+// runTestSession runs a short session against testGamePage with the given fakes. Media is
+// written under a temporary working directory.
+func runTestSession(t *testing.T, detector *FakeDetector, eval *FakeEvaluator) (*evaluator.PlayabilityScore, []string, error) {
+	t.Helper()
+	options := browserOptions(t)
+	t.Chdir(t.TempDir())
+
+	game := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, testGamePage)
+	}))
+	defer game.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	report, err := RunSession(ctx, Options{
+		URL:            game.URL,
+		Headless:       true,
+		MaxDuration:    2 * time.Second,
+		Controls:       []string{"ArrowRight"},
+		BrowserOptions: options,
+		NewDetector:    NewFakeDetector(detector),
+		NewVisionDetector: func(ctx context.Context) (VisionDetector, error) {
+			return nil, errors.New("vision disabled in tests")
+		},
+		Evaluator: eval,
+	})
+	if err != nil && strings.Contains(err.Error(), "chrome failed to start") {
+		t.Skipf("Chrome can't run here: %v", err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if report.Evidence == nil || len(report.Evidence.Screenshots) < 2 {
+		t.Fatalf("report has too little evidence: %+v", report.Evidence)
+	}
+	var logs []string
+	for _, log := range report.Evidence.ConsoleLogs {
+		logs = append(logs, log.Message)
+	}
+	return report.Score, logs, nil
+}
+
+func TestRunSession(t *testing.T) {
+	want := &evaluator.PlayabilityScore{OverallScore: 75, LoadsCorrectly: true, Reasoning: "fake"}
+	detector := &FakeDetector{StartButton: true, Canvas: true}
+	eval := &FakeEvaluator{Score: want}
+
+	score, logs, err := runTestSession(t, detector, eval)
+	if err != nil {
+		t.Fatalf("RunSession: %v", err)
+	}
+	if score == nil || score.OverallScore != want.OverallScore {
+		t.Errorf("report score = %+v, want %+v", score, want)
+	}
+	if screenshots, _ := eval.Evidence(); screenshots < 2 {
+		t.Errorf("evaluator got %d screenshots, want the initial and final ones at least", screenshots)
+	}
+	if !containsString(logs, "game loaded") {
+		t.Errorf("report console logs %q don't include the game's log", logs)
+	}
+	if keys := detector.Keys(); len(keys) == 0 || keys[0] != "ArrowRight" {
+		t.Errorf("gameplay sent keys %q, want ArrowRight", keys)
+	}
+}
+
+func TestRunSessionDetectorFailure(t *testing.T) {
+	// A failing detector costs the test its input, not its report
+	detector := &FakeDetector{Err: errors.New("page went away")}
+	eval := &FakeEvaluator{Score: &evaluator.PlayabilityScore{OverallScore: 20}}
+
+	score, _, err := runTestSession(t, detector, eval)
+	if err != nil {
+		t.Fatalf("RunSession: %v", err)
+	}
+	if score == nil || score.OverallScore != 20 {
+		t.Errorf("report score = %+v, want the evaluator's", score)
+	}
+	if keys := detector.Keys(); len(keys) != 0 {
+		t.Errorf("failing detector recorded keys %q", keys)
+	}
+}
+
+func TestRunSessionEvaluatorFailure(t *testing.T) {
+	eval := &FakeEvaluator{Err: errors.New("rate limited")}
+
+	_, _, err := runTestSession(t, &FakeDetector{Canvas: true}, eval)
+	if err == nil || !strings.Contains(err.Error(), "evaluation failed") || !strings.Contains(err.Error(), "rate limited") {
+		t.Fatalf("RunSession error = %v, want the evaluation failure", err)
+	}
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+// Package client provides a typed Go client for the DreamUp QA HTTP API.
+//
+// The request and response types in this package are the same types the server
+// uses, so integrations stay in sync with the API as it evolves. Reports are decoded
+// into Report, which mirrors the server's report JSON.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultPollInterval is how often WaitForCompletion checks test status
+const DefaultPollInterval = 2 * time.Second
+
+// Client talks to a DreamUp QA API server
+type Client struct {
+	// BaseURL is the server root, e.g. http://localhost:8080
+	BaseURL string
+	// HTTPClient is used for all requests (defaults to a client with a 30s timeout)
+	HTTPClient *http.Client
+	// Token is sent as a bearer token when the server requires API_AUTH_TOKEN
+	Token string
+}
+
+// APIError is returned when the server responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error (%d): %s", e.StatusCode, e.Message)
+}
+
+// New creates a client for the server at baseURL
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SubmitTest queues a single game test
+func (c *Client) SubmitTest(ctx context.Context, req TestRequest) (*TestResponse, error) {
+	var resp TestResponse
+	if err := c.do(ctx, http.MethodPost, "/api/tests", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RerunTest queues a new test with the same configuration as testID
+func (c *Client) RerunTest(ctx context.Context, testID string) (*RerunResponse, error) {
+	var resp RerunResponse
+	if err := c.do(ctx, http.MethodPost, "/api/tests/"+url.PathEscape(testID)+"/rerun", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ReplayTest queues a new test that replays testID's recorded actions with their original
+// timing, instead of playing the game
+func (c *Client) ReplayTest(ctx context.Context, testID string) (*ReplayResponse, error) {
+	var resp ReplayResponse
+	if err := c.do(ctx, http.MethodPost, "/api/tests/"+url.PathEscape(testID)+"/replay", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetStatus returns the current status of a test
+func (c *Client) GetStatus(ctx context.Context, testID string) (*TestStatus, error) {
+	var status TestStatus
+	if err := c.do(ctx, http.MethodGet, "/api/tests/"+url.PathEscape(testID), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// WaitForCompletion polls a test until it finishes or ctx is done.
+// A pollInterval of zero uses DefaultPollInterval.
+func (c *Client) WaitForCompletion(ctx context.Context, testID string, pollInterval time.Duration) (*TestStatus, error) {
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		status, err := c.GetStatus(ctx, testID)
+		if err != nil {
+			return nil, err
+		}
+		if status.IsFinished() {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// CancelTest cancels a queued or running test.
+// The server responds with 409 Conflict if the test has already finished.
+func (c *Client) CancelTest(ctx context.Context, testID string) (*TestStatus, error) {
+	var status TestStatus
+	if err := c.do(ctx, http.MethodDelete, "/api/tests/"+url.PathEscape(testID), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetReport returns the report for a completed test
+func (c *Client) GetReport(ctx context.Context, testID string) (*Report, error) {
+	var report Report
+	if err := c.do(ctx, http.MethodGet, "/api/reports/"+url.PathEscape(testID), nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// SubmitBatch queues a batch of up to 10 game tests
+func (c *Client) SubmitBatch(ctx context.Context, req BatchTestRequest) (*BatchTestResponse, error) {
+	var resp BatchTestResponse
+	if err := c.do(ctx, http.MethodPost, "/api/batch-tests", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetBatchStatus returns the aggregated status of a batch
+func (c *Client) GetBatchStatus(ctx context.Context, batchID string) (*BatchTestStatus, error) {
+	var status BatchTestStatus
+	if err := c.do(ctx, http.MethodGet, "/api/batch-tests/"+url.PathEscape(batchID), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// do sends a JSON request and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dreamup/qa-agent/internal/reporter"
+)
+
+var fillTime = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// fill sets every exported field reachable from v to a non-zero value, so omitempty
+// fields are encoded too
+func fill(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem())
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(fillTime) {
+			v.Set(reflect.ValueOf(fillTime))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).CanSet() {
+				fill(v.Field(i))
+			}
+		}
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), 1, 1)
+		fill(s.Index(0))
+		v.Set(s)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		key, elem := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+		fill(key)
+		fill(elem)
+		m.SetMapIndex(key, elem)
+		v.Set(m)
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(7)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(7)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(0.5)
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Interface:
+		v.Set(reflect.ValueOf("x"))
+	}
+}
+
+// TestReportMirrorsServerReport catches fields added to the server's report that Report
+// doesn't decode
+func TestReportMirrorsServerReport(t *testing.T) {
+	var server reporter.Report
+	fill(reflect.ValueOf(&server).Elem())
+	serverJSON, err := json.Marshal(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(serverJSON, &report); err != nil {
+		t.Fatalf("decoding server report: %v", err)
+	}
+	clientJSON, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want, got interface{}
+	if err := json.Unmarshal(serverJSON, &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(clientJSON, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Report lost fields of the server's report\nserver: %s\nclient: %s", serverJSON, clientJSON)
+	}
+}
+
+func TestGetReport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/reports/test-1" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"report_id": "test-1",
+			"game_url":  "https://example.com/game",
+			"score":     map[string]interface{}{"overall_score": 82, "loads_correctly": true},
+			"summary":   map[string]interface{}{"status": "passed"},
+		})
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	report, err := c.GetReport(context.Background(), "test-1")
+	if err != nil {
+		t.Fatalf("GetReport: %v", err)
+	}
+	if report.ReportID != "test-1" || report.Score == nil || report.Score.OverallScore != 82 || report.Summary.Status != "passed" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	_, err = c.GetReport(context.Background(), "missing")
+	if apiErr, ok := err.(*APIError); !ok || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("GetReport(missing) error = %v, want a 404 APIError", err)
+	}
+}
@@ -0,0 +1,242 @@
+package client
+
+import "time"
+
+// Report is a completed test's report, as returned by GET /api/reports/{id}
+type Report struct {
+	ReportID  string    `json:"report_id"`
+	GameURL   string    `json:"game_url"`
+	Timestamp time.Time `json:"timestamp"`
+	// Duration is how long the test took (encoded in nanoseconds despite the field name)
+	Duration time.Duration `json:"duration_ms"`
+	// Score is the LLM evaluation (nil if the game wasn't evaluated)
+	Score    *PlayabilityScore `json:"score,omitempty"`
+	Evidence *Evidence         `json:"evidence"`
+	Summary  *Summary          `json:"summary"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// PlayabilityScore is the LLM's evaluation of a game
+type PlayabilityScore struct {
+	OverallScore       int             `json:"overall_score"`       // 0-100
+	LoadsCorrectly     bool            `json:"loads_correctly"`     // Loaded without errors
+	InteractivityScore int             `json:"interactivity_score"` // 0-100
+	VisualQuality      int             `json:"visual_quality"`      // 0-100
+	ErrorSeverity      int             `json:"error_severity"`      // 0-100, 0 = no errors
+	Reasoning          string          `json:"reasoning"`
+	Issues             []string        `json:"issues"`
+	Recommendations    []string        `json:"recommendations"`
+	IssueEvidence      []IssueEvidence `json:"issue_evidence"`
+	// Confidence is how sure the evaluator is of its scores (0-1, 0 if not reported)
+	Confidence float64 `json:"confidence"`
+	// Ensemble describes the runs behind an ensemble evaluation (nil for a single run)
+	Ensemble *EnsembleStats `json:"ensemble,omitempty"`
+}
+
+// IssueEvidence points an issue at the screenshot context (initial, gameplay or final)
+// or console log it's based on
+type IssueEvidence struct {
+	Issue      string `json:"issue"`
+	Screenshot string `json:"screenshot"`
+	LogSnippet string `json:"log_snippet"`
+}
+
+// EnsembleStats describes the runs behind an ensemble evaluation
+type EnsembleStats struct {
+	Runs          int      `json:"runs"`
+	Failed        int      `json:"failed,omitempty"`
+	Models        []string `json:"models,omitempty"`
+	OverallScores []int    `json:"overall_scores"`
+	Variance      float64  `json:"variance"`
+	StdDev        float64  `json:"std_dev"`
+}
+
+// Evidence holds a report's test artifacts
+type Evidence struct {
+	Screenshots        []ScreenshotInfo    `json:"screenshots"`
+	VideoURL           string              `json:"video_url,omitempty"`
+	ConsoleLogs        []ConsoleLog        `json:"console_logs"`
+	LogSummary         LogSummary          `json:"log_summary"`
+	DetectedElements   map[string]string   `json:"detected_elements,omitempty"`
+	Audio              *AudioStatus        `json:"audio,omitempty"`
+	PerformanceMetrics *PerformanceMetrics `json:"performance_metrics,omitempty"`
+	// Truncated is set when evidence was sampled down to fit the server's evidence budget
+	Truncated *TruncationInfo `json:"truncated,omitempty"`
+	// NetworkLog is the HAR capture's filename, served at /api/reports/{id}/har
+	NetworkLog  string `json:"network_log,omitempty"`
+	DOMSnapshot string `json:"dom_snapshot,omitempty"`
+	// VisualRegression compares the final screen with the game's baseline (nil if it has none)
+	VisualRegression *VisualRegression `json:"visual_regression,omitempty"`
+	// Actions are the clicks, drags, key and gamepad events sent during the test, in order
+	Actions        []RecordedAction `json:"actions,omitempty"`
+	ActionsDropped int              `json:"actions_dropped,omitempty"`
+}
+
+// ScreenshotInfo describes a report screenshot
+type ScreenshotInfo struct {
+	Context           string    `json:"context"` // initial, gameplay or final
+	Filepath          string    `json:"filepath"`
+	S3URL             string    `json:"s3_url,omitempty"`
+	AnnotatedFilepath string    `json:"annotated_filepath,omitempty"`
+	Timestamp         time.Time `json:"timestamp"`
+	Width             int       `json:"width"`
+	Height            int       `json:"height"`
+}
+
+// ConsoleLog is a browser console message (the server encodes these with Go field names)
+type ConsoleLog struct {
+	Level       string
+	Message     string
+	Timestamp   time.Time
+	Source      string
+	Args        []interface{}
+	StackTrace  string `json:"StackTrace,omitempty"`
+	Occurrences int    `json:"Occurrences,omitempty"`
+}
+
+// LogSummary counts a test's console logs
+type LogSummary struct {
+	Total            int           `json:"total"`
+	Errors           int           `json:"errors"`
+	Warnings         int           `json:"warnings"`
+	Info             int           `json:"info"`
+	Debug            int           `json:"debug"`
+	Exceptions       int           `json:"exceptions"`
+	UniqueErrors     int           `json:"unique_errors"`
+	UniqueWarnings   int           `json:"unique_warnings"`
+	UniqueExceptions int           `json:"unique_exceptions"`
+	TopRepeated      []RepeatedLog `json:"top_repeated,omitempty"`
+}
+
+// RepeatedLog is a console message logged many times
+type RepeatedLog struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// AudioStatus describes whether the game produced sound
+type AudioStatus struct {
+	Detected             bool `json:"detected"`
+	AudioContexts        int  `json:"audio_contexts"`
+	RunningContexts      int  `json:"running_contexts"`
+	SourcesStarted       int  `json:"sources_started"`
+	MediaElementsPlayed  int  `json:"media_elements_played"`
+	MediaElementsPlaying int  `json:"media_elements_playing"`
+}
+
+// PerformanceMetrics groups a test's performance and accessibility measurements
+type PerformanceMetrics struct {
+	FPS           *FPSMetrics           `json:"fps,omitempty"`
+	LoadTime      *LoadTimeMetrics      `json:"load_time,omitempty"`
+	Accessibility *AccessibilityMetrics `json:"accessibility,omitempty"`
+	Runtime       []*RuntimeStats       `json:"runtime,omitempty"`
+	// Errors records sub-collections that failed, so partial results are still reported
+	Errors      []string  `json:"errors,omitempty"`
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+// FPSMetrics summarizes the frame rate sampled during gameplay
+type FPSMetrics struct {
+	Average    float64   `json:"average"`
+	Min        float64   `json:"min"`
+	Max        float64   `json:"max"`
+	P1         float64   `json:"p1"`
+	P50        float64   `json:"p50"`
+	Frames     []float64 `json:"frames"`
+	DurationMs float64   `json:"duration_ms"`
+}
+
+// LoadTimeMetrics are page load timings in milliseconds
+type LoadTimeMetrics struct {
+	TimeToFirstByte        float64 `json:"time_to_first_byte_ms"`
+	DOMContentLoaded       float64 `json:"dom_content_loaded_ms"`
+	LoadComplete           float64 `json:"load_complete_ms"`
+	FirstPaint             float64 `json:"first_paint_ms"`
+	FirstContentfulPaint   float64 `json:"first_contentful_paint_ms"`
+	LargestContentfulPaint float64 `json:"largest_contentful_paint_ms"`
+	CumulativeLayoutShift  float64 `json:"cumulative_layout_shift"`
+	TotalBlockingTime      float64 `json:"total_blocking_time_ms"`
+}
+
+// AccessibilityMetrics are the results of the axe accessibility audit
+type AccessibilityMetrics struct {
+	Score         int                  `json:"score"`
+	Penalty       float64              `json:"penalty"`
+	Violations    int                  `json:"violations"`
+	Critical      int                  `json:"critical"`
+	Serious       int                  `json:"serious"`
+	Moderate      int                  `json:"moderate"`
+	Minor         int                  `json:"minor"`
+	AffectedNodes int                  `json:"affected_nodes"`
+	Issues        []AccessibilityIssue `json:"issues,omitempty"`
+}
+
+// AccessibilityIssue is a violated axe rule
+type AccessibilityIssue struct {
+	ID          string `json:"id"`
+	Impact      string `json:"impact"`
+	Description string `json:"description"`
+	Nodes       int    `json:"nodes"`
+}
+
+// RuntimeStats is a sample of the page's resource use
+type RuntimeStats struct {
+	Label            string    `json:"label"`
+	JSHeapUsedBytes  int64     `json:"js_heap_used_bytes"`
+	JSHeapTotalBytes int64     `json:"js_heap_total_bytes"`
+	DOMNodes         int64     `json:"dom_nodes"`
+	Documents        int64     `json:"documents"`
+	JSEventListeners int64     `json:"js_event_listeners"`
+	LayoutCount      int64     `json:"layout_count"`
+	RecalcStyleCount int64     `json:"recalc_style_count"`
+	ScriptDurationMs float64   `json:"script_duration_ms"`
+	TaskDurationMs   float64   `json:"task_duration_ms"`
+	CollectedAt      time.Time `json:"collected_at"`
+}
+
+// TruncationInfo describes evidence sampled out of a report
+type TruncationInfo struct {
+	OriginalScreenshots int    `json:"original_screenshots"`
+	ScreenshotsDropped  int    `json:"screenshots_dropped"`
+	OriginalConsoleLogs int    `json:"original_console_logs"`
+	ConsoleLogsDropped  int    `json:"console_logs_dropped"`
+	Reason              string `json:"reason"`
+}
+
+// VisualRegression compares a test's final screen with the game's baseline
+type VisualRegression struct {
+	BaselineReportID   string    `json:"baseline_report_id,omitempty"`
+	BaselineCapturedAt time.Time `json:"baseline_captured_at"`
+	DiffRatio          float64   `json:"diff_ratio"` // Fraction of pixels that changed (0-1)
+	Threshold          float64   `json:"threshold"`
+	Regressed          bool      `json:"regressed"`
+	DiffImage          string    `json:"diff_image,omitempty"`
+}
+
+// RecordedAction is an input event sent to the game
+type RecordedAction struct {
+	Timestamp  time.Time `json:"timestamp"`
+	OffsetMs   int64     `json:"offset_ms"`
+	Kind       string    `json:"kind"` // click, drag, key, key_down, key_up, gamepad_button or gamepad_axis
+	X          int       `json:"x,omitempty"`
+	Y          int       `json:"y,omitempty"`
+	EndX       int       `json:"end_x,omitempty"`
+	EndY       int       `json:"end_y,omitempty"`
+	Selector   string    `json:"selector,omitempty"`
+	Text       string    `json:"text,omitempty"`
+	Key        string    `json:"key,omitempty"`
+	Target     string    `json:"target,omitempty"`
+	Index      int       `json:"index,omitempty"`
+	Value      float64   `json:"value,omitempty"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	HoldMs     int64     `json:"hold_ms,omitempty"`
+}
+
+// Summary is a report's high-level outcome
+type Summary struct {
+	Status         string   `json:"status"` // passed, failed or error
+	PassedChecks   []string `json:"passed_checks"`
+	FailedChecks   []string `json:"failed_checks"`
+	CriticalIssues []string `json:"critical_issues"`
+}
@@ -5,6 +5,9 @@ set -e
 
 echo "🏗️  Building Lambda function..."
 
+# Embed the pinned axe-core bundle so accessibility audits work offline
+[ -f internal/agent/axe/axe.min.js ] || sh scripts/fetch-axe.sh
+
 # Build for Linux AMD64
 GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -tags lambda.norpc -o bootstrap cmd/lambda/main.go
 
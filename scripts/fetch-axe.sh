@@ -0,0 +1,14 @@
+#!/bin/sh
+# Download the pinned axe-core bundle that accessibility audits embed (see internal/agent/axe)
+
+set -e
+
+VERSION=$(sed -n 's/^const axeCoreVersion = "\(.*\)"$/\1/p' internal/agent/axe.go)
+if [ -z "$VERSION" ]; then
+    echo "❌ Could not read axeCoreVersion from internal/agent/axe.go" >&2
+    exit 1
+fi
+
+echo "⬇️  Fetching axe-core $VERSION..."
+wget -q -O internal/agent/axe/axe.min.js "https://cdnjs.cloudflare.com/ajax/libs/axe-core/$VERSION/axe.min.js"
+echo "✅ Saved internal/agent/axe/axe.min.js"